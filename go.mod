@@ -9,9 +9,11 @@ require (
 	github.com/alexflint/go-filemutex v1.2.0
 	github.com/docker/docker v24.0.4+incompatible
 	github.com/gen2brain/beeep v0.0.0-20230602101333-f384c29b62dd
+	github.com/google/go-containerregistry v0.16.1
 	github.com/gookit/color v1.5.3
 	github.com/hectane/go-acl v0.0.0-20190604041725-da78bae5fc95
 	github.com/hokaccha/go-prettyjson v0.0.0-20211117102719-0474bc63780f
+	github.com/klauspost/compress v1.17.4
 	github.com/mattn/go-zglob v0.0.4
 	github.com/mitchellh/ioprogress v0.0.0-20180201004757-6a23b12fa88e
 	github.com/moby/term v0.5.0
@@ -26,6 +28,7 @@ require (
 	golang.org/x/net v0.12.0
 	golang.org/x/sync v0.3.0
 	golang.org/x/term v0.10.0
+	google.golang.org/protobuf v1.30.0
 )
 
 // TODO: Revert when https://github.com/otiai10/copy/pull/94 is merged
@@ -37,6 +40,7 @@ require (
 	github.com/Azure/go-ansiterm v0.0.0-20230124172434-306776ec8161 // indirect
 	github.com/Microsoft/go-winio v0.6.1 // indirect
 	github.com/containerd/console v1.0.4-0.20230313162750-1ae8d489ac81 // indirect
+	github.com/containerd/stargz-snapshotter/estargz v0.14.3 // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/docker/distribution v2.8.2+incompatible // indirect
@@ -58,6 +62,7 @@ require (
 	github.com/spf13/afero v1.9.5 // indirect
 	github.com/stretchr/objx v0.5.0 // indirect
 	github.com/tadvi/systray v0.0.0-20190226123456-11a2b8fa57af // indirect
+	github.com/vbatts/tar-split v0.11.3 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	golang.org/x/mod v0.10.0 // indirect
 	golang.org/x/time v0.3.0 // indirect
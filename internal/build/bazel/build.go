@@ -30,6 +30,9 @@ type BuilderOptions struct {
 	Stderr     io.Writer
 	TempDir    string
 	Verbose    bool
+	// The directory in which bazel is executed, the current working
+	// directory if empty
+	WorkDir string
 }
 
 func (opts *BuilderOptions) Validate() error {
@@ -63,12 +66,12 @@ func NewBuilder(opts *BuilderOptions) (*Builder, error) {
 		return nil, err
 	}
 
-	err = checkCIFuzzBazelRepoCommit()
+	err = checkCIFuzzBazelRepoCommit(opts.WorkDir)
 	if err != nil {
 		return nil, err
 	}
 
-	err = checkRulesFuzzingVersion()
+	err = checkRulesFuzzingVersion(opts.WorkDir)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +100,7 @@ func (b *Builder) BuildForRun(fuzzTests []string) ([]*build.Result, error) {
 		// if the fuzz test name  appended with "_bin" is a valid target
 		// and use that in that case
 		cmd := exec.Command("bazel", "query", fuzzTests[i]+"_bin")
+		cmd.Dir = b.WorkDir
 		err := cmd.Run()
 		if err == nil {
 			binLabels = append(binLabels, fuzzTests[i]+"_bin")
@@ -112,6 +116,7 @@ func (b *Builder) BuildForRun(fuzzTests []string) ([]*build.Result, error) {
 	// When building via bazel, the "output_base" directory contains
 	// all artifacts, so we use that as the BuildDir.
 	cmd := exec.Command("bazel", "info", "output_base")
+	cmd.Dir = b.WorkDir
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, cmdutils.WrapExecError(errors.WithStack(err), cmd)
@@ -176,6 +181,7 @@ func (b *Builder) BuildForRun(fuzzTests []string) ([]*build.Result, error) {
 	args = append(args, binLabels...)
 
 	cmd = exec.Command("bazel", args...)
+	cmd.Dir = b.WorkDir
 	cmd.Stdout = b.Stdout
 	cmd.Stderr = b.Stderr
 	if err != nil {
@@ -192,13 +198,12 @@ func (b *Builder) BuildForRun(fuzzTests []string) ([]*build.Result, error) {
 
 	for _, fuzzTest := range fuzzTests {
 		// Turn the fuzz test label into a valid path
-		path, err := PathFromLabel(fuzzTest, commonFlags)
+		path, err := pathFromLabel(fuzzTest, commonFlags, b.WorkDir)
 		if err != nil {
 			return nil, err
 		}
 		seedCorpus := filepath.Join(b.ProjectDir, path+"_inputs")
-		generatedCorpusBasename := "." + filepath.Base(path) + "_cifuzz_corpus"
-		generatedCorpus := filepath.Join(b.ProjectDir, filepath.Dir(path), generatedCorpusBasename)
+		generatedCorpus := GeneratedCorpusDir(b.ProjectDir, path)
 
 		result := &build.Result{
 			Name:            path,
@@ -312,6 +317,7 @@ func (b *Builder) BuildForBundle(sanitizers []string, fuzzTests []string) ([]*bu
 	args = append(args, labels...)
 
 	cmd := exec.Command("bazel", args...)
+	cmd.Dir = b.WorkDir
 	cmd.Stdout = b.Stdout
 	cmd.Stderr = b.Stderr
 	log.Debugf("Command: %s", cmd.String())
@@ -330,6 +336,7 @@ func (b *Builder) BuildForBundle(sanitizers []string, fuzzTests []string) ([]*bu
 		args = append(args, buildAndCQueryFlags...)
 		args = append(args, fuzzTest+"_oss_fuzz")
 		cmd = exec.Command("bazel", args...)
+		cmd.Dir = b.WorkDir
 		out, err := cmd.Output()
 		if err != nil {
 			return nil, cmdutils.WrapExecError(errors.WithStack(err), cmd)
@@ -346,7 +353,7 @@ func (b *Builder) BuildForBundle(sanitizers []string, fuzzTests []string) ([]*bu
 			return nil, err
 		}
 
-		path, err := PathFromLabel(fuzzTest, commonFlags)
+		path, err := pathFromLabel(fuzzTest, commonFlags, b.WorkDir)
 		if err != nil {
 			return nil, err
 		}
@@ -434,10 +441,17 @@ func (b *Builder) setLibFuzzerEnv(env []string) ([]string, error) {
 // passed via the flags argument (to avoid bazel discarding the analysis
 // cache).
 func PathFromLabel(label string, flags []string) (string, error) {
+	return pathFromLabel(label, flags, "")
+}
+
+// pathFromLabel does the same as PathFromLabel, but executes bazel in
+// the specified directory
+func pathFromLabel(label string, flags []string, workDir string) (string, error) {
 	// Get a canonical form of label via `bazel query`
 	args := append([]string{"query"}, flags...)
 	args = append(args, label)
 	cmd := exec.Command("bazel", args...)
+	cmd.Dir = workDir
 	log.Debugf("Command: %s", cmd.String())
 	out, err := cmd.Output()
 	if err != nil {
@@ -445,16 +459,28 @@ func PathFromLabel(label string, flags []string) (string, error) {
 	}
 	canonicalLabel := strings.TrimSpace(string(out))
 
-	// Transform the label into a valid path below the directory which
-	// contains the BUILD file, which:
-	// * Doesn't contain any leading '//'
-	// * Has any ':' and '/' replaced with the path separator (':' is
-	//   not allowed in filenames on Windows)
-	res := strings.TrimPrefix(canonicalLabel, "//")
+	return PathFromCanonicalLabel(canonicalLabel), nil
+}
+
+// PathFromCanonicalLabel transforms a canonical label (of the form
+// //<package>:<name>) into a valid path below the directory which
+// contains the BUILD file, which:
+//   - Doesn't contain any leading '//'
+//   - Has any ':' and '/' replaced with the path separator (':' is
+//     not allowed in filenames on Windows)
+func PathFromCanonicalLabel(label string) string {
+	res := strings.TrimPrefix(label, "//")
 	res = strings.ReplaceAll(res, ":", "/")
 	res = strings.ReplaceAll(res, "/", string(filepath.Separator))
+	return res
+}
 
-	return res, nil
+// GeneratedCorpusDir returns the directory of the generated corpus of
+// the fuzz test with the given path, as returned by
+// PathFromCanonicalLabel.
+func GeneratedCorpusDir(projectDir, path string) string {
+	generatedCorpusBasename := "." + filepath.Base(path) + "_cifuzz_corpus"
+	return filepath.Join(projectDir, filepath.Dir(path), generatedCorpusBasename)
 }
 
 // Parses formatted bazel query --output=build output such as:
@@ -472,8 +498,9 @@ var cifuzzCommitRegex = regexp.MustCompile(`(?m)^\s*(?:commit|branch)\s*=\s*"([^
 
 var rulesFuzzingSHA256Regex = regexp.MustCompile(`(?m)^\s*sha256\s*=\s*"([^"]*)"`)
 
-func checkCIFuzzBazelRepoCommit() error {
+func checkCIFuzzBazelRepoCommit(workDir string) error {
 	cmd := exec.Command("bazel", "query", "--output=build", "//external:cifuzz")
+	cmd.Dir = workDir
 	out, err := cmd.Output()
 	if err != nil {
 		// If the reason for the error is that the cifuzz repository is
@@ -506,8 +533,9 @@ Current : %[2]s`,
 	return nil
 }
 
-func checkRulesFuzzingVersion() error {
+func checkRulesFuzzingVersion(workDir string) error {
 	cmd := exec.Command("bazel", "query", "--output=build", "//external:rules_fuzzing")
+	cmd.Dir = workDir
 	out, err := cmd.Output()
 	if err != nil {
 		// If the reason for the error is that the cifuzz repository is
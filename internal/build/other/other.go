@@ -58,6 +58,9 @@ type BuilderOptions struct {
 	RunfilesFinder runfiles.RunfilesFinder
 	Stdout         io.Writer
 	Stderr         io.Writer
+	// The directory in which the build and clean commands are executed,
+	// the current working directory if empty
+	WorkDir string
 }
 
 func (opts *BuilderOptions) Validate() error {
@@ -153,8 +156,17 @@ func (b *Builder) Build(fuzzTest string) (*build.Result, error) {
 		return nil, err
 	}
 
+	wd := b.WorkDir
+	if wd == "" {
+		wd, err = os.Getwd()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
 	// Run the build command
 	cmd := exec.Command("/bin/sh", "-c", b.BuildCommand)
+	cmd.Dir = wd
 	cmd.Stdout = b.Stdout
 	cmd.Stderr = b.Stderr
 	cmd.Env = b.env
@@ -164,7 +176,7 @@ func (b *Builder) Build(fuzzTest string) (*build.Result, error) {
 		return nil, cmdutils.WrapExecError(errors.WithStack(err), cmd)
 	}
 
-	executable, err := b.findFuzzTestExecutable(fuzzTest)
+	executable, err := b.findFuzzTestExecutable(fuzzTest, wd)
 	if err != nil {
 		return nil, err
 	}
@@ -181,11 +193,6 @@ func (b *Builder) Build(fuzzTest string) (*build.Result, error) {
 		return nil, err
 	}
 
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-
 	generatedCorpus := filepath.Join(b.ProjectDir, ".cifuzz-corpus", fuzzTest)
 	return &build.Result{
 		Name:            fuzzTest,
@@ -213,6 +220,7 @@ func (b *Builder) Clean() error {
 
 	// Run the clean command
 	cmd := exec.Command("/bin/sh", "-c", b.CleanCommand)
+	cmd.Dir = b.WorkDir
 	cmd.Stdout = b.Stdout
 	cmd.Stderr = b.Stderr
 	cmd.Env = b.env
@@ -383,9 +391,13 @@ func (b *Builder) setCoverageEnv() error {
 	return nil
 }
 
-func (b *Builder) findFuzzTestExecutable(fuzzTest string) (string, error) {
-	if exists, _ := fileutil.Exists(fuzzTest); exists {
-		absPath, err := filepath.Abs(fuzzTest)
+func (b *Builder) findFuzzTestExecutable(fuzzTest, dir string) (string, error) {
+	path := fuzzTest
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if exists, _ := fileutil.Exists(path); exists {
+		absPath, err := filepath.Abs(path)
 		if err != nil {
 			return "", errors.WithStack(err)
 		}
@@ -394,7 +406,7 @@ func (b *Builder) findFuzzTestExecutable(fuzzTest string) (string, error) {
 	}
 
 	var executable string
-	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return errors.WithStack(err)
 		}
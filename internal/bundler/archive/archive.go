@@ -2,11 +2,18 @@ package archive
 
 import (
 	"archive/tar"
-	"compress/gzip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -45,55 +52,135 @@ func (w *NullArchiveWriter) HasFileEntry(string) bool {
 	return true
 }
 
-// TarArchiveWriter provides functions to create a gzip-compressed tar archive.
-type TarArchiveWriter struct {
-	*tar.Writer
-	manifest   map[string]string
-	gzipWriter *gzip.Writer
+// ContentManifestFileName is the name of the file in the root of a
+// bundle which lists the SHA-256 checksums of the files in the bundle,
+// in the format of sha256sum
+const ContentManifestFileName = "manifest.sha256"
+
+// WriterOptions configure the archive created by a Writer
+type WriterOptions struct {
+	// The format of the archive
+	Format Format
+	// The compression level, which depends on the format: 1 (fastest) to
+	// 9 (best) for gzip and zip, 1 to 22 for zstd. Zero selects the
+	// default level of the format.
+	CompressionLevel int
+	// The number of goroutines used for zstd compression. Zero uses
+	// GOMAXPROCS goroutines.
+	Concurrency int
+	// Create a reproducible archive: The entries are sorted by name,
+	// all entries have the modification time ModTime, owner and group 0,
+	// and the permissions 0755 for directories and executables and 0644
+	// for all other files.
+	Deterministic bool
+	// The modification time of all entries in deterministic mode
+	ModTime time.Time
+	// If not empty, a manifest with the SHA-256 checksums of the files
+	// in the archive is added with this archive path when the writer is
+	// closed
+	ContentManifest string
+	// If set, the content manifest is signed with this key and the
+	// signature is added with the archive path of the content manifest
+	// followed by ".sig"
+	SignKey ed25519.PrivateKey
+}
+
+// Writer provides functions to create an archive in one of the
+// supported formats.
+type Writer struct {
+	out      entryWriter
+	opts     *WriterOptions
+	manifest map[string]string
+
+	// The SHA-256 checksums of the files written to the archive
+	checksums map[string]string
+	// The entries which are written when the writer is closed, in
+	// deterministic mode
+	pendingEntries []*pendingEntry
+	// The source paths of the targets of hard links, for formats which
+	// don't support hard links
+	linkSources map[string]string
 }
 
-func NewTarArchiveWriter(w io.Writer, compress bool) *TarArchiveWriter {
-	var gzipWriter *gzip.Writer
-	var writer *tar.Writer
+type pendingEntry struct {
+	archivePath string
+	sourcePath  string
+	// The target of a hard link, empty for files and directories
+	linkTarget string
+}
 
+// NewTarArchiveWriter returns a Writer which creates a tar archive,
+// which is compressed with gzip if compress is true.
+func NewTarArchiveWriter(w io.Writer, compress bool) *Writer {
+	format := FormatTar
 	if compress {
-		gzipWriter = gzip.NewWriter(w)
-		writer = tar.NewWriter(gzipWriter)
-	} else {
-		writer = tar.NewWriter(w)
+		format = FormatTarGz
 	}
+	// Creating a writer for these formats can't fail
+	writer, _ := NewWriter(w, &WriterOptions{Format: format})
+	return writer
+}
 
-	return &TarArchiveWriter{
-		Writer:     writer,
-		manifest:   make(map[string]string),
-		gzipWriter: gzipWriter,
+// NewWriter returns a Writer which creates an archive in the format
+// specified by the options
+func NewWriter(w io.Writer, opts *WriterOptions) (*Writer, error) {
+	out, err := newEntryWriter(w, opts)
+	if err != nil {
+		return nil, err
 	}
+	return &Writer{
+		out:         out,
+		opts:        opts,
+		manifest:    make(map[string]string),
+		checksums:   make(map[string]string),
+		linkSources: make(map[string]string),
+	}, nil
 }
 
-// Close closes the tar writer and the gzip writer. It does not close
-// the underlying io.Writer.
-func (w *TarArchiveWriter) Close() error {
-	var err error
-	err = w.Writer.Close()
+// SourceDateEpoch returns the time specified by the SOURCE_DATE_EPOCH
+// environment variable, which is the standard way to specify the
+// timestamps of reproducible build artifacts (see
+// https://reproducible-builds.org/specs/source-date-epoch/). If the
+// variable is not set, the Unix epoch is returned.
+func SourceDateEpoch() (time.Time, error) {
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return errors.WithStack(err)
+		return time.Time{}, errors.Errorf("Invalid value of SOURCE_DATE_EPOCH: %q is not a number of seconds", value)
 	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
 
-	if w.gzipWriter != nil {
-		err = w.gzipWriter.Close()
+// Close writes the pending entries (in deterministic mode) and the
+// content manifest and closes the archive and the compressor. It does
+// not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	var err error
+	if w.opts.Deterministic {
+		err = w.writePendingEntries()
+		if err != nil {
+			return err
+		}
 	}
 
-	if err != nil {
-		return errors.WithStack(err)
+	if w.opts.ContentManifest != "" {
+		err = w.writeContentManifest()
+		if err != nil {
+			return err
+		}
 	}
-	return nil
+
+	return w.out.Close()
 }
 
 // WriteFile writes the contents of sourcePath to the archive, with the
 // filename archivePath (so when the archive is extracted, the file will
 // be created at archivePath). Symlinks will be followed.
 // WriteFile only handles regular files and symlinks.
-func (w *TarArchiveWriter) WriteFile(archivePath string, sourcePath string) error {
+func (w *Writer) WriteFile(archivePath string, sourcePath string) error {
 	if fileutil.IsDir(sourcePath) {
 		return errors.Errorf("file is a directory: %s", sourcePath)
 	}
@@ -103,7 +190,7 @@ func (w *TarArchiveWriter) WriteFile(archivePath string, sourcePath string) erro
 // writeFileOrEmptyDir does the same as WriteFile but doesn't return an
 // error when passed a directory. If passed a directory, it creates an
 // empty directory at archivePath.
-func (w *TarArchiveWriter) writeFileOrEmptyDir(archivePath string, sourcePath string) error {
+func (w *Writer) writeFileOrEmptyDir(archivePath string, sourcePath string) error {
 	// To match the tar specification, which requires forward slashes as path separators,
 	// we convert potential windows path separators to forward slashes.
 	// Otherwise tars created on Windows will not work correctly on other platforms.
@@ -118,6 +205,28 @@ func (w *TarArchiveWriter) writeFileOrEmptyDir(archivePath string, sourcePath st
 		}
 	}
 
+	if w.opts.Deterministic {
+		// Check that the file can be added to the archive, but defer
+		// writing it until the writer is closed, to write the entries
+		// in sorted order
+		info, err := os.Stat(sourcePath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return errors.Errorf("not a regular file: %s", sourcePath)
+		}
+		w.pendingEntries = append(w.pendingEntries, &pendingEntry{archivePath: archivePath, sourcePath: sourcePath})
+		w.manifest[archivePath] = sourcePath
+		return nil
+	}
+
+	return w.writeFileOrEmptyDirEntry(archivePath, sourcePath)
+}
+
+// writeFileOrEmptyDirEntry writes the header and the content of the
+// file or directory to the archive
+func (w *Writer) writeFileOrEmptyDirEntry(archivePath string, sourcePath string) error {
 	f, err := os.Open(sourcePath)
 	if err != nil {
 		return errors.WithStack(err)
@@ -136,7 +245,10 @@ func (w *TarArchiveWriter) writeFileOrEmptyDir(archivePath string, sourcePath st
 		return errors.WithStack(err)
 	}
 	header.Name = archivePath
-	err = w.WriteHeader(header)
+	if w.opts.Deterministic {
+		w.normalizeHeader(header)
+	}
+	err = w.out.WriteHeader(header)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -148,40 +260,162 @@ func (w *TarArchiveWriter) writeFileOrEmptyDir(archivePath string, sourcePath st
 		return errors.Errorf("not a regular file: %s", sourcePath)
 	}
 
-	_, err = io.Copy(w.Writer, f)
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(w.out, hash), f)
 	if err != nil {
 		return errors.Wrapf(err, "failed to add file to archive: %s", sourcePath)
 	}
+	w.checksums[archivePath] = hex.EncodeToString(hash.Sum(nil))
 
 	w.manifest[archivePath] = sourcePath
 	return nil
 }
 
+// normalizeHeader removes all information from the header which
+// depends on the environment in which the archive is created
+func (w *Writer) normalizeHeader(header *tar.Header) {
+	mode := int64(0o644)
+	if header.Typeflag == tar.TypeDir || header.Mode&0o111 != 0 {
+		mode = 0o755
+	}
+	header.Mode = mode
+	header.ModTime = w.opts.ModTime
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+	header.Uid = 0
+	header.Gid = 0
+	header.Uname = ""
+	header.Gname = ""
+	header.Devmajor = 0
+	header.Devminor = 0
+	header.PAXRecords = nil
+	header.Format = tar.FormatUnknown
+}
+
 // WriteHardLink adds a hard link header to the archive. When the
 // archive is extracted, a hard link to target with the name linkname is
-// created.
-func (w *TarArchiveWriter) WriteHardLink(target string, linkname string) error {
+// created. In formats which don't support hard links, a copy of the
+// target is added instead.
+func (w *Writer) WriteHardLink(target string, linkname string) error {
 	existingAbsPath, conflict := w.manifest[linkname]
 	if conflict {
 		return errors.Errorf("conflict for archive path %q: %q and %q", target, existingAbsPath, linkname)
 	}
+	if sourcePath, ok := w.manifest[target]; ok {
+		if _, captured := w.linkSources[target]; !captured {
+			w.linkSources[target] = sourcePath
+		}
+	}
+
+	if w.opts.Deterministic {
+		w.pendingEntries = append(w.pendingEntries, &pendingEntry{archivePath: linkname, linkTarget: target})
+		w.manifest[target] = linkname
+		return nil
+	}
+	return w.writeHardLinkEntry(target, linkname)
+}
+
+func (w *Writer) writeHardLinkEntry(target string, linkname string) error {
+	if !w.out.SupportsHardLinks() {
+		sourcePath, ok := w.linkSources[target]
+		if !ok {
+			return errors.Errorf("target of hard link %q is not in the archive: %q", linkname, target)
+		}
+		err := w.writeFileOrEmptyDirEntry(linkname, sourcePath)
+		if err != nil {
+			return err
+		}
+		w.manifest[target] = linkname
+		return nil
+	}
 
 	header := &tar.Header{
 		Typeflag: tar.TypeLink,
 		Name:     linkname,
 		Linkname: target,
 	}
-	err := w.WriteHeader(header)
+	if w.opts.Deterministic {
+		w.normalizeHeader(header)
+	}
+	err := w.out.WriteHeader(header)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	w.manifest[target] = linkname
+	if checksum, ok := w.checksums[target]; ok {
+		w.checksums[linkname] = checksum
+	}
+	return nil
+}
+
+// writePendingEntries writes the files and directories sorted by their
+// archive path, followed by the hard links, which can only be extracted
+// after their targets
+func (w *Writer) writePendingEntries() error {
+	sort.SliceStable(w.pendingEntries, func(i, j int) bool {
+		a, b := w.pendingEntries[i], w.pendingEntries[j]
+		if (a.linkTarget == "") != (b.linkTarget == "") {
+			return a.linkTarget == ""
+		}
+		return a.archivePath < b.archivePath
+	})
+	for _, entry := range w.pendingEntries {
+		var err error
+		if entry.linkTarget != "" {
+			err = w.writeHardLinkEntry(entry.linkTarget, entry.archivePath)
+		} else {
+			err = w.writeFileOrEmptyDirEntry(entry.archivePath, entry.sourcePath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	w.pendingEntries = nil
+	return nil
+}
+
+// writeContentManifest writes the SHA-256 checksums of the files in the
+// archive, sorted by their archive path, to the content manifest
+func (w *Writer) writeContentManifest() error {
+	var paths []string
+	for archivePath := range w.checksums {
+		paths = append(paths, archivePath)
+	}
+	sort.Strings(paths)
+
+	var content bytes.Buffer
+	for _, archivePath := range paths {
+		fmt.Fprintf(&content, "%s  %s\n", w.checksums[archivePath], archivePath)
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     w.opts.ContentManifest,
+		Mode:     0o644,
+		Size:     int64(content.Len()),
+		ModTime:  time.Now(),
+	}
+	if w.opts.Deterministic {
+		w.normalizeHeader(header)
+	}
+	err := w.out.WriteHeader(header)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.out.Write(content.Bytes())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if w.opts.SignKey != nil {
+		return w.writeSignature(content.Bytes())
+	}
 	return nil
 }
 
 // WriteDir traverses sourceDir recursively and writes all regular files
 // and symlinks to the archive.
-func (w *TarArchiveWriter) WriteDir(archiveBasePath string, sourceDir string) error {
+func (w *Writer) WriteDir(archiveBasePath string, sourceDir string) error {
 	return filepath.WalkDir(sourceDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -203,25 +437,35 @@ func (w *TarArchiveWriter) WriteDir(archiveBasePath string, sourceDir string) er
 	})
 }
 
-func (w *TarArchiveWriter) GetSourcePath(archivePath string) string {
+func (w *Writer) GetSourcePath(archivePath string) string {
 	return w.manifest[archivePath]
 }
 
-func (w *TarArchiveWriter) HasFileEntry(archivePath string) bool {
+func (w *Writer) HasFileEntry(archivePath string) bool {
 	_, exists := w.manifest[archivePath]
 	return exists
 }
 
-// Extract extracts the gzip-compressed tar archive bundle into dir.
+// Extract extracts the archive bundle into dir. The format of the
+// archive is detected automatically.
 func Extract(bundle, dir string) error {
+	format, err := DetectFormat(bundle)
+	if err != nil {
+		return err
+	}
+	if format == FormatZip {
+		return archiveutil.Unzip(bundle, dir)
+	}
+
 	f, err := os.Open(bundle)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	gr, err := gzip.NewReader(f)
+	defer f.Close()
+	r, err := newDecompressor(f, format)
 	if err != nil {
-		return errors.WithStack(err)
+		return err
 	}
-	defer gr.Close()
-	return archiveutil.Untar(gr, dir)
+	defer r.Close()
+	return archiveutil.Untar(r, dir)
 }
@@ -3,8 +3,11 @@ package archive
 import (
 	"archive/tar"
 	"bufio"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"os/exec"
@@ -12,8 +15,10 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/otiai10/copy"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"code-intelligence.com/cifuzz/internal/testutil"
@@ -234,3 +239,112 @@ func createArchive(t *testing.T, files []fileEntry) *os.File {
 	t.Logf("Created archive at: %s", archiveFile.Name())
 	return archiveFile
 }
+
+func TestDeterministicArchive(t *testing.T) {
+	testdataDir := filepath.Join("testdata", "archive_test")
+	require.DirExists(t, testdataDir)
+	modTime := time.Unix(1700000000, 0).UTC()
+
+	createDeterministicArchive := func(mtime time.Time) []byte {
+		// Copy the testdata with different modification times to
+		// check that they don't affect the archive
+		dir := testutil.MkdirTemp(t, "", "deterministic-archive-test-*")
+		err := copy.Copy(testdataDir, dir)
+		require.NoError(t, err)
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(path, mtime, mtime)
+		})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		archiveWriter, err := NewWriter(&buf, &WriterOptions{
+			Format:          FormatTarGz,
+			Deterministic:   true,
+			ModTime:         modTime,
+			ContentManifest: ContentManifestFileName,
+		})
+		require.NoError(t, err)
+		// Add the entries in an unsorted order
+		err = archiveWriter.WriteFile("z.txt", filepath.Join(dir, "dir1", "dir2", "test.txt"))
+		require.NoError(t, err)
+		err = archiveWriter.WriteDir("", dir)
+		require.NoError(t, err)
+		err = archiveWriter.WriteHardLink(filepath.Join("dir1", "dir2", "test.sh"), filepath.Join("dir1", "hardlink"))
+		require.NoError(t, err)
+		err = archiveWriter.Close()
+		require.NoError(t, err)
+		return buf.Bytes()
+	}
+
+	archive1 := createDeterministicArchive(time.Now())
+	archive2 := createDeterministicArchive(time.Now().Add(-time.Hour))
+	require.Equal(t, archive1, archive2)
+
+	gr, err := gzip.NewReader(bytes.NewReader(archive1))
+	require.NoError(t, err)
+	assert.True(t, gr.ModTime.IsZero())
+	assert.Empty(t, gr.Name)
+	tr := tar.NewReader(gr)
+	var names []string
+	var manifest string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+		assert.Equal(t, modTime.Unix(), header.ModTime.Unix(), header.Name)
+		assert.Equal(t, 0, header.Uid, header.Name)
+		assert.Equal(t, 0, header.Gid, header.Name)
+		assert.Empty(t, header.Uname, header.Name)
+		switch header.Name {
+		case "dir1", "dir1/dir2/test.sh":
+			assert.Equal(t, int64(0o755), header.Mode, header.Name)
+		case "dir1/dir2/test.txt":
+			assert.Equal(t, int64(0o644), header.Mode, header.Name)
+		case ContentManifestFileName:
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			manifest = string(content)
+		}
+	}
+	assert.Equal(t, []string{
+		"dir1",
+		"dir1/dir2",
+		"dir1/dir2/test.sh",
+		"dir1/dir2/test.txt",
+		"dir1/symlink",
+		"z.txt",
+		"dir1/hardlink",
+		ContentManifestFileName,
+	}, names)
+
+	testShChecksum := sha256.Sum256([]byte("#!/usr/bin/env bash"))
+	testTxtChecksum := sha256.Sum256([]byte("foobar"))
+	assert.Equal(t, fmt.Sprintf(`%[1]x  dir1/dir2/test.sh
+%[2]x  dir1/dir2/test.txt
+%[1]x  dir1/hardlink
+%[1]x  dir1/symlink
+%[2]x  z.txt
+`, testShChecksum, testTxtChecksum), manifest)
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	epoch, err := SourceDateEpoch()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), epoch.Unix())
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	epoch, err = SourceDateEpoch()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000), epoch.Unix())
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, err = SourceDateEpoch()
+	require.Error(t, err)
+}
@@ -0,0 +1,333 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Format is the format of an archive
+type Format string
+
+const (
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarZst Format = "tar.zst"
+	FormatZip    Format = "zip"
+)
+
+// Formats are the supported formats of bundles
+var Formats = []Format{FormatTarGz, FormatTarZst, FormatZip}
+
+// Extension returns the file extension of archives in the format,
+// including the leading dot
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// ValidateCompressionLevel returns an error if the compression level is
+// not supported by the format
+func (f Format) ValidateCompressionLevel(level int) error {
+	if f == FormatTar && level != 0 {
+		return errors.New("Uncompressed tar archives don't support a compression level")
+	}
+	maxLevel := 9
+	if f == FormatTarZst {
+		maxLevel = 22
+	}
+	if level < 0 || level > maxLevel {
+		return errors.Errorf("Invalid compression level %d for format %s, must be between 1 and %d", level, f, maxLevel)
+	}
+	return nil
+}
+
+// ParseFormat returns the format with the specified name
+func ParseFormat(name string) (Format, error) {
+	if name == string(FormatTar) {
+		return FormatTar, nil
+	}
+	var names []string
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+		names = append(names, string(format))
+	}
+	return "", errors.Errorf("Unsupported bundle format %q, supported formats are: %s", name, strings.Join(names, ", "))
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte("PK\x03\x04")
+	// The magic of an empty zip archive, which only consists of the end
+	// of central directory record
+	emptyZipMagic = []byte("PK\x05\x06")
+)
+
+// DetectFormat returns the format of the archive, which is determined
+// from its content, not its file extension
+func DetectFormat(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", errors.Wrapf(err, "failed to read %s", path)
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return FormatTarGz, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		return FormatTarZst, nil
+	case bytes.HasPrefix(header, zipMagic), bytes.HasPrefix(header, emptyZipMagic):
+		return FormatZip, nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return FormatTar, nil
+	}
+	return "", errors.Errorf("%s is not a tar, tar.gz, tar.zst or zip archive", path)
+}
+
+// newDecompressor returns a reader of the uncompressed tar stream of an
+// archive in one of the tar formats
+func newDecompressor(r io.Reader, format Format) (io.ReadCloser, error) {
+	r = bufio.NewReader(r)
+	switch format {
+	case FormatTar:
+		return io.NopCloser(r), nil
+	case FormatTarGz:
+		gr, err := gzip.NewReader(r)
+		return gr, errors.WithStack(err)
+	case FormatTarZst:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, errors.Errorf("%s is not a tar format", format)
+}
+
+// walkArchive calls fn for each entry of the archive with a reader of
+// its content. The format of the archive is detected automatically.
+func walkArchive(bundle string, fn func(entry *Entry, content io.Reader) error) error {
+	format, err := DetectFormat(bundle)
+	if err != nil {
+		return err
+	}
+	if format == FormatZip {
+		return walkZip(bundle, fn)
+	}
+
+	f, err := os.Open(bundle)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	r, err := newDecompressor(f, format)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read bundle %s", bundle)
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read bundle %s", bundle)
+		}
+		entry := &Entry{
+			Name:     cleanArchivePath(header.Name),
+			Size:     header.Size,
+			Typeflag: header.Typeflag,
+			Linkname: header.Linkname,
+			Mode:     header.Mode,
+		}
+		if entry.Typeflag == tar.TypeLink {
+			entry.Linkname = cleanArchivePath(header.Linkname)
+		}
+		err = fn(entry, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func walkZip(bundle string, fn func(entry *Entry, content io.Reader) error) error {
+	zr, err := zip.OpenReader(bundle)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read bundle %s", bundle)
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		entry := &Entry{
+			Name:     cleanArchivePath(file.Name),
+			Size:     int64(file.UncompressedSize64),
+			Typeflag: tar.TypeReg,
+			Mode:     int64(file.Mode().Perm()),
+		}
+		if file.FileInfo().IsDir() {
+			entry.Typeflag = tar.TypeDir
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read bundle %s", bundle)
+		}
+		err = fn(entry, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryWriter writes the entries of an archive in a particular format.
+// The entries are described by tar headers, independent of the format.
+type entryWriter interface {
+	WriteHeader(header *tar.Header) error
+	Write(p []byte) (int, error)
+	Close() error
+	SupportsHardLinks() bool
+}
+
+func newEntryWriter(w io.Writer, opts *WriterOptions) (entryWriter, error) {
+	err := opts.Format.ValidateCompressionLevel(opts.CompressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Format {
+	case FormatTar:
+		return &tarEntryWriter{Writer: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		level := gzip.DefaultCompression
+		if opts.CompressionLevel != 0 {
+			level = opts.CompressionLevel
+		}
+		// The default gzip header doesn't contain a modification time
+		// or file name, so it doesn't depend on when and where the
+		// archive was created
+		gzipWriter, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &tarEntryWriter{Writer: tar.NewWriter(gzipWriter), compressor: gzipWriter}, nil
+	case FormatTarZst:
+		var zstdOpts []zstd.EOption
+		if opts.CompressionLevel != 0 {
+			zstdOpts = append(zstdOpts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.CompressionLevel)))
+		}
+		if opts.Concurrency != 0 {
+			zstdOpts = append(zstdOpts, zstd.WithEncoderConcurrency(opts.Concurrency))
+		}
+		zstdWriter, err := zstd.NewWriter(w, zstdOpts...)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &tarEntryWriter{Writer: tar.NewWriter(zstdWriter), compressor: zstdWriter}, nil
+	case FormatZip:
+		zipWriter := zip.NewWriter(w)
+		if opts.CompressionLevel != 0 {
+			level := opts.CompressionLevel
+			zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(out, level)
+			})
+		}
+		return &zipEntryWriter{zipWriter: zipWriter}, nil
+	}
+	return nil, errors.Errorf("Unsupported archive format %q", opts.Format)
+}
+
+type tarEntryWriter struct {
+	*tar.Writer
+	// The gzip or zstd writer, nil for uncompressed archives
+	compressor io.WriteCloser
+}
+
+func (w *tarEntryWriter) Close() error {
+	err := w.Writer.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if w.compressor != nil {
+		return errors.WithStack(w.compressor.Close())
+	}
+	return nil
+}
+
+func (w *tarEntryWriter) SupportsHardLinks() bool {
+	return true
+}
+
+type zipEntryWriter struct {
+	zipWriter *zip.Writer
+	// The writer of the content of the current entry
+	current io.Writer
+}
+
+// The MS-DOS timestamps of zip archives can't represent times before
+// 1980
+var minZipModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (w *zipEntryWriter) WriteHeader(header *tar.Header) error {
+	mode := fs.FileMode(header.Mode).Perm()
+	fileHeader := &zip.FileHeader{
+		Name:     header.Name,
+		Method:   zip.Deflate,
+		Modified: header.ModTime,
+	}
+	if fileHeader.Modified.Before(minZipModTime) {
+		fileHeader.Modified = minZipModTime
+	}
+	switch header.Typeflag {
+	case tar.TypeDir:
+		fileHeader.Name = strings.TrimSuffix(fileHeader.Name, "/") + "/"
+		fileHeader.Method = zip.Store
+		mode |= fs.ModeDir
+	case tar.TypeReg:
+	default:
+		return errors.Errorf("unsupported entry type in zip archive: %q", header.Typeflag)
+	}
+	fileHeader.SetMode(mode)
+
+	var err error
+	w.current, err = w.zipWriter.CreateHeader(fileHeader)
+	return errors.WithStack(err)
+}
+
+func (w *zipEntryWriter) Write(p []byte) (int, error) {
+	if w.current == nil {
+		return 0, errors.New("zip entry written before its header")
+	}
+	return w.current.Write(p)
+}
+
+func (w *zipEntryWriter) Close() error {
+	return errors.WithStack(w.zipWriter.Close())
+}
+
+func (w *zipEntryWriter) SupportsHardLinks() bool {
+	return false
+}
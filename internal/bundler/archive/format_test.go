@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+func TestFormats(t *testing.T) {
+	testdataDir := filepath.Join("testdata", "archive_test")
+	require.DirExists(t, testdataDir)
+
+	for _, format := range append([]Format{FormatTar}, Formats...) {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			// Use a misleading file extension to check that the format
+			// is detected from the content
+			bundle := filepath.Join(testutil.MkdirTemp(t, "", "format-test-*"), "bundle.tar.gz")
+			f, err := os.Create(bundle)
+			require.NoError(t, err)
+			archiveWriter, err := NewWriter(f, &WriterOptions{
+				Format:          format,
+				Deterministic:   true,
+				ContentManifest: ContentManifestFileName,
+			})
+			require.NoError(t, err)
+			err = archiveWriter.WriteDir("", testdataDir)
+			require.NoError(t, err)
+			err = archiveWriter.WriteHardLink("dir1/dir2/test.sh", "dir1/hardlink")
+			require.NoError(t, err)
+			err = archiveWriter.Close()
+			require.NoError(t, err)
+			err = f.Close()
+			require.NoError(t, err)
+
+			detectedFormat, err := DetectFormat(bundle)
+			require.NoError(t, err)
+			assert.Equal(t, format, detectedFormat)
+
+			dir := testutil.MkdirTemp(t, "", "format-test-extracted-*")
+			err = Extract(bundle, dir)
+			require.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dir, "dir1", "hardlink"))
+			require.NoError(t, err)
+			assert.Equal(t, "#!/usr/bin/env bash", string(content))
+			content, err = os.ReadFile(filepath.Join(dir, "dir1", "dir2", "test.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "foobar", string(content))
+			if runtime.GOOS != "windows" {
+				info, err := os.Stat(filepath.Join(dir, "dir1", "dir2", "test.sh"))
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o100), info.Mode()&0o100, "Expected test.sh to be executable")
+			}
+			require.NoError(t, VerifyDir(dir, nil))
+			require.NoError(t, VerifyArchive(bundle, nil))
+
+			// ReadContents fails because the archive has no metadata,
+			// but the error shows that the archive could be read
+			_, err = ReadContents(bundle)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "doesn't contain "+MetadataFileName)
+		})
+	}
+}
+
+func TestZstdCompressionOptions(t *testing.T) {
+	testFile := filepath.Join("testdata", "dummy.blob")
+	require.FileExists(t, testFile)
+
+	createArchive := func(level int) []byte {
+		var buf bytes.Buffer
+		archiveWriter, err := NewWriter(&buf, &WriterOptions{
+			Format:           FormatTarZst,
+			CompressionLevel: level,
+			Concurrency:      4,
+			Deterministic:    true,
+		})
+		require.NoError(t, err)
+		err = archiveWriter.WriteFile("dummy.blob", testFile)
+		require.NoError(t, err)
+		err = archiveWriter.Close()
+		require.NoError(t, err)
+		return buf.Bytes()
+	}
+
+	// Archives created with the same options are identical, even with
+	// multithreaded compression
+	assert.Equal(t, createArchive(3), createArchive(3))
+	assert.Equal(t, createArchive(19), createArchive(19))
+
+	_, err := NewWriter(&bytes.Buffer{}, &WriterOptions{Format: FormatTarZst, CompressionLevel: 23})
+	require.Error(t, err)
+	_, err = NewWriter(&bytes.Buffer{}, &WriterOptions{Format: FormatTarGz, CompressionLevel: 10})
+	require.Error(t, err)
+	_, err = NewWriter(&bytes.Buffer{}, &WriterOptions{Format: "rar"})
+	require.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("tar.zst")
+	require.NoError(t, err)
+	assert.Equal(t, FormatTarZst, format)
+	assert.Equal(t, ".tar.zst", format.Extension())
+
+	_, err = ParseFormat("rar")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tar.gz, tar.zst, zip")
+}
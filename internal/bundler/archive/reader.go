@@ -0,0 +1,99 @@
+package archive
+
+import (
+	"archive/tar"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Entry is a file, directory or link in an artifact archive
+type Entry struct {
+	Name     string
+	Size     int64
+	Typeflag byte
+	// The target of a hard link or symlink
+	Linkname string
+	Mode     int64
+}
+
+// IsDir returns true if the entry is a directory
+func (e *Entry) IsDir() bool {
+	return e.Typeflag == tar.TypeDir
+}
+
+// Contents holds the metadata and the entries of an artifact archive
+type Contents struct {
+	Metadata *Metadata
+	// The entries of the archive, sorted by name
+	Entries []*Entry
+
+	entries map[string]*Entry
+}
+
+// ReadContents reads the metadata and the entries of the archive bundle
+// without extracting it. The format of the archive is detected
+// automatically.
+func ReadContents(bundle string) (*Contents, error) {
+	contents := &Contents{entries: make(map[string]*Entry)}
+	err := walkArchive(bundle, func(entry *Entry, content io.Reader) error {
+		contents.Entries = append(contents.Entries, entry)
+		contents.entries[entry.Name] = entry
+
+		if entry.Name == MetadataFileName {
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			contents.Metadata = &Metadata{}
+			return contents.Metadata.FromYaml(data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if contents.Metadata == nil {
+		return nil, errors.Errorf("bundle %s doesn't contain %s", bundle, MetadataFileName)
+	}
+
+	sort.Slice(contents.Entries, func(i, j int) bool {
+		return contents.Entries[i].Name < contents.Entries[j].Name
+	})
+	return contents, nil
+}
+
+// Entry returns the entry with the specified path, nil if there is no
+// such entry. Directories are found even if the archive doesn't have an
+// entry for them but only for the files in them.
+func (c *Contents) Entry(archivePath string) *Entry {
+	archivePath = cleanArchivePath(archivePath)
+	if entry, ok := c.entries[archivePath]; ok {
+		return entry
+	}
+	if c.HasFilesIn(archivePath) {
+		return &Entry{Name: archivePath, Typeflag: tar.TypeDir}
+	}
+	return nil
+}
+
+// HasFilesIn returns true if the archive contains files below the
+// directory
+func (c *Contents) HasFilesIn(dir string) bool {
+	prefix := cleanArchivePath(dir) + "/"
+	for _, entry := range c.Entries {
+		if strings.HasPrefix(entry.Name, prefix) && !entry.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanArchivePath converts the path of an archive entry into the form
+// used by Contents, without leading "./" and trailing slashes
+func cleanArchivePath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+}
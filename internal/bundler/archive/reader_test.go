@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+func TestReadContents(t *testing.T) {
+	dir := testutil.MkdirTemp(t, "", "read-contents-test-*")
+	metadata := &Metadata{
+		RunEnvironment: &RunEnvironment{Docker: "ubuntu:rolling"},
+		Fuzzers: []*Fuzzer{{
+			Target: "my_fuzz_test",
+			Path:   "libfuzzer/address/my_fuzz_test/bin/my_fuzz_test",
+			Engine: "LIBFUZZER",
+		}},
+	}
+	metadataYaml, err := metadata.ToYaml()
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, MetadataFileName), metadataYaml, 0o644)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "my_fuzz_test"), []byte("fuzzer"), 0o755)
+	require.NoError(t, err)
+
+	bundle := filepath.Join(dir, "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	archiveWriter := NewTarArchiveWriter(f, true)
+	err = archiveWriter.WriteFile(MetadataFileName, filepath.Join(dir, MetadataFileName))
+	require.NoError(t, err)
+	err = archiveWriter.WriteFile("libfuzzer/address/my_fuzz_test/bin/my_fuzz_test", filepath.Join(dir, "my_fuzz_test"))
+	require.NoError(t, err)
+	err = archiveWriter.WriteHardLink("libfuzzer/address/my_fuzz_test/bin/my_fuzz_test", "link")
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	contents, err := ReadContents(bundle)
+	require.NoError(t, err)
+	assert.Equal(t, "ubuntu:rolling", contents.Metadata.Docker)
+	require.Len(t, contents.Metadata.Fuzzers, 1)
+	assert.Equal(t, "my_fuzz_test", contents.Metadata.Fuzzers[0].Target)
+
+	var names []string
+	for _, entry := range contents.Entries {
+		names = append(names, entry.Name)
+	}
+	assert.Equal(t, []string{MetadataFileName, "libfuzzer/address/my_fuzz_test/bin/my_fuzz_test", "link"}, names)
+
+	entry := contents.Entry("./libfuzzer/address/my_fuzz_test/bin/my_fuzz_test")
+	require.NotNil(t, entry)
+	assert.Equal(t, int64(len("fuzzer")), entry.Size)
+	assert.False(t, entry.IsDir())
+
+	// Directories are found even without an entry of their own
+	entry = contents.Entry("libfuzzer/address/")
+	require.NotNil(t, entry)
+	assert.True(t, entry.IsDir())
+	assert.True(t, contents.HasFilesIn("libfuzzer"))
+	assert.False(t, contents.HasFilesIn("libfuzzer/address/my_fuzz_test/bin/my_fuzz_test"))
+	assert.Nil(t, contents.Entry("libfuzzer/undefined"))
+
+	assert.Equal(t, "libfuzzer/address/my_fuzz_test/bin/my_fuzz_test", contents.Entry("link").Linkname)
+}
+
+func TestReadContents_MissingMetadata(t *testing.T) {
+	dir := testutil.MkdirTemp(t, "", "read-contents-test-*")
+	bundle := filepath.Join(dir, "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	err = NewTarArchiveWriter(f, true).Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	_, err = ReadContents(bundle)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), MetadataFileName)
+}
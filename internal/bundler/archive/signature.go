@@ -0,0 +1,341 @@
+package archive
+
+import (
+	"archive/tar"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SignatureFileName is the name of the file in the root of a bundle
+// which contains the base64-encoded ed25519 signature of the content
+// manifest
+const SignatureFileName = ContentManifestFileName + ".sig"
+
+// ErrUnsigned is returned by the verification functions if a public
+// key was specified but the bundle is not signed
+var ErrUnsigned = errors.New("bundle is not signed")
+
+// LoadPrivateKey returns the ed25519 private key in the key file at
+// path. Relative paths are interpreted relative to baseDir. See
+// ParsePrivateKey for the supported formats. The key can only be read
+// from a file (or passed by value via ParsePrivateKey), so that it
+// doesn't end up in the project config, the shell history or the
+// command line of the process.
+func LoadPrivateKey(path string, baseDir string) (ed25519.PrivateKey, error) {
+	data, err := readKeyFile(path, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+// ParsePrivateKey parses an ed25519 private key. Supported formats are
+// PEM-encoded PKCS #8 keys (as created by
+// 'openssl genpkey -algorithm ed25519') and the base64-encoded seed or
+// private key.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse private key")
+		}
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.Errorf("private key is a %T, not an ed25519 key", key)
+		}
+		return privateKey, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.New("private key is neither PEM- nor base64-encoded")
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, errors.Errorf("invalid length of ed25519 private key: %d bytes", len(raw))
+	}
+}
+
+// LoadPublicKey returns the ed25519 public key specified by value,
+// which is either the key itself or the path of a key file. Relative
+// paths are interpreted relative to baseDir. Supported formats are
+// PEM-encoded PKIX keys (as created by 'openssl pkey -pubout') and the
+// base64-encoded public key. If value is empty, nil is returned.
+func LoadPublicKey(value string, baseDir string) (ed25519.PublicKey, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data := []byte(value)
+	if !isPublicKey(value) {
+		var err error
+		data, err = readKeyFile(value, baseDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse public key")
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("public key is a %T, not an ed25519 key", key)
+		}
+		return publicKey, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.New("public key is neither PEM- nor base64-encoded")
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("invalid length of ed25519 public key: %d bytes", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// isPublicKey returns true if value is a PEM-encoded or base64-encoded
+// public key rather than the path of a key file
+func isPublicKey(value string) bool {
+	if strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN ") {
+		return true
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	return err == nil && len(raw) == ed25519.PublicKeySize
+}
+
+// readKeyFile returns the content of the key file at path, which is
+// interpreted relative to baseDir if it's relative
+func readKeyFile(path string, baseDir string) ([]byte, error) {
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Errorf("key file %s not found", path)
+	}
+	return data, errors.WithStack(err)
+}
+
+// Sign returns the base64-encoded signature of the content manifest
+func Sign(manifest []byte, key ed25519.PrivateKey) []byte {
+	signature := ed25519.Sign(key, manifest)
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n")
+}
+
+// VerifyDir verifies the integrity of the bundle which was extracted
+// into dir: It checks that the files match the checksums of the content
+// manifest, that there are no files which are not listed in it and, if
+// key is not nil, that the manifest was signed with the private key of
+// key. Bundles without a content manifest are only accepted if key is
+// nil. ignoredPaths are the slash-separated paths of files and
+// directories which were added to dir after the bundle was extracted.
+func VerifyDir(dir string, key ed25519.PublicKey, ignoredPaths ...string) error {
+	manifest, err := readOptionalFile(filepath.Join(dir, ContentManifestFileName))
+	if err != nil {
+		return err
+	}
+	signature, err := readOptionalFile(filepath.Join(dir, SignatureFileName))
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return verifyManifest(manifest, signature, key, unlistedFiles(files, ignoredPaths), func(archivePath string) (string, error) {
+		return fileChecksum(filepath.Join(dir, filepath.FromSlash(archivePath)))
+	})
+}
+
+// VerifyArchive does the same as VerifyDir for a bundle which is not
+// extracted
+func VerifyArchive(bundle string, key ed25519.PublicKey) error {
+	var manifest, signature []byte
+	var files []string
+	checksums := make(map[string]string)
+	err := walkArchive(bundle, func(entry *Entry, content io.Reader) error {
+		if entry.Typeflag != tar.TypeDir {
+			files = append(files, path.Clean(entry.Name))
+		}
+		var err error
+		switch {
+		case entry.Typeflag == tar.TypeLink:
+			// Hard links are always written after their targets
+			checksums[entry.Name] = checksums[entry.Linkname]
+		case entry.Typeflag != tar.TypeReg:
+			return nil
+		case entry.Name == ContentManifestFileName:
+			manifest, err = io.ReadAll(content)
+		case entry.Name == SignatureFileName:
+			signature, err = io.ReadAll(content)
+		default:
+			hash := sha256.New()
+			_, err = io.Copy(hash, content)
+			checksums[entry.Name] = hex.EncodeToString(hash.Sum(nil))
+		}
+		return errors.Wrapf(err, "failed to read bundle %s", bundle)
+	})
+	if err != nil {
+		return err
+	}
+
+	return verifyManifest(manifest, signature, key, unlistedFiles(files, nil), func(archivePath string) (string, error) {
+		checksum, ok := checksums[archivePath]
+		if !ok {
+			return "", os.ErrNotExist
+		}
+		return checksum, nil
+	})
+}
+
+// unlistedFiles returns the files which must be listed in the content
+// manifest, which are all files except for the manifest, its signature
+// and the ignored files and directories
+func unlistedFiles(files []string, ignoredPaths []string) []string {
+	var result []string
+outer:
+	for _, file := range files {
+		if file == ContentManifestFileName || file == SignatureFileName {
+			continue
+		}
+		for _, ignored := range ignoredPaths {
+			if file == ignored || strings.HasPrefix(file, ignored+"/") {
+				continue outer
+			}
+		}
+		result = append(result, file)
+	}
+	return result
+}
+
+// verifyManifest checks the signature of the manifest, the checksums of
+// the files listed in it and that it lists all of the specified files
+func verifyManifest(manifest, signature []byte, key ed25519.PublicKey, files []string, checksum func(string) (string, error)) error {
+	if key != nil {
+		if manifest == nil || signature == nil {
+			return ErrUnsigned
+		}
+		rawSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || !ed25519.Verify(key, manifest, rawSignature) {
+			return errors.New("the signature of the bundle doesn't match the trusted public key")
+		}
+	}
+	if manifest == nil {
+		return nil
+	}
+
+	var mismatches []string
+	listed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(manifest)), "\n") {
+		if line == "" {
+			continue
+		}
+		expected, archivePath, found := strings.Cut(line, "  ")
+		if !found {
+			return errors.Errorf("invalid line in %s: %q", ContentManifestFileName, line)
+		}
+		listed[archivePath] = true
+		actual, err := checksum(archivePath)
+		if errors.Is(err, os.ErrNotExist) {
+			mismatches = append(mismatches, archivePath+" is missing")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			mismatches = append(mismatches, archivePath+" was modified")
+		}
+	}
+	for _, file := range files {
+		if !listed[file] {
+			mismatches = append(mismatches, file+" is not listed")
+		}
+	}
+	if len(mismatches) > 0 {
+		return errors.Errorf("the bundle doesn't match its content manifest:\n  %s", strings.Join(mismatches, "\n  "))
+	}
+	return nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readOptionalFile returns the content of the file, nil if it doesn't
+// exist
+func readOptionalFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, errors.WithStack(err)
+}
+
+// writeSignature writes the signature of the content manifest to the
+// archive
+func (w *Writer) writeSignature(manifest []byte) error {
+	signature := Sign(manifest, w.opts.SignKey)
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     w.opts.ContentManifest + ".sig",
+		Mode:     0o644,
+		Size:     int64(len(signature)),
+		ModTime:  time.Now(),
+	}
+	if w.opts.Deterministic {
+		w.normalizeHeader(header)
+	}
+	err := w.out.WriteHeader(header)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = w.out.Write(signature)
+	return errors.WithStack(err)
+}
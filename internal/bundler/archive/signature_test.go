@@ -0,0 +1,185 @@
+package archive
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+// createSignedArchive writes the testdata to a bundle which is signed
+// with the key, if it's not nil
+func createSignedArchive(t *testing.T, key ed25519.PrivateKey) string {
+	bundle := filepath.Join(testutil.MkdirTemp(t, "", "signed-archive-test-*"), "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	archiveWriter, err := NewWriter(f, &WriterOptions{
+		Format:          FormatTarGz,
+		Deterministic:   true,
+		ContentManifest: ContentManifestFileName,
+		SignKey:         key,
+	})
+	require.NoError(t, err)
+	err = archiveWriter.WriteDir("", filepath.Join("testdata", "archive_test"))
+	require.NoError(t, err)
+	err = archiveWriter.WriteHardLink("dir1/dir2/test.sh", "dir1/hardlink")
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+	return bundle
+}
+
+func TestLoadKeys(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// PEM-encoded key files
+	dir := testutil.MkdirTemp(t, "", "load-keys-test-*")
+	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	privateKeyFile := filepath.Join(dir, "key.pem")
+	err = os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER}), 0o600)
+	require.NoError(t, err)
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	publicKeyFile := filepath.Join(dir, "key.pub.pem")
+	err = os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER}), 0o644)
+	require.NoError(t, err)
+
+	loadedPrivateKey, err := LoadPrivateKey(privateKeyFile, "")
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, loadedPrivateKey)
+	loadedPublicKey, err := LoadPublicKey(publicKeyFile, "")
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, loadedPublicKey)
+
+	// Relative paths are resolved against the base directory
+	loadedPrivateKey, err = LoadPrivateKey("key.pem", dir)
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, loadedPrivateKey)
+	loadedPublicKey, err = LoadPublicKey("key.pub.pem", dir)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, loadedPublicKey)
+
+	// Base64-encoded keys
+	loadedPrivateKey, err = ParsePrivateKey([]byte(base64.StdEncoding.EncodeToString(privateKey.Seed())))
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, loadedPrivateKey)
+	loadedPublicKey, err = LoadPublicKey(base64.StdEncoding.EncodeToString(publicKey), dir)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, loadedPublicKey)
+
+	loadedPublicKey, err = LoadPublicKey("", "")
+	require.NoError(t, err)
+	assert.Nil(t, loadedPublicKey)
+
+	// Values which are not keys are treated as paths
+	_, err = LoadPrivateKey("missing.pem", dir)
+	require.ErrorContains(t, err, "key file "+filepath.Join(dir, "missing.pem")+" not found")
+	_, err = LoadPublicKey("missing.pub.pem", dir)
+	require.ErrorContains(t, err, "key file "+filepath.Join(dir, "missing.pub.pem")+" not found")
+	_, err = LoadPublicKey(base64.StdEncoding.EncodeToString([]byte("too short")), dir)
+	require.ErrorContains(t, err, "not found")
+	_, err = ParsePrivateKey([]byte("not a key"))
+	require.Error(t, err)
+}
+
+func TestVerifySignedArchive(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundle := createSignedArchive(t, privateKey)
+	require.NoError(t, VerifyArchive(bundle, publicKey))
+	require.NoError(t, VerifyArchive(bundle, nil))
+	require.Error(t, VerifyArchive(bundle, otherPublicKey))
+
+	dir := testutil.MkdirTemp(t, "", "verify-signed-archive-test-*")
+	err = Extract(bundle, dir)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, SignatureFileName))
+	require.NoError(t, VerifyDir(dir, publicKey))
+	require.Error(t, VerifyDir(dir, otherPublicKey))
+
+	// Modify a file of the extracted bundle
+	err = os.WriteFile(filepath.Join(dir, "dir1", "dir2", "test.txt"), []byte("modified"), 0o644)
+	require.NoError(t, err)
+	err = VerifyDir(dir, publicKey)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dir1/dir2/test.txt was modified")
+	// The checksums are also checked without a trusted key
+	require.Error(t, VerifyDir(dir, nil))
+
+	err = os.Remove(filepath.Join(dir, "dir1", "hardlink"))
+	require.NoError(t, err)
+	err = VerifyDir(dir, publicKey)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dir1/hardlink is missing")
+}
+
+func TestVerifyUnsignedArchive(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundle := createSignedArchive(t, nil)
+	require.NoError(t, VerifyArchive(bundle, nil))
+	require.ErrorIs(t, VerifyArchive(bundle, publicKey), ErrUnsigned)
+
+	dir := testutil.MkdirTemp(t, "", "verify-unsigned-archive-test-*")
+	err = Extract(bundle, dir)
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(dir, SignatureFileName))
+	require.NoError(t, VerifyDir(dir, nil))
+	require.ErrorIs(t, VerifyDir(dir, publicKey), ErrUnsigned)
+}
+
+func TestVerifyArchiveWithUnlistedFile(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundle := createSignedArchive(t, privateKey)
+	dir := testutil.MkdirTemp(t, "", "verify-unlisted-file-test-*")
+	err = Extract(bundle, dir)
+	require.NoError(t, err)
+
+	// Add a file which is not listed in the signed content manifest
+	err = os.WriteFile(filepath.Join(dir, "dir1", "injected.sh"), []byte("echo injected"), 0o755)
+	require.NoError(t, err)
+	err = VerifyDir(dir, publicKey)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dir1/injected.sh is not listed")
+	// The manifest is also checked without a trusted key
+	require.Error(t, VerifyDir(dir, nil))
+	// Ignored files and files in ignored directories are accepted
+	require.NoError(t, VerifyDir(dir, publicKey, "dir1/injected.sh"))
+	require.NoError(t, VerifyDir(dir, publicKey, "dir1"))
+
+	// Repack the bundle with the signed manifest and the additional file
+	modifiedBundle := filepath.Join(testutil.MkdirTemp(t, "", "verify-unlisted-file-test-*"), "bundle.tar.gz")
+	f, err := os.Create(modifiedBundle)
+	require.NoError(t, err)
+	archiveWriter, err := NewWriter(f, &WriterOptions{Format: FormatTarGz})
+	require.NoError(t, err)
+	err = archiveWriter.WriteDir("", dir)
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	err = VerifyArchive(modifiedBundle, publicKey)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dir1/injected.sh is not listed")
+}
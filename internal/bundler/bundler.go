@@ -2,6 +2,7 @@ package bundler
 
 import (
 	"bufio"
+	"crypto/ed25519"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -54,9 +55,30 @@ func (b *Bundler) Bundle() (string, error) {
 		}
 	}()
 
-	// Create archive writer
+	// Create archive writer. Bundles are reproducible, i.e. bundles
+	// created from the same sources are identical, so that they can be
+	// cached and compared.
+	modTime, err := archive.SourceDateEpoch()
+	if err != nil {
+		return "", err
+	}
+	signKey, err := b.loadSignKey()
+	if err != nil {
+		return "", errors.WithMessage(err, "Failed to load the signing key")
+	}
 	bufWriter := bufio.NewWriter(bundle)
-	archiveWriter := archive.NewTarArchiveWriter(bufWriter, true)
+	archiveWriter, err := archive.NewWriter(bufWriter, &archive.WriterOptions{
+		Format:           archive.Format(b.opts.Format),
+		CompressionLevel: b.opts.CompressionLevel,
+		Concurrency:      b.opts.CompressionThreads,
+		Deterministic:    true,
+		ModTime:          modTime,
+		ContentManifest:  archive.ContentManifestFileName,
+		SignKey:          signKey,
+	})
+	if err != nil {
+		return "", err
+	}
 
 	var fuzzers []*archive.Fuzzer
 	switch b.opts.BuildSystem {
@@ -64,6 +86,8 @@ func (b *Bundler) Bundle() (string, error) {
 		fuzzers, err = newLibfuzzerBundler(b.opts, archiveWriter).bundle()
 	case config.BuildSystemMaven, config.BuildSystemGradle:
 		fuzzers, err = newJazzerBundler(b.opts, archiveWriter).bundle()
+	case config.BuildSystemNodeJS:
+		fuzzers, err = newJazzerjsBundler(b.opts, archiveWriter).bundle()
 	default:
 		err = errors.Errorf("Unknown build system for bundler: %s", b.opts.BuildSystem)
 	}
@@ -110,8 +134,25 @@ func (b *Bundler) Bundle() (string, error) {
 	return bundle.Name(), nil
 }
 
+// loadSignKey returns the private key which is used to sign the bundle,
+// nil if the bundle isn't signed. The key is read from the key file
+// specified via --sign-key or else from the SignKeyEnv environment
+// variable.
+func (b *Bundler) loadSignKey() (ed25519.PrivateKey, error) {
+	if b.opts.SignKey != "" {
+		return archive.LoadPrivateKey(b.opts.SignKey, b.opts.ProjectDir)
+	}
+	if key := os.Getenv(SignKeyEnv); key != "" {
+		return archive.ParsePrivateKey([]byte(key))
+	}
+	return nil, nil
+}
+
 func (b *Bundler) createEmptyBundle() (*os.File, error) {
-	archiveExt := ".tar.gz"
+	if b.opts.Format == "" {
+		b.opts.Format = formatFromOutputPath(b.opts.OutputPath)
+	}
+	archiveExt := archive.Format(b.opts.Format).Extension()
 
 	if b.opts.OutputPath != "" {
 		// Check that outpath path makes sense
@@ -146,6 +187,9 @@ func (b *Bundler) determineDockerImageForBundle() string {
 		case config.BuildSystemMaven, config.BuildSystemGradle:
 			// Maven and Gradle should use a Docker image with Java
 			dockerImageUsedInBundle = "eclipse-temurin:20"
+		case config.BuildSystemNodeJS:
+			// Node.js projects need a Docker image with Node.js and npm
+			dockerImageUsedInBundle = "node:20"
 		}
 	}
 
@@ -93,3 +93,11 @@ func TestRemoveBundleOnError(t *testing.T) {
 
 	assert.NoFileExists(t, bundlePath)
 }
+
+func TestFormatFromOutputPath(t *testing.T) {
+	assert.Equal(t, "tar.gz", formatFromOutputPath(""))
+	assert.Equal(t, "tar.gz", formatFromOutputPath("bundle"))
+	assert.Equal(t, "tar.gz", formatFromOutputPath("bundle.tar.gz"))
+	assert.Equal(t, "tar.zst", formatFromOutputPath(filepath.Join("out", "bundle.tar.zst")))
+	assert.Equal(t, "zip", formatFromOutputPath("bundle.zip"))
+}
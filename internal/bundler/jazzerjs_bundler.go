@@ -0,0 +1,355 @@
+package bundler
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/pkg/dependencies"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// The directory inside the fuzzing artifact which contains the Node.js
+// project. Jest is run from this directory when the bundle is executed.
+const nodeProjectPath = "project"
+
+// The lock files from which the dependencies of a Node.js project can be
+// installed if the project doesn't contain a node_modules directory
+var nodeLockFiles = []string{"package-lock.json", "yarn.lock"}
+
+// The platform of the fuzz container images in which bundles are
+// executed
+const bundlePlatform = "linux/amd64"
+
+// The platform on which the bundle is created. This is a variable so
+// that it can be changed in tests.
+var hostPlatform = runtime.GOOS + "/" + runtime.GOARCH
+
+type jazzerjsBundler struct {
+	opts          *Opts
+	archiveWriter archive.ArchiveWriter
+}
+
+// nodeFuzzTest is a single Jazzer.js fuzz test in a fuzz test file
+type nodeFuzzTest struct {
+	// The identifier of the fuzz test, as listed by
+	// cmdutils.ListNodeFuzzTests
+	name string
+	// The absolute path of the fuzz test file
+	testFile string
+}
+
+func newJazzerjsBundler(opts *Opts, archiveWriter archive.ArchiveWriter) *jazzerjsBundler {
+	return &jazzerjsBundler{opts, archiveWriter}
+}
+
+func (b *jazzerjsBundler) bundle() ([]*archive.Fuzzer, error) {
+	err := b.checkDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	fuzzTests, err := b.fuzzTests()
+	if err != nil {
+		return nil, err
+	}
+
+	return b.assembleArtifacts(fuzzTests)
+}
+
+func (b *jazzerjsBundler) assembleArtifacts(fuzzTests []*nodeFuzzTest) ([]*archive.Fuzzer, error) {
+	err := b.copyProject()
+	if err != nil {
+		return nil, err
+	}
+
+	var archiveDict string
+	if b.opts.Dictionary != "" {
+		archiveDict = "dict"
+		err := b.archiveWriter.WriteFile(archiveDict, b.opts.Dictionary)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var archiveSeedsDir string
+	if len(b.opts.SeedCorpusDirs) > 0 {
+		archiveSeedsDir = "seeds"
+		err := prepareSeeds(b.opts.SeedCorpusDirs, archiveSeedsDir, b.archiveWriter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fuzzers []*archive.Fuzzer
+	for _, fuzzTest := range fuzzTests {
+		relPath, err := filepath.Rel(b.opts.ProjectDir, fuzzTest.testFile)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		fuzzer := &archive.Fuzzer{
+			Name:       fuzzTest.name,
+			Engine:     "JAVASCRIPT_LIBFUZZER",
+			Path:       filepath.ToSlash(filepath.Join(nodeProjectPath, relPath)),
+			ProjectDir: b.opts.ProjectDir,
+			Dictionary: archiveDict,
+			Seeds:      archiveSeedsDir,
+			// The project directory contains the sources and the
+			// node_modules required to run the fuzz test
+			RuntimePaths: []string{nodeProjectPath},
+			EngineOptions: archive.EngineOptions{
+				Env:   b.opts.Env,
+				Flags: b.opts.EngineArgs,
+			},
+			MaxRunTime: uint(b.opts.Timeout.Seconds()),
+		}
+		fuzzers = append(fuzzers, fuzzer)
+	}
+
+	return fuzzers, nil
+}
+
+// copyProject adds the Node.js project to the archive. The node_modules
+// directory is added if it exists and was installed on the platform of
+// the fuzz container, else the dependencies are installed from the lock
+// file when the bundle is executed, so that native addons (like the
+// prebuilt fuzzer addon of Jazzer.js) match the platform.
+func (b *jazzerjsBundler) copyProject() error {
+	projectDir, err := filepath.Abs(b.opts.ProjectDir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	outputPath, err := filepath.Abs(b.opts.OutputPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// The bundle which is currently being created is skipped
+	skippedPaths := map[string]bool{outputPath: true}
+
+	nodeModules := filepath.Join(projectDir, "node_modules")
+	hasNodeModules, err := fileutil.Exists(nodeModules)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	lockFile, err := findNodeLockFile(projectDir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !hasNodeModules && lockFile == "":
+		err = errors.Errorf(`The project directory contains neither a node_modules directory
+nor a lock file (%s). Please run 'npm install' before creating the bundle.`, strings.Join(nodeLockFiles, ", "))
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	case !hasNodeModules:
+		log.Infof("No node_modules directory found, the dependencies will be installed from %s when the bundle is executed", lockFile)
+	case hostPlatform != bundlePlatform && lockFile != "":
+		log.Infof("The node_modules directory was installed on %s, the dependencies will be installed from %s on %s when the bundle is executed",
+			hostPlatform, lockFile, bundlePlatform)
+		skippedPaths[nodeModules] = true
+	case hostPlatform != bundlePlatform:
+		log.Warnf(`The node_modules directory was installed on %s, but the bundle is executed on
+%s. Native addons will fail to load unless a lock file (%s) is added to
+the project, from which the dependencies are installed on execution.`,
+			hostPlatform, bundlePlatform, strings.Join(nodeLockFiles, ", "))
+	}
+
+	return b.copyDir(projectDir, nodeProjectPath, skippedPaths, make(map[string]bool))
+}
+
+// copyDir adds the files in dir to the archive below archiveDir, except
+// for skippedPaths and the files skipped by skipProjectFile. Symlinks to
+// directories, which are created for example by npm workspaces,
+// 'npm link' and pnpm, are followed, because the archive only contains
+// regular files. ancestors contains the resolved paths of the
+// directories which are currently being copied, to detect symlink loops.
+func (b *jazzerjsBundler) copyDir(dir, archiveDir string, skippedPaths, ancestors map[string]bool) error {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if ancestors[resolved] {
+		log.Debugf("Skipping %s, which is a symlink to one of its parent directories", dir)
+		return nil
+	}
+	ancestors[resolved] = true
+	defer delete(ancestors, resolved)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		archivePath := filepath.Join(archiveDir, entry.Name())
+		if skippedPaths[path] {
+			continue
+		}
+		// Follow symlinks
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			log.Debugf("Skipping broken symlink %s", path)
+			continue
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if !info.IsDir() {
+			isDependency := strings.Contains(filepath.ToSlash(archivePath), "/node_modules/")
+			if !isDependency && skipProjectFile(entry.Name()) {
+				log.Debugf("Skipping %s", path)
+				continue
+			}
+			err = b.archiveWriter.WriteFile(archivePath, path)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.Name() == ".git" || strings.HasPrefix(entry.Name(), ".cifuzz-") {
+			// Skip the version control and the files created by cifuzz
+			continue
+		}
+		// Directories are created implicitly by the files they contain
+		err = b.copyDir(path, archivePath, skippedPaths, ancestors)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipProjectFile returns true if the file of the project (not of its
+// dependencies) with the specified name is not added to the bundle:
+// Bundles created before, which would be included in every new bundle,
+// and .env files, which usually contain secrets
+func skipProjectFile(name string) bool {
+	for _, format := range archive.Formats {
+		if strings.HasSuffix(name, format.Extension()) {
+			return true
+		}
+	}
+	return name == ".env" || strings.HasPrefix(name, ".env.")
+}
+
+// fuzzTests returns the fuzz tests specified by the fuzz test arguments,
+// all fuzz tests of the project if none were specified
+func (b *jazzerjsBundler) fuzzTests() ([]*nodeFuzzTest, error) {
+	allFuzzTests, err := findNodeFuzzTests(b.opts.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(allFuzzTests) == 0 {
+		log.Error(errors.Errorf("No fuzz test(s) could be found in the project directory '%s'.", b.opts.ProjectDir))
+		return nil, cmdutils.ErrSilent
+	}
+	if len(b.opts.FuzzTests) == 0 {
+		return allFuzzTests, nil
+	}
+
+	var fuzzTests []*nodeFuzzTest
+	for _, arg := range b.opts.FuzzTests {
+		// The test name may be specified with or without quotes
+		arg = strings.ReplaceAll(arg, "\"", "")
+		var matches []*nodeFuzzTest
+		for _, fuzzTest := range allFuzzTests {
+			name := strings.ReplaceAll(fuzzTest.name, "\"", "")
+			// If only the fuzz test file is specified, all fuzz tests
+			// in the file are bundled
+			if name == arg || strings.HasPrefix(name, arg+":") {
+				matches = append(matches, fuzzTest)
+			}
+		}
+		if len(matches) == 0 {
+			log.Error(errors.Errorf("Fuzz test '%s' could not be found in the project directory '%s'", arg, b.opts.ProjectDir))
+			return nil, cmdutils.ErrSilent
+		}
+		fuzzTests = append(fuzzTests, matches...)
+	}
+
+	return fuzzTests, nil
+}
+
+func (b *jazzerjsBundler) checkDependencies() error {
+	err := dependencies.Check([]dependencies.Key{dependencies.Node}, b.opts.ProjectDir)
+	if err != nil {
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+	return nil
+}
+
+// findNodeFuzzTests returns the fuzz tests in the *.fuzz.js and
+// *.fuzz.ts files of the project. Fuzz test files which contain
+// multiple fuzz tests result in one fuzz test per test name.
+func findNodeFuzzTests(projectDir string) ([]*nodeFuzzTest, error) {
+	var fuzzTests []*nodeFuzzTest
+	err := filepath.WalkDir(projectDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if d.IsDir() {
+			if path != projectDir && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		var fuzzTest string
+		switch {
+		case strings.HasSuffix(d.Name(), ".fuzz.js"):
+			fuzzTest = strings.TrimSuffix(d.Name(), ".fuzz.js")
+		case strings.HasSuffix(d.Name(), ".fuzz.ts"):
+			fuzzTest = strings.TrimSuffix(d.Name(), ".fuzz.ts")
+		default:
+			return nil
+		}
+
+		testNames, err := cmdutils.GetTargetMethodsFromNodeTestFile(path)
+		if err != nil {
+			return err
+		}
+		// If the names of the fuzz tests in the file can't be
+		// determined, the file is bundled as a single fuzz test
+		if len(testNames) <= 1 {
+			fuzzTests = append(fuzzTests, &nodeFuzzTest{name: fuzzTest, testFile: path})
+			return nil
+		}
+		for _, testName := range testNames {
+			fuzzTests = append(fuzzTests, &nodeFuzzTest{
+				name:     fuzzTest + ":" + fmt.Sprintf("%q", testName),
+				testFile: path,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fuzzTests, nil
+}
+
+// findNodeLockFile returns the name of the lock file in the project
+// directory, an empty string if there is none
+func findNodeLockFile(projectDir string) (string, error) {
+	for _, lockFile := range nodeLockFiles {
+		exists, err := fileutil.Exists(filepath.Join(projectDir, lockFile))
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if exists {
+			return lockFile, nil
+		}
+	}
+	return "", nil
+}
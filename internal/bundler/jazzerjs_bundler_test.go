@@ -0,0 +1,236 @@
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+// createNodeProject creates a Node.js project with a fuzz test file which
+// contains two fuzz tests and one which contains a single fuzz test
+func createNodeProject(t *testing.T) string {
+	projectDir := testutil.MkdirTemp(t, "", "jazzerjs-bundler-test-*")
+	files := map[string]string{
+		"package.json":      `{"devDependencies": {"@jazzer.js/jest-runner": "^1.5.1"}}`,
+		"package-lock.json": "{}",
+		"src/parser.fuzz.js": `
+describe("parser", () => {
+	it.fuzz("parses input", (data) => {});
+	it.fuzz("parses numbers", (data) => {});
+});
+`,
+		"single.fuzz.ts":             `test.fuzz("single", (data) => {});`,
+		"node_modules/jest/index.js": "",
+		// Fuzz test files of dependencies are not fuzz tests of the project
+		"node_modules/dep/dep.fuzz.js": `test.fuzz("dep", (data) => {});`,
+		".cifuzz-corpus/input":         "",
+		".git/HEAD":                    "",
+		// Bundles and .env files are not added to the bundle
+		"old-bundle.tar.gz":     "",
+		"bundles/fuzz.zip":      "",
+		".env":                  "SECRET=1",
+		".env.local":            "SECRET=1",
+		"node_modules/dep/.env": "",
+	}
+	for path, content := range files {
+		path = filepath.Join(projectDir, filepath.FromSlash(path))
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		require.NoError(t, err)
+		err = os.WriteFile(path, []byte(content), 0o644)
+		require.NoError(t, err)
+	}
+	return projectDir
+}
+
+// setHostPlatform changes the platform on which the bundle is created
+// for the duration of the test
+func setHostPlatform(t *testing.T, platform string) {
+	orig := hostPlatform
+	hostPlatform = platform
+	t.Cleanup(func() { hostPlatform = orig })
+}
+
+func TestAssembleArtifactsJazzerJS(t *testing.T) {
+	setHostPlatform(t, bundlePlatform)
+	projectDir := createNodeProject(t)
+
+	bundle := filepath.Join(testutil.MkdirTemp(t, "", "jazzerjs-bundle-*"), "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	archiveWriter, err := archive.NewWriter(f, &archive.WriterOptions{Format: archive.FormatTarGz})
+	require.NoError(t, err)
+
+	b := newJazzerjsBundler(&Opts{
+		ProjectDir: projectDir,
+		Env:        []string{"FOO=foo"},
+		EngineArgs: []string{"-runs=100"},
+		OutputPath: bundle,
+	}, archiveWriter)
+	fuzzTests, err := b.fuzzTests()
+	require.NoError(t, err)
+	fuzzers, err := b.assembleArtifacts(fuzzTests)
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	require.Len(t, fuzzers, 3)
+	assert.Equal(t, &archive.Fuzzer{
+		Name:         "single",
+		Engine:       "JAVASCRIPT_LIBFUZZER",
+		Path:         "project/single.fuzz.ts",
+		ProjectDir:   projectDir,
+		RuntimePaths: []string{"project"},
+		EngineOptions: archive.EngineOptions{
+			Env:   []string{"FOO=foo"},
+			Flags: []string{"-runs=100"},
+		},
+	}, fuzzers[0])
+	assert.Equal(t, `parser:"parses input"`, fuzzers[1].Name)
+	assert.Equal(t, "project/src/parser.fuzz.js", fuzzers[1].Path)
+	assert.Equal(t, `parser:"parses numbers"`, fuzzers[2].Name)
+
+	out := testutil.MkdirTemp(t, "", "jazzerjs-bundle-extracted-*")
+	err = archive.Extract(bundle, out)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(out, "project", "package.json"))
+	assert.FileExists(t, filepath.Join(out, "project", "src", "parser.fuzz.js"))
+	assert.FileExists(t, filepath.Join(out, "project", "node_modules", "jest", "index.js"))
+	assert.NoDirExists(t, filepath.Join(out, "project", ".cifuzz-corpus"))
+	assert.NoDirExists(t, filepath.Join(out, "project", ".git"))
+	assert.NoFileExists(t, filepath.Join(out, "project", "old-bundle.tar.gz"))
+	assert.NoFileExists(t, filepath.Join(out, "project", "bundles", "fuzz.zip"))
+	assert.NoFileExists(t, filepath.Join(out, "project", ".env"))
+	assert.NoFileExists(t, filepath.Join(out, "project", ".env.local"))
+	// Files of dependencies are added regardless of their names
+	assert.FileExists(t, filepath.Join(out, "project", "node_modules", "dep", ".env"))
+}
+
+func TestJazzerJSCopyProjectOnOtherPlatform(t *testing.T) {
+	setHostPlatform(t, "darwin/arm64")
+	projectDir := createNodeProject(t)
+
+	bundle := filepath.Join(testutil.MkdirTemp(t, "", "jazzerjs-bundle-*"), "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	archiveWriter, err := archive.NewWriter(f, &archive.WriterOptions{Format: archive.FormatTarGz})
+	require.NoError(t, err)
+	b := newJazzerjsBundler(&Opts{ProjectDir: projectDir, OutputPath: bundle}, archiveWriter)
+	err = b.copyProject()
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	// The node_modules directory, which may contain native addons for
+	// the host platform, is replaced by installing the dependencies from
+	// the lock file when the bundle is executed
+	out := testutil.MkdirTemp(t, "", "jazzerjs-bundle-extracted-*")
+	err = archive.Extract(bundle, out)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(out, "project", "package-lock.json"))
+	assert.NoDirExists(t, filepath.Join(out, "project", "node_modules"))
+}
+
+func TestJazzerJSCopyProjectWithSymlinks(t *testing.T) {
+	setHostPlatform(t, bundlePlatform)
+	projectDir := createNodeProject(t)
+	// A workspace package which is linked into node_modules, like by npm
+	// workspaces and pnpm
+	err := os.MkdirAll(filepath.Join(projectDir, "packages", "lib"), 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(projectDir, "packages", "lib", "index.js"), []byte("lib"), 0o644)
+	require.NoError(t, err)
+	err = os.Symlink(filepath.Join("..", "packages", "lib"), filepath.Join(projectDir, "node_modules", "lib"))
+	require.NoError(t, err)
+	// A package outside of the project, like one added via 'npm link'
+	linkedDir := testutil.MkdirTemp(t, "", "jazzerjs-linked-package-*")
+	err = os.WriteFile(filepath.Join(linkedDir, "index.js"), []byte("linked"), 0o644)
+	require.NoError(t, err)
+	err = os.Symlink(linkedDir, filepath.Join(projectDir, "node_modules", "linked"))
+	require.NoError(t, err)
+	// A symlink loop and a broken symlink
+	err = os.Symlink("..", filepath.Join(projectDir, "packages", "lib", "loop"))
+	require.NoError(t, err)
+	err = os.Symlink("does-not-exist", filepath.Join(projectDir, "node_modules", "broken"))
+	require.NoError(t, err)
+
+	bundle := filepath.Join(testutil.MkdirTemp(t, "", "jazzerjs-bundle-*"), "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	archiveWriter, err := archive.NewWriter(f, &archive.WriterOptions{Format: archive.FormatTarGz})
+	require.NoError(t, err)
+	b := newJazzerjsBundler(&Opts{ProjectDir: projectDir, OutputPath: bundle}, archiveWriter)
+	err = b.copyProject()
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	out := testutil.MkdirTemp(t, "", "jazzerjs-bundle-extracted-*")
+	err = archive.Extract(bundle, out)
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(out, "project", "node_modules", "lib", "index.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "lib", string(content))
+	content, err = os.ReadFile(filepath.Join(out, "project", "node_modules", "linked", "index.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "linked", string(content))
+	assert.FileExists(t, filepath.Join(out, "project", "packages", "lib", "index.js"))
+	// Symlinks to parent directories are not followed
+	assert.NoDirExists(t, filepath.Join(out, "project", "packages", "lib", "loop"))
+}
+
+func TestJazzerJSFuzzTests(t *testing.T) {
+	projectDir := createNodeProject(t)
+
+	fuzzTestNames := func(args ...string) []string {
+		b := newJazzerjsBundler(&Opts{ProjectDir: projectDir, FuzzTests: args}, &archive.NullArchiveWriter{})
+		fuzzTests, err := b.fuzzTests()
+		require.NoError(t, err)
+		var names []string
+		for _, fuzzTest := range fuzzTests {
+			names = append(names, fuzzTest.name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{`parser:"parses input"`, `parser:"parses numbers"`}, fuzzTestNames("parser"))
+	assert.Equal(t, []string{`parser:"parses numbers"`}, fuzzTestNames(`parser:"parses numbers"`))
+	assert.Equal(t, []string{`parser:"parses numbers"`}, fuzzTestNames("parser:parses numbers"))
+	assert.Equal(t, []string{"single"}, fuzzTestNames("single"))
+
+	// Fuzz test files whose fuzz test names can't be determined are
+	// bundled as a single fuzz test
+	err := os.WriteFile(filepath.Join(projectDir, "quoted.fuzz.js"), []byte(`test.fuzz('quoted', (data) => {});`), 0o644)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"quoted"}, fuzzTestNames("quoted"))
+
+	b := newJazzerjsBundler(&Opts{ProjectDir: projectDir, FuzzTests: []string{"dep"}}, &archive.NullArchiveWriter{})
+	_, err = b.fuzzTests()
+	require.Error(t, err)
+}
+
+func TestJazzerJSWithoutDependencies(t *testing.T) {
+	projectDir := createNodeProject(t)
+	err := os.RemoveAll(filepath.Join(projectDir, "node_modules"))
+	require.NoError(t, err)
+
+	// Without node_modules, the dependencies are installed from the
+	// lock file when the bundle is executed
+	b := newJazzerjsBundler(&Opts{ProjectDir: projectDir}, &archive.NullArchiveWriter{})
+	require.NoError(t, b.copyProject())
+
+	err = os.Remove(filepath.Join(projectDir, "package-lock.json"))
+	require.NoError(t, err)
+	require.Error(t, b.copyProject())
+}
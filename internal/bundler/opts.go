@@ -8,13 +8,19 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/config"
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/util/sliceutil"
 )
 
+// SignKeyEnv is the environment variable which contains the private key
+// which is used to sign bundles if no key file is specified
+const SignKeyEnv = "CIFUZZ_SIGN_KEY"
+
 type Opts struct {
 	Branch          string        `mapstructure:"branch"`
 	BuildCommand    string        `mapstructure:"build-command"`
@@ -32,11 +38,19 @@ type Opts struct {
 	ConfigDir       string        `mapstructure:"config-dir"`
 	AdditionalFiles []string      `mapstructure:"add"`
 
+	// The format of the bundle, see archive.Formats
+	Format             string `mapstructure:"bundle-format"`
+	CompressionLevel   int    `mapstructure:"compression-level"`
+	CompressionThreads int    `mapstructure:"compression-threads"`
+
 	// Fields which are not configurable via viper (i.e. via cifuzz.yaml
 	// and CIFUZZ_* environment variables), by setting
 	// mapstructure:"-"
-	FuzzTests       []string  `mapstructure:"-"`
-	OutputPath      string    `mapstructure:"-"`
+	FuzzTests  []string `mapstructure:"-"`
+	OutputPath string   `mapstructure:"-"`
+	// The path of the key file with the private key which is used to
+	// sign the bundle, only settable via the --sign-key flag
+	SignKey         string    `mapstructure:"-"`
 	BuildSystemArgs []string  `mapstructure:"-"`
 	Stdout          io.Writer `mapstructure:"-"`
 	Stderr          io.Writer `mapstructure:"-"`
@@ -52,6 +66,11 @@ type Opts struct {
 func (opts *Opts) Validate() error {
 	var err error
 
+	if viper.InConfig("sign-key") {
+		log.Warnf(`The "sign-key" setting in %s is ignored. Please specify the key file
+via --sign-key or the key via the %s environment variable instead.`, config.ProjectConfigFile, SignKeyEnv)
+	}
+
 	// Ensure that the fuzz tests contain no duplicates
 	opts.FuzzTests = sliceutil.RemoveDuplicates(opts.FuzzTests)
 
@@ -109,6 +128,22 @@ system type "other"`
 		}
 	}
 
+	if opts.Format == "" {
+		opts.Format = formatFromOutputPath(opts.OutputPath)
+	}
+	format, err := archive.ParseFormat(opts.Format)
+	if err != nil {
+		return cmdutils.WrapIncorrectUsageError(err)
+	}
+	err = format.ValidateCompressionLevel(opts.CompressionLevel)
+	if err != nil {
+		return cmdutils.WrapIncorrectUsageError(err)
+	}
+	if opts.CompressionThreads < 0 {
+		msg := fmt.Sprintf("invalid argument %d for \"--compression-threads\" flag: must not be negative", opts.CompressionThreads)
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+
 	if opts.Timeout != 0 && opts.Timeout < time.Second {
 		msg := fmt.Sprintf("invalid argument %q for \"--timeout\" flag: timeout can't be less than a second", opts.Timeout)
 		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
@@ -135,3 +170,14 @@ system type "other"`
 
 	return nil
 }
+
+// formatFromOutputPath returns the bundle format which matches the file
+// extension of the output path, the default format tar.gz if none does
+func formatFromOutputPath(outputPath string) string {
+	for _, format := range archive.Formats {
+		if strings.HasSuffix(outputPath, format.Extension()) {
+			return string(format)
+		}
+	}
+	return string(archive.FormatTarGz)
+}
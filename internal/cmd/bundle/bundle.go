@@ -12,6 +12,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"code-intelligence.com/cifuzz/internal/bundler"
+	inspectCmd "code-intelligence.com/cifuzz/internal/cmd/bundle/inspect"
+	verifyCmd "code-intelligence.com/cifuzz/internal/cmd/bundle/verify"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/cmdutils/logging"
 	"code-intelligence.com/cifuzz/internal/cmdutils/resolve"
@@ -31,12 +33,6 @@ func (opts *options) Validate() error {
 		return cmdutils.WrapSilentError(err)
 	}
 
-	if opts.BuildSystem == config.BuildSystemNodeJS && !config.AllowUnsupportedPlatforms() {
-		err = errors.Errorf(config.NotSupportedErrorMessage("bundle", opts.BuildSystem))
-		log.Error(err)
-		return cmdutils.WrapSilentError(err)
-	}
-
 	return opts.Opts.Validate()
 }
 
@@ -65,6 +61,23 @@ configured for the project.
 This command will select an appropriate Docker image for execution based
 on the build system. This can be overridden with a docker-image flag.
 
+Bundles are created as gzip-compressed tar archives by default. With
+--format, bundles can also be created as zstd-compressed tar archives,
+which are compressed faster and by multiple threads, or as zip archives.
+The format of a bundle is detected automatically when it's used.
+
+Bundles are reproducible: Bundles created from the same artifacts are
+identical. All files in the bundle have the modification time specified
+by the SOURCE_DATE_EPOCH environment variable, or the Unix epoch if it
+is not set. The bundle contains a manifest.sha256 file with the SHA-256
+checksums of all files in the bundle. If an ed25519 private key is
+specified via --sign-key (a key file) or the CIFUZZ_SIGN_KEY environment
+variable, the manifest is signed and the signature is added as
+manifest.sha256.sig.
+
+The contents of a bundle can be shown with 'cifuzz bundle inspect' and
+checked for completeness with 'cifuzz bundle verify'.
+
 ` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("CMake") + `
   <fuzz test> is the name of the fuzz test defined in the add_fuzz_test
   command in your CMakeLists.txt.
@@ -101,6 +114,24 @@ on the build system. This can be overridden with a docker-image flag.
 
   If no fuzz tests are specified, all fuzz tests are added to the bundle.
 
+` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("Node.js") + `
+  <fuzz test> is the name of the fuzz test file without the .fuzz.js or
+  .fuzz.ts extension. A single fuzz test of a file which contains multiple
+  fuzz tests can be selected by appending its name, separated by a colon:
+
+    cifuzz bundle FuzzTestCase:"My fuzz test"
+
+  The project directory, including its node_modules directory, is added
+  to the bundle, except for bundles and .env files. If the project has
+  no node_modules directory, or if the bundle is not created on
+  linux/amd64 (the platform of the fuzz container) and the project has a
+  lock file, the dependencies are installed from its package-lock.json
+  or yarn.lock when the bundle is executed.
+
+  The --build-command flag is ignored.
+
+  If no fuzz tests are specified, all fuzz tests are added to the bundle.
+
 ` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("Other build systems") + `
   <fuzz test> is either the path or basename of the fuzz test executable
   created by the build command. If it's the basename, it will be searched
@@ -172,6 +203,11 @@ on the build system. This can be overridden with a docker-image flag.
 			}
 			opts.FuzzTests = fuzzTests
 			opts.BuildSystemArgs = argsToPass
+			// The sign key is not bound to viper, see AddSignKeyFlag
+			opts.SignKey, err = cmd.Flags().GetString("sign-key")
+			if err != nil {
+				return errors.WithStack(err)
+			}
 
 			return opts.Validate()
 		},
@@ -206,17 +242,24 @@ on the build system. This can be overridden with a docker-image flag.
 		cmdutils.AddBuildCommandFlag,
 		cmdutils.AddCleanCommandFlag,
 		cmdutils.AddBuildJobsFlag,
+		cmdutils.AddBundleFormatFlag,
 		cmdutils.AddCommitFlag,
+		cmdutils.AddCompressionLevelFlag,
+		cmdutils.AddCompressionThreadsFlag,
 		cmdutils.AddDictFlag,
 		cmdutils.AddDockerImageFlag,
 		cmdutils.AddEngineArgFlag,
 		cmdutils.AddEnvFlag,
 		cmdutils.AddProjectDirFlag,
 		cmdutils.AddSeedCorpusFlag,
+		cmdutils.AddSignKeyFlag,
 		cmdutils.AddTimeoutFlag,
 		cmdutils.AddResolveSourceFileFlag,
 	)
-	cmd.Flags().StringVarP(&opts.OutputPath, "output", "o", "", "Output path of the bundle (.tar.gz)")
+	cmd.Flags().StringVarP(&opts.OutputPath, "output", "o", "", "Output path of the bundle (.tar.gz, .tar.zst or .zip)")
+
+	cmd.AddCommand(inspectCmd.New())
+	cmd.AddCommand(verifyCmd.New())
 
 	return cmd
 }
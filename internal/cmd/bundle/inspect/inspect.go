@@ -0,0 +1,178 @@
+package inspect
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+type inspectCmd struct {
+	*cobra.Command
+}
+
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect [flags] <bundle>",
+		Short: "Show the contents of a bundle",
+		Long: `This command prints the metadata of a bundle created by 'cifuzz bundle',
+i.e. the fuzzers with their engines, sanitizers, library and runtime
+paths, the code revision and the Docker image, and a tree of the files
+in the bundle with their sizes.
+
+The bundle is not extracted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			cmd := inspectCmd{Command: c}
+			return cmd.run(args[0])
+		},
+	}
+	return cmd
+}
+
+func (c *inspectCmd) run(bundle string) error {
+	contents, err := archive.ReadContents(bundle)
+	if err != nil {
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	err = PrintMetadata(c.OutOrStdout(), contents.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.OutOrStdout(), "\nFiles:")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return PrintFileTree(c.OutOrStdout(), contents.Entries)
+}
+
+// PrintMetadata prints the metadata of a bundle in a human-readable form
+func PrintMetadata(w io.Writer, metadata *archive.Metadata) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if metadata.RunEnvironment != nil {
+		fmt.Fprintf(tw, "Docker image:\t%s\n", metadata.Docker)
+	}
+	if metadata.CodeRevision != nil && metadata.CodeRevision.Git != nil {
+		revision := metadata.CodeRevision.Git.Commit
+		if metadata.CodeRevision.Git.Branch != "" {
+			revision += fmt.Sprintf(" (%s)", metadata.CodeRevision.Git.Branch)
+		}
+		fmt.Fprintf(tw, "Code revision:\t%s\n", revision)
+	}
+	fmt.Fprintf(tw, "Fuzzers:\t%d\n", len(metadata.Fuzzers))
+	for _, fuzzer := range metadata.Fuzzers {
+		name := fuzzer.Target
+		if name == "" {
+			name = fuzzer.Name
+		}
+		fmt.Fprintf(tw, "\n%s\n", name)
+		printField(tw, "Engine", fuzzer.Engine)
+		printField(tw, "Sanitizer", fuzzer.Sanitizer)
+		printField(tw, "Path", fuzzer.Path)
+		printField(tw, "Project dir", fuzzer.ProjectDir)
+		printField(tw, "Seeds", fuzzer.Seeds)
+		printField(tw, "Dictionary", fuzzer.Dictionary)
+		printField(tw, "Library paths", fuzzer.LibraryPaths...)
+		printField(tw, "Runtime paths", fuzzer.RuntimePaths...)
+		printField(tw, "Engine flags", fuzzer.EngineOptions.Flags...)
+		printField(tw, "Engine env", fuzzer.EngineOptions.Env...)
+		if fuzzer.MaxRunTime != 0 {
+			printField(tw, "Max run time", fmt.Sprintf("%ds", fuzzer.MaxRunTime))
+		}
+	}
+	return errors.WithStack(tw.Flush())
+}
+
+// printField prints a field of a fuzzer, one line per value. Fields
+// without values are omitted.
+func printField(w io.Writer, name string, values ...string) {
+	label := name + ":"
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", label, value)
+		label = ""
+	}
+}
+
+type node struct {
+	name     string
+	entry    *archive.Entry
+	size     int64
+	children map[string]*node
+}
+
+// PrintFileTree prints a tree of the entries of a bundle with their
+// sizes. The size of a directory is the total size of the files in it.
+func PrintFileTree(w io.Writer, entries []*archive.Entry) error {
+	root := &node{children: make(map[string]*node)}
+	for _, entry := range entries {
+		n := root
+		for _, part := range strings.Split(entry.Name, "/") {
+			if part == "." || part == "" {
+				continue
+			}
+			child, ok := n.children[part]
+			if !ok {
+				child = &node{name: part, children: make(map[string]*node)}
+				n.children[part] = child
+			}
+			child.size += entry.Size
+			n = child
+		}
+		if n != root {
+			n.entry = entry
+		}
+	}
+
+	var sb strings.Builder
+	root.print(&sb, "")
+	_, err := io.WriteString(w, sb.String())
+	return errors.WithStack(err)
+}
+
+func (n *node) print(sb *strings.Builder, indent string) {
+	var names []string
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := n.children[name]
+		switch {
+		case len(child.children) > 0 || (child.entry != nil && child.entry.IsDir()):
+			fmt.Fprintf(sb, "%s%s/ (%s)\n", indent, child.name, FormatSize(child.size))
+			child.print(sb, indent+"  ")
+		case child.entry != nil && (child.entry.Typeflag == tar.TypeLink || child.entry.Typeflag == tar.TypeSymlink):
+			fmt.Fprintf(sb, "%s%s -> %s\n", indent, child.name, child.entry.Linkname)
+		default:
+			fmt.Fprintf(sb, "%s%s (%s)\n", indent, child.name, FormatSize(child.size))
+		}
+	}
+}
+
+// FormatSize formats a size in bytes with a binary unit
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,77 @@
+package inspect
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+)
+
+func TestPrintMetadata(t *testing.T) {
+	metadata := &archive.Metadata{
+		RunEnvironment: &archive.RunEnvironment{Docker: "ubuntu:rolling"},
+		CodeRevision: &archive.CodeRevision{Git: &archive.GitRevision{
+			Commit: "0123456789abcdef",
+			Branch: "main",
+		}},
+		Fuzzers: []*archive.Fuzzer{{
+			Target:       "my_fuzz_test",
+			Path:         "libfuzzer/address/my_fuzz_test/bin/my_fuzz_test",
+			Engine:       "LIBFUZZER",
+			Sanitizer:    "ADDRESS",
+			LibraryPaths: []string{"libfuzzer/address/my_fuzz_test/lib", "external_libs"},
+		}},
+	}
+
+	var out bytes.Buffer
+	err := PrintMetadata(&out, metadata)
+	require.NoError(t, err)
+	assert.Equal(t, `Docker image:   ubuntu:rolling
+Code revision:  0123456789abcdef (main)
+Fuzzers:        1
+
+my_fuzz_test
+  Engine:         LIBFUZZER
+  Sanitizer:      ADDRESS
+  Path:           libfuzzer/address/my_fuzz_test/bin/my_fuzz_test
+  Library paths:  libfuzzer/address/my_fuzz_test/lib
+                  external_libs
+`, out.String())
+}
+
+func TestPrintFileTree(t *testing.T) {
+	entries := []*archive.Entry{
+		{Name: "bundle.yaml", Size: 100, Typeflag: tar.TypeReg},
+		{Name: "libfuzzer/address/my_fuzz_test/bin/my_fuzz_test", Size: 2048, Typeflag: tar.TypeReg},
+		{Name: "libfuzzer/address/my_fuzz_test/lib/libfoo.so", Size: 1024, Typeflag: tar.TypeReg},
+		{Name: "libfuzzer/address/my_fuzz_test/lib/libbar.so", Typeflag: tar.TypeLink, Linkname: "libfuzzer/address/my_fuzz_test/lib/libfoo.so"},
+		{Name: "seeds", Typeflag: tar.TypeDir},
+	}
+
+	var out bytes.Buffer
+	err := PrintFileTree(&out, entries)
+	require.NoError(t, err)
+	assert.Equal(t, `bundle.yaml (100 B)
+libfuzzer/ (3.0 KiB)
+  address/ (3.0 KiB)
+    my_fuzz_test/ (3.0 KiB)
+      bin/ (2.0 KiB)
+        my_fuzz_test (2.0 KiB)
+      lib/ (1.0 KiB)
+        libbar.so -> libfuzzer/address/my_fuzz_test/lib/libfoo.so
+        libfoo.so (1.0 KiB)
+seeds/ (0 B)
+`, out.String())
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0 B", FormatSize(0))
+	assert.Equal(t, "1023 B", FormatSize(1023))
+	assert.Equal(t, "1.0 KiB", FormatSize(1024))
+	assert.Equal(t, "1.5 MiB", FormatSize(1536*1024))
+	assert.Equal(t, "2.0 GiB", FormatSize(2*1024*1024*1024))
+}
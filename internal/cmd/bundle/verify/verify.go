@@ -0,0 +1,357 @@
+package verify
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/container"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/fileutil"
+	"code-intelligence.com/cifuzz/util/sliceutil"
+	"code-intelligence.com/cifuzz/util/stringutil"
+)
+
+type options struct {
+	// If set, the shared library dependencies are not checked against
+	// the system libraries of the Docker image of the bundle
+	SkipImage bool
+	// The public key which the bundle must be signed with
+	TrustedKey string
+	// The container backend which lists the system libraries of the
+	// Docker image and the local base image used by the runc backend
+	ContainerBackend string
+	BaseImage        string
+}
+
+func (opts *options) validate() error {
+	if opts.ContainerBackend == "" {
+		opts.ContainerBackend = container.BackendAuto
+	}
+	if !stringutil.Contains(container.Backends, opts.ContainerBackend) {
+		msg := fmt.Sprintf("Flag \"backend\" must be %s", strings.Join(container.Backends, " or "))
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if !opts.SkipImage && opts.ContainerBackend == container.BackendRunc && opts.BaseImage == "" {
+		msg := `Flag "base-image" must be set for the runc backend`
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	return nil
+}
+
+type verifyCmd struct {
+	*cobra.Command
+	opts *options
+}
+
+// Result holds the problems found in a bundle
+type Result struct {
+	// Problems which prevent the bundle from being executed
+	Errors []string
+	// Potential problems which could not be checked
+	Warnings []string
+}
+
+func New() *cobra.Command {
+	return newWithOptions(&options{})
+}
+
+func newWithOptions(opts *options) *cobra.Command {
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "verify [flags] <bundle>",
+		Short: "Check that a bundle is complete",
+		Long: `This command checks that a bundle created by 'cifuzz bundle' contains
+everything which is required to run its fuzzers:
+
+  * The path of each fuzzer and all of its runtime and library paths
+    exist in the bundle.
+  * The seed corpus directories are not empty.
+  * The shared library dependencies of the fuzzer executables and the
+    bundled libraries are satisfied by the libraries in the bundle or by
+    the system libraries of the Docker image of the bundle.
+  * The files match the checksums of the content manifest of the bundle
+    and there are no files which are not listed in it.
+    If a trusted public key is specified via --trusted-key (or the
+    trusted-key setting in cifuzz.yaml), the bundle must be signed with
+    the corresponding private key.
+
+The system libraries of the Docker image are listed by running a
+container of the image via the container backend selected with
+--backend (see 'cifuzz container run'). The runc backend lists the
+library files of the local base image specified via --base-image
+instead, because it can't pull the image. Use --skip-image to only
+check the libraries in the bundle.
+
+The command exits with a non-zero exit code if a problem was found.`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			bindFlags()
+			opts.TrustedKey = viper.GetString("trusted-key")
+			opts.ContainerBackend = viper.GetString("container-backend")
+			opts.BaseImage = viper.GetString("base-image")
+			return opts.validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			cmd := verifyCmd{Command: c, opts: opts}
+			return cmd.run(args[0])
+		},
+	}
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddBaseImageFlag,
+		cmdutils.AddContainerBackendFlag,
+		cmdutils.AddTrustedKeyFlag,
+	)
+	cmd.Flags().BoolVar(&opts.SkipImage, "skip-image", false, "Don't check the shared library dependencies against the system libraries of the Docker image.")
+	return cmd
+}
+
+func (c *verifyCmd) run(bundle string) error {
+	trustedKey, err := archive.LoadPublicKey(c.opts.TrustedKey, "")
+	if err != nil {
+		log.Errorf(err, "Failed to load the trusted key: %v", err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	contents, err := archive.ReadContents(bundle)
+	if err != nil {
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	var systemLibs map[string]bool
+	if !c.opts.SkipImage && contents.Metadata.RunEnvironment != nil && contents.Metadata.Docker != "" {
+		systemLibs, err = c.systemLibraries(contents.Metadata.Docker)
+		if err != nil {
+			log.Warnf("Failed to list the system libraries of image %s: %v", contents.Metadata.Docker, err)
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "bundle-verify-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tmpDir)
+	err = archive.Extract(bundle, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	result := Verify(contents, tmpDir, systemLibs)
+	err = archive.VerifyDir(tmpDir, trustedKey)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Integrity check failed: %v", err))
+	} else if trustedKey == nil && contents.Entry(archive.SignatureFileName) != nil {
+		result.Warnings = append(result.Warnings, "The bundle is signed, but the signature was not checked because no trusted key was specified")
+	}
+	for _, warning := range result.Warnings {
+		log.Warn(warning)
+	}
+	if len(result.Errors) > 0 {
+		for _, problem := range result.Errors {
+			log.Print(problem + "\n")
+		}
+		err = errors.Errorf("Found %d problems in bundle %s", len(result.Errors), bundle)
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+	log.Successf("Bundle %s is complete", bundle)
+	return nil
+}
+
+// systemLibraries lists the system libraries of the image via the
+// selected container backend
+func (c *verifyCmd) systemLibraries(image string) (map[string]bool, error) {
+	backend, err := container.NewBackend(c.opts.ContainerBackend, &container.BackendOptions{
+		BaseImage: c.opts.BaseImage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer backend.Close()
+	libraries, err := backend.SystemLibraries(image)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Found %d system libraries in image %s", len(libraries), image)
+	return libraries, nil
+}
+
+// Verify checks the contents of a bundle which was extracted into
+// extractedDir. If systemLibs is nil, shared library dependencies which
+// are not satisfied by the bundled libraries are reported as warnings
+// instead of errors.
+func Verify(contents *archive.Contents, extractedDir string, systemLibs map[string]bool) *Result {
+	result := &Result{}
+	checkedExecutables := make(map[string]bool)
+	var unverifiedLibs []string
+
+	for _, fuzzer := range contents.Metadata.Fuzzers {
+		name := fuzzer.Target
+		if name == "" {
+			name = fuzzer.Name
+		}
+		errorf := func(format string, args ...any) {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s (%s): ", name, fuzzer.Engine)+fmt.Sprintf(format, args...))
+		}
+
+		if fuzzer.Path != "" {
+			entry := contents.Entry(fuzzer.Path)
+			if entry == nil {
+				errorf("fuzzer path %s doesn't exist", fuzzer.Path)
+			} else if entry.IsDir() {
+				errorf("fuzzer path %s is a directory", fuzzer.Path)
+			}
+		}
+		for _, p := range fuzzer.RuntimePaths {
+			if contents.Entry(p) == nil {
+				errorf("runtime path %s doesn't exist", p)
+			}
+		}
+		for _, p := range fuzzer.LibraryPaths {
+			if contents.Entry(p) == nil {
+				errorf("library path %s doesn't exist", p)
+			}
+		}
+		if fuzzer.Dictionary != "" && contents.Entry(fuzzer.Dictionary) == nil {
+			errorf("dictionary %s doesn't exist", fuzzer.Dictionary)
+		}
+		if fuzzer.Seeds != "" && !contents.HasFilesIn(fuzzer.Seeds) {
+			errorf("seed corpus directory %s is empty", fuzzer.Seeds)
+		}
+
+		if entry := contents.Entry(fuzzer.Path); entry == nil || entry.IsDir() {
+			continue
+		}
+		key := fuzzer.Path + "\x00" + strings.Join(fuzzer.LibraryPaths, "\x00")
+		if checkedExecutables[key] {
+			continue
+		}
+		checkedExecutables[key] = true
+
+		missing, err := missingLibraries(contents, extractedDir, fuzzer.Path, fuzzer.LibraryPaths)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: failed to check shared library dependencies: %v", name, err))
+			continue
+		}
+		for _, lib := range missing {
+			switch {
+			case systemLibs == nil:
+				unverifiedLibs = append(unverifiedLibs, lib.name)
+			case !systemLibs[lib.name]:
+				errorf("shared library %s required by %s is neither bundled nor provided by image %s", lib.name, lib.requiredBy, contents.Metadata.Docker)
+			}
+		}
+	}
+
+	if len(unverifiedLibs) > 0 {
+		sort.Strings(unverifiedLibs)
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"The following shared libraries are not bundled and could not be checked against the system libraries of the image: %s",
+			strings.Join(sliceutil.RemoveDuplicates(unverifiedLibs), ", ")))
+	}
+	return result
+}
+
+type library struct {
+	name       string
+	requiredBy string
+}
+
+// missingLibraries returns the shared library dependencies of the
+// executable and the bundled libraries it (transitively) depends on
+// which are not satisfied by the libraries in the library paths or the
+// RUNPATH/RPATH of the binary in the bundle
+func missingLibraries(contents *archive.Contents, extractedDir, executable string, libraryPaths []string) ([]*library, error) {
+	var missing []*library
+	seen := map[string]bool{}
+	queue := []string{executable}
+	for len(queue) > 0 {
+		binary := queue[0]
+		queue = queue[1:]
+		if seen[binary] {
+			continue
+		}
+		seen[binary] = true
+
+		f, err := elf.Open(filepath.Join(extractedDir, filepath.FromSlash(binary)))
+		if err != nil {
+			// Files which are not ELF files (for example JAR files or
+			// linker scripts) are not checked
+			var formatErr *elf.FormatError
+			if errors.As(err, &formatErr) {
+				continue
+			}
+			return nil, errors.WithStack(err)
+		}
+		needed, err := f.ImportedLibraries()
+		if err != nil {
+			f.Close()
+			return nil, errors.WithStack(err)
+		}
+		searchPaths := append(runPaths(f, path.Dir(binary)), libraryPaths...)
+		f.Close()
+
+		for _, lib := range needed {
+			found := ""
+			for _, dir := range searchPaths {
+				candidate := path.Join(dir, lib)
+				if contents.Entry(candidate) != nil {
+					found = candidate
+					break
+				}
+			}
+			if found != "" {
+				queue = append(queue, found)
+				continue
+			}
+			if !containsLibrary(missing, lib) {
+				missing = append(missing, &library{name: lib, requiredBy: binary})
+			}
+		}
+	}
+	return missing, nil
+}
+
+// runPaths returns the directories of the RUNPATH and RPATH entries of
+// the binary which are relative to its location ($ORIGIN), as paths in
+// the bundle
+func runPaths(f *elf.File, origin string) []string {
+	var res []string
+	for _, tag := range []elf.DynTag{elf.DT_RUNPATH, elf.DT_RPATH} {
+		values, err := f.DynString(tag)
+		if err != nil {
+			continue
+		}
+		for _, value := range values {
+			for _, dir := range strings.Split(value, ":") {
+				if !strings.HasPrefix(dir, "$ORIGIN") && !strings.HasPrefix(dir, "${ORIGIN}") {
+					continue
+				}
+				dir = strings.Replace(strings.Replace(dir, "${ORIGIN}", origin, 1), "$ORIGIN", origin, 1)
+				res = append(res, path.Clean(dir))
+			}
+		}
+	}
+	return res
+}
+
+func containsLibrary(libs []*library, name string) bool {
+	for _, lib := range libs {
+		if lib.name == name {
+			return true
+		}
+	}
+	return false
+}
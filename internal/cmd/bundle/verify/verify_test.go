@@ -0,0 +1,150 @@
+package verify
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+// createBundle writes the files in the source directory and the
+// metadata into a bundle and returns the contents of the bundle and the
+// directory it was extracted to
+func createBundle(t *testing.T, sourceDir string, metadata *archive.Metadata) (*archive.Contents, string) {
+	metadataYaml, err := metadata.ToYaml()
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(sourceDir, archive.MetadataFileName), metadataYaml, 0o644)
+	require.NoError(t, err)
+
+	bundle := filepath.Join(testutil.MkdirTemp(t, "", "verify-test-bundle-*"), "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	archiveWriter := archive.NewTarArchiveWriter(f, true)
+	err = archiveWriter.WriteDir("", sourceDir)
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	contents, err := archive.ReadContents(bundle)
+	require.NoError(t, err)
+	extractedDir := testutil.MkdirTemp(t, "", "verify-test-extracted-*")
+	err = archive.Extract(bundle, extractedDir)
+	require.NoError(t, err)
+	return contents, extractedDir
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(path, []byte(content), 0o755)
+	require.NoError(t, err)
+}
+
+func TestVerify_MissingPaths(t *testing.T) {
+	dir := testutil.MkdirTemp(t, "", "verify-test-*")
+	writeFile(t, filepath.Join(dir, "fuzzer", "bin", "my_fuzz_test"), "not an executable")
+	writeFile(t, filepath.Join(dir, "fuzzer", "runtime", "agent.jar"), "")
+	writeFile(t, filepath.Join(dir, "seeds", "non_empty", "input"), "seed")
+	err := os.MkdirAll(filepath.Join(dir, "seeds", "empty"), 0o755)
+	require.NoError(t, err)
+
+	metadata := &archive.Metadata{
+		RunEnvironment: &archive.RunEnvironment{Docker: "ubuntu:rolling"},
+		Fuzzers: []*archive.Fuzzer{
+			{
+				Target:       "complete",
+				Engine:       "JAVA_LIBFUZZER",
+				RuntimePaths: []string{"fuzzer/runtime/agent.jar"},
+				Seeds:        "seeds/non_empty",
+			},
+			{
+				Target:       "incomplete",
+				Engine:       "LIBFUZZER",
+				Path:         "fuzzer/bin/missing",
+				RuntimePaths: []string{"fuzzer/runtime/missing.jar"},
+				LibraryPaths: []string{"fuzzer/lib"},
+				Dictionary:   "fuzzer/missing.dict",
+				Seeds:        "seeds/empty",
+			},
+			{
+				Target: "directory",
+				Engine: "LIBFUZZER",
+				Path:   "fuzzer/bin",
+			},
+		},
+	}
+	contents, extractedDir := createBundle(t, dir, metadata)
+
+	result := Verify(contents, extractedDir, map[string]bool{})
+	assert.Equal(t, []string{
+		"incomplete (LIBFUZZER): fuzzer path fuzzer/bin/missing doesn't exist",
+		"incomplete (LIBFUZZER): runtime path fuzzer/runtime/missing.jar doesn't exist",
+		"incomplete (LIBFUZZER): library path fuzzer/lib doesn't exist",
+		"incomplete (LIBFUZZER): dictionary fuzzer/missing.dict doesn't exist",
+		"incomplete (LIBFUZZER): seed corpus directory seeds/empty is empty",
+		"directory (LIBFUZZER): fuzzer path fuzzer/bin is a directory",
+	}, result.Errors)
+	assert.Empty(t, result.Warnings)
+}
+
+func TestVerify_SharedLibraries(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Shared library dependencies are only checked on Linux")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("No C compiler found")
+	}
+
+	dir := testutil.MkdirTemp(t, "", "verify-test-*")
+	buildDir := testutil.MkdirTemp(t, "", "verify-test-build-*")
+	writeFile(t, filepath.Join(buildDir, "foo.c"), "int foo(void) { return 0; }\n")
+	writeFile(t, filepath.Join(buildDir, "main.c"), "int foo(void);\nint main(void) { return foo(); }\n")
+	libDir := filepath.Join(dir, "fuzzer", "lib")
+	err = os.MkdirAll(libDir, 0o755)
+	require.NoError(t, err)
+	binDir := filepath.Join(dir, "fuzzer", "bin")
+	err = os.MkdirAll(binDir, 0o755)
+	require.NoError(t, err)
+	out, err := exec.Command(cc, "-shared", "-fPIC", "-o", filepath.Join(libDir, "libfoo.so"), filepath.Join(buildDir, "foo.c")).CombinedOutput()
+	require.NoError(t, err, string(out))
+	out, err = exec.Command(cc, "-o", filepath.Join(binDir, "my_fuzz_test"), filepath.Join(buildDir, "main.c"), "-L"+libDir, "-lfoo").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	metadata := &archive.Metadata{
+		RunEnvironment: &archive.RunEnvironment{Docker: "ubuntu:rolling"},
+		Fuzzers: []*archive.Fuzzer{{
+			Target: "my_fuzz_test",
+			Engine: "LIBFUZZER",
+			Path:   "fuzzer/bin/my_fuzz_test",
+		}},
+	}
+	contents, extractedDir := createBundle(t, dir, metadata)
+	systemLibs := map[string]bool{"libc.so.6": true}
+
+	// Without the library path, libfoo.so is not found in the bundle
+	result := Verify(contents, extractedDir, systemLibs)
+	assert.Equal(t, []string{
+		"my_fuzz_test (LIBFUZZER): shared library libfoo.so required by fuzzer/bin/my_fuzz_test is neither bundled nor provided by image ubuntu:rolling",
+	}, result.Errors)
+
+	// If the system libraries are unknown, a warning is printed instead
+	result = Verify(contents, extractedDir, nil)
+	assert.Empty(t, result.Errors)
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "libc.so.6, libfoo.so")
+
+	contents.Metadata.Fuzzers[0].LibraryPaths = []string{"fuzzer/lib"}
+	result = Verify(contents, extractedDir, systemLibs)
+	assert.Empty(t, result.Errors)
+	assert.Empty(t, result.Warnings)
+}
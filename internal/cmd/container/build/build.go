@@ -0,0 +1,177 @@
+package build
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"code-intelligence.com/cifuzz/internal/bundler"
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/cmdutils/resolve"
+	"code-intelligence.com/cifuzz/internal/completion"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/internal/container"
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+type containerBuildOpts struct {
+	bundler.Opts `mapstructure:",squash"`
+
+	BundlePath   string `mapstructure:"-"`
+	BaseImage    string `mapstructure:"base-image"`
+	CIFuzzBinary string `mapstructure:"-"`
+	ImagePath    string `mapstructure:"-"`
+	Tag          string `mapstructure:"-"`
+}
+
+func (opts *containerBuildOpts) Validate() error {
+	if opts.BaseImage == "" {
+		msg := `Flag "base-image" must be set`
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if opts.BundlePath != "" {
+		// The bundle already exists, so the bundler options are not used
+		return nil
+	}
+	return opts.Opts.Validate()
+}
+
+type containerBuildCmd struct {
+	*cobra.Command
+	opts *containerBuildOpts
+}
+
+func New() *cobra.Command {
+	return newWithOptions(&containerBuildOpts{})
+}
+
+func newWithOptions(opts *containerBuildOpts) *cobra.Command {
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "build [flags] [<fuzz test>]...",
+		Short: "Build a Fuzz Test container image without a Docker daemon",
+		Long: `This command builds a Fuzz Test container image without a Docker daemon,
+for example on CI runners which don't support Docker-in-Docker.
+
+The image consists of the layers of a base image, which is read from
+a local OCI image layout directory or tarball, or from a tarball created
+by 'docker save', and a layer which contains the bundle of the specified
+fuzz tests and the cifuzz executable. If --bundle is specified, the
+existing bundle is used instead of creating a new one.
+
+If the output path ends with ".tar", the image is written as a tarball
+which can be loaded with 'docker load'. Otherwise, it's written as an OCI
+image layout directory, which can be pushed to a registry with tools like
+skopeo, crane or oras.
+
+The running cifuzz executable is added to the image if it matches the
+platform of the base image. A cifuzz executable for a different platform
+can be specified via --cifuzz-binary.`,
+		Example: `  # Build an image from a base image saved with 'docker save ubuntu:rolling'
+  cifuzz container build --base-image ubuntu.tar --output my_fuzz_test.tar my_fuzz_test
+
+  # Build an image from an existing bundle and an OCI image layout
+  cifuzz container build --base-image ./ubuntu --bundle my_fuzz_test.tar.gz --output ./image`,
+		ValidArgsFunction: completion.ValidFuzzTests,
+		Args:              cobra.ArbitraryArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind viper keys to flags. We can't do this in the New
+			// function, because that would re-bind viper keys which
+			// were bound to the flags of other commands before.
+			bindFlags()
+
+			if opts.BundlePath != "" {
+				if len(args) > 0 {
+					msg := "Fuzz tests can't be specified together with an existing bundle"
+					return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+				}
+				// The project config is not parsed if an existing bundle
+				// is used, so the base image can only be specified via
+				// the flag or the environment variable
+				opts.BaseImage = viper.GetString("base-image")
+				return opts.Validate()
+			}
+
+			var argsToPass []string
+			if cmd.ArgsLenAtDash() != -1 {
+				argsToPass = args[cmd.ArgsLenAtDash():]
+				args = args[:cmd.ArgsLenAtDash()]
+			}
+
+			err := config.FindAndParseProjectConfig(opts)
+			if err != nil {
+				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+
+			fuzzTests, err := resolve.FuzzTestArguments(opts.ResolveSourceFilePath, args, opts.BuildSystem, opts.ProjectDir)
+			if err != nil {
+				log.Print(err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			opts.FuzzTests = fuzzTests
+			opts.BuildSystemArgs = argsToPass
+			// The sign key is not bound to viper, see AddSignKeyFlag
+			opts.SignKey, err = cmd.Flags().GetString("sign-key")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			return opts.Validate()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			cmd := &containerBuildCmd{Command: c, opts: opts}
+			return cmd.run()
+		},
+	}
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddAdditionalFilesFlag,
+		cmdutils.AddBaseImageFlag,
+		cmdutils.AddBranchFlag,
+		cmdutils.AddBuildCommandFlag,
+		cmdutils.AddCleanCommandFlag,
+		cmdutils.AddBuildJobsFlag,
+		cmdutils.AddCommitFlag,
+		cmdutils.AddDictFlag,
+		cmdutils.AddDockerImageFlag,
+		cmdutils.AddEngineArgFlag,
+		cmdutils.AddEnvFlag,
+		cmdutils.AddProjectDirFlag,
+		cmdutils.AddSeedCorpusFlag,
+		cmdutils.AddSignKeyFlag,
+		cmdutils.AddTimeoutFlag,
+		cmdutils.AddResolveSourceFileFlag,
+	)
+	cmd.Flags().StringVar(&opts.BundlePath, "bundle", "", "Path of an existing bundle to build the image from.")
+	cmd.Flags().StringVar(&opts.CIFuzzBinary, "cifuzz-binary", "", "Path of the cifuzz executable to add to the image.\nDefaults to the running executable.")
+	cmd.Flags().StringVarP(&opts.ImagePath, "output", "o", "cifuzz-image", "Output path of the image (OCI image layout directory, or tarball if it ends with .tar)")
+	cmd.Flags().StringVar(&opts.Tag, "tag", "cifuzz:latest", "Name and tag of the image.")
+
+	return cmd
+}
+
+func (c *containerBuildCmd) run() error {
+	bundlePath := c.opts.BundlePath
+	if bundlePath == "" {
+		var err error
+		bundlePath, err = bundler.New(&c.opts.Opts).Bundle()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := container.BuildOCIImageFromBundle(bundlePath, &container.OCIImageOptions{
+		BaseImage:    c.opts.BaseImage,
+		CIFuzzBinary: c.opts.CIFuzzBinary,
+		OutputPath:   c.opts.ImagePath,
+		Tag:          c.opts.Tag,
+	})
+	if err != nil {
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	log.Successf("Created fuzz container image %s at %s", c.opts.Tag, c.opts.ImagePath)
+	return nil
+}
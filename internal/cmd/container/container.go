@@ -3,6 +3,7 @@ package container
 import (
 	"github.com/spf13/cobra"
 
+	containerBuildCmd "code-intelligence.com/cifuzz/internal/cmd/container/build"
 	containerRemoteRunCmd "code-intelligence.com/cifuzz/internal/cmd/container/remoterun"
 	containerRunCmd "code-intelligence.com/cifuzz/internal/cmd/container/run"
 )
@@ -22,6 +23,7 @@ func newWithOptions() *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(containerBuildCmd.New())
 	cmd.AddCommand(containerRunCmd.New())
 	cmd.AddCommand(containerRemoteRunCmd.New())
 
@@ -2,17 +2,18 @@ package run
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"fmt"
-	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
-	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"code-intelligence.com/cifuzz/internal/bundler"
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/cmdutils/logging"
 	"code-intelligence.com/cifuzz/internal/cmdutils/resolve"
@@ -20,17 +21,23 @@ import (
 	"code-intelligence.com/cifuzz/internal/config"
 	"code-intelligence.com/cifuzz/internal/container"
 	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
 type containerRunOpts struct {
-	bundler.Opts  `mapstructure:",squash"`
-	Interactive   bool   `mapstructure:"interactive"`
-	ContainerPath string `mapstructure:"container"`
+	bundler.Opts     `mapstructure:",squash"`
+	Interactive      bool   `mapstructure:"interactive"`
+	ContainerPath    string `mapstructure:"container"`
+	TrustedKey       string `mapstructure:"trusted-key"`
+	ContainerBackend string `mapstructure:"container-backend"`
+	BaseImage        string `mapstructure:"base-image"`
+	PrintJSON        bool   `mapstructure:"print-json"`
 }
 
 type containerRunCmd struct {
 	*cobra.Command
-	opts *containerRunOpts
+	opts       *containerRunOpts
+	trustedKey ed25519.PublicKey
 }
 
 func New() *cobra.Command {
@@ -38,6 +45,17 @@ func New() *cobra.Command {
 }
 
 func (opts *containerRunOpts) Validate() error {
+	if opts.ContainerBackend == "" {
+		opts.ContainerBackend = container.BackendAuto
+	}
+	if !stringutil.Contains(container.Backends, opts.ContainerBackend) {
+		msg := fmt.Sprintf("Flag \"backend\" must be %s", strings.Join(container.Backends, " or "))
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if opts.ContainerBackend == container.BackendRunc && opts.BaseImage == "" {
+		msg := `Flag "base-image" must be set for the runc backend`
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
 	return opts.Opts.Validate()
 }
 
@@ -49,7 +67,31 @@ func newWithOptions(opts *containerRunOpts) *cobra.Command {
 		Short: "Build and run a Fuzz Test container image locally",
 		Long: `This command builds and runs a Fuzz Test container image locally.
 It can be used as a containerized version of the 'cifuzz bundle' command, where the
-container is built and run locally instead of being pushed to a CI Sense server.`,
+container is built and run locally instead of being pushed to a CI Sense server.
+
+If a trusted public key is specified via --trusted-key, the bundle is
+verified before the container image is built and again by 'cifuzz execute'
+in the container, and rejected if it is not signed with the corresponding
+private key (see --sign-key).
+
+The container is built and run by one of the following backends, which
+can be selected via --backend:
+
+  docker  The Docker daemon.
+  podman  Podman, via its Docker-compatible API socket if the Podman
+          service is running, else via the podman command.
+  runc    runc with an OCI runtime bundle. The image is built from the
+          local base image specified via --base-image (see 'cifuzz
+          container build'), because runc can't pull images.
+
+By default, Docker is used if its socket exists or DOCKER_HOST is set,
+else Podman if it's installed.
+
+After the run, the findings and the generated corpus are copied out of
+the container. New findings are added to the .cifuzz-findings directory
+of the project and reported like by 'cifuzz run', new corpus inputs are
+added to the generated corpus of the fuzz test (for CMake, Bazel and
+other build systems).`,
 		ValidArgsFunction: completion.ValidFuzzTests,
 		Args:              cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -77,6 +119,11 @@ container is built and run locally instead of being pushed to a CI Sense server.
 			}
 			opts.FuzzTests = fuzzTests
 			opts.BuildSystemArgs = argsToPass
+			// The sign key is not bound to viper, see AddSignKeyFlag
+			opts.SignKey, err = cmd.Flags().GetString("sign-key")
+			if err != nil {
+				return errors.WithStack(err)
+			}
 
 			return opts.Validate()
 		},
@@ -87,11 +134,13 @@ container is built and run locally instead of being pushed to a CI Sense server.
 	}
 	bindFlags = cmdutils.AddFlags(cmd,
 		cmdutils.AddAdditionalFilesFlag,
+		cmdutils.AddBaseImageFlag,
 		cmdutils.AddBranchFlag,
 		cmdutils.AddBuildCommandFlag,
 		cmdutils.AddCleanCommandFlag,
 		cmdutils.AddBuildJobsFlag,
 		cmdutils.AddCommitFlag,
+		cmdutils.AddContainerBackendFlag,
 		cmdutils.AddDictFlag,
 		cmdutils.AddDockerImageFlag,
 		cmdutils.AddEngineArgFlag,
@@ -101,7 +150,9 @@ container is built and run locally instead of being pushed to a CI Sense server.
 		cmdutils.AddProjectFlag,
 		cmdutils.AddSeedCorpusFlag,
 		cmdutils.AddServerFlag,
+		cmdutils.AddSignKeyFlag,
 		cmdutils.AddTimeoutFlag,
+		cmdutils.AddTrustedKeyFlag,
 		cmdutils.AddResolveSourceFileFlag,
 	)
 	cmd.Flags().StringVar(&opts.ContainerPath, "container", "", "Path of an existing container to start a run with.")
@@ -111,9 +162,24 @@ container is built and run locally instead of being pushed to a CI Sense server.
 
 func (c *containerRunCmd) run() error {
 	var err error
+	c.trustedKey, err = archive.LoadPublicKey(c.opts.TrustedKey, c.opts.ProjectDir)
+	if err != nil {
+		log.Errorf(err, "Failed to load the trusted key: %v", err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	backend, err := container.NewBackend(c.opts.ContainerBackend, &container.BackendOptions{
+		BaseImage:  c.opts.BaseImage,
+		TrustedKey: c.trustedKey,
+	})
+	if err != nil {
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+	defer backend.Close()
 
 	logging.StartBuildProgressSpinner(log.ContainerBuildInProgressMsg)
-	containerID, err := c.buildContainerFromImage()
+	containerID, err := c.buildContainerFromImage(backend)
 	if err != nil {
 		logging.StopBuildProgressSpinnerOnError(log.ContainerBuildInProgressErrorMsg)
 		return err
@@ -129,47 +195,56 @@ func (c *containerRunCmd) run() error {
 	go func() {
 		<-sigChan
 		logging.StopBuildProgressSpinnerOnError("Received interrupt, stopping container and cifuzz...")
-		err := container.Stop(containerID)
+		err := backend.Stop(containerID)
 		if err != nil {
 			log.Error(errors.Wrap(err, "container could not be stopped"))
 		}
 	}()
 
-	out, err := container.Start(containerID)
-	if err != nil {
-		logging.StopBuildProgressSpinnerOnError(log.ContainerRunInProgressErrorMsg)
-		return err
-	}
-	logging.StopBuildProgressSpinnerOnSuccess(log.ContainerRunInProgressSuccessMsg, false)
-
 	// Copy the logs to two different vars, so that we can pass them around
 	// independently.
 	containerStdOut := new(bytes.Buffer)
 	containerStdErr := new(bytes.Buffer)
-	_, err = stdcopy.StdCopy(containerStdOut, containerStdErr, out)
-	if err != nil && err != io.EOF {
+	err = backend.Start(containerID, containerStdOut, containerStdErr)
+	if err != nil {
+		logging.StopBuildProgressSpinnerOnError(log.ContainerRunInProgressErrorMsg)
 		return err
 	}
+	logging.StopBuildProgressSpinnerOnSuccess(log.ContainerRunInProgressSuccessMsg, false)
 
 	// TODO: make output pretty
 	//  Remove 'cifuzz version' from output
-	fmt.Println(containerStdOut.String())
-	fmt.Println(containerStdErr.String())
+	// When --json is used, only the JSON reports of the findings are
+	// printed to stdout
+	containerOutput := os.Stdout
+	if c.opts.PrintJSON {
+		containerOutput = os.Stderr
+	}
+	_, _ = fmt.Fprintln(containerOutput, containerStdOut.String())
+	_, _ = fmt.Fprintln(containerOutput, containerStdErr.String())
 
-	return nil
+	return c.syncOutput(backend, containerID)
 }
 
-func (c *containerRunCmd) buildContainerFromImage() (string, error) {
+func (c *containerRunCmd) buildContainerFromImage(backend container.Backend) (string, error) {
 	b := bundler.New(&c.opts.Opts)
 	bundlePath, err := b.Bundle()
 	if err != nil {
 		return "", err
 	}
 
-	err = container.BuildImageFromBundle(bundlePath)
+	if c.trustedKey != nil {
+		err = archive.VerifyArchive(bundlePath, c.trustedKey)
+		if err != nil {
+			return "", errors.WithMessage(err, "Rejecting the bundle")
+		}
+		log.Debugf("Verified the signature of bundle %s", bundlePath)
+	}
+
+	err = backend.BuildImage(bundlePath)
 	if err != nil {
 		return "", err
 	}
 
-	return container.Create(c.opts.FuzzTests[0])
+	return backend.Create(c.opts.FuzzTests[0])
 }
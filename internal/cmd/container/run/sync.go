@@ -0,0 +1,187 @@
+package run
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/build/bazel"
+	"code-intelligence.com/cifuzz/internal/cmd/run/reporthandler"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/internal/container"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/report"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// syncOutput copies the findings and the generated corpus of the fuzz
+// test out of the container and merges them into the project, so that
+// they are not lost when the container is removed
+func (c *containerRunCmd) syncOutput(backend container.Backend, containerID string) error {
+	tempDir, err := os.MkdirTemp("", "cifuzz-container-output-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tempDir)
+
+	err = backend.CopyFrom(containerID, container.OutputDir, tempDir)
+	if err != nil {
+		// The output directory doesn't exist if 'cifuzz execute' failed
+		// before the fuzz test was started
+		log.Warnf("Failed to copy the findings and the corpus out of the container: %v", err)
+		return nil
+	}
+	outputDir := filepath.Join(tempDir, filepath.Base(container.OutputDir))
+
+	corpusDir := c.generatedCorpusDir()
+	if corpusDir == "" {
+		log.Warnf("Not syncing the generated corpus of fuzz test %s, because its location is unknown for build system %s",
+			c.opts.FuzzTests[0], c.opts.BuildSystem)
+	} else {
+		numInputs, err := mergeCorpus(filepath.Join(outputDir, "corpus"), corpusDir)
+		if err != nil {
+			return err
+		}
+		if numInputs > 0 {
+			log.Infof("Added %d new inputs to the generated corpus in %s", numInputs, fileutil.PrettifyPath(corpusDir))
+		}
+	}
+
+	return c.mergeFindings(outputDir)
+}
+
+// generatedCorpusDir returns the directory in which 'cifuzz run' of the
+// build system stores the generated corpus of the fuzz test, or an
+// empty string if it's unknown.
+func (c *containerRunCmd) generatedCorpusDir() string {
+	fuzzTest := c.opts.FuzzTests[0]
+	switch c.opts.BuildSystem {
+	case config.BuildSystemCMake, config.BuildSystemOther:
+		return filepath.Join(c.opts.ProjectDir, ".cifuzz-corpus", fuzzTest)
+	case config.BuildSystemBazel:
+		// Labels which are not canonical can only be resolved via
+		// bazel query, which we don't run here
+		if !strings.HasPrefix(fuzzTest, "//") || !strings.Contains(fuzzTest, ":") {
+			return ""
+		}
+		return bazel.GeneratedCorpusDir(c.opts.ProjectDir, bazel.PathFromCanonicalLabel(fuzzTest))
+	default:
+		// Jazzer and Jazzer.js manage the generated corpus of fuzz
+		// tests run by 'cifuzz run' themselves
+		return ""
+	}
+}
+
+// mergeCorpus copies the inputs in srcDir which don't exist in destDir
+// to destDir and returns the number of copied inputs. The fuzzers name
+// the inputs of the generated corpus after the hash of their content,
+// so inputs with the same name are duplicates.
+func mergeCorpus(srcDir, destDir string) (int, error) {
+	exists, err := fileutil.Exists(srcDir)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var numInputs int
+	err = filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		dest := filepath.Join(destDir, relPath)
+		exists, err := fileutil.Exists(dest)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		err = copy.Copy(path, dest)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		numInputs++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return numInputs, nil
+}
+
+// mergeFindings adds the findings in the .cifuzz-findings directory of
+// outputDir to the project and reports them like 'cifuzz run' does.
+// Findings which already exist in the project are skipped. The names of
+// findings are derived from their stack trace and crashing input, so
+// findings with the same name are duplicates.
+func (c *containerRunCmd) mergeFindings(outputDir string) error {
+	findings, err := finding.ListFindings(outputDir, nil)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	// The crashing inputs are not added to the seed corpus like by
+	// 'cifuzz run', because its location is determined by the build
+	// system, which is not run here
+	reportHandler, err := reporthandler.NewReportHandler(
+		c.opts.FuzzTests[0],
+		&reporthandler.ReportHandlerOptions{
+			ProjectDir: c.opts.ProjectDir,
+			PrintJSON:  c.opts.PrintJSON,
+		})
+	if err != nil {
+		return err
+	}
+
+	// Report the findings in the order in which they were found
+	for i := len(findings) - 1; i >= 0; i-- {
+		f := findings[i]
+		exists, err := f.Exists(c.opts.ProjectDir)
+		if err != nil {
+			return err
+		}
+		if exists {
+			log.Debugf("Skipping finding %s, which already exists in the project", f.Name)
+			continue
+		}
+		if f.InputFile != "" {
+			// The path of the crashing input is relative to the output
+			// directory, the report handler copies it to the project.
+			// The finding was written in the container, so we don't
+			// trust it to not reference files outside of the output
+			// directory.
+			inputFile := filepath.Join(outputDir, f.InputFile)
+			isBelow, err := fileutil.IsBelow(inputFile, outputDir)
+			if err != nil {
+				return err
+			}
+			if !isBelow {
+				log.Warnf("Skipping finding %s, its crashing input %s is outside of the output directory", f.Name, f.InputFile)
+				continue
+			}
+			f.InputFile = inputFile
+		}
+		err = reportHandler.Handle(&report.Report{Finding: f})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package run
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otiai10/copy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/bundler"
+	"code-intelligence.com/cifuzz/internal/cmd/run/reporthandler"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/internal/container"
+	"code-intelligence.com/cifuzz/internal/testutil"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+	"code-intelligence.com/cifuzz/pkg/report"
+)
+
+// fakeBackend copies the output directory of the container from a
+// directory on the host
+type fakeBackend struct {
+	outputDir string
+}
+
+func (b *fakeBackend) BuildImage(string) error                  { return nil }
+func (b *fakeBackend) Create(string) (string, error)            { return "id", nil }
+func (b *fakeBackend) Start(string, io.Writer, io.Writer) error { return nil }
+func (b *fakeBackend) Stop(string) error                        { return nil }
+func (b *fakeBackend) Close() error                             { return nil }
+
+func (b *fakeBackend) SystemLibraries(string) (map[string]bool, error) { return nil, nil }
+
+func (b *fakeBackend) CopyFrom(id, path, destDir string) error {
+	return copy.Copy(b.outputDir, filepath.Join(destDir, filepath.Base(path)))
+}
+
+// createContainerOutput creates an output directory like the one
+// written by 'cifuzz execute --output-dir' with two corpus inputs and
+// a finding
+func createContainerOutput(t *testing.T) string {
+	outputDir := testutil.MkdirTemp(t, "", "container-output-*")
+	for _, input := range []string{"input-a", "input-b"} {
+		err := os.MkdirAll(filepath.Join(outputDir, "corpus"), 0o755)
+		require.NoError(t, err)
+		err = os.WriteFile(filepath.Join(outputDir, "corpus", input), []byte(input), 0o644)
+		require.NoError(t, err)
+	}
+
+	crashingInput := filepath.Join(testutil.MkdirTemp(t, "", "crash-*"), "crash-123")
+	err := os.WriteFile(crashingInput, []byte("crash"), 0o644)
+	require.NoError(t, err)
+	reportHandler, err := reporthandler.NewReportHandler("my_fuzz_test", &reporthandler.ReportHandlerOptions{
+		ProjectDir: outputDir,
+	})
+	require.NoError(t, err)
+	err = reportHandler.Handle(&report.Report{Finding: &finding.Finding{
+		Type:       finding.ErrorTypeCrash,
+		Details:    "heap-buffer-overflow",
+		InputData:  []byte("crash"),
+		InputFile:  crashingInput,
+		StackTrace: []*stacktrace.StackFrame{{SourceFile: "src/parser.c", Line: 12, Function: "parse"}},
+	}})
+	require.NoError(t, err)
+	return outputDir
+}
+
+func TestSyncOutput(t *testing.T) {
+	projectDir := testutil.MkdirTemp(t, "", "container-run-project-*")
+	corpusDir := filepath.Join(projectDir, ".cifuzz-corpus", "my_fuzz_test")
+	err := os.MkdirAll(corpusDir, 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(corpusDir, "input-a"), []byte("input-a"), 0o644)
+	require.NoError(t, err)
+
+	c := &containerRunCmd{opts: &containerRunOpts{Opts: bundler.Opts{
+		ProjectDir:  projectDir,
+		BuildSystem: config.BuildSystemCMake,
+		FuzzTests:   []string{"my_fuzz_test"},
+	}}}
+	backend := &fakeBackend{outputDir: createContainerOutput(t)}
+
+	err = c.syncOutput(backend, "id")
+	require.NoError(t, err)
+
+	// The new corpus input was added to the generated corpus
+	entries, err := os.ReadDir(corpusDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.FileExists(t, filepath.Join(corpusDir, "input-b"))
+
+	// The finding was added to the project with its crashing input
+	findings, err := finding.ListFindings(projectDir, nil)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	f := findings[0]
+	assert.Equal(t, "my_fuzz_test", f.FuzzTest)
+	assert.Equal(t, filepath.Join(".cifuzz-findings", f.Name, "crashing-input"), f.InputFile)
+	content, err := os.ReadFile(filepath.Join(projectDir, f.InputFile))
+	require.NoError(t, err)
+	assert.Equal(t, "crash", string(content))
+
+	// Findings which already exist in the project are not overwritten
+	f.FirstBadCommit = "1234567"
+	err = f.Save(projectDir)
+	require.NoError(t, err)
+	err = c.syncOutput(backend, "id")
+	require.NoError(t, err)
+	f, err = finding.LoadFinding(projectDir, f.Name, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1234567", f.FirstBadCommit)
+}
+
+func TestSyncOutput_NoOutput(t *testing.T) {
+	c := &containerRunCmd{opts: &containerRunOpts{Opts: bundler.Opts{
+		ProjectDir:  testutil.MkdirTemp(t, "", "container-run-project-*"),
+		BuildSystem: config.BuildSystemCMake,
+		FuzzTests:   []string{"my_fuzz_test"},
+	}}}
+	// If 'cifuzz execute' failed before the fuzz test was started,
+	// there is nothing to sync
+	backend := &fakeBackend{outputDir: filepath.Join(c.opts.ProjectDir, "does-not-exist")}
+	err := c.syncOutput(backend, "id")
+	require.NoError(t, err)
+}
+
+func TestSyncOutput_GeneratedCorpusDir(t *testing.T) {
+	projectDir := testutil.MkdirTemp(t, "", "container-run-project-*")
+	backend := &fakeBackend{outputDir: createContainerOutput(t)}
+
+	c := &containerRunCmd{opts: &containerRunOpts{Opts: bundler.Opts{
+		ProjectDir:  projectDir,
+		BuildSystem: config.BuildSystemBazel,
+		FuzzTests:   []string{"//src/parser:parser_fuzz_test"},
+	}}}
+	err := c.syncOutput(backend, "id")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(projectDir, "src", "parser", ".parser_fuzz_test_cifuzz_corpus", "input-a"))
+
+	// The generated corpus of Jazzer is not synced, because it's not
+	// in a location known to cifuzz
+	projectDir = testutil.MkdirTemp(t, "", "container-run-project-*")
+	c = &containerRunCmd{opts: &containerRunOpts{Opts: bundler.Opts{
+		ProjectDir:  projectDir,
+		BuildSystem: config.BuildSystemMaven,
+		FuzzTests:   []string{"com.example.FuzzTest::myFuzzTest"},
+	}}}
+	err = c.syncOutput(backend, "id")
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(projectDir, ".cifuzz-corpus"))
+	// The findings are synced anyway
+	findings, err := finding.ListFindings(projectDir, nil)
+	require.NoError(t, err)
+	assert.Len(t, findings, 1)
+}
+
+func TestSyncOutput_InputFileOutsideOfOutputDir(t *testing.T) {
+	projectDir := testutil.MkdirTemp(t, "", "container-run-project-*")
+	outputDir := createContainerOutput(t)
+	secret := filepath.Join(testutil.MkdirTemp(t, "", "secret-*"), "secret")
+	err := os.WriteFile(secret, []byte("secret"), 0o644)
+	require.NoError(t, err)
+
+	// Let the crashing input of the finding reference a file outside
+	// of the output directory
+	findings, err := finding.ListFindings(outputDir, nil)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	findings[0].InputFile = strings.Repeat("../", 32) + strings.TrimPrefix(filepath.ToSlash(secret), "/")
+	err = findings[0].Save(outputDir)
+	require.NoError(t, err)
+
+	c := &containerRunCmd{opts: &containerRunOpts{Opts: bundler.Opts{
+		ProjectDir:  projectDir,
+		BuildSystem: config.BuildSystemCMake,
+		FuzzTests:   []string{"my_fuzz_test"},
+	}}}
+	err = c.syncOutput(&fakeBackend{outputDir: outputDir}, "id")
+	require.NoError(t, err)
+
+	findings, err = finding.ListFindings(projectDir, nil)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+var _ container.Backend = &fakeBackend{}
@@ -12,15 +12,27 @@ import (
 
 	"code-intelligence.com/cifuzz/internal/build"
 	"code-intelligence.com/cifuzz/internal/build/bazel"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/htmlreport"
 	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/pkg/runfiles"
 	"code-intelligence.com/cifuzz/util/envutil"
+	"code-intelligence.com/cifuzz/util/fileutil"
 )
 
 type CoverageGenerator struct {
-	FuzzTest        string
+	FuzzTest string
+	// If set, a merged coverage report of these fuzz tests is generated
+	// instead of a report for FuzzTest
+	FuzzTests []string
+	// If set, a merged coverage report of all fuzz tests of the
+	// workspace is generated
+	AllFuzzTests bool
+	// The files which are included in the report
+	Filter *summary.Filter
+	// The renderer of HTML reports, one of coverage.HTMLRenderers
+	HTMLRenderer    string
 	OutputFormat    string
 	OutputPath      string
 	BuildSystemArgs []string
@@ -32,31 +44,29 @@ type CoverageGenerator struct {
 	BuildStdout     io.Writer
 	BuildStderr     io.Writer
 	Verbose         bool
+
+	coverageSummary *summary.CoverageSummary
 }
 
 func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
 	var err error
 
-	// The cc_fuzz_test rule defines multiple bazel targets: If the
-	// name is "foo", it defines the targets "foo", "foo_bin", and
-	// others. We need to run the "foo" target here but want to
-	// allow users to specify either "foo" or "foo_bin", so we check
-	// if the fuzz test name  with a "_bin" suffix removed is a valid
-	// target and use that in that case.
-	if strings.HasSuffix(cov.FuzzTest, "_bin") {
-		trimmedLabel := strings.TrimSuffix(cov.FuzzTest, "_bin")
-		cmd := exec.Command("bazel", "query", trimmedLabel)
-		err = cmd.Run()
-		if err == nil {
-			cov.FuzzTest = trimmedLabel
-		}
-	}
-
 	commonFlags, err := cov.getBazelCommandFlags()
 	if err != nil {
 		return err
 	}
 
+	if cov.AllFuzzTests {
+		cov.FuzzTests, err = listFuzzTests(commonFlags)
+		if err != nil {
+			return err
+		}
+	}
+	cov.FuzzTest = fuzzTestLabel(cov.FuzzTest)
+	for i, fuzzTest := range cov.FuzzTests {
+		cov.FuzzTests[i] = fuzzTestLabel(fuzzTest)
+	}
+
 	// Flags which should only be used for bazel run because they are
 	// not supported by the other bazel commands we use
 	coverageFlags := []string{
@@ -83,7 +93,7 @@ func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
 	args = append(args, commonFlags...)
 	args = append(args, coverageFlags...)
 	args = append(args, cov.BuildSystemArgs...)
-	args = append(args, cov.FuzzTest)
+	args = append(args, cov.labels()...)
 
 	cmd := exec.Command("bazel", args...)
 	// Redirect the build command's stdout to stderr to only have
@@ -115,17 +125,26 @@ func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
-	reportReader := strings.NewReader(string(lcovReportContent))
-	summary.ParseLcov(reportReader).PrintTable(cov.Stderr)
+	lcovReport := summary.FilterLcov(string(lcovReportContent), cov.Filter)
+	coverageSummary := summary.ParseLcov(strings.NewReader(lcovReport))
 
 	commonFlags, err := cov.getBazelCommandFlags()
 	if err != nil {
 		return "", err
 	}
 
+	if len(cov.FuzzTests) > 0 {
+		err = cov.addFuzzTestSummaries(coverageSummary, commonFlags)
+		if err != nil {
+			return "", err
+		}
+	}
+	coverageSummary.PrintTable(cov.Stderr)
+	cov.coverageSummary = coverageSummary
+
 	if cov.OutputFormat == "lcov" {
 		if cov.OutputPath == "" {
-			path, err := bazel.PathFromLabel(cov.FuzzTest, commonFlags)
+			path, err := cov.reportName(commonFlags)
 			if err != nil {
 				return "", err
 			}
@@ -135,12 +154,9 @@ func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
 		// We don't use copy.Copy here to be able to set the permissions
 		// to 0o644 before umask - copy.Copy just copies the permissions
 		// from the source file, which has permissions 555 like all
-		// files created by bazel.
-		content, err := os.ReadFile(reportPath)
-		if err != nil {
-			return "", errors.WithStack(err)
-		}
-		err = os.WriteFile(cov.OutputPath, content, 0o644)
+		// files created by bazel. Also, the report must only contain
+		// the files matched by the coverage filter.
+		err = os.WriteFile(cov.OutputPath, []byte(lcovReport), 0o644)
 		if err != nil {
 			return "", errors.WithStack(err)
 		}
@@ -154,18 +170,43 @@ func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
 		if err != nil {
 			return "", errors.WithStack(err)
 		}
-		path, err := bazel.PathFromLabel(cov.FuzzTest, commonFlags)
+		path, err := cov.reportName(commonFlags)
 		if err != nil {
 			return "", err
 		}
 		cov.OutputPath = filepath.Join(outputDir, path)
 	}
 
+	if htmlreport.UseBuiltinRenderer(cov.HTMLRenderer) {
+		name, err := cov.reportName(commonFlags)
+		if err != nil {
+			return "", err
+		}
+		err = htmlreport.Generate(coverageSummary, cov.OutputPath, cov.ProjectDir, name)
+		if err != nil {
+			return "", err
+		}
+		return cov.OutputPath, nil
+	}
+
 	// Create an HTML report via genhtml
 	genHTML, err := runfiles.Finder.GenHTMLPath()
 	if err != nil {
 		return "", err
 	}
+	if !cov.Filter.Empty() {
+		filteredReport, err := os.CreateTemp("", "coverage-*.lcov")
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		defer fileutil.Cleanup(filteredReport.Name())
+		_, err = filteredReport.WriteString(lcovReport)
+		filteredReport.Close()
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		reportPath = filteredReport.Name()
+	}
 	args := []string{"--output", cov.OutputPath, reportPath}
 
 	cmd = exec.Command(genHTML, args...)
@@ -180,6 +221,99 @@ func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
 	return cov.OutputPath, nil
 }
 
+// CoverageSummary returns the summary of the coverage report created by
+// GenerateCoverageReport
+func (cov *CoverageGenerator) CoverageSummary() *summary.CoverageSummary {
+	return cov.coverageSummary
+}
+
+// fuzzTestLabel returns the label of the target which runs the fuzz
+// test.
+func fuzzTestLabel(fuzzTest string) string {
+	// The cc_fuzz_test rule defines multiple bazel targets: If the
+	// name is "foo", it defines the targets "foo", "foo_bin", and
+	// others. We need to run the "foo" target here but want to
+	// allow users to specify either "foo" or "foo_bin", so we check
+	// if the fuzz test name  with a "_bin" suffix removed is a valid
+	// target and use that in that case.
+	if strings.HasSuffix(fuzzTest, "_bin") {
+		trimmedLabel := strings.TrimSuffix(fuzzTest, "_bin")
+		cmd := exec.Command("bazel", "query", trimmedLabel)
+		err := cmd.Run()
+		if err == nil {
+			return trimmedLabel
+		}
+	}
+	return fuzzTest
+}
+
+// listFuzzTests returns the labels of all fuzz tests of the workspace,
+// i.e. the test targets created by the cc_fuzz_test macro which are not
+// tagged as manual
+func listFuzzTests(flags []string) ([]string, error) {
+	query := `attr(generator_function, "^cc_fuzz_test$", tests(//...)) except attr(tags, "\bmanual\b", //...)`
+	args := append([]string{"query"}, flags...)
+	args = append(args, query)
+	cmd := exec.Command("bazel", args...)
+	log.Debugf("Command: %s", cmd.String())
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	labels := strings.Fields(string(out))
+	if len(labels) == 0 {
+		return nil, errors.New("No fuzz tests found in the workspace")
+	}
+	return labels, nil
+}
+
+func (cov *CoverageGenerator) labels() []string {
+	if len(cov.FuzzTests) > 0 {
+		return cov.FuzzTests
+	}
+	return []string{cov.FuzzTest}
+}
+
+// reportName returns the name of the coverage report if no output path
+// was specified
+func (cov *CoverageGenerator) reportName(flags []string) (string, error) {
+	if len(cov.FuzzTests) > 0 {
+		return "merged", nil
+	}
+	return bazel.PathFromLabel(cov.FuzzTest, flags)
+}
+
+// addFuzzTestSummaries adds the coverage of the individual fuzz tests,
+// which bazel stores in the test logs directory, to the summary of the
+// combined report
+func (cov *CoverageGenerator) addFuzzTestSummaries(coverageSummary *summary.CoverageSummary, flags []string) error {
+	cmd := exec.Command("bazel", "info", "bazel-testlogs")
+	out, err := cmd.Output()
+	if err != nil {
+		return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	testLogsDir := strings.TrimSpace(string(out))
+
+	for _, label := range cov.FuzzTests {
+		path, err := bazel.PathFromLabel(label, flags)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(filepath.Join(testLogsDir, path, "coverage.dat"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Warnf("No coverage data was collected for %s", label)
+				continue
+			}
+			return errors.WithStack(err)
+		}
+		fuzzTestSummary := summary.ParseLcov(strings.NewReader(string(content)))
+		fuzzTestSummary.ApplyFilter(cov.Filter)
+		coverageSummary.AddFuzzTest(label, fuzzTestSummary)
+	}
+	return nil
+}
+
 // getBazelCommandFlags returns flags to be used when executing a bazel command
 // to avoid part of the loading and/or analysis phase to rerun.
 func (cov *CoverageGenerator) getBazelCommandFlags() ([]string, error) {
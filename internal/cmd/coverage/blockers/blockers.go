@@ -0,0 +1,411 @@
+// Package blockers finds the functions and branches which block the
+// fuzzer from covering more code: Functions which are called from
+// covered code but were never entered, and branches which were reached
+// but of which an outcome was never taken. The blockers are ranked by
+// the amount of uncovered code behind them, which is estimated from the
+// line coverage.
+package blockers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+const (
+	KindFunction = "function"
+	KindBranch   = "branch"
+)
+
+type Blocker struct {
+	Kind     string `json:"kind"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function,omitempty"`
+	// The number of taken and of all outcomes of the branches of the
+	// line, only set for branch blockers
+	BranchesHit   int `json:"branches_hit,omitempty"`
+	BranchesFound int `json:"branches_found,omitempty"`
+	// The location of a covered call of the function, only set for
+	// function blockers
+	Caller string `json:"caller,omitempty"`
+	// The estimated number of uncovered lines which are only reachable
+	// via the blocker
+	UncoveredLines int `json:"uncovered_lines"`
+}
+
+type function struct {
+	name  string
+	start int
+	// The last line of the function, estimated as the line before the
+	// next function of the file
+	end  int
+	hits int
+}
+
+// Matches calls like "foo(", "foo (" and "new Foo("
+var callPattern = regexp.MustCompile(`([A-Za-z_$][A-Za-z0-9_$]*)\s*\(`)
+
+// Find returns the blockers of the coverage report, ranked by the
+// number of uncovered lines behind them. The source files are used to
+// find covered calls of the functions which were never entered.
+// Relative filenames are interpreted relative to the source
+// directories. If none of the source files can be read, all functions
+// which were never entered in files with covered functions are
+// considered reachable.
+func Find(cs *summary.CoverageSummary, sourceDirs []string) []*Blocker {
+	var blockers []*Blocker
+
+	callers, sourcesAvailable := coveredCalls(cs, sourceDirs)
+
+	for _, file := range cs.Files {
+		name := displayName(file.Filename, sourceDirs)
+		functions := fileFunctions(file)
+		lines := instrumentedLines(file)
+
+		fileCovered := false
+		for _, f := range functions {
+			if f.hits > 0 {
+				fileCovered = true
+			}
+		}
+
+		// Functions which were never entered
+		for _, f := range functions {
+			if f.hits > 0 {
+				continue
+			}
+			caller, called := callers[callableName(f.name)]
+			if sourcesAvailable && !called {
+				continue
+			}
+			if !sourcesAvailable && !fileCovered {
+				continue
+			}
+			uncovered := 0
+			for _, line := range lines {
+				if line >= f.start && line <= f.end {
+					uncovered++
+				}
+			}
+			if uncovered == 0 {
+				continue
+			}
+			blockers = append(blockers, &Blocker{
+				Kind:           KindFunction,
+				File:           name,
+				Line:           f.start,
+				Function:       f.name,
+				Caller:         caller,
+				UncoveredLines: uncovered,
+			})
+		}
+
+		// Branches which were reached but of which an outcome was never
+		// taken
+		var branchLines []int
+		for line, branches := range file.LineBranches {
+			if branches.Hit >= branches.Found {
+				continue
+			}
+			hits, instrumented := file.LineHits[line]
+			if (instrumented && hits == 0) || (!instrumented && branches.Hit == 0) {
+				continue
+			}
+			branchLines = append(branchLines, line)
+		}
+		sort.Ints(branchLines)
+
+		for i, line := range branchLines {
+			f := enclosingFunction(functions, line)
+			end := lines[len(lines)-1]
+			if f != nil {
+				end = f.end
+			}
+			if i+1 < len(branchLines) && branchLines[i+1] <= end {
+				end = branchLines[i+1] - 1
+			}
+			uncovered := uncoveredLinesAfter(file, lines, line, end)
+			if uncovered == 0 {
+				continue
+			}
+			blocker := &Blocker{
+				Kind:           KindBranch,
+				File:           name,
+				Line:           line,
+				BranchesHit:    file.LineBranches[line].Hit,
+				BranchesFound:  file.LineBranches[line].Found,
+				UncoveredLines: uncovered,
+			}
+			if f != nil {
+				blocker.Function = f.name
+			}
+			blockers = append(blockers, blocker)
+		}
+	}
+
+	sort.SliceStable(blockers, func(i, j int) bool {
+		a, b := blockers[i], blockers[j]
+		if a.UncoveredLines != b.UncoveredLines {
+			return a.UncoveredLines > b.UncoveredLines
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return blockers
+}
+
+// uncoveredLinesAfter returns the number of uncovered lines of the first
+// block of uncovered lines after the line, up to the end line
+func uncoveredLinesAfter(file *summary.FileCoverage, lines []int, line int, end int) int {
+	uncovered := 0
+	for _, l := range lines {
+		if l <= line || l > end {
+			continue
+		}
+		if file.LineHits[l] == 0 {
+			uncovered++
+		} else if uncovered > 0 {
+			break
+		}
+	}
+	return uncovered
+}
+
+func instrumentedLines(file *summary.FileCoverage) []int {
+	var lines []int
+	for line := range file.LineHits {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	if len(lines) == 0 {
+		// Avoid special cases for files without line data
+		lines = []int{0}
+	}
+	return lines
+}
+
+func fileFunctions(file *summary.FileCoverage) []*function {
+	var functions []*function
+	for name, hits := range file.FunctionHits {
+		start, ok := file.FunctionLines[name]
+		if !ok {
+			continue
+		}
+		functions = append(functions, &function{name: name, start: start, hits: hits})
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].start != functions[j].start {
+			return functions[i].start < functions[j].start
+		}
+		return functions[i].name < functions[j].name
+	})
+
+	lastLine := 0
+	for line := range file.LineHits {
+		if line > lastLine {
+			lastLine = line
+		}
+	}
+	for i, f := range functions {
+		f.end = lastLine
+		for _, next := range functions[i+1:] {
+			if next.start > f.start {
+				f.end = next.start - 1
+				break
+			}
+		}
+	}
+	return functions
+}
+
+func enclosingFunction(functions []*function, line int) *function {
+	var enclosing *function
+	for _, f := range functions {
+		if f.start <= line && line <= f.end {
+			enclosing = f
+		}
+	}
+	return enclosing
+}
+
+// coveredCalls returns the names of the functions which are called in
+// covered lines, together with the location of the first call. The
+// second return value is false if none of the source files could be
+// read.
+func coveredCalls(cs *summary.CoverageSummary, sourceDirs []string) (map[string]string, bool) {
+	calls := make(map[string]string)
+	sourcesAvailable := false
+	for _, file := range cs.Files {
+		lines, err := readSource(file.Filename, sourceDirs)
+		if err != nil {
+			continue
+		}
+		sourcesAvailable = true
+		name := displayName(file.Filename, sourceDirs)
+		for i, text := range lines {
+			if file.LineHits[i+1] == 0 {
+				continue
+			}
+			for _, match := range callPattern.FindAllStringSubmatch(text, -1) {
+				if _, ok := calls[match[1]]; !ok {
+					calls[match[1]] = fmt.Sprintf("%s:%d", name, i+1)
+				}
+			}
+		}
+	}
+	return calls, sourcesAvailable
+}
+
+func readSource(filename string, sourceDirs []string) ([]string, error) {
+	candidates := []string{filename}
+	if !filepath.IsAbs(filename) {
+		candidates = nil
+		for _, dir := range sourceDirs {
+			candidates = append(candidates, filepath.Join(dir, filename))
+		}
+	}
+	for _, path := range candidates {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		var lines []string
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		return lines, errors.WithStack(scanner.Err())
+	}
+	return nil, errors.Errorf("Source file %s not found", filename)
+}
+
+func displayName(filename string, sourceDirs []string) string {
+	if filepath.IsAbs(filename) {
+		for _, dir := range sourceDirs {
+			rel, err := filepath.Rel(dir, filename)
+			if err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.ToSlash(filename)
+}
+
+// callableName returns the name by which a function is called in the
+// source code, for the function names of llvm-cov (which are mangled
+// for C++ and prefixed with the filename for static C functions) and
+// the method names of JaCoCo reports
+func callableName(name string) string {
+	// JaCoCo: com.example.App.parse(Ljava/lang/String;)V
+	if i := strings.Index(name, "("); i != -1 {
+		name = name[:i]
+		if strings.HasSuffix(name, ".<init>") {
+			// Constructors are called via the class name
+			name = strings.TrimSuffix(name, ".<init>")
+		}
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+	// Static C functions: foo.c:bar
+	name = name[strings.LastIndex(name, ":")+1:]
+	if strings.HasPrefix(name, "_Z") {
+		return demangledName(name)
+	}
+	return name
+}
+
+// demangledName returns the unqualified name of a function with a name
+// mangled according to the Itanium C++ ABI, or the mangled name if it
+// can't be parsed
+func demangledName(mangled string) string {
+	s := strings.TrimPrefix(mangled, "_Z")
+	nested := strings.HasPrefix(s, "N")
+	if nested {
+		s = strings.TrimLeft(s[1:], "rVKRO")
+	}
+	last := ""
+	for len(s) > 0 {
+		if s[0] == 'S' && len(s) > 1 && s[1] == 't' {
+			// The std:: namespace
+			s = s[2:]
+			continue
+		}
+		digits := 0
+		for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+			digits++
+		}
+		if digits == 0 {
+			break
+		}
+		length, err := strconv.Atoi(s[:digits])
+		if err != nil || digits+length > len(s) {
+			break
+		}
+		last = s[digits : digits+length]
+		s = s[digits+length:]
+		if !nested {
+			break
+		}
+	}
+	if last == "" {
+		return mangled
+	}
+	return last
+}
+
+// PrintTable prints the blockers, at most max if max is not zero
+func PrintTable(w io.Writer, blockers []*Blocker, max int) error {
+	if len(blockers) == 0 {
+		_, _ = fmt.Fprintln(w, "No fuzz blockers found")
+		return nil
+	}
+	shown := blockers
+	if max > 0 && len(blockers) > max {
+		shown = blockers[:max]
+	}
+
+	data := pterm.TableData{{"Uncovered Lines", "Kind", "Location", "Function", "Details"}}
+	for _, b := range shown {
+		details := ""
+		switch b.Kind {
+		case KindBranch:
+			details = fmt.Sprintf("%d of %d branch outcomes taken", b.BranchesHit, b.BranchesFound)
+		case KindFunction:
+			details = "never entered"
+			if b.Caller != "" {
+				details = "never entered, called at " + b.Caller
+			}
+		}
+		data = append(data, []string{
+			fmt.Sprint(b.UncoveredLines),
+			b.Kind,
+			fmt.Sprintf("%s:%d", b.File, b.Line),
+			b.Function,
+			details,
+		})
+	}
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(data).Srender()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, _ = fmt.Fprintln(w, table)
+	if len(shown) < len(blockers) {
+		_, _ = fmt.Fprintf(w, "Showing %d of %d fuzz blockers\n", len(shown), len(blockers))
+	}
+	return nil
+}
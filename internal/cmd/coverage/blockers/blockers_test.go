@@ -0,0 +1,132 @@
+package blockers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+const source = `int parse_header(const char *data) {
+  if (data[0] == 'H') {
+    check_magic(data);
+    return 1;
+  }
+  return 0;
+}
+
+void check_magic(const char *data) {
+  if (data[1] == 'M') {
+    return;
+  }
+}
+
+void unused() {
+  return;
+}
+
+void fuzz(const char *data) {
+  parse_header(data);
+}
+`
+
+const report = `SF:src/parser.c
+FN:1,parse_header
+FN:9,check_magic
+FN:15,unused
+FN:19,fuzz
+FNDA:5,parse_header
+FNDA:0,check_magic
+FNDA:0,unused
+FNDA:5,fuzz
+DA:1,5
+DA:2,5
+DA:3,0
+DA:4,0
+DA:6,5
+DA:9,0
+DA:10,0
+DA:11,0
+DA:13,0
+DA:15,0
+DA:16,0
+DA:19,5
+DA:20,5
+DA:21,5
+BRDA:2,0,0,0
+BRDA:2,0,1,5
+end_of_record
+`
+
+func TestFind(t *testing.T) {
+	sourceDir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(sourceDir, "src"), 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(sourceDir, "src", "parser.c"), []byte(source), 0o644)
+	require.NoError(t, err)
+
+	cs := summary.ParseLcov(strings.NewReader(report))
+	blockers := Find(cs, []string{sourceDir})
+
+	// check_magic is only called in uncovered code and unused is never
+	// called, so the only blocker is the branch in parse_header
+	require.Len(t, blockers, 1)
+	assert.Equal(t, &Blocker{
+		Kind:           KindBranch,
+		File:           "src/parser.c",
+		Line:           2,
+		Function:       "parse_header",
+		BranchesHit:    1,
+		BranchesFound:  2,
+		UncoveredLines: 2,
+	}, blockers[0])
+
+	// If the call of check_magic is covered, it's a blocker
+	cs = summary.ParseLcov(strings.NewReader(strings.Replace(report, "DA:3,0", "DA:3,5", 1)))
+	blockers = Find(cs, []string{sourceDir})
+	require.Len(t, blockers, 2)
+	assert.Equal(t, &Blocker{
+		Kind:           KindFunction,
+		File:           "src/parser.c",
+		Line:           9,
+		Function:       "check_magic",
+		Caller:         "src/parser.c:3",
+		UncoveredLines: 4,
+	}, blockers[0])
+	assert.Equal(t, KindBranch, blockers[1].Kind)
+	assert.Equal(t, 1, blockers[1].UncoveredLines)
+
+	var out bytes.Buffer
+	err = PrintTable(&out, blockers, 1)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "never entered, called at src/parser.c:3")
+	assert.Contains(t, out.String(), "Showing 1 of 2 fuzz blockers")
+}
+
+func TestFind_NoSources(t *testing.T) {
+	cs := summary.ParseLcov(strings.NewReader(report))
+	blockers := Find(cs, []string{t.TempDir()})
+
+	// Without sources, all functions which were never entered in files
+	// with covered functions are considered reachable
+	require.Len(t, blockers, 3)
+	assert.Equal(t, "check_magic", blockers[0].Function)
+	assert.Equal(t, KindBranch, blockers[1].Kind)
+	assert.Equal(t, "unused", blockers[2].Function)
+}
+
+func TestCallableName(t *testing.T) {
+	assert.Equal(t, "parse", callableName("parse"))
+	assert.Equal(t, "parse", callableName("parser.c:parse"))
+	assert.Equal(t, "parse", callableName("_Z5parsePKc"))
+	assert.Equal(t, "parse", callableName("_ZN6parser6Parser5parseEPKcm"))
+	assert.Equal(t, "parse", callableName("_ZNK6parser6Parser5parseEv"))
+	assert.Equal(t, "parse", callableName("com.example.Parser.parse(Ljava/lang/String;)V"))
+	assert.Equal(t, "Parser", callableName("com.example.Parser.<init>()V"))
+}
@@ -17,10 +17,13 @@ import (
 	"code-intelligence.com/cifuzz/internal/build/gradle"
 	"code-intelligence.com/cifuzz/internal/build/maven"
 	bazelCoverage "code-intelligence.com/cifuzz/internal/cmd/coverage/bazel"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/blockers"
 	gradleCoverage "code-intelligence.com/cifuzz/internal/cmd/coverage/gradle"
 	llvmCoverage "code-intelligence.com/cifuzz/internal/cmd/coverage/llvm"
 	mavenCoverage "code-intelligence.com/cifuzz/internal/cmd/coverage/maven"
 	nodeCoverage "code-intelligence.com/cifuzz/internal/cmd/coverage/node"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/threshold"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/cmdutils/logging"
 	"code-intelligence.com/cifuzz/internal/cmdutils/resolve"
@@ -29,18 +32,25 @@ import (
 	"code-intelligence.com/cifuzz/internal/coverage"
 	"code-intelligence.com/cifuzz/pkg/dependencies"
 	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/fileutil"
 	"code-intelligence.com/cifuzz/util/sliceutil"
 	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
+// The number of chunks in which the corpus is run to record a coverage
+// timeline if only the output file of the timeline is specified
+const defaultTimelineChunks = 10
+
 type Generator interface {
 	BuildFuzzTestForCoverage() error
 	GenerateCoverageReport() (string, error)
+	CoverageSummary() *summary.CoverageSummary
 }
 
 type coverageOptions struct {
 	OutputFormat   string   `mapstructure:"format"`
 	OutputPath     string   `mapstructure:"output"`
+	HTMLRenderer   string   `mapstructure:"html-renderer"`
 	BuildSystem    string   `mapstructure:"build-system"`
 	BuildCommand   string   `mapstructure:"build-command"`
 	CleanCommand   string   `mapstructure:"clean-command"`
@@ -48,11 +58,31 @@ type coverageOptions struct {
 	SeedCorpusDirs []string `mapstructure:"seed-corpus-dirs"`
 	UseSandbox     bool     `mapstructure:"use-sandbox"`
 
+	// The include and exclude globs of the files of the report
+	Filter *summary.Filter `mapstructure:"coverage"`
+
+	Thresholds *threshold.Options `mapstructure:"coverage-thresholds"`
+	// The thresholds specified via flags, which take precedence over
+	// the ones from the config file
+	thresholdFlags threshold.Options
+
 	ResolveSourceFilePath bool
 	Preset                string
 	ProjectDir            string
-
-	fuzzTest        string
+	All                   bool
+	Blockers              bool
+	MaxBlockers           int
+	TimelineChunks        int
+	TimelineOutput        string
+
+	fuzzTest string
+	// The fuzz tests of which a merged coverage report is generated.
+	// Only set if multiple fuzz tests were specified.
+	fuzzTests []string
+	// If set, only the inputs in these directories are used to generate
+	// the coverage report. Only supported for CMake and other build
+	// systems.
+	corpusDirs      []string
 	targetMethod    string
 	testNamePattern string
 	argsToPass      []string
@@ -88,6 +118,64 @@ func (opts *coverageOptions) validate() error {
 		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
 	}
 
+	if opts.MaxBlockers < 0 {
+		msg := "Flag \"max-blockers\" must not be negative"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+
+	if opts.TimelineChunks < 0 {
+		msg := "Flag \"timeline-chunks\" must not be negative"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if opts.TimelineOutput != "" && opts.TimelineChunks == 0 {
+		opts.TimelineChunks = defaultTimelineChunks
+	}
+	if opts.TimelineChunks > 0 && !stringutil.Contains([]string{config.BuildSystemCMake, config.BuildSystemOther}, opts.BuildSystem) {
+		msg := fmt.Sprintf("Coverage timelines are not supported for build system %q", opts.BuildSystem)
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+
+	if opts.HTMLRenderer == "" {
+		opts.HTMLRenderer = coverage.HTMLRendererAuto
+	}
+	if !stringutil.Contains(coverage.HTMLRenderers, opts.HTMLRenderer) {
+		msg := fmt.Sprintf("Flag \"html-renderer\" must be %s", strings.Join(coverage.HTMLRenderers, " or "))
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+
+	err = opts.Filter.Validate()
+	if err != nil {
+		return cmdutils.WrapIncorrectUsageError(err)
+	}
+	if opts.Filter != nil {
+		// The file names of JaCoCo reports are relative to the source
+		// directory, while the globs are relative to the project
+		// directory
+		opts.Filter.SourceDirs = opts.sourceDirs()
+	}
+
+	if opts.Thresholds != nil {
+		thresholds := []float64{opts.Thresholds.MinLineCoverage, opts.Thresholds.MinBranchCoverage}
+		for _, rule := range opts.Thresholds.Paths {
+			if rule.Path == "" {
+				msg := "Each rule in \"coverage-thresholds.paths\" must specify a path"
+				return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+			}
+			thresholds = append(thresholds, rule.MinLineCoverage, rule.MinBranchCoverage)
+		}
+		for _, t := range thresholds {
+			if t < 0 || t > 100 {
+				msg := fmt.Sprintf("Coverage thresholds must be between 0 and 100, got %g", t)
+				return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+			}
+		}
+	}
+
+	if opts.All && opts.BuildSystem == config.BuildSystemOther {
+		msg := "Flag \"all\" is not supported for build system type \"other\", please specify the fuzz tests"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+
 	// To build with other build systems, a build command must be provided
 	if opts.BuildSystem == config.BuildSystemOther && opts.BuildCommand == "" {
 		msg := `Flag 'build-command' must be set when using the build system type 'other'`
@@ -97,6 +185,101 @@ func (opts *coverageOptions) validate() error {
 	return nil
 }
 
+// setFuzzTests sets the fuzz test or, if multiple fuzz tests are
+// specified, the fuzz tests of the merged coverage report from the
+// <fuzz test> arguments
+func (opts *coverageOptions) setFuzzTests(args []string) error {
+	// Fuzz tests of Node.js projects can contain a filter for the test
+	// name and fuzz tests of JVM projects can specify a method of the
+	// fuzz test class, which are removed before resolving the fuzz test
+	// and added again afterwards.
+	separator := ""
+	switch opts.BuildSystem {
+	case config.BuildSystemNodeJS:
+		separator = ":"
+	case config.BuildSystemMaven, config.BuildSystemGradle:
+		separator = "::"
+	}
+	suffixes := make([]string, len(args))
+	fuzzTests := make([]string, len(args))
+	for i, arg := range args {
+		fuzzTests[i] = arg
+		if separator != "" && strings.Contains(arg, separator) {
+			split := strings.Split(arg, separator)
+			fuzzTests[i], suffixes[i] = split[0], split[1]
+		}
+	}
+
+	fuzzTests, err := resolve.FuzzTestArguments(opts.ResolveSourceFilePath, fuzzTests, opts.BuildSystem, opts.ProjectDir)
+	if err != nil {
+		return err
+	}
+
+	if len(fuzzTests) == 1 {
+		opts.fuzzTest = fuzzTests[0]
+		switch opts.BuildSystem {
+		case config.BuildSystemNodeJS:
+			opts.testNamePattern = strings.ReplaceAll(suffixes[0], "\"", "")
+		case config.BuildSystemMaven, config.BuildSystemGradle:
+			opts.targetMethod = suffixes[0]
+		}
+		return nil
+	}
+
+	for i, fuzzTest := range fuzzTests {
+		if suffixes[i] != "" {
+			fuzzTest += separator + suffixes[i]
+		}
+		opts.fuzzTests = append(opts.fuzzTests, fuzzTest)
+	}
+	opts.fuzzTests = sliceutil.RemoveDuplicates(opts.fuzzTests)
+	return nil
+}
+
+// listFuzzTests returns all fuzz tests of the project for the build
+// systems for which the fuzz tests can be listed without building the
+// project. For CMake and Bazel, the fuzz tests are listed by the
+// coverage generator.
+func (opts *coverageOptions) listFuzzTests() ([]string, error) {
+	var fuzzTests []string
+	var err error
+	switch opts.BuildSystem {
+	case config.BuildSystemMaven:
+		testDirs := []string{filepath.Join(opts.ProjectDir, "src", "test")}
+		fuzzTests, err = cmdutils.ListJVMFuzzTests(testDirs, "")
+	case config.BuildSystemGradle:
+		var testDirs []string
+		testDirs, err = gradle.GetTestSourceSets(opts.ProjectDir)
+		if err != nil {
+			return nil, err
+		}
+		fuzzTests, err = cmdutils.ListJVMFuzzTests(testDirs, "")
+	case config.BuildSystemNodeJS:
+		fuzzTests, err = cmdutils.ListNodeFuzzTests(opts.ProjectDir, "")
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(fuzzTests) == 0 {
+		return nil, errors.Errorf("No fuzz tests found in %s", opts.ProjectDir)
+	}
+	return fuzzTests, nil
+}
+
+// fuzzTestsDisplayName returns how the fuzz tests of the coverage report
+// are displayed to the user
+func (opts *coverageOptions) fuzzTestsDisplayName() string {
+	if opts.fuzzTest != "" {
+		return opts.fuzzTest
+	}
+	if opts.All && len(opts.fuzzTests) == 0 {
+		return "all fuzz tests"
+	}
+	return strings.Join(opts.fuzzTests, ", ")
+}
+
 type coverageCmd struct {
 	*cobra.Command
 	opts *coverageOptions
@@ -107,10 +290,16 @@ func New() *cobra.Command {
 	var bindFlags func()
 
 	cmd := &cobra.Command{
-		Use:   "coverage [flags] <fuzz test>",
+		Use:   "coverage [flags] <fuzz test>...",
 		Short: "Generate coverage report for fuzz test",
 		Long: `This command generates a coverage report for a fuzz test.
 
+If multiple fuzz tests are specified or the --all flag is used, the
+coverage of all those fuzz tests is merged into a single report and
+the coverage of the individual fuzz tests is printed in addition to
+the coverage of the merged report. The --all flag is not supported for
+the build system "other".
+
 The inputs found in the inputs directory of the fuzz test are used in
 addition to optional input directories specified with the seed-corpus flag.
 More details about the build system specific inputs directory location
@@ -118,8 +307,23 @@ can be found in the help message of the run command.
 
 Additional arguments for CMake and Bazel can be passed after a "--".
 
+With --blockers, the command lists the "fuzz blockers" which prevent
+the fuzzer from covering more code: Functions which are called from
+covered code but were never entered, and branches which were reached
+but of which an outcome was never taken. They are ranked by the
+estimated number of uncovered lines behind them. Adding dictionary
+entries or seeds which get past the top blockers is usually the most
+effective way to increase the coverage.
+
+If coverage thresholds are configured via --min-line-coverage,
+--min-branch-coverage or the "coverage-thresholds" section of
+cifuzz.yaml, the command fails if the coverage is below them. With
+--ratchet-baseline, it also fails if the coverage decreased compared
+to the baseline stored in the specified file.
+
 The output can be displayed in the browser or written as a HTML
-or a lcov trace file.
+report, a lcov trace file, a JaCoCo XML report, a Cobertura XML report
+or a JSON file with the line, branch and function counts of each file.
 
 ` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("Browser") + `
     cifuzz coverage <fuzz test>
@@ -132,7 +336,17 @@ or a lcov trace file.
 
 ` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("XML (Jacoco Report)") + `
     cifuzz coverage --format=jacocoxml <fuzz test>
+
+` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("Cobertura XML") + `
+    cifuzz coverage --format=cobertura <fuzz test>
+
+` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("JSON") + `
+    cifuzz coverage --format=json <fuzz test>
+
+` + pterm.Style{pterm.Reset, pterm.Bold}.Sprint("Merged report of all fuzz tests") + `
+    cifuzz coverage --all
 `,
+		Args:              cobra.ArbitraryArgs,
 		ValidArgsFunction: completion.ValidFuzzTests,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			// Bind viper keys to flags. We can't do this in the New
@@ -141,6 +355,7 @@ or a lcov trace file.
 			bindFlags()
 			cmdutils.ViperMustBindPFlag("format", cmd.Flags().Lookup("format"))
 			cmdutils.ViperMustBindPFlag("output", cmd.Flags().Lookup("output"))
+			cmdutils.ViperMustBindPFlag("html-renderer", cmd.Flags().Lookup("html-renderer"))
 
 			var lenFuzzTestArgs int
 			var argsToPass []string
@@ -151,8 +366,12 @@ or a lcov trace file.
 			} else {
 				lenFuzzTestArgs = len(args)
 			}
-			if lenFuzzTestArgs != 1 {
-				msg := fmt.Sprintf("Exactly one <fuzz test> argument must be provided, got %d", lenFuzzTestArgs)
+			if opts.All && lenFuzzTestArgs != 0 {
+				msg := "No <fuzz test> arguments must be provided when the --all flag is used"
+				return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+			}
+			if !opts.All && lenFuzzTestArgs == 0 {
+				msg := "At least one <fuzz test> argument must be provided or the --all flag must be used"
 				return cmdutils.WrapIncorrectUsageError(errors.New(msg))
 			}
 
@@ -162,42 +381,61 @@ or a lcov trace file.
 				return cmdutils.WrapSilentError(err)
 			}
 
+			if opts.Filter == nil {
+				opts.Filter = &summary.Filter{}
+			}
+			opts.Filter.BaseDir = opts.ProjectDir
+
+			if opts.Thresholds == nil {
+				opts.Thresholds = &threshold.Options{}
+			}
+			opts.Thresholds.ResolvePaths(opts.ProjectDir)
+			// Flags take precedence over the config file
+			if cmd.Flags().Changed("min-line-coverage") {
+				opts.Thresholds.MinLineCoverage = opts.thresholdFlags.MinLineCoverage
+			}
+			if cmd.Flags().Changed("min-branch-coverage") {
+				opts.Thresholds.MinBranchCoverage = opts.thresholdFlags.MinBranchCoverage
+			}
+			if cmd.Flags().Changed("ratchet-baseline") {
+				opts.Thresholds.RatchetBaseline, err = filepath.Abs(opts.thresholdFlags.RatchetBaseline)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
 			if opts.BuildSystem == config.BuildSystemNodeJS {
 				if os.Getenv("CIFUZZ_PRERELEASE") == "" {
 					fmt.Println("cifuzz does not support Node.js projects yet.")
 					os.Exit(0)
 				}
-				// Check if the fuzz test contains a filter for the test name
-				if strings.Contains(args[0], ":") {
-					split := strings.Split(args[0], ":")
-					args[0], opts.testNamePattern = split[0], strings.ReplaceAll(split[1], "\"", "")
-				}
 			}
 
-			if sliceutil.Contains(
-				[]string{config.BuildSystemMaven, config.BuildSystemGradle},
-				opts.BuildSystem,
-			) {
-				// Check if the fuzz test is a method of a class
-				// And remove method from fuzz test argument
-				if strings.Contains(args[0], "::") {
-					split := strings.Split(args[0], "::")
-					args[0], opts.targetMethod = split[0], split[1]
+			if opts.All {
+				opts.fuzzTests, err = opts.listFuzzTests()
+				if err != nil {
+					log.Error(err)
+					return cmdutils.WrapSilentError(err)
+				}
+			} else {
+				err = opts.setFuzzTests(args)
+				if err != nil {
+					log.Error(err)
+					return cmdutils.WrapSilentError(err)
 				}
 			}
-
-			fuzzTest, err := resolve.FuzzTestArguments(opts.ResolveSourceFilePath, args, opts.BuildSystem, opts.ProjectDir)
-			if err != nil {
-				log.Error(err)
-				return cmdutils.WrapSilentError(err)
-			}
-			opts.fuzzTest = fuzzTest[0]
 			opts.argsToPass = argsToPass
 
+			logNames := opts.fuzzTests
+			if opts.fuzzTest != "" {
+				logNames = []string{opts.fuzzTest}
+			} else if opts.All {
+				logNames = []string{"all"}
+			}
 			opts.buildStdout = cmd.OutOrStdout()
 			opts.buildStderr = cmd.OutOrStderr()
 			if logging.ShouldLogBuildToFile() {
-				opts.buildStdout, err = logging.BuildOutputToFile(opts.ProjectDir, []string{opts.fuzzTest})
+				opts.buildStdout, err = logging.BuildOutputToFile(opts.ProjectDir, logNames)
 				if err != nil {
 					log.Errorf(err, "Failed to setup logging: %v", err.Error())
 					return cmdutils.WrapSilentError(err)
@@ -231,13 +469,24 @@ or a lcov trace file.
 	if err != nil {
 		panic(err)
 	}
-	cmd.Flags().StringP("format", "f", "html", "Output format of the coverage report (html/lcov).")
+	cmd.Flags().BoolVar(&opts.All, "all", false, "Generate a merged coverage report of all fuzz tests.")
+	cmd.Flags().Float64Var(&opts.thresholdFlags.MinLineCoverage, "min-line-coverage", 0, "Fail if the line coverage is below this `percentage`.")
+	cmd.Flags().Float64Var(&opts.thresholdFlags.MinBranchCoverage, "min-branch-coverage", 0, "Fail if the branch coverage is below this `percentage`.")
+	cmd.Flags().StringVar(&opts.thresholdFlags.RatchetBaseline, "ratchet-baseline", "", "Fail if the coverage decreased compared to the baseline stored in this `file` and raise the baseline if it increased.")
+	cmd.Flags().BoolVar(&opts.Blockers, "blockers", false, "List the functions and branches which were reached but never entered or taken, ranked by the uncovered code behind them.")
+	cmd.Flags().IntVar(&opts.MaxBlockers, "max-blockers", 20, "The maximum `number` of fuzz blockers which are listed (0 means unlimited).")
+	cmd.Flags().IntVar(&opts.TimelineChunks, "timeline-chunks", 0, "Run the corpus in this `number` of chunks in the order in which the inputs were created and record the coverage after each chunk (default 10 if --timeline is used).")
+	cmd.Flags().StringVar(&opts.TimelineOutput, "timeline", "", "Write the coverage timeline to this `file`, as CSV if it has the .csv extension and as JSON otherwise.")
+	cmd.Flags().String("html-renderer", coverage.HTMLRendererAuto, "The renderer of HTML reports of C/C++ projects (auto/genhtml/builtin). By default, genhtml is used if it's installed.")
+	cmd.Flags().StringP("format", "f", "html", "Output format of the coverage report (html/lcov/jacocoxml/cobertura/json).")
 	cmd.Flags().StringP("output", "o", "", "Output path of the coverage report.")
 	err = cmd.RegisterFlagCompletionFunc("format", completion.ValidCoverageOutputFormat)
 	if err != nil {
 		panic(err)
 	}
 
+	cmd.AddCommand(newDiffCmd())
+
 	return cmd
 }
 
@@ -266,11 +515,69 @@ func (c *coverageCmd) run() error {
 		c.opts.OutputPath = output
 	}
 
+	var reportPath string
+	var coverageSummary *summary.CoverageSummary
+	if coverage.IsConvertedFormat(c.opts.OutputFormat) {
+		reportPath, coverageSummary, err = c.generateConvertedReport()
+	} else {
+		reportPath, coverageSummary, err = c.generate()
+	}
+	if err != nil {
+		return err
+	}
+
+	err = c.handleReport(reportPath)
+	if err != nil {
+		return err
+	}
+
+	if c.opts.Blockers {
+		log.Print("\n")
+		log.Successf("Fuzz blockers:\n")
+		b := blockers.Find(coverageSummary, c.opts.sourceDirs())
+		err = blockers.PrintTable(c.OutOrStdout(), b, c.opts.MaxBlockers)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.opts.Thresholds.Enabled() {
+		return c.checkThresholds(coverageSummary)
+	}
+	return nil
+}
+
+func (c *coverageCmd) handleReport(reportPath string) error {
+	switch c.opts.OutputFormat {
+	case coverage.FormatHTML:
+		return c.handleHTMLReport(reportPath)
+	case coverage.FormatLCOV:
+		log.Successf("Created coverage lcov report: %s", reportPath)
+		return nil
+	case coverage.FormatJacocoXML:
+		log.Successf("Created jacoco.xml coverage report: %s", reportPath)
+		return nil
+	case coverage.FormatCobertura:
+		log.Successf("Created Cobertura coverage report: %s", reportPath)
+		return nil
+	case coverage.FormatJSON:
+		log.Successf("Created JSON coverage report: %s", reportPath)
+		return nil
+	default:
+		return errors.Errorf("Unsupported output format")
+	}
+}
+
+func (c *coverageCmd) newGenerator() (Generator, error) {
 	var gen Generator
 	switch c.opts.BuildSystem {
 	case config.BuildSystemBazel:
 		gen = &bazelCoverage.CoverageGenerator{
 			FuzzTest:        c.opts.fuzzTest,
+			FuzzTests:       c.opts.fuzzTests,
+			AllFuzzTests:    c.opts.All,
+			HTMLRenderer:    c.opts.HTMLRenderer,
+			Filter:          c.opts.Filter,
 			OutputFormat:    c.opts.OutputFormat,
 			OutputPath:      c.opts.OutputPath,
 			BuildSystemArgs: c.opts.argsToPass,
@@ -300,8 +607,16 @@ func (c *coverageCmd) run() error {
 			CleanCommand:    c.opts.CleanCommand,
 			NumBuildJobs:    c.opts.NumBuildJobs,
 			SeedCorpusDirs:  c.opts.SeedCorpusDirs,
+			CorpusDirs:      c.opts.corpusDirs,
 			UseSandbox:      c.opts.UseSandbox,
 			FuzzTest:        c.opts.fuzzTest,
+			FuzzTests:       c.opts.fuzzTests,
+			AllFuzzTests:    c.opts.All,
+			HTMLRenderer:    c.opts.HTMLRenderer,
+			DetailedSummary: c.opts.Blockers,
+			Filter:          c.opts.Filter,
+			TimelineChunks:  c.opts.TimelineChunks,
+			TimelineOutput:  c.opts.TimelineOutput,
 			ProjectDir:      c.opts.ProjectDir,
 			Stderr:          c.OutOrStderr(),
 			BuildStdout:     c.opts.buildStdout,
@@ -315,9 +630,13 @@ func (c *coverageCmd) run() error {
 
 		gen = &gradleCoverage.CoverageGenerator{
 			OutputPath:   c.opts.OutputPath,
+			OutputFormat: c.opts.OutputFormat,
 			FuzzTest:     c.opts.fuzzTest,
 			TargetMethod: c.opts.targetMethod,
+			FuzzTests:    c.opts.fuzzTests,
 			ProjectDir:   c.opts.ProjectDir,
+			SourceDir:    c.opts.sourceDirs()[0],
+			Filter:       c.opts.Filter,
 			Parallel: gradle.ParallelOptions{
 				Enabled: viper.IsSet("build-jobs"),
 			},
@@ -336,9 +655,13 @@ func (c *coverageCmd) run() error {
 
 		gen = &mavenCoverage.CoverageGenerator{
 			OutputPath:   c.opts.OutputPath,
+			OutputFormat: c.opts.OutputFormat,
 			FuzzTest:     c.opts.fuzzTest,
+			FuzzTests:    c.opts.fuzzTests,
 			TargetMethod: c.opts.targetMethod,
 			ProjectDir:   c.opts.ProjectDir,
+			SourceDir:    c.opts.sourceDirs()[0],
+			Filter:       c.opts.Filter,
 			Parallel: maven.ParallelOptions{
 				Enabled: viper.IsSet("build-jobs"),
 				NumJobs: c.opts.NumBuildJobs,
@@ -361,18 +684,30 @@ func (c *coverageCmd) run() error {
 			OutputFormat:    c.opts.OutputFormat,
 			TestPathPattern: c.opts.fuzzTest,
 			TestNamePattern: c.opts.testNamePattern,
+			FuzzTests:       c.opts.fuzzTests,
 			ProjectDir:      c.opts.ProjectDir,
+			Filter:          c.opts.Filter,
 			Stderr:          c.OutOrStderr(),
 			BuildStdout:     c.opts.buildStdout,
 			BuildStderr:     c.opts.buildStderr,
 		}
 	default:
-		return errors.Errorf("Unsupported build system \"%s\"", c.opts.BuildSystem)
+		return nil, errors.Errorf("Unsupported build system \"%s\"", c.opts.BuildSystem)
+	}
+	return gen, nil
+}
+
+// generate builds the fuzz tests, runs them on their corpora and returns
+// the path and the summary of the coverage report
+func (c *coverageCmd) generate() (string, *summary.CoverageSummary, error) {
+	gen, err := c.newGenerator()
+	if err != nil {
+		return "", nil, err
 	}
 
 	if c.opts.BuildSystem != config.BuildSystemNodeJS {
 		logging.StartBuildProgressSpinner(log.BuildInProgressMsg)
-		log.Infof("Building %s", pterm.Style{pterm.Reset, pterm.FgLightBlue}.Sprint(c.opts.fuzzTest))
+		log.Infof("Building %s", pterm.Style{pterm.Reset, pterm.FgLightBlue}.Sprint(c.opts.fuzzTestsDisplayName()))
 
 		err = gen.BuildFuzzTestForCoverage()
 		if err != nil {
@@ -383,31 +718,152 @@ func (c *coverageCmd) run() error {
 				// configuration so we print the error without the stack trace
 				// (in non-verbose mode) and silence it
 				log.Error(err)
-				return cmdutils.ErrSilent
+				return "", nil, cmdutils.ErrSilent
 			}
-			return err
+			return "", nil, err
 		}
 
 		logging.StopBuildProgressSpinnerOnSuccess(log.BuildInProgressSuccessMsg, true)
 	}
 
 	reportPath, err := gen.GenerateCoverageReport()
+	if err != nil {
+		return "", nil, err
+	}
+	return reportPath, gen.CoverageSummary(), nil
+}
+
+// checkThresholds checks the coverage against the configured thresholds
+// and the ratchet baseline. If a check fails, a silent error is
+// returned, which results in a non-zero exit code.
+func (c *coverageCmd) checkThresholds(coverageSummary *summary.CoverageSummary) error {
+	opts := c.opts.Thresholds
+	m := threshold.Measure(coverageSummary, opts, c.opts.ProjectDir)
+
+	var baseline *threshold.Measurement
+	var err error
+	if opts.RatchetBaseline != "" {
+		baseline, err = threshold.LoadBaseline(opts.RatchetBaseline)
+		if err != nil {
+			return err
+		}
+	}
+
+	result := threshold.Evaluate(m, opts, baseline)
+	err = result.PrintTable(c.ErrOrStderr())
 	if err != nil {
 		return err
 	}
+	if failed := result.Failed(); len(failed) > 0 {
+		err = errors.Errorf("%d of %d coverage checks failed", len(failed), len(result.Checks))
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+	if len(result.Checks) > 0 {
+		log.Successf("All %d coverage checks passed", len(result.Checks))
+	}
+
+	if opts.RatchetBaseline != "" {
+		changed, err := threshold.SaveBaseline(opts.RatchetBaseline, m, baseline)
+		if err != nil {
+			return err
+		}
+		if baseline == nil {
+			log.Infof("Created coverage baseline %s", opts.RatchetBaseline)
+		} else if changed {
+			log.Infof("Raised coverage baseline %s", opts.RatchetBaseline)
+		}
+	}
+	return nil
+}
+
+// generateConvertedReport generates an lcov or JaCoCo XML report,
+// depending on the build system, and converts it into the output format
+func (c *coverageCmd) generateConvertedReport() (string, *summary.CoverageSummary, error) {
+	tmpDir, err := os.MkdirTemp("", "coverage-")
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tmpDir)
+
+	opts := *c.opts
+	opts.OutputFormat = coverage.FormatLCOV
+	if stringutil.Contains(coverage.ValidOutputFormats[opts.BuildSystem], coverage.FormatJacocoXML) {
+		opts.OutputFormat = coverage.FormatJacocoXML
+	}
+	opts.OutputPath = filepath.Join(tmpDir, "report")
+	cmd := &coverageCmd{Command: c.Command, opts: &opts}
+	reportPath, _, err := cmd.generate()
+	if err != nil {
+		return "", nil, err
+	}
+	coverageSummary, err := parseReport(reportPath)
+	if err != nil {
+		return "", nil, err
+	}
+	coverageSummary.ApplyFilter(c.opts.Filter)
+
+	outputPath := c.opts.OutputPath
+	if outputPath == "" {
+		// Like lcov reports, the report is created in the current
+		// working directory if no output path is specified
+		outputPath = c.opts.reportName() + ".coverage." + c.opts.OutputFormat
+		if c.opts.OutputFormat == coverage.FormatCobertura {
+			outputPath += ".xml"
+		}
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	defer f.Close()
 
 	switch c.opts.OutputFormat {
-	case coverage.FormatHTML:
-		return c.handleHTMLReport(reportPath)
-	case coverage.FormatLCOV:
-		log.Successf("Created coverage lcov report: %s", reportPath)
-		return nil
-	case coverage.FormatJacocoXML:
-		log.Successf("Created jacoco.xml coverage report: %s", reportPath)
-		return nil
+	case coverage.FormatCobertura:
+		err = coverageSummary.WriteCobertura(f, c.opts.sourceDirs())
+	case coverage.FormatJSON:
+		err = coverageSummary.WriteJSON(f)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return outputPath, coverageSummary, nil
+}
+
+// reportName returns the name of converted reports if no output path
+// was specified
+func (opts *coverageOptions) reportName() string {
+	if opts.fuzzTest == "" {
+		return "merged"
+	}
+	name := strings.Trim(opts.fuzzTest, "/:")
+	return strings.NewReplacer("/", "-", ":", "-").Replace(name)
+}
+
+// sourceDirs returns the directories relative to which the files of
+// converted reports are specified. JaCoCo reports contain the paths of
+// the source files relative to the source root, for which we assume the
+// default layout of Maven and Gradle projects.
+func (opts *coverageOptions) sourceDirs() []string {
+	switch opts.BuildSystem {
+	case config.BuildSystemMaven, config.BuildSystemGradle:
+		return []string{filepath.Join(opts.ProjectDir, "src", "main", "java")}
 	default:
-		return errors.Errorf("Unsupported output format")
+		return []string{opts.ProjectDir}
+	}
+}
+
+// parseReport parses an lcov or JaCoCo XML report
+func parseReport(path string) (*summary.CoverageSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	if filepath.Ext(path) == ".xml" {
+		return summary.ParseJacocoXML(f), nil
 	}
+	return summary.ParseLcov(f), nil
 }
 
 func (c *coverageCmd) handleHTMLReport(reportPath string) error {
@@ -459,7 +915,6 @@ func (c *coverageCmd) checkDependencies() error {
 	case config.BuildSystemBazel:
 		deps = []dependencies.Key{
 			dependencies.Bazel,
-			dependencies.GenHTML,
 		}
 	case config.BuildSystemCMake:
 		deps = []dependencies.Key{
@@ -467,13 +922,12 @@ func (c *coverageCmd) checkDependencies() error {
 			dependencies.LLVMSymbolizer,
 			dependencies.LLVMCov,
 			dependencies.LLVMProfData,
-			dependencies.GenHTML,
 		}
 		switch runtime.GOOS {
 		case "linux", "darwin":
 			deps = append(deps, dependencies.Clang)
 		case "windows":
-			deps = append(deps, dependencies.VisualStudio, dependencies.Perl)
+			deps = append(deps, dependencies.VisualStudio)
 		}
 	case config.BuildSystemMaven:
 		deps = []dependencies.Key{dependencies.Maven}
@@ -487,11 +941,21 @@ func (c *coverageCmd) checkDependencies() error {
 			dependencies.LLVMSymbolizer,
 			dependencies.LLVMCov,
 			dependencies.LLVMProfData,
-			dependencies.GenHTML,
 		}
 	default:
 		return errors.Errorf("Unsupported build system \"%s\"", c.opts.BuildSystem)
 	}
+	// genhtml is only required if it's explicitly requested, otherwise
+	// the built-in renderer is used if genhtml is not installed
+	if c.opts.OutputFormat == coverage.FormatHTML && c.opts.HTMLRenderer == coverage.HTMLRendererGenHTML {
+		switch c.opts.BuildSystem {
+		case config.BuildSystemBazel, config.BuildSystemCMake, config.BuildSystemOther:
+			deps = append(deps, dependencies.GenHTML)
+			if runtime.GOOS == "windows" {
+				deps = append(deps, dependencies.Perl)
+			}
+		}
+	}
 	err := dependencies.Check(deps, c.opts.ProjectDir)
 	if err != nil {
 		log.Error(err)
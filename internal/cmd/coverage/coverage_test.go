@@ -113,3 +113,70 @@ func TestNodeMissing(t *testing.T) {
 
 	assert.Contains(t, stdErr, fmt.Sprintf(dependencies.MessageMissing, "node"))
 }
+
+func TestAllWithFuzzTests(t *testing.T) {
+	_, _, err := cmdutils.ExecuteCommand(t, New(), os.Stdin, "--all", "my_fuzz_test")
+	require.Error(t, err)
+	var usageErr *cmdutils.IncorrectUsageError
+	assert.ErrorAs(t, err, &usageErr)
+}
+
+func TestSetFuzzTests(t *testing.T) {
+	opts := &coverageOptions{BuildSystem: config.BuildSystemMaven}
+	err := opts.setFuzzTests([]string{"com.example.FuzzTest::myFuzzTest"})
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.FuzzTest", opts.fuzzTest)
+	assert.Equal(t, "myFuzzTest", opts.targetMethod)
+	assert.Empty(t, opts.fuzzTests)
+
+	opts = &coverageOptions{BuildSystem: config.BuildSystemMaven}
+	err = opts.setFuzzTests([]string{"com.example.FuzzTest::myFuzzTest", "com.example.OtherFuzzTest", "com.example.OtherFuzzTest"})
+	require.NoError(t, err)
+	assert.Empty(t, opts.fuzzTest)
+	assert.Equal(t, []string{"com.example.FuzzTest::myFuzzTest", "com.example.OtherFuzzTest"}, opts.fuzzTests)
+
+	opts = &coverageOptions{BuildSystem: config.BuildSystemNodeJS}
+	err = opts.setFuzzTests([]string{`FuzzTestCase:"my fuzz test"`})
+	require.NoError(t, err)
+	assert.Equal(t, "FuzzTestCase", opts.fuzzTest)
+	assert.Equal(t, "my fuzz test", opts.testNamePattern)
+}
+
+func TestReportName(t *testing.T) {
+	opts := &coverageOptions{fuzzTest: "//src/parser:parser_fuzz_test"}
+	assert.Equal(t, "src-parser-parser_fuzz_test", opts.reportName())
+
+	opts = &coverageOptions{fuzzTests: []string{"com.example.FuzzTest", "com.example.OtherFuzzTest"}}
+	assert.Equal(t, "merged", opts.reportName())
+}
+
+func TestInvalidThreshold(t *testing.T) {
+	_, cleanup := testutil.BootstrapExampleProjectForTest("coverage-cmd-test", config.BuildSystemCMake)
+	defer cleanup()
+
+	_, _, err := cmdutils.ExecuteCommand(t, New(), os.Stdin, "--min-line-coverage", "120", "my_fuzz_test")
+	require.Error(t, err)
+	var usageErr *cmdutils.IncorrectUsageError
+	assert.ErrorAs(t, err, &usageErr)
+}
+
+func TestInvalidHTMLRenderer(t *testing.T) {
+	_, cleanup := testutil.BootstrapExampleProjectForTest("coverage-cmd-test", config.BuildSystemCMake)
+	defer cleanup()
+
+	_, _, err := cmdutils.ExecuteCommand(t, New(), os.Stdin, "--html-renderer", "foo", "my_fuzz_test")
+	require.Error(t, err)
+	var usageErr *cmdutils.IncorrectUsageError
+	assert.ErrorAs(t, err, &usageErr)
+}
+
+func TestTimelineUnsupportedBuildSystem(t *testing.T) {
+	_, cleanup := testutil.BootstrapExampleProjectForTest("coverage-cmd-test", config.BuildSystemMaven)
+	defer cleanup()
+
+	_, _, err := cmdutils.ExecuteCommand(t, New(), os.Stdin, "--timeline", "timeline.csv", "com.example.FuzzTestCase")
+	require.Error(t, err)
+	var usageErr *cmdutils.IncorrectUsageError
+	assert.ErrorAs(t, err, &usageErr)
+	assert.Contains(t, err.Error(), "timelines are not supported")
+}
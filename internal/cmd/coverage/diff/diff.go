@@ -0,0 +1,170 @@
+// Package diff compares the line and function coverage of two coverage
+// reports.
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+// FileDiff is the difference between the coverage of a file in two
+// coverage reports
+type FileDiff struct {
+	Filename                string   `json:"filename"`
+	NewlyCoveredLines       []int    `json:"newly_covered_lines,omitempty"`
+	NewlyUncoveredLines     []int    `json:"newly_uncovered_lines,omitempty"`
+	NewlyCoveredFunctions   []string `json:"newly_covered_functions,omitempty"`
+	NewlyUncoveredFunctions []string `json:"newly_uncovered_functions,omitempty"`
+}
+
+// Diff is the difference between two coverage reports. It only contains
+// the files whose line or function coverage changed.
+type Diff struct {
+	Files []*FileDiff       `json:"files"`
+	Old   *summary.Coverage `json:"old"`
+	New   *summary.Coverage `json:"new"`
+}
+
+// Compare returns the difference between the line and function
+// coverage of the old and the new coverage report. Files are matched by
+// their file name, so both reports must use the same paths.
+func Compare(oldSummary, newSummary *summary.CoverageSummary) *Diff {
+	oldFiles := filesByName(oldSummary)
+	newFiles := filesByName(newSummary)
+
+	var filenames []string
+	for filename := range oldFiles {
+		filenames = append(filenames, filename)
+	}
+	for filename := range newFiles {
+		if _, ok := oldFiles[filename]; !ok {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+
+	d := &Diff{Old: oldSummary.Total, New: newSummary.Total}
+	for _, filename := range filenames {
+		fileDiff := compareFiles(filename, oldFiles[filename], newFiles[filename])
+		if fileDiff.changed() {
+			d.Files = append(d.Files, fileDiff)
+		}
+	}
+	return d
+}
+
+func filesByName(s *summary.CoverageSummary) map[string]*summary.FileCoverage {
+	files := make(map[string]*summary.FileCoverage)
+	for _, file := range s.Files {
+		files[file.Filename] = file
+	}
+	return files
+}
+
+func compareFiles(filename string, oldFile, newFile *summary.FileCoverage) *FileDiff {
+	var oldLines, newLines map[int]int
+	var oldFunctions, newFunctions map[string]int
+	if oldFile != nil {
+		oldLines, oldFunctions = oldFile.LineHits, oldFile.FunctionHits
+	}
+	if newFile != nil {
+		newLines, newFunctions = newFile.LineHits, newFile.FunctionHits
+	}
+
+	fileDiff := &FileDiff{Filename: filename}
+	fileDiff.NewlyCoveredLines = newlyCovered(oldLines, newLines)
+	fileDiff.NewlyUncoveredLines = newlyCovered(newLines, oldLines)
+	fileDiff.NewlyCoveredFunctions = newlyCovered(oldFunctions, newFunctions)
+	fileDiff.NewlyUncoveredFunctions = newlyCovered(newFunctions, oldFunctions)
+	return fileDiff
+}
+
+// newlyCovered returns the keys which have a non-zero count in the new
+// map but not in the old map, sorted in ascending order
+func newlyCovered[K int | string](oldHits, newHits map[K]int) []K {
+	var res []K
+	for key, count := range newHits {
+		if count > 0 && oldHits[key] == 0 {
+			res = append(res, key)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func (d *FileDiff) changed() bool {
+	return len(d.NewlyCoveredLines) > 0 || len(d.NewlyUncoveredLines) > 0 ||
+		len(d.NewlyCoveredFunctions) > 0 || len(d.NewlyUncoveredFunctions) > 0
+}
+
+// Empty returns true if the line and function coverage of the reports
+// is the same
+func (d *Diff) Empty() bool {
+	return len(d.Files) == 0
+}
+
+// Print prints the newly covered and newly uncovered lines and functions
+// of each file followed by the totals.
+func (d *Diff) Print(w io.Writer) {
+	var coveredLines, uncoveredLines, coveredFunctions, uncoveredFunctions int
+	for _, file := range d.Files {
+		_, _ = fmt.Fprintln(w, file.Filename)
+		if len(file.NewlyCoveredLines) > 0 {
+			_, _ = fmt.Fprintf(w, "  + lines %s\n", LineRanges(file.NewlyCoveredLines))
+		}
+		if len(file.NewlyUncoveredLines) > 0 {
+			_, _ = fmt.Fprintf(w, "  - lines %s\n", LineRanges(file.NewlyUncoveredLines))
+		}
+		for _, function := range file.NewlyCoveredFunctions {
+			_, _ = fmt.Fprintf(w, "  + function %s\n", function)
+		}
+		for _, function := range file.NewlyUncoveredFunctions {
+			_, _ = fmt.Fprintf(w, "  - function %s\n", function)
+		}
+		coveredLines += len(file.NewlyCoveredLines)
+		uncoveredLines += len(file.NewlyUncoveredLines)
+		coveredFunctions += len(file.NewlyCoveredFunctions)
+		uncoveredFunctions += len(file.NewlyUncoveredFunctions)
+	}
+	if len(d.Files) > 0 {
+		_, _ = fmt.Fprintln(w)
+	}
+
+	_, _ = fmt.Fprintf(w, "Newly covered:   %d lines, %d functions\n", coveredLines, coveredFunctions)
+	_, _ = fmt.Fprintf(w, "Newly uncovered: %d lines, %d functions\n", uncoveredLines, uncoveredFunctions)
+	_, _ = fmt.Fprintf(w, "Line coverage:     %s -> %s\n",
+		formatCoverage(d.Old.LinesHit, d.Old.LinesFound), formatCoverage(d.New.LinesHit, d.New.LinesFound))
+	_, _ = fmt.Fprintf(w, "Function coverage: %s -> %s\n",
+		formatCoverage(d.Old.FunctionsHit, d.Old.FunctionsFound), formatCoverage(d.New.FunctionsHit, d.New.FunctionsFound))
+}
+
+func formatCoverage(hit, found int) string {
+	percent := 100.0
+	if found != 0 {
+		percent = (float64(hit) * 100) / float64(found)
+	}
+	return fmt.Sprintf("%d / %d (%.1f%%)", hit, found, percent)
+}
+
+// LineRanges formats sorted line numbers as comma-separated ranges,
+// e.g. "3-5, 9"
+func LineRanges(lines []int) string {
+	var ranges []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprint(lines[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
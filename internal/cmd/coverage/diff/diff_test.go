@@ -0,0 +1,127 @@
+package diff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+const oldReport = `SF:foo.cpp
+FN:1,foo
+FN:5,bar
+FNDA:1,foo
+FNDA:0,bar
+DA:1,1
+DA:2,1
+DA:5,0
+DA:6,0
+DA:7,0
+LH:2
+LF:5
+FNH:1
+FNF:2
+end_of_record
+SF:unchanged.cpp
+DA:1,1
+end_of_record
+`
+
+const newReport = `SF:foo.cpp
+FN:1,foo
+FN:5,bar
+FNDA:0,foo
+FNDA:2,bar
+DA:1,0
+DA:2,0
+DA:5,2
+DA:6,2
+DA:7,1
+LH:3
+LF:5
+FNH:1
+FNF:2
+end_of_record
+SF:new.cpp
+DA:3,1
+end_of_record
+SF:unchanged.cpp
+DA:1,5
+end_of_record
+`
+
+func compareReports(t *testing.T) *Diff {
+	oldSummary := summary.ParseLcov(strings.NewReader(oldReport))
+	newSummary := summary.ParseLcov(strings.NewReader(newReport))
+	return Compare(oldSummary, newSummary)
+}
+
+func TestCompare(t *testing.T) {
+	d := compareReports(t)
+	require.Len(t, d.Files, 2)
+
+	assert.Equal(t, "foo.cpp", d.Files[0].Filename)
+	assert.Equal(t, []int{5, 6, 7}, d.Files[0].NewlyCoveredLines)
+	assert.Equal(t, []int{1, 2}, d.Files[0].NewlyUncoveredLines)
+	assert.Equal(t, []string{"bar"}, d.Files[0].NewlyCoveredFunctions)
+	assert.Equal(t, []string{"foo"}, d.Files[0].NewlyUncoveredFunctions)
+
+	assert.Equal(t, "new.cpp", d.Files[1].Filename)
+	assert.Equal(t, []int{3}, d.Files[1].NewlyCoveredLines)
+	assert.Empty(t, d.Files[1].NewlyUncoveredLines)
+}
+
+func TestPrint(t *testing.T) {
+	var out bytes.Buffer
+	compareReports(t).Print(&out)
+	assert.Equal(t, `foo.cpp
+  + lines 5-7
+  - lines 1-2
+  + function bar
+  - function foo
+new.cpp
+  + lines 3
+
+Newly covered:   4 lines, 1 functions
+Newly uncovered: 2 lines, 1 functions
+Line coverage:     2 / 5 (40.0%) -> 3 / 5 (60.0%)
+Function coverage: 1 / 2 (50.0%) -> 1 / 2 (50.0%)
+`, out.String())
+}
+
+func TestLineRanges(t *testing.T) {
+	assert.Equal(t, "", LineRanges(nil))
+	assert.Equal(t, "1", LineRanges([]int{1}))
+	assert.Equal(t, "1-3, 5, 7-8", LineRanges([]int{1, 2, 3, 5, 7, 8}))
+}
+
+func TestWriteHTML(t *testing.T) {
+	sourceDir := t.TempDir()
+	var source []string
+	for i := 1; i <= 20; i++ {
+		source = append(source, "line <"+strings.Repeat("x", i)+">")
+	}
+	err := os.WriteFile(filepath.Join(sourceDir, "foo.cpp"), []byte(strings.Join(source, "\n")), 0o644)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = compareReports(t).WriteHTML(&out, sourceDir)
+	require.NoError(t, err)
+	html := out.String()
+
+	assert.Contains(t, html, "<h2>foo.cpp</h2>")
+	assert.Contains(t, html, "+ lines 5-7")
+	assert.Contains(t, html, `<div class="line covered"><span class="nr">5</span>line &lt;xxxxx&gt;</div>`)
+	assert.Contains(t, html, `<div class="line uncovered"><span class="nr">1</span>`)
+	// Lines 1-10 are shown as context, line 11 isn't
+	assert.Contains(t, html, `<span class="nr">10</span>`)
+	assert.NotContains(t, html, `<span class="nr">11</span>`)
+	// The source of new.cpp doesn't exist
+	assert.Contains(t, html, "<h2>new.cpp</h2>")
+}
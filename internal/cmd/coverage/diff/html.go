@@ -0,0 +1,167 @@
+package diff
+
+import (
+	"bufio"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+// The number of unchanged lines shown around changed lines
+const contextLines = 3
+
+const (
+	lineCovered   = "covered"
+	lineUncovered = "uncovered"
+)
+
+type htmlLine struct {
+	Number int
+	Text   string
+	Class  string
+}
+
+type htmlHunk struct {
+	Lines []*htmlLine
+}
+
+type htmlFile struct {
+	*FileDiff
+	CoveredLineRanges   string
+	UncoveredLineRanges string
+	// The changed lines with their context, only set if the source file
+	// could be read
+	Hunks []*htmlHunk
+}
+
+var htmlTemplate = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage Diff</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table.totals td, table.totals th { padding: 0.2em 1em; text-align: right; }
+h2 { font-family: monospace; font-size: 1.1em; border-bottom: 1px solid #ccc; }
+pre { margin: 0; }
+.hunk { border: 1px solid #ddd; margin: 0.5em 0; font-family: monospace; }
+.line { white-space: pre; }
+.line .nr { display: inline-block; width: 5em; color: #888; text-align: right; padding-right: 1em; }
+.covered { background-color: #d7f5d7; }
+.uncovered { background-color: #f8d4d4; }
+.added { color: #1a7f37; }
+.removed { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Coverage Diff</h1>
+<table class="totals">
+<tr><th></th><th>Old</th><th>New</th></tr>
+<tr><th>Lines</th><td>{{.Old.LinesHit}} / {{.Old.LinesFound}}</td><td>{{.New.LinesHit}} / {{.New.LinesFound}}</td></tr>
+<tr><th>Functions</th><td>{{.Old.FunctionsHit}} / {{.Old.FunctionsFound}}</td><td>{{.New.FunctionsHit}} / {{.New.FunctionsFound}}</td></tr>
+<tr><th>Branches</th><td>{{.Old.BranchesHit}} / {{.Old.BranchesFound}}</td><td>{{.New.BranchesHit}} / {{.New.BranchesFound}}</td></tr>
+</table>
+{{if not .Files}}<p>The line and function coverage didn't change.</p>{{end}}
+{{range .Files}}
+<h2>{{.Filename}}</h2>
+{{if .CoveredLineRanges}}<div class="added">+ lines {{.CoveredLineRanges}}</div>{{end}}
+{{if .UncoveredLineRanges}}<div class="removed">- lines {{.UncoveredLineRanges}}</div>{{end}}
+{{range .NewlyCoveredFunctions}}<div class="added">+ function {{.}}</div>{{end}}
+{{range .NewlyUncoveredFunctions}}<div class="removed">- function {{.}}</div>{{end}}
+{{range .Hunks}}<div class="hunk">{{range .Lines}}<div class="line {{.Class}}"><span class="nr">{{.Number}}</span>{{.Text}}</div>{{end}}</div>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes an HTML page which shows the diff. The changed lines
+// of source files which can be found, either at their path or relative
+// to the source directory, are shown with their context.
+func (d *Diff) WriteHTML(w io.Writer, sourceDir string) error {
+	data := struct {
+		*Diff
+		Files []*htmlFile
+	}{Diff: d}
+
+	for _, file := range d.Files {
+		f := &htmlFile{
+			FileDiff:            file,
+			CoveredLineRanges:   LineRanges(file.NewlyCoveredLines),
+			UncoveredLineRanges: LineRanges(file.NewlyUncoveredLines),
+		}
+		if len(file.NewlyCoveredLines) > 0 || len(file.NewlyUncoveredLines) > 0 {
+			var err error
+			f.Hunks, err = hunks(file, sourcePath(file.Filename, sourceDir))
+			if err != nil {
+				log.Debugf("Not showing source of %s: %v", file.Filename, err)
+			}
+		}
+		data.Files = append(data.Files, f)
+	}
+
+	return errors.WithStack(htmlTemplate.Execute(w, data))
+}
+
+func sourcePath(filename string, sourceDir string) string {
+	if filepath.IsAbs(filename) || sourceDir == "" {
+		return filename
+	}
+	return filepath.Join(sourceDir, filename)
+}
+
+// hunks returns the changed lines of the file with their context
+func hunks(file *FileDiff, path string) ([]*htmlHunk, error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer source.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(source)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	classes := make(map[int]string)
+	for _, nr := range file.NewlyCoveredLines {
+		classes[nr] = lineCovered
+	}
+	for _, nr := range file.NewlyUncoveredLines {
+		classes[nr] = lineUncovered
+	}
+
+	var res []*htmlHunk
+	var current *htmlHunk
+	lastShown := 0
+	for nr := 1; nr <= len(lines); nr++ {
+		if !nearChange(nr, classes) {
+			continue
+		}
+		if current == nil || nr != lastShown+1 {
+			current = &htmlHunk{}
+			res = append(res, current)
+		}
+		current.Lines = append(current.Lines, &htmlLine{Number: nr, Text: lines[nr-1], Class: classes[nr]})
+		lastShown = nr
+	}
+	return res, nil
+}
+
+func nearChange(nr int, classes map[int]string) bool {
+	for i := nr - contextLines; i <= nr+contextLines; i++ {
+		if _, ok := classes[i]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,425 @@
+package coverage
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/diff"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/completion"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/internal/coverage"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/runfiles"
+	"code-intelligence.com/cifuzz/pkg/vcs"
+	"code-intelligence.com/cifuzz/util/fileutil"
+	"code-intelligence.com/cifuzz/util/stringutil"
+)
+
+type diffOptions struct {
+	coverageOptions `mapstructure:",squash"`
+	PrintJSON       bool `mapstructure:"print-json"`
+
+	OldCorpus  string
+	NewCorpus  string
+	OldRef     string
+	NewRef     string
+	Objects    []string
+	HTMLOutput string
+}
+
+// A coverage report which is compared, either specified by the user or
+// generated from a corpus or a git ref
+type diffSide struct {
+	name       string
+	corpusDir  string
+	ref        string
+	projectDir string
+}
+
+type diffCmd struct {
+	*cobra.Command
+	opts *diffOptions
+}
+
+func newDiffCmd() *cobra.Command {
+	opts := &diffOptions{}
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "diff [flags] <old report> <new report> | <fuzz test>...",
+		Short: "Compare the coverage of two coverage reports",
+		Long: `This command compares the line and function coverage of two coverage
+reports and prints the newly covered and newly uncovered lines and
+functions of each file.
+
+The coverage reports can be lcov trace files, JaCoCo XML reports or
+indexed LLVM profiles (.profdata). For LLVM profiles, the instrumented
+binaries have to be specified via --object.
+
+Instead of comparing existing reports, the reports can be generated
+for the specified fuzz tests (or all fuzz tests via --all) from:
+
+* Two corpus directories, specified via --old-corpus and
+  --new-corpus. Only the inputs in these directories are used. This is
+  only supported for CMake and other build systems.
+* Two git refs, specified via --old-ref and --new-ref. If --new-ref is
+  not specified, the working tree is used. The refs are checked out in
+  temporary git worktrees, so the fuzz tests are built from scratch and
+  only the inputs committed in the respective ref are used.
+
+An HTML view of the diff, which shows the changed lines with their
+context, can be created via --html.
+
+  cifuzz coverage diff old.lcov new.lcov
+  cifuzz coverage diff --old-corpus corpus-v1 --new-corpus corpus-v2 my_fuzz_test
+  cifuzz coverage diff --old-ref main --html coverage-diff my_fuzz_test
+`,
+		ValidArgsFunction: completion.ValidFuzzTests,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind viper keys to flags. We can't do this in the New
+			// function, because that would re-bind viper keys which
+			// were bound to the flags of other commands before.
+			bindFlags()
+			err := config.FindAndParseProjectConfig(opts)
+			if err != nil {
+				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			if opts.Filter == nil {
+				opts.Filter = &summary.Filter{}
+			}
+			opts.Filter.BaseDir = opts.ProjectDir
+			err = opts.validate(args)
+			if err != nil {
+				return err
+			}
+			if opts.Filter.SourceDirs == nil {
+				// The reports were passed as arguments, so the build
+				// system was not determined during validation. The
+				// reports might have been created elsewhere, so an
+				// unknown build system is not an error here.
+				if opts.BuildSystem == "" {
+					opts.BuildSystem, _ = config.DetermineBuildSystem(opts.ProjectDir)
+				}
+				opts.Filter.SourceDirs = opts.sourceDirs()
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			cmd := diffCmd{Command: c, opts: opts}
+			return cmd.run(args)
+		},
+	}
+
+	// Note: If a flag should be configurable via cifuzz.yaml as well,
+	// bind it to viper in the PreRunE function.
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddBuildCommandFlag,
+		cmdutils.AddBuildJobsFlag,
+		cmdutils.AddCleanCommandFlag,
+		cmdutils.AddPrintJSONFlag,
+		cmdutils.AddProjectDirFlag,
+		cmdutils.AddResolveSourceFileFlag,
+		cmdutils.AddSeedCorpusFlag,
+		cmdutils.AddUseSandboxFlag,
+	)
+	cmd.Flags().BoolVar(&opts.All, "all", false, "Generate the coverage reports of all fuzz tests.")
+	cmd.Flags().StringVar(&opts.OldCorpus, "old-corpus", "", "The corpus `directory` from which the old coverage report is generated.")
+	cmd.Flags().StringVar(&opts.NewCorpus, "new-corpus", "", "The corpus `directory` from which the new coverage report is generated.")
+	cmd.Flags().StringVar(&opts.OldRef, "old-ref", "", "The git `ref` from which the old coverage report is generated.")
+	cmd.Flags().StringVar(&opts.NewRef, "new-ref", "", "The git `ref` from which the new coverage report is generated (default: the working tree).")
+	cmd.Flags().StringArrayVar(&opts.Objects, "object", nil, "An instrumented `binary` for reports in the .profdata format. Can be specified multiple times.")
+	cmd.Flags().StringVar(&opts.HTMLOutput, "html", "", "The `directory` in which an HTML view of the diff is created.")
+
+	return cmd
+}
+
+func (opts *diffOptions) generatesReports() bool {
+	return opts.OldCorpus != "" || opts.NewCorpus != "" || opts.OldRef != "" || opts.NewRef != ""
+}
+
+func (opts *diffOptions) validate(args []string) error {
+	if !opts.generatesReports() {
+		if len(args) != 2 || opts.All {
+			msg := "Exactly two coverage reports must be specified, unless the reports are generated via --old-corpus or --old-ref"
+			return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+		}
+		return nil
+	}
+
+	corpusMode := opts.OldCorpus != "" || opts.NewCorpus != ""
+	refMode := opts.OldRef != "" || opts.NewRef != ""
+	if corpusMode && refMode {
+		msg := "The --old-corpus and --new-corpus flags can't be used together with --old-ref and --new-ref"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if corpusMode && (opts.OldCorpus == "" || opts.NewCorpus == "") {
+		msg := "Both --old-corpus and --new-corpus must be specified"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if refMode && opts.OldRef == "" {
+		msg := "Flag --old-ref must be specified if --new-ref is used"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if corpusMode && !stringutil.Contains([]string{config.BuildSystemCMake, config.BuildSystemOther}, opts.BuildSystem) {
+		msg := fmt.Sprintf("Generating coverage reports from corpus directories is not supported for build system %q", opts.BuildSystem)
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if opts.All && len(args) != 0 {
+		msg := "No <fuzz test> arguments must be provided when the --all flag is used"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+	if !opts.All && len(args) == 0 {
+		msg := "At least one <fuzz test> argument must be provided or the --all flag must be used"
+		return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+	}
+
+	var err error
+	if opts.All {
+		opts.fuzzTests, err = opts.listFuzzTests()
+	} else {
+		err = opts.setFuzzTests(args)
+	}
+	if err != nil {
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	// Generate the reports in a machine-readable format
+	opts.OutputFormat = coverage.FormatLCOV
+	if stringutil.Contains(coverage.ValidOutputFormats[opts.BuildSystem], coverage.FormatJacocoXML) {
+		opts.OutputFormat = coverage.FormatJacocoXML
+	}
+	return opts.coverageOptions.validate()
+}
+
+func (c *diffCmd) run(args []string) error {
+	var oldSummary, newSummary *summary.CoverageSummary
+	var err error
+	if c.opts.generatesReports() {
+		err = c.checkDependencies()
+		if err != nil {
+			return err
+		}
+		oldSummary, err = c.generateReport(&diffSide{name: "old", corpusDir: c.opts.OldCorpus, ref: c.opts.OldRef})
+		if err != nil {
+			return err
+		}
+		newSummary, err = c.generateReport(&diffSide{name: "new", corpusDir: c.opts.NewCorpus, ref: c.opts.NewRef})
+		if err != nil {
+			return err
+		}
+	} else {
+		oldSummary, err = c.loadReport(args[0])
+		if err != nil {
+			log.Error(err)
+			return cmdutils.WrapSilentError(err)
+		}
+		newSummary, err = c.loadReport(args[1])
+		if err != nil {
+			log.Error(err)
+			return cmdutils.WrapSilentError(err)
+		}
+	}
+
+	d := diff.Compare(oldSummary, newSummary)
+
+	if c.opts.PrintJSON {
+		s, err := stringutil.ToJSONString(d)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(c.OutOrStdout(), s)
+	} else {
+		d.Print(c.OutOrStdout())
+	}
+
+	if c.opts.HTMLOutput != "" {
+		err = c.writeHTML(d)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *diffCmd) writeHTML(d *diff.Diff) error {
+	err := os.MkdirAll(c.opts.HTMLOutput, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	htmlFile := filepath.Join(c.opts.HTMLOutput, "index.html")
+	f, err := os.Create(htmlFile)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	err = d.WriteHTML(f, c.opts.ProjectDir)
+	if err != nil {
+		return err
+	}
+	log.Successf("Created coverage diff HTML report: %s", htmlFile)
+	return nil
+}
+
+// loadReport parses a coverage report in the lcov, JaCoCo XML or
+// .profdata format and applies the coverage filter of the project
+func (c *diffCmd) loadReport(path string) (*summary.CoverageSummary, error) {
+	var coverageSummary *summary.CoverageSummary
+	switch filepath.Ext(path) {
+	case ".profdata":
+		report, err := c.exportProfile(path)
+		if err != nil {
+			return nil, err
+		}
+		coverageSummary = summary.ParseLcov(strings.NewReader(report))
+	default:
+		var err error
+		coverageSummary, err = parseReport(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	coverageSummary.ApplyFilter(c.opts.Filter)
+	return coverageSummary, nil
+}
+
+// exportProfile converts an indexed LLVM profile into an lcov report
+func (c *diffCmd) exportProfile(path string) (string, error) {
+	if len(c.opts.Objects) == 0 {
+		return "", errors.Errorf("The instrumented binaries of %s must be specified via --object", path)
+	}
+	llvmCov, err := runfiles.Finder.LLVMCovPath()
+	if err != nil {
+		return "", err
+	}
+	args := []string{"export", "-format=lcov", "-instr-profile=" + path, c.opts.Objects[0]}
+	for _, object := range c.opts.Objects[1:] {
+		args = append(args, "-object="+object)
+	}
+	cmd := exec.Command(llvmCov, args...)
+	cmd.Stderr = os.Stderr
+	log.Debugf("Command: %s", cmd.String())
+	out, err := cmd.Output()
+	if err != nil {
+		return "", cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return string(out), nil
+}
+
+// generateReport generates the coverage report of the fuzz tests from
+// the corpus directory or the git ref of the side
+func (c *diffCmd) generateReport(side *diffSide) (*summary.CoverageSummary, error) {
+	tmpDir, err := os.MkdirTemp("", "coverage-diff-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tmpDir)
+
+	opts := c.opts.coverageOptions
+	opts.buildStdout = c.OutOrStderr()
+	opts.buildStderr = c.OutOrStderr()
+	opts.OutputPath = filepath.Join(tmpDir, "report")
+	if opts.OutputFormat == coverage.FormatLCOV {
+		opts.OutputPath += ".lcov"
+	}
+
+	if side.corpusDir != "" {
+		corpusDir, err := filepath.Abs(side.corpusDir)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		opts.corpusDirs = []string{corpusDir}
+		log.Infof("Generating the %s coverage report from %s", side.name, side.corpusDir)
+	} else if side.ref != "" {
+		worktree := filepath.Join(tmpDir, "worktree")
+		opts.ProjectDir, err = c.addWorktree(side.ref, worktree)
+		if err != nil {
+			log.Error(err)
+			return nil, cmdutils.WrapSilentError(err)
+		}
+		defer func() {
+			err := vcs.GitWorktreeRemove(c.opts.ProjectDir, worktree)
+			if err != nil {
+				log.Warnf("Failed to remove git worktree %s: %v", worktree, err)
+			}
+		}()
+		log.Infof("Generating the %s coverage report from %s", side.name, side.ref)
+	} else {
+		log.Infof("Generating the %s coverage report from the working tree", side.name)
+	}
+	side.projectDir = opts.ProjectDir
+
+	cmd := &coverageCmd{Command: c.Command, opts: &opts}
+	reportPath, _, err := cmd.generate()
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.loadReport(reportPath)
+	if err != nil {
+		return nil, err
+	}
+	makeFilenamesRelative(s, side.projectDir)
+	return s, nil
+}
+
+// addWorktree checks out the ref in a new git worktree and returns the
+// path of the project directory in the worktree
+func (c *diffCmd) addWorktree(ref string, worktree string) (string, error) {
+	if !vcs.GitIsRef(c.opts.ProjectDir, ref) {
+		return "", errors.Errorf("%q is not a valid git ref", ref)
+	}
+	topLevel, err := vcs.GitTopLevel(c.opts.ProjectDir)
+	if err != nil {
+		return "", err
+	}
+	// The project directory can be a subdirectory of the git repository
+	projectDir, err := filepath.EvalSymlinks(c.opts.ProjectDir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	relProjectDir, err := filepath.Rel(topLevel, projectDir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	err = vcs.GitWorktreeAdd(c.opts.ProjectDir, worktree, ref)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(worktree, relProjectDir), nil
+}
+
+// makeFilenamesRelative makes the file names of the report relative to
+// the project directory, so that reports generated in different
+// worktrees can be compared
+func makeFilenamesRelative(s *summary.CoverageSummary, projectDir string) {
+	projectDirs := []string{projectDir}
+	if resolved, err := filepath.EvalSymlinks(projectDir); err == nil && resolved != projectDir {
+		projectDirs = append(projectDirs, resolved)
+	}
+	for _, file := range s.Files {
+		for _, dir := range projectDirs {
+			if !filepath.IsAbs(file.Filename) {
+				break
+			}
+			rel, err := filepath.Rel(dir, file.Filename)
+			if err == nil && !strings.HasPrefix(rel, "..") {
+				file.Filename = filepath.ToSlash(rel)
+				break
+			}
+		}
+	}
+}
+
+func (c *diffCmd) checkDependencies() error {
+	cmd := &coverageCmd{Command: c.Command, opts: &c.opts.coverageOptions}
+	return cmd.checkDependencies()
+}
@@ -0,0 +1,76 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+func TestDiff_Reports(t *testing.T) {
+	projectDir, cleanup := testutil.BootstrapExampleProjectForTest("coverage-diff-test", config.BuildSystemCMake)
+	defer cleanup()
+
+	oldReport := filepath.Join(projectDir, "old.lcov")
+	err := os.WriteFile(oldReport, []byte(`SF:src/foo.c
+FN:1,foo
+FNDA:1,foo
+FN:5,bar
+FNDA:0,bar
+DA:1,1
+DA:2,1
+DA:5,0
+DA:6,0
+end_of_record
+`), 0o644)
+	require.NoError(t, err)
+	newReport := filepath.Join(projectDir, "new.lcov")
+	err = os.WriteFile(newReport, []byte(`SF:src/foo.c
+FN:1,foo
+FNDA:1,foo
+FN:5,bar
+FNDA:3,bar
+DA:1,1
+DA:2,0
+DA:5,3
+DA:6,3
+end_of_record
+`), 0o644)
+	require.NoError(t, err)
+
+	stdout, _, err := cmdutils.ExecuteCommand(t, New(), os.Stdin, "diff", oldReport, newReport)
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "src/foo.c")
+	assert.Contains(t, stdout, "+ lines 5-6")
+	assert.Contains(t, stdout, "- lines 2")
+	assert.Contains(t, stdout, "+ function bar")
+
+	htmlDir := filepath.Join(projectDir, "coverage-diff")
+	_, _, err = cmdutils.ExecuteCommand(t, New(), os.Stdin, "diff", "--html", htmlDir, oldReport, newReport)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(htmlDir, "index.html"))
+}
+
+func TestDiff_IncorrectUsage(t *testing.T) {
+	_, cleanup := testutil.BootstrapExampleProjectForTest("coverage-diff-test", config.BuildSystemCMake)
+	defer cleanup()
+
+	for _, args := range [][]string{
+		{"diff", "old.lcov"},
+		{"diff", "--old-corpus", "corpus-v1", "my_fuzz_test"},
+		{"diff", "--old-corpus", "corpus-v1", "--new-corpus", "corpus-v2"},
+		{"diff", "--old-corpus", "corpus-v1", "--new-corpus", "corpus-v2", "--old-ref", "main", "my_fuzz_test"},
+		{"diff", "--new-ref", "main", "my_fuzz_test"},
+	} {
+		_, _, err := cmdutils.ExecuteCommand(t, New(), os.Stdin, args...)
+		require.Error(t, err, args)
+		var usageErr *cmdutils.IncorrectUsageError
+		assert.ErrorAs(t, err, &usageErr, args)
+	}
+}
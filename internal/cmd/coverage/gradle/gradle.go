@@ -1,6 +1,7 @@
 package gradle
 
 import (
+	_ "embed"
 	"fmt"
 	"io"
 	"os"
@@ -12,16 +13,27 @@ import (
 	"github.com/pkg/errors"
 
 	"code-intelligence.com/cifuzz/internal/build/gradle"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/htmlreport"
 	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/coverage"
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/pkg/runfiles"
 	"code-intelligence.com/cifuzz/util/executil"
+	"code-intelligence.com/cifuzz/util/fileutil"
 	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
-const GradleReportTask = "cifuzzReport"
+const (
+	GradleReportTask       = "cifuzzReport"
+	GradleMergedReportTask = "cifuzzMergedReport"
+)
+
+// The init script which configures the execution data files of the
+// fuzz tests and adds the task which creates the merged report
+//
+//go:embed merged-coverage.gradle
+var mergedCoverageInitScript []byte
 
 type GradleRunner interface {
 	RunCommand(args []string) error
@@ -40,58 +52,202 @@ type CoverageGenerator struct {
 	OutputPath   string
 	FuzzTest     string
 	TargetMethod string
-	ProjectDir   string
+	// If set, a merged coverage report of these fuzz tests is generated
+	// instead of a report for FuzzTest. A fuzz test can specify a target
+	// method via "<class>::<method>".
+	FuzzTests  []string
+	ProjectDir string
+	// The directory of the Java source files, relative to which the
+	// file names of the JaCoCo report are resolved
+	SourceDir string
+	// The files which are included in the report. The JaCoCo XML report
+	// is filtered by cifuzz, the HTML report is then created from the
+	// filtered XML report by the built-in renderer.
+	Filter *summary.Filter
 
 	Parallel gradle.ParallelOptions
 	Stderr   io.Writer
 
 	GradleRunner GradleRunner
+
+	tmpDir          string
+	coverageSummary *summary.CoverageSummary
 }
 
 func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
+	err := cov.setOutputPath()
+	if err != nil {
+		return err
+	}
+
+	if len(cov.FuzzTests) > 0 {
+		return cov.buildFuzzTestsForMergedCoverage()
+	}
+
 	testParam := fmt.Sprintf("-Pcifuzz.fuzztest=%s", cov.FuzzTest)
 	if cov.TargetMethod != "" {
 		testParam += fmt.Sprintf(".%s", cov.TargetMethod)
 	}
 	gradleArgs := []string{testParam}
+	gradleArgs = append(gradleArgs, cov.reportArgs(GradleReportTask, cov.OutputPath, cov.reportFormat())...)
 
-	if cov.OutputPath == "" {
-		buildDir, err := gradle.GetBuildDirectory(cov.ProjectDir)
+	return cov.GradleRunner.RunCommand(gradleArgs)
+}
+
+// buildFuzzTestsForMergedCoverage runs each fuzz test with a separate
+// JaCoCo execution data file and creates an XML report for each of
+// them. The merged report is created from all execution data files by
+// the task which the init script adds to the root project.
+func (cov *CoverageGenerator) buildFuzzTestsForMergedCoverage() error {
+	var err error
+	cov.tmpDir, err = os.MkdirTemp("", "gradle-coverage-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	initScript := filepath.Join(cov.tmpDir, "merged-coverage.gradle")
+	err = os.WriteFile(initScript, mergedCoverageInitScript, 0o644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var execFiles []string
+	for _, fuzzTest := range cov.FuzzTests {
+		execFile := cov.fuzzTestReportDir(fuzzTest) + ".exec"
+		gradleArgs := []string{
+			"--init-script", initScript,
+			"-Pcifuzz.fuzztest=" + strings.Replace(fuzzTest, "::", ".", 1),
+			"-Pcifuzz.coverage.execfile=" + execFile,
+		}
+		gradleArgs = append(gradleArgs, cov.reportArgs(GradleReportTask, cov.fuzzTestReportDir(fuzzTest), coverage.FormatJacocoXML)...)
+		err = cov.GradleRunner.RunCommand(gradleArgs)
 		if err != nil {
 			return err
 		}
-		cov.OutputPath = filepath.Join(buildDir, "reports", "jacoco", GradleReportTask)
+
+		exists, err := fileutil.Exists(execFile)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			log.Warnf("No coverage data was collected for %s", fuzzTest)
+			continue
+		}
+		execFiles = append(execFiles, execFile)
+	}
+	if len(execFiles) == 0 {
+		return errors.New("No coverage data was collected for any of the fuzz tests")
 	}
 
-	// Make sure that directory exists, otherwise the command for --format=jacocoxml will fail
-	err := os.MkdirAll(cov.OutputPath, 0700)
-	if err != nil {
-		return err
+	gradleArgs := []string{
+		"--init-script", initScript,
+		"-Pcifuzz.coverage.execfiles=" + strings.Join(execFiles, ","),
 	}
+	gradleArgs = append(gradleArgs, cov.reportArgs(GradleMergedReportTask, cov.OutputPath, cov.reportFormat())...)
+	return cov.GradleRunner.RunCommand(gradleArgs)
+}
 
-	gradleArgs = append(gradleArgs, GradleReportTask, fmt.Sprintf("-Pcifuzz.report.output=%s", cov.OutputPath))
+func (cov *CoverageGenerator) reportArgs(task string, outputPath string, format string) []string {
+	args := []string{task, fmt.Sprintf("-Pcifuzz.report.output=%s", outputPath)}
+	if format == coverage.FormatJacocoXML {
+		args = append(args, fmt.Sprintf("-Pcifuzz.report.format=%s", coverage.FormatJacocoXML))
+	}
+	return args
+}
 
-	if cov.OutputFormat == coverage.FormatJacocoXML {
-		gradleArgs = append(gradleArgs, fmt.Sprintf("-Pcifuzz.report.format=%s", coverage.FormatJacocoXML))
+func (cov *CoverageGenerator) reportFormat() string {
+	// The HTML report of a filtered report is created by cifuzz
+	if !cov.Filter.Empty() {
+		return coverage.FormatJacocoXML
+	}
+	return cov.OutputFormat
+}
+
+func (cov *CoverageGenerator) setOutputPath() error {
+	if cov.OutputPath == "" {
+		buildDir, err := gradle.GetBuildDirectory(cov.ProjectDir)
+		if err != nil {
+			return err
+		}
+		cov.OutputPath = filepath.Join(buildDir, "reports", "jacoco", GradleReportTask)
 	}
 
-	return cov.GradleRunner.RunCommand(gradleArgs)
+	// Make sure that directory exists, otherwise the command for --format=jacocoxml will fail
+	return errors.WithStack(os.MkdirAll(cov.OutputPath, 0700))
+}
+
+// fuzzTestReportDir returns the directory of the XML report of a single
+// fuzz test when creating a merged coverage report
+func (cov *CoverageGenerator) fuzzTestReportDir(fuzzTest string) string {
+	return filepath.Join(cov.tmpDir, strings.ReplaceAll(fuzzTest, "::", "_"))
 }
 
 func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
+	if cov.tmpDir != "" {
+		defer fileutil.Cleanup(cov.tmpDir)
+	}
+
 	reportPath := filepath.Join(cov.OutputPath, "jacoco.xml")
-	reportFile, err := os.Open(reportPath)
+	err := summary.FilterJacocoXMLFile(reportPath, cov.Filter)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return "", err
 	}
-	defer reportFile.Close()
-	summary.ParseJacocoXML(reportFile).PrintTable(cov.Stderr)
+	coverageSummary, err := parseJacocoXMLReport(reportPath)
+	if err != nil {
+		return "", err
+	}
+	for _, fuzzTest := range cov.FuzzTests {
+		reportPath := filepath.Join(cov.fuzzTestReportDir(fuzzTest), "jacoco.xml")
+		exists, err := fileutil.Exists(reportPath)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			continue
+		}
+		fuzzTestSummary, err := parseJacocoXMLReport(reportPath)
+		if err != nil {
+			return "", err
+		}
+		fuzzTestSummary.ApplyFilter(cov.Filter)
+		coverageSummary.AddFuzzTest(fuzzTest, fuzzTestSummary)
+	}
+	coverageSummary.PrintTable(cov.Stderr)
+	cov.coverageSummary = coverageSummary
 
 	if cov.OutputFormat == coverage.FormatJacocoXML {
 		return filepath.Join(cov.OutputPath, "jacoco.xml"), nil
 	}
 
-	return filepath.Join(cov.OutputPath, "html"), nil
+	htmlDir := filepath.Join(cov.OutputPath, "html")
+	if !cov.Filter.Empty() {
+		err = htmlreport.Generate(coverageSummary, htmlDir, cov.SourceDir, cov.reportTitle())
+		if err != nil {
+			return "", err
+		}
+	}
+	return htmlDir, nil
+}
+
+func (cov *CoverageGenerator) reportTitle() string {
+	if len(cov.FuzzTests) > 0 {
+		return strings.Join(cov.FuzzTests, ", ")
+	}
+	return cov.FuzzTest
+}
+
+// CoverageSummary returns the summary of the coverage report created by
+// GenerateCoverageReport
+func (cov *CoverageGenerator) CoverageSummary() *summary.CoverageSummary {
+	return cov.coverageSummary
+}
+
+func parseJacocoXMLReport(path string) (*summary.CoverageSummary, error) {
+	reportFile, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer reportFile.Close()
+	return summary.ParseJacocoXML(reportFile), nil
 }
 
 func (runner *GradleRunnerImpl) RunCommand(args []string) error {
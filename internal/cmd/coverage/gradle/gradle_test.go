@@ -2,12 +2,19 @@ package gradle
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"code-intelligence.com/cifuzz/internal/build/gradle"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+	"code-intelligence.com/cifuzz/internal/coverage"
+	"code-intelligence.com/cifuzz/util/fileutil"
 )
 
 type GradleRunnerMock struct {
@@ -47,3 +54,94 @@ func TestBuildFuzzTestForCoverage(t *testing.T) {
 	require.NoError(t, err)
 	runnerMock.AssertExpectations(t)
 }
+
+func TestBuildFuzzTestForCoverage_Filter(t *testing.T) {
+	outputPath := "project-dir/cov-output"
+	fuzzTest := "com.example.FuzzTestCase"
+
+	runnerMock := &GradleRunnerMock{}
+	gen := &CoverageGenerator{
+		OutputPath:   outputPath,
+		OutputFormat: coverage.FormatHTML,
+		FuzzTest:     fuzzTest,
+		Filter:       &summary.Filter{Exclude: []string{"src/main/java/com/example/generated"}},
+		GradleRunner: runnerMock,
+	}
+
+	// The HTML report of a filtered report is created by cifuzz from
+	// the XML report
+	expectedArgs := []string{
+		fmt.Sprintf("-Pcifuzz.fuzztest=%s", fuzzTest),
+		"cifuzzReport",
+		fmt.Sprintf("-Pcifuzz.report.output=%s", outputPath),
+		"-Pcifuzz.report.format=jacocoxml",
+	}
+	runnerMock.On("RunCommand", expectedArgs).Return(nil)
+
+	err := gen.BuildFuzzTestForCoverage()
+	require.NoError(t, err)
+	runnerMock.AssertExpectations(t)
+}
+
+func TestBuildFuzzTestForCoverage_Merged(t *testing.T) {
+	outputPath := t.TempDir()
+	fuzzTests := []string{"com.example.FuzzTestCase::MyFuzzTest", "com.example.OtherFuzzTest"}
+
+	runnerMock := &GradleRunnerMock{}
+	gen := &CoverageGenerator{
+		OutputPath:   outputPath,
+		FuzzTests:    fuzzTests,
+		GradleRunner: runnerMock,
+	}
+	// Create the execution data file of the first fuzz test, like the
+	// test task would with the init script
+	runnerMock.On("RunCommand", mock.Anything).Run(func(args mock.Arguments) {
+		for _, arg := range args.Get(0).([]string) {
+			if execFile, found := strings.CutPrefix(arg, "-Pcifuzz.coverage.execfile="); found && strings.Contains(execFile, "MyFuzzTest") {
+				require.NoError(t, os.WriteFile(execFile, nil, 0o644))
+			}
+		}
+	}).Return(nil)
+
+	err := gen.BuildFuzzTestForCoverage()
+	require.NoError(t, err)
+	defer fileutil.Cleanup(gen.tmpDir)
+
+	initScript := filepath.Join(gen.tmpDir, "merged-coverage.gradle")
+	require.FileExists(t, initScript)
+	firstExecFile := filepath.Join(gen.tmpDir, "com.example.FuzzTestCase_MyFuzzTest.exec")
+
+	require.Len(t, runnerMock.Calls, 3)
+	assert.Equal(t, []string{
+		"--init-script", initScript,
+		"-Pcifuzz.fuzztest=com.example.FuzzTestCase.MyFuzzTest",
+		"-Pcifuzz.coverage.execfile=" + firstExecFile,
+		"cifuzzReport",
+		"-Pcifuzz.report.output=" + filepath.Join(gen.tmpDir, "com.example.FuzzTestCase_MyFuzzTest"),
+		"-Pcifuzz.report.format=jacocoxml",
+	}, runnerMock.Calls[0].Arguments.Get(0))
+	assert.Contains(t, runnerMock.Calls[1].Arguments.Get(0), "-Pcifuzz.fuzztest=com.example.OtherFuzzTest")
+	// The second fuzz test didn't produce any execution data, so only
+	// the data of the first one is used for the merged report
+	assert.Equal(t, []string{
+		"--init-script", initScript,
+		"-Pcifuzz.coverage.execfiles=" + firstExecFile,
+		"cifuzzMergedReport",
+		"-Pcifuzz.report.output=" + outputPath,
+	}, runnerMock.Calls[2].Arguments.Get(0))
+}
+
+func TestBuildFuzzTestForCoverage_MergedWithoutCoverageData(t *testing.T) {
+	runnerMock := &GradleRunnerMock{}
+	gen := &CoverageGenerator{
+		OutputPath:   t.TempDir(),
+		FuzzTests:    []string{"com.example.FuzzTestCase", "com.example.OtherFuzzTest"},
+		GradleRunner: runnerMock,
+	}
+	runnerMock.On("RunCommand", mock.Anything).Return(nil)
+
+	err := gen.BuildFuzzTestForCoverage()
+	defer fileutil.Cleanup(gen.tmpDir)
+	require.Error(t, err)
+	assert.Len(t, runnerMock.Calls, 2)
+}
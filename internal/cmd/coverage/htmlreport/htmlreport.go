@@ -0,0 +1,385 @@
+// Package htmlreport renders coverage reports as HTML, with an index of
+// the directories, a page per directory listing its files and a page
+// per file with the annotated source code. It is used instead of
+// genhtml, which requires Perl, if genhtml is not available or if the
+// built-in renderer is requested.
+package htmlreport
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+	"code-intelligence.com/cifuzz/internal/coverage"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/runfiles"
+)
+
+//go:embed style.css
+var styleCSS []byte
+
+//go:embed templates.html
+var templatesHTML string
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"rate": rate,
+}).Parse(templatesHTML))
+
+// The name of the page of the directory which contains files which
+// are not in a subdirectory of the source directory
+const rootDirPage = "_root"
+
+type directory struct {
+	Name     string
+	Link     string
+	Coverage summary.Coverage
+	Files    []*file
+}
+
+type file struct {
+	Name     string
+	Base     string
+	Link     string
+	Coverage *summary.Coverage
+	coverage *summary.FileCoverage
+	dir      *directory
+}
+
+type function struct {
+	Name string
+	Line int
+	Hits int
+}
+
+type line struct {
+	Number int
+	Source string
+	// Empty if the line is not instrumented
+	Hits     string
+	Class    string
+	Branches string
+	// Set if a function starts at this line
+	Functions []string
+}
+
+// A Link is an additional page of the report, like the coverage
+// timeline chart, which is linked from the index page
+type Link struct {
+	Name string
+	// The path of the page relative to the output directory
+	Path string
+}
+
+type page struct {
+	Title     string
+	Root      string
+	Generated string
+	Coverage  summary.Coverage
+	// Set for the index page
+	Links []*Link
+	// Set for the index and directory pages
+	Rows []*row
+	// Set for the directory and file pages
+	Directory *directory
+	// Set for the file pages
+	File            *file
+	Functions       []*function
+	Lines           []*line
+	SourceAvailable bool
+}
+
+type row struct {
+	Name     string
+	Link     string
+	Coverage summary.Coverage
+}
+
+type coverageRate struct {
+	Percent string
+	Width   int
+	Class   string
+	Hit     int
+	Found   int
+}
+
+func rate(hit, found int) *coverageRate {
+	if found == 0 {
+		return &coverageRate{Percent: "-", Class: "none"}
+	}
+	percent := float64(hit) * 100 / float64(found)
+	class := "lo"
+	if percent >= 90 {
+		class = "hi"
+	} else if percent >= 75 {
+		class = "med"
+	}
+	return &coverageRate{
+		Percent: fmt.Sprintf("%.1f%%", percent),
+		Width:   int(percent),
+		Class:   class,
+		Hit:     hit,
+		Found:   found,
+	}
+}
+
+func addCoverage(c *summary.Coverage, other *summary.Coverage) {
+	c.LinesFound += other.LinesFound
+	c.LinesHit += other.LinesHit
+	c.FunctionsFound += other.FunctionsFound
+	c.FunctionsHit += other.FunctionsHit
+	c.BranchesFound += other.BranchesFound
+	c.BranchesHit += other.BranchesHit
+}
+
+// displayName returns the path of the file relative to the source
+// directory, or the absolute path (with slashes) if the file is not in
+// the source directory
+func displayName(filename string, sourceDir string) string {
+	if filepath.IsAbs(filename) && sourceDir != "" {
+		rel, err := filepath.Rel(sourceDir, filename)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filename)
+}
+
+// pageDir returns the path of the directory of the page of a source
+// directory, relative to the output directory
+func pageDir(dir string) string {
+	dir = strings.TrimLeft(dir, "/")
+	// Remove the volume name of absolute Windows paths
+	dir = strings.ReplaceAll(dir, ":", "")
+	if dir == "." || dir == "" {
+		return rootDirPage
+	}
+	// Avoid escaping the output directory
+	var parts []string
+	for _, part := range strings.Split(dir, "/") {
+		if part == ".." {
+			part = "_"
+		}
+		parts = append(parts, part)
+	}
+	return path.Join(parts...)
+}
+
+// relativeRoot returns the relative path from the page directory to the
+// output directory
+func relativeRoot(dir string) string {
+	return strings.Repeat("../", strings.Count(dir, "/")+1)
+}
+
+// Generate renders the HTML report of the coverage summary in the
+// output directory. The source files are read from their paths in the
+// summary, relative paths are interpreted relative to sourceDir. The
+// links are added to the index page.
+func Generate(cs *summary.CoverageSummary, outputDir string, sourceDir string, title string, links ...*Link) error {
+	dirs := make(map[string]*directory)
+	for _, fc := range cs.Files {
+		name := displayName(fc.Filename, sourceDir)
+		dirName := path.Dir(name)
+		dir, ok := dirs[dirName]
+		if !ok {
+			dir = &directory{Name: dirName, Link: pageDir(dirName) + "/index.html"}
+			dirs[dirName] = dir
+		}
+		f := &file{
+			Name:     name,
+			Base:     path.Base(name),
+			Link:     pageDir(dirName) + "/" + path.Base(name) + ".html",
+			Coverage: fc.Coverage,
+			coverage: fc,
+			dir:      dir,
+		}
+		dir.Files = append(dir.Files, f)
+		addCoverage(&dir.Coverage, fc.Coverage)
+	}
+
+	var sortedDirs []*directory
+	for _, dir := range dirs {
+		sort.Slice(dir.Files, func(i, j int) bool { return dir.Files[i].Name < dir.Files[j].Name })
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Slice(sortedDirs, func(i, j int) bool { return sortedDirs[i].Name < sortedDirs[j].Name })
+
+	err := os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = os.WriteFile(filepath.Join(outputDir, "style.css"), styleCSS, 0o644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	generated := time.Now().Format("2006-01-02 15:04:05")
+
+	index := &page{Title: title, Root: "", Generated: generated, Coverage: *cs.Total, Links: links}
+	for _, dir := range sortedDirs {
+		index.Rows = append(index.Rows, &row{Name: dir.Name, Link: dir.Link, Coverage: dir.Coverage})
+	}
+	err = render(filepath.Join(outputDir, "index.html"), "index", index)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range sortedDirs {
+		root := relativeRoot(pageDir(dir.Name))
+		dirPage := &page{Title: title, Root: root, Generated: generated, Coverage: dir.Coverage, Directory: dir}
+		for _, f := range dir.Files {
+			dirPage.Rows = append(dirPage.Rows, &row{Name: f.Base, Link: root + f.Link, Coverage: *f.Coverage})
+		}
+		err = render(filepath.Join(outputDir, filepath.FromSlash(dir.Link)), "directory", dirPage)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range dir.Files {
+			filePage := &page{
+				Title:     title,
+				Root:      root,
+				Generated: generated,
+				Coverage:  *f.Coverage,
+				Directory: dir,
+				File:      f,
+			}
+			filePage.Functions = functions(f.coverage)
+			filePage.Lines, filePage.SourceAvailable = annotatedLines(f.coverage, sourceDir)
+			err = render(filepath.Join(outputDir, filepath.FromSlash(f.Link)), "file", filePage)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func render(outputPath string, name string, p *page) error {
+	err := os.MkdirAll(filepath.Dir(outputPath), 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	err = templates.ExecuteTemplate(f, name, p)
+	return errors.WithStack(err)
+}
+
+func functions(fc *summary.FileCoverage) []*function {
+	var functions []*function
+	for name, hits := range fc.FunctionHits {
+		functions = append(functions, &function{Name: name, Line: fc.FunctionLines[name], Hits: hits})
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].Line != functions[j].Line {
+			return functions[i].Line < functions[j].Line
+		}
+		return functions[i].Name < functions[j].Name
+	})
+	return functions
+}
+
+// annotatedLines returns the lines of the source file annotated with
+// their coverage. If the source file can't be read, only the
+// instrumented lines are returned, without source code.
+func annotatedLines(fc *summary.FileCoverage, sourceDir string) ([]*line, bool) {
+	sourcePath := fc.Filename
+	if !filepath.IsAbs(sourcePath) {
+		sourcePath = filepath.Join(sourceDir, sourcePath)
+	}
+
+	var source []string
+	sourceAvailable := false
+	f, err := os.Open(sourcePath)
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			source = append(source, strings.ReplaceAll(scanner.Text(), "\t", "    "))
+		}
+		sourceAvailable = scanner.Err() == nil
+	}
+	if !sourceAvailable {
+		log.Debugf("Source file %s is not available for the HTML coverage report", sourcePath)
+		source = nil
+	}
+
+	functionsByLine := make(map[int][]string)
+	for name, l := range fc.FunctionLines {
+		functionsByLine[l] = append(functionsByLine[l], name)
+	}
+
+	annotate := func(number int, text string) *line {
+		l := &line{Number: number, Source: text, Functions: functionsByLine[number]}
+		sort.Strings(l.Functions)
+		if hits, ok := fc.LineHits[number]; ok {
+			l.Hits = fmt.Sprint(hits)
+			l.Class = "uncovered"
+			if hits > 0 {
+				l.Class = "covered"
+			}
+		}
+		if branches, ok := fc.LineBranches[number]; ok && branches.Found > 0 {
+			l.Branches = fmt.Sprintf("%d/%d", branches.Hit, branches.Found)
+			if l.Class == "covered" && branches.Hit < branches.Found {
+				l.Class = "partial"
+			}
+		}
+		return l
+	}
+
+	var lines []*line
+	if sourceAvailable {
+		for i, text := range source {
+			lines = append(lines, annotate(i+1, text))
+		}
+		return lines, true
+	}
+
+	var numbers []int
+	for number := range fc.LineHits {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	for _, number := range numbers {
+		lines = append(lines, annotate(number, ""))
+	}
+	return lines, false
+}
+
+// UseBuiltinRenderer returns true if the HTML report should be rendered
+// by this package instead of genhtml
+func UseBuiltinRenderer(renderer string) bool {
+	switch renderer {
+	case coverage.HTMLRendererBuiltin:
+		return true
+	case coverage.HTMLRendererGenHTML:
+		return false
+	}
+
+	_, err := runfiles.Finder.GenHTMLPath()
+	if err == nil && runtime.GOOS == "windows" {
+		// genhtml is a perl script, which is run via perl on Windows
+		_, err = runfiles.Finder.PerlPath()
+	}
+	if err != nil {
+		log.Debugf("Using the built-in HTML renderer because genhtml is not available: %v", err)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package htmlreport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+func TestGenerate(t *testing.T) {
+	sourceDir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(sourceDir, "src"), 0o755)
+	require.NoError(t, err)
+	source := `int foo(int x) {
+  if (x > 0)
+    return 1;
+  return 0;
+}
+
+int bar() { return 2; }
+`
+	err = os.WriteFile(filepath.Join(sourceDir, "src", "foo.c"), []byte(source), 0o644)
+	require.NoError(t, err)
+
+	report := `SF:` + filepath.Join(sourceDir, "src", "foo.c") + `
+FN:1,foo
+FN:7,bar
+FNDA:4,foo
+FNDA:0,bar
+FNF:2
+FNH:1
+DA:1,4
+DA:2,4
+DA:3,4
+DA:4,0
+DA:7,0
+LF:5
+LH:3
+BRDA:2,0,0,4
+BRDA:2,0,1,0
+BRF:2
+BRH:1
+end_of_record
+SF:main.c
+DA:1,1
+LF:1
+LH:1
+end_of_record
+`
+	cs := summary.ParseLcov(strings.NewReader(report))
+	outputDir := filepath.Join(t.TempDir(), "report")
+	err = Generate(cs, outputDir, sourceDir, "my_fuzz_test", &Link{Name: "Coverage timeline", Path: "timeline.html"})
+	require.NoError(t, err)
+
+	index, err := os.ReadFile(filepath.Join(outputDir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), `<a href="src/index.html">src</a>`)
+	assert.Contains(t, string(index), `| <a href="timeline.html">Coverage timeline</a>`)
+	assert.Contains(t, string(index), `<a href="_root/index.html">.</a>`)
+	assert.FileExists(t, filepath.Join(outputDir, "style.css"))
+
+	dir, err := os.ReadFile(filepath.Join(outputDir, "src", "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dir), `<a href="../src/foo.c.html">foo.c</a>`)
+	assert.NotContains(t, string(dir), `timeline.html`)
+	assert.Contains(t, string(dir), `60.0%`)
+
+	file, err := os.ReadFile(filepath.Join(outputDir, "src", "foo.c.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(file), `<a href="../src/index.html">src</a> / foo.c`)
+	assert.Contains(t, string(file), `<a href="#L7">bar</a>`)
+	assert.Contains(t, string(file), `<tr id="L2" class="partial"><td class="number"><a href="#L2">2</a></td><td class="branches">1/2</td><td class="hits">4</td>`)
+	assert.Contains(t, string(file), `<tr id="L4" class="uncovered">`)
+	assert.Contains(t, string(file), `<tr id="L6" class=""><td class="number"><a href="#L6">6</a></td><td class="branches"></td><td class="hits"></td>`)
+	assert.Contains(t, string(file), `<pre>  if (x &gt; 0)</pre>`)
+
+	// The source of main.c doesn't exist, so only the instrumented
+	// lines are shown
+	file, err = os.ReadFile(filepath.Join(outputDir, "_root", "main.c.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(file), "The source file is not available")
+	assert.Contains(t, string(file), `<tr id="L1" class="covered">`)
+}
+
+func TestPageDir(t *testing.T) {
+	assert.Equal(t, rootDirPage, pageDir("."))
+	assert.Equal(t, "src/parser", pageDir("src/parser"))
+	assert.Equal(t, "usr/include", pageDir("/usr/include"))
+	assert.Equal(t, "_/include", pageDir("../include"))
+	assert.Equal(t, "C/src", pageDir("C:/src"))
+}
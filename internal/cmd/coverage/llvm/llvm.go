@@ -18,7 +18,9 @@ import (
 	"code-intelligence.com/cifuzz/internal/build"
 	"code-intelligence.com/cifuzz/internal/build/cmake"
 	"code-intelligence.com/cifuzz/internal/build/other"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/htmlreport"
 	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/timeline"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/config"
 	"code-intelligence.com/cifuzz/pkg/binary"
@@ -28,6 +30,7 @@ import (
 	"code-intelligence.com/cifuzz/util/envutil"
 	"code-intelligence.com/cifuzz/util/executil"
 	"code-intelligence.com/cifuzz/util/fileutil"
+	"code-intelligence.com/cifuzz/util/sliceutil"
 	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
@@ -40,17 +43,42 @@ type CoverageGenerator struct {
 	CleanCommand    string
 	NumBuildJobs    uint
 	SeedCorpusDirs  []string
-	UseSandbox      bool
-	FuzzTest        string
+	// If set, only the inputs in these directories are used instead of
+	// the seed corpus dirs and the inputs directories of the fuzz tests
+	CorpusDirs []string
+	UseSandbox bool
+	FuzzTest   string
+	// If set, a merged coverage report of these fuzz tests is generated
+	// instead of a report for FuzzTest
+	FuzzTests []string
+	// If set, a merged coverage report of all fuzz tests of the project
+	// is generated. Only supported for CMake.
+	AllFuzzTests bool
+	// The renderer of HTML reports, one of coverage.HTMLRenderers
+	HTMLRenderer string
+	// The files which are included in the report
+	Filter *summary.Filter
+	// If set, the corpus is run in this number of chunks in the order
+	// in which the inputs were created, and the coverage after each
+	// chunk is recorded in a timeline
+	TimelineChunks int
+	// The file to which the timeline is written, as CSV if it has the
+	// .csv extension and as JSON otherwise
+	TimelineOutput string
+	// If set, the summary of the report includes the line, function
+	// and branch data of the files, not only their coverage counts
+	DetailedSummary bool
 	ProjectDir      string
 	Stderr          io.Writer
 	BuildStdout     io.Writer
 	BuildStderr     io.Writer
 
-	buildResult    *build.Result
-	tmpDir         string
-	outputDir      string
-	runfilesFinder runfiles.RunfilesFinder
+	buildResults    []*build.Result
+	tmpDir          string
+	outputDir       string
+	runfilesFinder  runfiles.RunfilesFinder
+	coverageSummary *summary.CoverageSummary
+	timeline        *timeline.Timeline
 }
 
 func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
@@ -94,9 +122,22 @@ func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
 	if err != nil {
 		return "", err
 	}
+
+	if cov.timeline != nil {
+		err = cov.writeTimeline(reportPath)
+		if err != nil {
+			return "", err
+		}
+	}
 	return reportPath, nil
 }
 
+// CoverageSummary returns the summary of the coverage report created by
+// GenerateCoverageReport
+func (cov *CoverageGenerator) CoverageSummary() *summary.CoverageSummary {
+	return cov.coverageSummary
+}
+
 func (cov *CoverageGenerator) build() error {
 	switch cov.BuildSystem {
 	case config.BuildSystemCMake:
@@ -121,11 +162,20 @@ func (cov *CoverageGenerator) build() error {
 		if err != nil {
 			return err
 		}
-		buildResults, err := builder.Build([]string{cov.FuzzTest})
+		fuzzTests := cov.fuzzTests()
+		if cov.AllFuzzTests {
+			fuzzTests, err = builder.ListFuzzTests()
+			if err != nil {
+				return err
+			}
+			if len(fuzzTests) == 0 {
+				return errors.Errorf("No fuzz tests found in %s", cov.ProjectDir)
+			}
+		}
+		cov.buildResults, err = builder.Build(fuzzTests)
 		if err != nil {
 			return err
 		}
-		cov.buildResult = buildResults[0]
 		return nil
 
 	case config.BuildSystemOther:
@@ -145,41 +195,78 @@ func (cov *CoverageGenerator) build() error {
 			return err
 		}
 
+		if cov.AllFuzzTests {
+			return errors.New("Building all fuzz tests is not supported for build system type \"other\"")
+		}
+
 		if err := builder.Clean(); err != nil {
 			return err
 		}
 
-		buildResult, err := builder.Build(cov.FuzzTest)
-		if err != nil {
-			return err
+		for _, fuzzTest := range cov.fuzzTests() {
+			buildResult, err := builder.Build(fuzzTest)
+			if err != nil {
+				return err
+			}
+			cov.buildResults = append(cov.buildResults, buildResult)
 		}
-		cov.buildResult = buildResult
 		return nil
 
 	}
 	return errors.New("unknown build system")
 }
 
+// fuzzTests returns the fuzz tests of which the coverage report is
+// generated, unless all fuzz tests are used
+func (cov *CoverageGenerator) fuzzTests() []string {
+	if len(cov.FuzzTests) > 0 {
+		return cov.FuzzTests
+	}
+	return []string{cov.FuzzTest}
+}
+
+// merged returns true if the coverage of multiple fuzz tests is merged
+// into one report
+func (cov *CoverageGenerator) merged() bool {
+	return len(cov.buildResults) > 1
+}
+
 func (cov *CoverageGenerator) run() error {
-	log.Infof("Running %s on corpus", pterm.Style{pterm.Reset, pterm.FgLightBlue}.Sprint(cov.FuzzTest))
-	log.Debugf("Executable: %s", cov.buildResult.Executable)
+	if cov.TimelineChunks > 0 {
+		return cov.runTimeline()
+	}
+	for _, buildResult := range cov.buildResults {
+		err := cov.runFuzzTest(buildResult)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Use user-specified seed corpus dirs (if any), the default seed
-	// corpus (if it exists), and the generated corpus (if it exists).
-	corpusDirs := cov.SeedCorpusDirs
-	exists, err := fileutil.Exists(cov.buildResult.SeedCorpus)
+func (cov *CoverageGenerator) runFuzzTest(buildResult *build.Result) error {
+	log.Infof("Running %s on corpus", pterm.Style{pterm.Reset, pterm.FgLightBlue}.Sprint(buildResult.Name))
+	log.Debugf("Executable: %s", buildResult.Executable)
+
+	corpusDirs, err := cov.resolvedCorpusDirs(buildResult)
 	if err != nil {
 		return err
 	}
-	if exists {
-		corpusDirs = append(corpusDirs, cov.buildResult.SeedCorpus)
-	}
-	exists, err = fileutil.Exists(cov.buildResult.GeneratedCorpus)
+
+	env, err := cov.prepareFuzzTestRun(buildResult)
 	if err != nil {
 		return err
 	}
-	if exists {
-		corpusDirs = append(corpusDirs, cov.buildResult.GeneratedCorpus)
+
+	return cov.runFuzzTestOnCorpus(buildResult, "merge-target", corpusDirs, env)
+}
+
+// resolvedCorpusDirs returns the corpus dirs of the fuzz test with
+// symlinks resolved
+func (cov *CoverageGenerator) resolvedCorpusDirs(buildResult *build.Result) ([]string, error) {
+	corpusDirs, err := cov.corpusDirs(buildResult)
+	if err != nil {
+		return nil, err
 	}
 
 	// Ensure that symlinks are resolved to be able to add minijail
@@ -187,72 +274,122 @@ func (cov *CoverageGenerator) run() error {
 	for i, dir := range corpusDirs {
 		corpusDirs[i], err = filepath.EvalSymlinks(dir)
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 	}
+	return corpusDirs, nil
+}
+
+// prepareFuzzTestRun creates the output directory of the fuzz test,
+// runs it on the empty input and returns the environment for running
+// it on the corpus
+func (cov *CoverageGenerator) prepareFuzzTestRun(buildResult *build.Result) ([]string, error) {
+	outputDir := cov.fuzzTestOutputDir(buildResult)
+	err := os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
 
-	executable := cov.buildResult.Executable
+	executable := buildResult.Executable
 	conModeSupport := binary.SupportsLlvmProfileContinuousMode(executable)
 	var env []string
-	env, err = envutil.Setenv(env, "LLVM_PROFILE_FILE", cov.rawProfilePattern(conModeSupport))
+	env, err = envutil.Setenv(env, "LLVM_PROFILE_FILE", cov.rawProfilePattern(buildResult, conModeSupport))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	env, err = envutil.Setenv(env, "NO_CIFUZZ", "1")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	dirWithEmptyFile := filepath.Join(cov.outputDir, "empty-file-corpus")
+	dirWithEmptyFile := filepath.Join(outputDir, "empty-file-corpus")
 	err = os.Mkdir(dirWithEmptyFile, 0o755)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = fileutil.Touch(filepath.Join(dirWithEmptyFile, "empty_file"))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	emptyDir := filepath.Join(cov.outputDir, "merge-target")
-	err = os.Mkdir(emptyDir, 0o755)
-	if err != nil {
-		return err
-	}
-	artifactsDir := filepath.Join(cov.outputDir, "merge-artifacts")
+	artifactsDir := filepath.Join(outputDir, "merge-artifacts")
 	err = os.Mkdir(artifactsDir, 0o755)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// libFuzzer emits crashing inputs in merge mode, but these aren't useful as we only run on already known inputs.
-	// Since there is no way to disable this behavior in libFuzzer, we instead emit artifacts into a dedicated temporary
-	// directory that is thrown away after the coverage run.
-	args := []string{"-artifact_prefix=" + artifactsDir + "/"}
-
 	// libFuzzer's merge mode never runs the empty input, whereas regular fuzzing runs and the replayer always try the
 	// empty input first. To achieve consistent behavior, manually run the empty input, ignoring any crashes. runFuzzer
 	// always logs any error we encounter.
 	// This line is responsible for empty inputs being skipped:
 	// https://github.com/llvm/llvm-project/blob/c7c0ce7d9ebdc0a49313bc77e14d1e856794f2e0/compiler-rt/lib/fuzzer/FuzzerIO.cpp#L127
-	_ = cov.runFuzzer(append(args, "-runs=0"), []string{dirWithEmptyFile}, env)
+	_ = cov.runFuzzer(buildResult, append(cov.artifactArgs(buildResult), "-runs=0"), []string{dirWithEmptyFile}, env)
+
+	return env, nil
+}
+
+// artifactArgs returns the libFuzzer arguments which make it emit
+// artifacts into the merge artifacts directory of the fuzz test
+func (cov *CoverageGenerator) artifactArgs(buildResult *build.Result) []string {
+	// libFuzzer emits crashing inputs in merge mode, but these aren't useful as we only run on already known inputs.
+	// Since there is no way to disable this behavior in libFuzzer, we instead emit artifacts into a dedicated temporary
+	// directory that is thrown away after the coverage run.
+	artifactsDir := filepath.Join(cov.fuzzTestOutputDir(buildResult), "merge-artifacts")
+	return []string{"-artifact_prefix=" + artifactsDir + "/"}
+}
+
+// runFuzzTestOnCorpus runs the fuzz test on the inputs in the corpus
+// dirs. The merge target is created in the output directory of the fuzz
+// test with the specified name.
+func (cov *CoverageGenerator) runFuzzTestOnCorpus(buildResult *build.Result, mergeTarget string, corpusDirs []string, env []string) error {
+	emptyDir := filepath.Join(cov.fuzzTestOutputDir(buildResult), mergeTarget)
+	err := os.Mkdir(emptyDir, 0o755)
+	if err != nil {
+		return err
+	}
 
 	// We use libFuzzer's crash-resistant merge mode to merge all corpus directories into an empty directory, which
 	// makes libFuzzer go over all inputs in a subprocess that is restarted in case it crashes. With LLVM's continuous
 	// mode (see rawProfilePattern) and since the LLVM coverage information is automatically appended to the existing
 	// .profraw file, we collect complete coverage information even if the target crashes on an input in the corpus.
-	return cov.runFuzzer(append(args, "-merge=1"), append([]string{emptyDir}, corpusDirs...), env)
+	return cov.runFuzzer(buildResult, append(cov.artifactArgs(buildResult), "-merge=1"), append([]string{emptyDir}, corpusDirs...), env)
 }
 
-func (cov *CoverageGenerator) runFuzzer(preCorpusArgs []string, corpusDirs []string, env []string) error {
+func (cov *CoverageGenerator) corpusDirs(buildResult *build.Result) ([]string, error) {
+	if len(cov.CorpusDirs) > 0 {
+		return append([]string{}, cov.CorpusDirs...), nil
+	}
+
+	// Use user-specified seed corpus dirs (if any), the default seed
+	// corpus (if it exists), and the generated corpus (if it exists).
+	corpusDirs := append([]string{}, cov.SeedCorpusDirs...)
+	exists, err := fileutil.Exists(buildResult.SeedCorpus)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		corpusDirs = append(corpusDirs, buildResult.SeedCorpus)
+	}
+	exists, err = fileutil.Exists(buildResult.GeneratedCorpus)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		corpusDirs = append(corpusDirs, buildResult.GeneratedCorpus)
+	}
+	return corpusDirs, nil
+}
+
+func (cov *CoverageGenerator) runFuzzer(buildResult *build.Result, preCorpusArgs []string, corpusDirs []string, env []string) error {
 	var err error
-	args := []string{cov.buildResult.Executable}
+	args := []string{buildResult.Executable}
 	args = append(args, preCorpusArgs...)
 	args = append(args, corpusDirs...)
 
 	if cov.UseSandbox {
 		bindings := []*minijail.Binding{
 			// The fuzz target must be accessible
-			{Source: cov.buildResult.Executable},
+			{Source: buildResult.Executable},
 		}
 
 		for _, dir := range corpusDirs {
@@ -263,7 +400,7 @@ func (cov *CoverageGenerator) runFuzzer(preCorpusArgs []string, corpusDirs []str
 		mj, err := minijail.NewMinijail(&minijail.Options{
 			Args:      args,
 			Bindings:  bindings,
-			OutputDir: cov.outputDir,
+			OutputDir: cov.fuzzTestOutputDir(buildResult),
 		})
 		if err != nil {
 			return err
@@ -304,17 +441,26 @@ func (cov *CoverageGenerator) runFuzzer(preCorpusArgs []string, corpusDirs []str
 }
 
 func (cov *CoverageGenerator) report() (string, error) {
-	err := cov.indexRawProfile()
-	if err != nil {
-		return "", err
+	for _, buildResult := range cov.buildResults {
+		err := cov.indexRawProfile(buildResult)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	lcovReportSummary, err := cov.lcovReportSummary()
+	if cov.merged() {
+		err := cov.mergeIndexedProfiles()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var err error
+	cov.coverageSummary, err = cov.summary()
 	if err != nil {
 		return "", err
 	}
-	reportReader := strings.NewReader(lcovReportSummary)
-	summary.ParseLcov(reportReader).PrintTable(cov.Stderr)
+	cov.coverageSummary.PrintTable(cov.Stderr)
 
 	reportPath := ""
 	switch cov.OutputFormat {
@@ -334,23 +480,69 @@ func (cov *CoverageGenerator) report() (string, error) {
 	return reportPath, nil
 }
 
-func (cov *CoverageGenerator) indexRawProfile() error {
-	rawProfileFiles, err := cov.rawProfileFiles()
+// summary returns the coverage summary of the report. If the coverage
+// of multiple fuzz tests is merged, it includes the coverage of the
+// individual fuzz tests.
+func (cov *CoverageGenerator) summary() (*summary.CoverageSummary, error) {
+	var coverageSummary *summary.CoverageSummary
+	if cov.DetailedSummary {
+		var err error
+		coverageSummary, err = cov.jsonReportSummary()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		lcovReportSummary, err := cov.lcovReportSummary(cov.mergedProfilePath(), cov.buildResults)
+		if err != nil {
+			return nil, err
+		}
+		coverageSummary = summary.ParseLcov(strings.NewReader(lcovReportSummary))
+	}
+
+	if cov.merged() {
+		for _, buildResult := range cov.buildResults {
+			lcovReportSummary, err := cov.lcovReportSummary(cov.indexedProfilePath(buildResult), []*build.Result{buildResult})
+			if err != nil {
+				return nil, err
+			}
+			coverageSummary.AddFuzzTest(buildResult.Name, summary.ParseLcov(strings.NewReader(lcovReportSummary)))
+		}
+	}
+
+	return coverageSummary, nil
+}
+
+func (cov *CoverageGenerator) indexRawProfile(buildResult *build.Result) error {
+	rawProfileFiles, err := cov.rawProfileFiles(buildResult)
 	if err != nil {
 		return err
 	}
 	if len(rawProfileFiles) == 0 {
 		// The rawProfilePattern parameter only governs whether we add "%c",
 		// which doesn't affect the actual raw profile location.
-		return errors.Errorf("%s did not generate .profraw files at %s", cov.buildResult.Executable, cov.rawProfilePattern(false))
+		return errors.Errorf("%s did not generate .profraw files at %s", buildResult.Executable, cov.rawProfilePattern(buildResult, false))
+	}
+
+	return cov.runLLVMProfDataMerge(cov.indexedProfilePath(buildResult), rawProfileFiles)
+}
+
+// mergeIndexedProfiles merges the indexed profiles of all fuzz tests
+// into a single indexed profile
+func (cov *CoverageGenerator) mergeIndexedProfiles() error {
+	var profiles []string
+	for _, buildResult := range cov.buildResults {
+		profiles = append(profiles, cov.indexedProfilePath(buildResult))
 	}
+	return cov.runLLVMProfDataMerge(cov.mergedProfilePath(), profiles)
+}
 
+func (cov *CoverageGenerator) runLLVMProfDataMerge(outputPath string, inputs []string) error {
 	llvmProfData, err := cov.runfilesFinder.LLVMProfDataPath()
 	if err != nil {
 		return err
 	}
 
-	args := append([]string{"merge", "-sparse", "-o", cov.indexedProfilePath()}, rawProfileFiles...)
+	args := append([]string{"merge", "-sparse", "-o", outputPath}, inputs...)
 	cmd := exec.Command(llvmProfData, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -362,7 +554,7 @@ func (cov *CoverageGenerator) indexRawProfile() error {
 	return nil
 }
 
-func (cov *CoverageGenerator) rawProfilePattern(supportsContinuousMode bool) string {
+func (cov *CoverageGenerator) rawProfilePattern(buildResult *build.Result, supportsContinuousMode bool) string {
 	// Use "%m" instead of a fixed path to support coverage of shared
 	// libraries: Each executable or library generates its own profile
 	// file, all of which we have to merge in the end. By using "%m",
@@ -376,7 +568,7 @@ func (cov *CoverageGenerator) rawProfilePattern(supportsContinuousMode bool) str
 	if supportsContinuousMode {
 		basePattern = "%c" + basePattern
 	}
-	return filepath.Join(cov.outputDir, basePattern)
+	return filepath.Join(cov.fuzzTestOutputDir(buildResult), basePattern)
 }
 
 func (cov *CoverageGenerator) generateHTMLReport() (string, error) {
@@ -386,10 +578,11 @@ func (cov *CoverageGenerator) generateHTMLReport() (string, error) {
 		return "", err
 	}
 	args = append(args, ignoreCIFuzzIncludesArgs...)
-	report, err := cov.runLlvmCov(args)
+	report, err := cov.runLlvmCov(args, cov.mergedProfilePath(), cov.buildResults)
 	if err != nil {
 		return "", err
 	}
+	report = summary.FilterLcov(report, cov.Filter)
 	// Write lcov report to temp dir
 	reportDir, err := os.MkdirTemp("", "coverage-")
 	if err != nil {
@@ -411,6 +604,10 @@ func (cov *CoverageGenerator) generateHTMLReport() (string, error) {
 		cov.OutputPath = filepath.Join(outputDir, cov.executableName())
 	}
 
+	if htmlreport.UseBuiltinRenderer(cov.HTMLRenderer) {
+		return cov.generateBuiltinHTMLReport()
+	}
+
 	// Create an HTML report via genhtml
 	genHTML, err := runfiles.Finder.GenHTMLPath()
 	if err != nil {
@@ -443,23 +640,71 @@ func (cov *CoverageGenerator) generateHTMLReport() (string, error) {
 	return cov.OutputPath, nil
 }
 
-func (cov *CoverageGenerator) runLlvmCov(args []string) (string, error) {
-	llvmCov, err := cov.runfilesFinder.LLVMCovPath()
+// generateBuiltinHTMLReport creates the HTML report from the JSON export
+// of llvm-cov, which unlike the lcov export includes the branches of
+// llvm-cov versions which don't support branch coverage in lcov
+func (cov *CoverageGenerator) generateBuiltinHTMLReport() (string, error) {
+	coverageSummary := cov.coverageSummary
+	if !cov.DetailedSummary {
+		var err error
+		coverageSummary, err = cov.jsonReportSummary()
+		if err != nil {
+			return "", err
+		}
+	}
+	var links []*htmlreport.Link
+	if cov.timeline != nil {
+		// The chart is written into the report directory by
+		// writeTimeline
+		links = append(links, &htmlreport.Link{Name: "Coverage timeline", Path: timelineChartFile})
+	}
+	err := htmlreport.Generate(coverageSummary, cov.OutputPath, cov.ProjectDir, cov.executableName(), links...)
 	if err != nil {
 		return "", err
 	}
+	return cov.OutputPath, nil
+}
 
-	// Add all runtime dependencies of the fuzz test to the binaries
-	// processed by llvm-cov to include them in the coverage report
-	args = append(args, "-instr-profile="+cov.indexedProfilePath())
-	args = append(args, cov.buildResult.Executable)
-	if archArg, err := cov.archFlagIfNeeded(cov.buildResult.Executable); err != nil {
+// jsonReportSummary returns the summary of the JSON export of llvm-cov,
+// which includes the line, function and branch data of the files
+func (cov *CoverageGenerator) jsonReportSummary() (*summary.CoverageSummary, error) {
+	args := []string{"export", "-format=text"}
+	ignoreCIFuzzIncludesArgs, err := cov.getIgnoreCIFuzzIncludesArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, ignoreCIFuzzIncludesArgs...)
+	report, err := cov.runLlvmCov(args, cov.mergedProfilePath(), cov.buildResults)
+	if err != nil {
+		return nil, err
+	}
+	coverageSummary := summary.ParseLLVMCovJSON(strings.NewReader(report))
+	coverageSummary.ApplyFilter(cov.Filter)
+	return coverageSummary, nil
+}
+
+func (cov *CoverageGenerator) runLlvmCov(args []string, profile string, buildResults []*build.Result) (string, error) {
+	llvmCov, err := cov.runfilesFinder.LLVMCovPath()
+	if err != nil {
 		return "", err
-	} else if archArg != "" {
-		args = append(args, archArg)
 	}
-	for _, path := range cov.buildResult.RuntimeDeps {
-		args = append(args, "-object="+path)
+
+	// Add the executables of all fuzz tests and all their runtime
+	// dependencies to the binaries processed by llvm-cov to include
+	// them in the coverage report
+	args = append(args, "-instr-profile="+profile)
+	var objects []string
+	for _, buildResult := range buildResults {
+		objects = append(objects, buildResult.Executable)
+		objects = append(objects, buildResult.RuntimeDeps...)
+	}
+	objects = sliceutil.RemoveDuplicates(objects)
+	for i, path := range objects {
+		if i == 0 {
+			args = append(args, path)
+		} else {
+			args = append(args, "-object="+path)
+		}
 		if archArg, err := cov.archFlagIfNeeded(path); err != nil {
 			return "", err
 		} else if archArg != "" {
@@ -484,10 +729,11 @@ func (cov *CoverageGenerator) generateLcovReport() (string, error) {
 		return "", err
 	}
 	args = append(args, ignoreCIFuzzIncludesArgs...)
-	report, err := cov.runLlvmCov(args)
+	report, err := cov.runLlvmCov(args, cov.mergedProfilePath(), cov.buildResults)
 	if err != nil {
 		return "", err
 	}
+	report = summary.FilterLcov(report, cov.Filter)
 
 	outputPath := cov.OutputPath
 	if cov.OutputPath == "" {
@@ -508,40 +754,72 @@ func (cov *CoverageGenerator) generateLcovReport() (string, error) {
 	return outputPath, nil
 }
 
-func (cov *CoverageGenerator) lcovReportSummary() (string, error) {
+func (cov *CoverageGenerator) lcovReportSummary(profile string, buildResults []*build.Result) (string, error) {
 	args := []string{"export", "-format=lcov", "-summary-only"}
 	ignoreCIFuzzIncludesArgs, err := cov.getIgnoreCIFuzzIncludesArgs()
 	if err != nil {
 		return "", err
 	}
 	args = append(args, ignoreCIFuzzIncludesArgs...)
-	output, err := cov.runLlvmCov(args)
+	output, err := cov.runLlvmCov(args, profile, buildResults)
 	if err != nil {
 		return "", err
 	}
 
-	return output, nil
+	return summary.FilterLcov(output, cov.Filter), nil
 }
 
+// getIgnoreCIFuzzIncludesArgs returns the llvm-cov arguments which
+// exclude the headers of cifuzz and the files excluded by the coverage
+// filter from the report. The include globs of the filter are applied
+// to the exported report, because llvm-cov doesn't support them.
 func (cov *CoverageGenerator) getIgnoreCIFuzzIncludesArgs() ([]string, error) {
 	cifuzzIncludePath, err := cov.runfilesFinder.CIFuzzIncludePath()
 	if err != nil {
 		return nil, err
 	}
-	return []string{"-ignore-filename-regex=" + regexp.QuoteMeta(cifuzzIncludePath) + "/.*"}, nil
+	args := []string{"-ignore-filename-regex=" + regexp.QuoteMeta(cifuzzIncludePath) + "/.*"}
+	for _, regex := range cov.Filter.IgnoreRegexes() {
+		args = append(args, "-ignore-filename-regex="+regex)
+	}
+	return args, nil
 }
 
-func (cov *CoverageGenerator) rawProfileFiles() ([]string, error) {
-	files, err := filepath.Glob(filepath.Join(cov.outputDir, "*.profraw"))
+func (cov *CoverageGenerator) rawProfileFiles(buildResult *build.Result) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(cov.fuzzTestOutputDir(buildResult), "*.profraw"))
 	return files, errors.WithStack(err)
 }
 
-func (cov *CoverageGenerator) indexedProfilePath() string {
-	return filepath.Join(cov.tmpDir, filepath.Base(cov.buildResult.Executable)+".profdata")
+// fuzzTestOutputDir returns the directory in which the raw profiles and
+// other output of running the fuzz test are stored
+func (cov *CoverageGenerator) fuzzTestOutputDir(buildResult *build.Result) string {
+	if !cov.merged() {
+		return cov.outputDir
+	}
+	return filepath.Join(cov.outputDir, buildResult.Name)
+}
+
+func (cov *CoverageGenerator) indexedProfilePath(buildResult *build.Result) string {
+	if !cov.merged() {
+		return filepath.Join(cov.tmpDir, filepath.Base(buildResult.Executable)+".profdata")
+	}
+	return filepath.Join(cov.tmpDir, buildResult.Name+".profdata")
+}
+
+// mergedProfilePath returns the path of the indexed profile from which
+// the coverage report is created
+func (cov *CoverageGenerator) mergedProfilePath() string {
+	if !cov.merged() {
+		return cov.indexedProfilePath(cov.buildResults[0])
+	}
+	return filepath.Join(cov.tmpDir, "merged.profdata")
 }
 
 func (cov *CoverageGenerator) executableName() string {
-	executable := cov.buildResult.Executable
+	if cov.merged() {
+		return "merged"
+	}
+	executable := cov.buildResults[0].Executable
 	// Remove .exe file extension on Windows
 	if runtime.GOOS == "windows" {
 		executable = strings.TrimSuffix(executable, filepath.Ext(executable))
@@ -0,0 +1,175 @@
+package llvm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+
+	"code-intelligence.com/cifuzz/internal/build"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/htmlreport"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/timeline"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// The file of the timeline chart in the HTML report directory
+const timelineChartFile = "timeline.html"
+
+// runTimeline runs the fuzz tests on the inputs of their corpora in the
+// order in which the inputs were created, split into chunks, and
+// records the coverage after each chunk
+func (cov *CoverageGenerator) runTimeline() error {
+	chunks := make([][][]*timeline.Input, len(cov.buildResults))
+	envs := make([][]string, len(cov.buildResults))
+	for i, buildResult := range cov.buildResults {
+		log.Infof("Running %s on corpus in %d chunks",
+			pterm.Style{pterm.Reset, pterm.FgLightBlue}.Sprint(buildResult.Name), cov.TimelineChunks)
+		log.Debugf("Executable: %s", buildResult.Executable)
+
+		corpusDirs, err := cov.resolvedCorpusDirs(buildResult)
+		if err != nil {
+			return err
+		}
+		inputs, err := timeline.ListInputs(corpusDirs)
+		if err != nil {
+			return err
+		}
+		chunks[i] = timeline.Split(inputs, cov.TimelineChunks)
+
+		envs[i], err = cov.prepareFuzzTestRun(buildResult)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The first point is the coverage of the empty input
+	cov.timeline = &timeline.Timeline{}
+	err := cov.addTimelinePoint(0, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	numInputs := 0
+	var newest time.Time
+	for c := 0; c < cov.TimelineChunks; c++ {
+		numInputsBefore := numInputs
+		for i, buildResult := range cov.buildResults {
+			chunk := chunks[i][c]
+			if len(chunk) == 0 {
+				continue
+			}
+			err = cov.runTimelineChunk(buildResult, c, chunk, envs[i])
+			if err != nil {
+				return err
+			}
+			numInputs += len(chunk)
+			if t := chunk[len(chunk)-1].Time; t.After(newest) {
+				newest = t
+			}
+		}
+		if numInputs == numInputsBefore {
+			// There are fewer inputs than chunks
+			continue
+		}
+		err = cov.addTimelinePoint(numInputs, newest)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTimelineChunk runs the fuzz test on the inputs of a chunk, which
+// are copied into a separate corpus directory for that purpose
+func (cov *CoverageGenerator) runTimelineChunk(buildResult *build.Result, c int, chunk []*timeline.Input, env []string) error {
+	name := fmt.Sprintf("timeline-chunk-%d", c)
+	chunkDir := filepath.Join(cov.fuzzTestOutputDir(buildResult), name)
+	err := os.Mkdir(chunkDir, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(chunkDir)
+
+	for i, input := range chunk {
+		// Prefix the inputs with their index because inputs from
+		// different corpus directories can have the same name
+		dest := filepath.Join(chunkDir, fmt.Sprintf("%06d-%s", i, filepath.Base(input.Path)))
+		err = copy.Copy(input.Path, dest)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	mergeTarget := name + "-merge-target"
+	defer fileutil.Cleanup(filepath.Join(cov.fuzzTestOutputDir(buildResult), mergeTarget))
+	return cov.runFuzzTestOnCorpus(buildResult, mergeTarget, []string{chunkDir}, env)
+}
+
+// addTimelinePoint adds the coverage of the raw profiles which were
+// collected so far to the timeline
+func (cov *CoverageGenerator) addTimelinePoint(numInputs int, newest time.Time) error {
+	for _, buildResult := range cov.buildResults {
+		err := cov.indexRawProfile(buildResult)
+		if err != nil {
+			return err
+		}
+	}
+	if cov.merged() {
+		err := cov.mergeIndexedProfiles()
+		if err != nil {
+			return err
+		}
+	}
+
+	lcovReportSummary, err := cov.lcovReportSummary(cov.mergedProfilePath(), cov.buildResults)
+	if err != nil {
+		return err
+	}
+	coverageSummary := summary.ParseLcov(strings.NewReader(lcovReportSummary))
+	cov.timeline.Add(numInputs, newest, coverageSummary.Total)
+	log.Debugf("Coverage after %d inputs: %d/%d lines, %d/%d branches", numInputs,
+		coverageSummary.Total.LinesHit, coverageSummary.Total.LinesFound,
+		coverageSummary.Total.BranchesHit, coverageSummary.Total.BranchesFound)
+	return nil
+}
+
+// writeTimeline writes the timeline to the timeline output file and, if
+// an HTML report was created, a chart of the timeline into the report
+// directory, which is linked from the index of the built-in renderer
+func (cov *CoverageGenerator) writeTimeline(reportPath string) error {
+	if cov.TimelineOutput != "" {
+		err := cov.timeline.Write(cov.TimelineOutput)
+		if err != nil {
+			return err
+		}
+		log.Successf("Created coverage timeline: %s", cov.TimelineOutput)
+	}
+
+	if cov.OutputFormat != "html" {
+		return nil
+	}
+	chartPath := filepath.Join(reportPath, timelineChartFile)
+	f, err := os.Create(chartPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	err = cov.timeline.WriteHTML(f)
+	if err != nil {
+		return err
+	}
+	if htmlreport.UseBuiltinRenderer(cov.HTMLRenderer) {
+		log.Successf("Created coverage timeline chart: %s", chartPath)
+	} else {
+		// The index of genhtml can't link to the chart
+		log.Successf("Created coverage timeline chart (not linked from the genhtml report): %s", chartPath)
+	}
+	return nil
+}
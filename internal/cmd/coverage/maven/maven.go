@@ -12,12 +12,14 @@ import (
 	"github.com/pkg/errors"
 
 	"code-intelligence.com/cifuzz/internal/build/maven"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/htmlreport"
 	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/coverage"
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/pkg/runfiles"
 	"code-intelligence.com/cifuzz/util/executil"
+	"code-intelligence.com/cifuzz/util/fileutil"
 	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
@@ -38,15 +40,92 @@ type CoverageGenerator struct {
 	OutputPath   string
 	FuzzTest     string
 	TargetMethod string
-	ProjectDir   string
+	// If set, a merged coverage report of these fuzz tests is generated
+	// instead of a report for FuzzTest. A fuzz test can specify a target
+	// method via "<class>::<method>".
+	FuzzTests  []string
+	ProjectDir string
+	// The directory of the Java source files, relative to which the
+	// file names of the JaCoCo report are resolved
+	SourceDir string
+	// The files which are included in the report. The JaCoCo XML report
+	// is filtered by cifuzz, the HTML report is then created from the
+	// filtered XML report by the built-in renderer.
+	Filter *summary.Filter
 
 	Parallel maven.ParallelOptions
 	Stderr   io.Writer
 
 	MavenRunner MavenRunner
+
+	tmpDir          string
+	coverageSummary *summary.CoverageSummary
 }
 
 func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
+	if len(cov.FuzzTests) > 0 {
+		return cov.buildFuzzTestsForMergedCoverage()
+	}
+
+	testParam := fmt.Sprintf("-Dtest=%s", cov.FuzzTest)
+	if cov.TargetMethod != "" {
+		testParam += fmt.Sprintf("#%s", cov.TargetMethod)
+	}
+	err := cov.MavenRunner.RunCommand(cov.testArgs(testParam))
+	if err != nil {
+		return err
+	}
+
+	return cov.MavenRunner.RunCommand(cov.reportArgs(cov.outputPath(), cov.reportFormat()))
+}
+
+// buildFuzzTestsForMergedCoverage runs each fuzz test with a separate
+// JaCoCo execution data file, creates an XML report for each of them
+// and a report of the concatenated execution data files, which JaCoCo
+// treats like the data of multiple sessions.
+func (cov *CoverageGenerator) buildFuzzTestsForMergedCoverage() error {
+	var err error
+	cov.tmpDir, err = os.MkdirTemp("", "maven-coverage-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var execFiles []string
+	for _, fuzzTest := range cov.FuzzTests {
+		testParam := "-Dtest=" + strings.Replace(fuzzTest, "::", "#", 1)
+		execFile := cov.fuzzTestReportDir(fuzzTest) + ".exec"
+		err = cov.MavenRunner.RunCommand(cov.testArgs(testParam, "-Djacoco.destFile="+execFile))
+		if err != nil {
+			return err
+		}
+
+		exists, err := fileutil.Exists(execFile)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			log.Warnf("No coverage data was collected for %s", fuzzTest)
+			continue
+		}
+		execFiles = append(execFiles, execFile)
+
+		reportArgs := cov.reportArgs(cov.fuzzTestReportDir(fuzzTest), "XML")
+		err = cov.MavenRunner.RunCommand(append(reportArgs, "-Djacoco.dataFile="+execFile))
+		if err != nil {
+			return err
+		}
+	}
+
+	mergedExecFile := filepath.Join(cov.tmpDir, "merged.exec")
+	err = concatenateFiles(mergedExecFile, execFiles)
+	if err != nil {
+		return err
+	}
+	reportArgs := cov.reportArgs(cov.outputPath(), cov.reportFormat())
+	return cov.MavenRunner.RunCommand(append(reportArgs, "-Djacoco.dataFile="+mergedExecFile))
+}
+
+func (cov *CoverageGenerator) testArgs(testParam string, extraArgs ...string) []string {
 	// Maven tests fail if fuzz tests fail, so we ignore the error here,
 	// so we can still generate the coverage report
 	mavenTestArgs := []string{"-Dmaven.test.failure.ignore=true"}
@@ -55,10 +134,7 @@ func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
 	mavenTestArgs = append(mavenTestArgs, "-Djazzer.hooks=false")
 
 	// Flags for cifuzz
-	testParam := fmt.Sprintf("-Dtest=%s", cov.FuzzTest)
-	if cov.TargetMethod != "" {
-		testParam += fmt.Sprintf("#%s", cov.TargetMethod)
-	}
+	mavenTestArgs = append(mavenTestArgs, extraArgs...)
 	mavenTestArgs = append(mavenTestArgs,
 		"-Pcifuzz",
 		testParam,
@@ -73,47 +149,129 @@ func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
 			mavenTestArgs = append(mavenTestArgs, "1C")
 		}
 	}
-	err := cov.MavenRunner.RunCommand(mavenTestArgs)
-	if err != nil {
-		return err
+	return mavenTestArgs
+}
+
+func (cov *CoverageGenerator) reportArgs(outputPath string, format string) []string {
+	return []string{
+		"-Pcifuzz",
+		"jacoco:report",
+		fmt.Sprintf("-Dcifuzz.report.output=%s", outputPath),
+		fmt.Sprintf("-Dcifuzz.report.format=%s", format),
+	}
+}
+
+func (cov *CoverageGenerator) reportFormat() string {
+	// The HTML report of a filtered report is created by cifuzz
+	if cov.OutputFormat == coverage.FormatJacocoXML || !cov.Filter.Empty() {
+		return "XML"
 	}
+	return "XML,HTML"
+}
 
+func (cov *CoverageGenerator) outputPath() string {
 	if cov.OutputPath == "" {
 		// We are using the .cifuzz-build directory
 		// because the build directory is unknown at this point
 		cov.OutputPath = filepath.Join(cov.ProjectDir, ".cifuzz-build", "report")
 	}
-	mavenReportArgs := []string{
-		"-Pcifuzz",
-		"jacoco:report",
-		fmt.Sprintf("-Dcifuzz.report.output=%s", cov.OutputPath),
-	}
-
-	if cov.OutputFormat == coverage.FormatJacocoXML {
-		mavenReportArgs = append(mavenReportArgs, "-Dcifuzz.report.format=XML")
-	} else {
-		mavenReportArgs = append(mavenReportArgs, "-Dcifuzz.report.format=XML,HTML")
-	}
+	return cov.OutputPath
+}
 
-	return cov.MavenRunner.RunCommand(mavenReportArgs)
+// fuzzTestReportDir returns the directory of the XML report of a single
+// fuzz test when creating a merged coverage report
+func (cov *CoverageGenerator) fuzzTestReportDir(fuzzTest string) string {
+	return filepath.Join(cov.tmpDir, strings.ReplaceAll(fuzzTest, "::", "_"))
 }
 
 func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
+	if cov.tmpDir != "" {
+		defer fileutil.Cleanup(cov.tmpDir)
+	}
+
 	reportPath := filepath.Join(cov.OutputPath, "jacoco.xml")
-	reportFile, err := os.Open(reportPath)
+	err := summary.FilterJacocoXMLFile(reportPath, cov.Filter)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return "", err
 	}
-	defer reportFile.Close()
-	summary.ParseJacocoXML(reportFile).PrintTable(cov.Stderr)
+	coverageSummary, err := parseJacocoXMLReport(reportPath)
+	if err != nil {
+		return "", err
+	}
+	for _, fuzzTest := range cov.FuzzTests {
+		reportPath := filepath.Join(cov.fuzzTestReportDir(fuzzTest), "jacoco.xml")
+		exists, err := fileutil.Exists(reportPath)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			continue
+		}
+		fuzzTestSummary, err := parseJacocoXMLReport(reportPath)
+		if err != nil {
+			return "", err
+		}
+		fuzzTestSummary.ApplyFilter(cov.Filter)
+		coverageSummary.AddFuzzTest(fuzzTest, fuzzTestSummary)
+	}
+	coverageSummary.PrintTable(cov.Stderr)
+	cov.coverageSummary = coverageSummary
 
 	if cov.OutputFormat == coverage.FormatJacocoXML {
 		return filepath.Join(cov.OutputPath, "jacoco.xml"), nil
 	}
 
+	if !cov.Filter.Empty() {
+		err = htmlreport.Generate(coverageSummary, cov.OutputPath, cov.SourceDir, cov.reportTitle())
+		if err != nil {
+			return "", err
+		}
+	}
 	return cov.OutputPath, nil
 }
 
+func (cov *CoverageGenerator) reportTitle() string {
+	if len(cov.FuzzTests) > 0 {
+		return strings.Join(cov.FuzzTests, ", ")
+	}
+	return cov.FuzzTest
+}
+
+// CoverageSummary returns the summary of the coverage report created by
+// GenerateCoverageReport
+func (cov *CoverageGenerator) CoverageSummary() *summary.CoverageSummary {
+	return cov.coverageSummary
+}
+
+func concatenateFiles(outputPath string, inputPaths []string) error {
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer out.Close()
+	for _, path := range inputPaths {
+		in, err := os.Open(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(out.Close())
+}
+
+func parseJacocoXMLReport(path string) (*summary.CoverageSummary, error) {
+	reportFile, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer reportFile.Close()
+	return summary.ParseJacocoXML(reportFile), nil
+}
+
 func (runner *MavenRunnerImpl) RunCommand(args []string) error {
 	// ensure a finder is set
 	if runner.runfilesFinder == nil {
@@ -2,12 +2,18 @@ package maven
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"code-intelligence.com/cifuzz/internal/build/maven"
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+	"code-intelligence.com/cifuzz/util/fileutil"
 )
 
 type MavenRunnerMock struct {
@@ -56,3 +62,106 @@ func TestBuildFuzzTestForCoverage(t *testing.T) {
 	require.NoError(t, err)
 	runnerMock.AssertExpectations(t)
 }
+
+func TestBuildFuzzTestForCoverage_Merged(t *testing.T) {
+	outputPath := "project-dir/cov-output"
+	fuzzTests := []string{"com.example.FuzzTestCase::MyFuzzTest", "com.example.OtherFuzzTest"}
+
+	runnerMock := &MavenRunnerMock{}
+	gen := &CoverageGenerator{
+		OutputPath:  outputPath,
+		FuzzTests:   fuzzTests,
+		MavenRunner: runnerMock,
+	}
+	runnerMock.On("RunCommand", mock.Anything).Return(nil)
+
+	err := gen.BuildFuzzTestForCoverage()
+	require.NoError(t, err)
+	defer fileutil.Cleanup(gen.tmpDir)
+
+	// The mock doesn't create execution data files, so only the tests
+	// and the report of the merged (empty) execution data are run
+	require.Len(t, runnerMock.Calls, 3)
+	firstExecFile := filepath.Join(gen.tmpDir, "com.example.FuzzTestCase_MyFuzzTest.exec")
+	assert.Equal(t, []string{
+		"-Dmaven.test.failure.ignore=true",
+		"-Djazzer.hooks=false",
+		"-Djacoco.destFile=" + firstExecFile,
+		"-Pcifuzz",
+		"-Dtest=com.example.FuzzTestCase#MyFuzzTest",
+		"test",
+	}, runnerMock.Calls[0].Arguments.Get(0))
+	assert.Contains(t, runnerMock.Calls[1].Arguments.Get(0), "-Dtest=com.example.OtherFuzzTest")
+	assert.Equal(t, []string{
+		"-Pcifuzz",
+		"jacoco:report",
+		fmt.Sprintf("-Dcifuzz.report.output=%s", outputPath),
+		"-Dcifuzz.report.format=XML,HTML",
+		"-Djacoco.dataFile=" + filepath.Join(gen.tmpDir, "merged.exec"),
+	}, runnerMock.Calls[2].Arguments.Get(0))
+}
+
+func TestConcatenateFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.exec")
+	b := filepath.Join(dir, "b.exec")
+	require.NoError(t, os.WriteFile(a, []byte("foo"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("bar"), 0o644))
+
+	merged := filepath.Join(dir, "merged.exec")
+	err := concatenateFiles(merged, []string{a, b})
+	require.NoError(t, err)
+	content, err := os.ReadFile(merged)
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", string(content))
+}
+
+func TestGenerateCoverageReportWithFilter(t *testing.T) {
+	projectDir := t.TempDir()
+	sourceDir := filepath.Join(projectDir, "src", "main", "java")
+	outputPath := filepath.Join(projectDir, "report")
+	require.NoError(t, os.MkdirAll(outputPath, 0o755))
+	err := os.WriteFile(filepath.Join(outputPath, "jacoco.xml"), []byte(`<report name="example">
+    <package name="com/example">
+        <class name="com/example/App" sourcefilename="App.java"/>
+        <sourcefile name="App.java">
+            <line nr="1" mi="0" ci="2" mb="0" cb="0"/>
+            <counter type="LINE" missed="0" covered="1"/>
+        </sourcefile>
+    </package>
+    <package name="com/example/generated">
+        <class name="com/example/generated/Proto" sourcefilename="Proto.java"/>
+        <sourcefile name="Proto.java">
+            <counter type="LINE" missed="10" covered="0"/>
+        </sourcefile>
+    </package>
+</report>`), 0o644)
+	require.NoError(t, err)
+
+	gen := &CoverageGenerator{
+		OutputFormat: "html",
+		OutputPath:   outputPath,
+		FuzzTest:     "com.example.FuzzTestCase",
+		ProjectDir:   projectDir,
+		SourceDir:    sourceDir,
+		Filter: &summary.Filter{
+			Exclude:    []string{"src/main/java/com/example/generated"},
+			BaseDir:    projectDir,
+			SourceDirs: []string{sourceDir},
+		},
+		Stderr: io.Discard,
+	}
+	// The HTML report is created by cifuzz from the filtered XML report
+	assert.Equal(t, "XML", gen.reportFormat())
+
+	reportPath, err := gen.GenerateCoverageReport()
+	require.NoError(t, err)
+	assert.Equal(t, outputPath, reportPath)
+	assert.FileExists(t, filepath.Join(outputPath, "index.html"))
+
+	report, err := os.ReadFile(filepath.Join(outputPath, "jacoco.xml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(report), "generated")
+	require.Len(t, gen.CoverageSummary().Files, 1)
+	assert.Equal(t, &summary.Coverage{LinesFound: 1, LinesHit: 1}, gen.CoverageSummary().Total)
+}
@@ -2,10 +2,12 @@ package node
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"strings"
 	"syscall"
@@ -18,6 +20,7 @@ import (
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/pkg/options"
 	"code-intelligence.com/cifuzz/util/executil"
+	"code-intelligence.com/cifuzz/util/fileutil"
 	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
@@ -26,11 +29,19 @@ type CoverageGenerator struct {
 	OutputPath      string
 	TestPathPattern string
 	TestNamePattern string
-	ProjectDir      string
+	// If set, a merged coverage report of these fuzz tests is generated
+	// instead of a report for TestPathPattern and TestNamePattern. A
+	// fuzz test can specify a test name via <path>:<name>.
+	FuzzTests  []string
+	ProjectDir string
+	// The files which are included in the report
+	Filter *summary.Filter
 
 	Stderr      io.Writer
 	BuildStdout io.Writer
 	BuildStderr io.Writer
+
+	coverageSummary *summary.CoverageSummary
 }
 
 func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
@@ -38,8 +49,18 @@ func (cov *CoverageGenerator) BuildFuzzTestForCoverage() error {
 }
 
 func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
+	var fuzzTestSummaries []*summary.FuzzTestCoverage
+	if len(cov.FuzzTests) > 0 {
+		var err error
+		fuzzTestSummaries, err = cov.fuzzTestSummaries()
+		if err != nil {
+			return "", err
+		}
+		cov.TestPathPattern, cov.TestNamePattern = mergedPatterns(cov.FuzzTests)
+	}
+
 	// check if the specified path and name patterns have at least one match
-	err := cov.validateFuzzTest()
+	err := cov.validateFuzzTest(cov.TestPathPattern, cov.TestNamePattern)
 	if err != nil {
 		return "", err
 	}
@@ -54,26 +75,22 @@ func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
 		}
 	}
 
-	args := []string{"jest", "--coverage"}
-	args = append(args, options.JazzerJSTestPathPatternFlag(cov.TestPathPattern))
-	args = append(args, options.JazzerJSTestNamePatternFlag(cov.TestNamePattern))
-	args = append(args, options.JazzerJSCoverageDirectoryFlag(cov.OutputPath))
 	// the lcov coverage reporter generates both the lcov.info and an html report
-	args = append(args, options.JazzerJSCoverageReportersFlag(coverage.FormatLCOV))
-
-	err = cov.runNPXCommand(args, cov.BuildStdout, cov.BuildStderr)
+	err = cov.runJestCoverage(cov.TestPathPattern, cov.TestNamePattern, cov.OutputPath, coverage.FormatLCOV)
 	if err != nil {
 		return "", err
 	}
 
 	// generate the summary table
 	reportPath := filepath.Join(cov.OutputPath, "lcov.info")
-	reportFile, err := os.Open(reportPath)
+	coverageSummary, err := parseLcovReport(reportPath)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return "", err
 	}
-	defer reportFile.Close()
-	summary.ParseLcov(reportFile).PrintTable(cov.Stderr)
+	coverageSummary.ApplyFilter(cov.Filter)
+	coverageSummary.FuzzTests = fuzzTestSummaries
+	coverageSummary.PrintTable(cov.Stderr)
+	cov.coverageSummary = coverageSummary
 
 	// the index.html file is located in the subfolder lcov-report
 	if cov.OutputFormat == "html" {
@@ -83,11 +100,117 @@ func (cov *CoverageGenerator) GenerateCoverageReport() (string, error) {
 	return reportPath, nil
 }
 
-func (cov *CoverageGenerator) validateFuzzTest() error {
+// CoverageSummary returns the summary of the coverage report created by
+// GenerateCoverageReport
+func (cov *CoverageGenerator) CoverageSummary() *summary.CoverageSummary {
+	return cov.coverageSummary
+}
+
+// fuzzTestSummaries runs each fuzz test separately to determine the
+// coverage of the individual fuzz tests
+func (cov *CoverageGenerator) fuzzTestSummaries() ([]*summary.FuzzTestCoverage, error) {
+	tmpDir, err := os.MkdirTemp("", "node-coverage-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tmpDir)
+
+	var res []*summary.FuzzTestCoverage
+	for i, fuzzTest := range cov.FuzzTests {
+		testPathPattern, testNamePattern := splitFuzzTest(fuzzTest)
+		err = cov.validateFuzzTest(testPathPattern, testNamePattern)
+		if err != nil {
+			return nil, err
+		}
+
+		outputDir := filepath.Join(tmpDir, fmt.Sprint(i))
+		err = cov.runJestCoverage(testPathPattern, testNamePattern, outputDir, "lcovonly")
+		if err != nil {
+			return nil, err
+		}
+		fuzzTestSummary, err := parseLcovReport(filepath.Join(outputDir, "lcov.info"))
+		if err != nil {
+			return nil, err
+		}
+		fuzzTestSummary.ApplyFilter(cov.Filter)
+		res = append(res, &summary.FuzzTestCoverage{FuzzTest: fuzzTest, Coverage: fuzzTestSummary.Total})
+	}
+	return res, nil
+}
+
+// splitFuzzTest splits a fuzz test identifier of the form
+// <path>[:<name>] into a test path pattern and a test name pattern
+func splitFuzzTest(fuzzTest string) (string, string) {
+	testPathPattern, testNamePattern, _ := strings.Cut(fuzzTest, ":")
+	return testPathPattern, strings.ReplaceAll(testNamePattern, "\"", "")
+}
+
+// mergedPatterns returns the test path and name patterns which match
+// all of the fuzz tests. The test name pattern is only set if all fuzz
+// tests specify a test name.
+func mergedPatterns(fuzzTests []string) (string, string) {
+	var testPathPatterns, testNamePatterns []string
+	for _, fuzzTest := range fuzzTests {
+		testPathPattern, testNamePattern := splitFuzzTest(fuzzTest)
+		testPathPatterns = append(testPathPatterns, testPathPattern)
+		if testNamePattern != "" {
+			testNamePatterns = append(testNamePatterns, testNamePattern)
+		}
+	}
+	if len(testNamePatterns) != len(fuzzTests) {
+		testNamePatterns = nil
+	}
+	return strings.Join(testPathPatterns, "|"), strings.Join(testNamePatterns, "|")
+}
+
+func (cov *CoverageGenerator) runJestCoverage(testPathPattern, testNamePattern, outputDir, reporter string) error {
+	args := []string{"jest", "--coverage"}
+	args = append(args, options.JazzerJSTestPathPatternFlag(testPathPattern))
+	args = append(args, options.JazzerJSTestNamePatternFlag(testNamePattern))
+	args = append(args, options.JazzerJSCoverageDirectoryFlag(outputDir))
+	args = append(args, options.JazzerJSCoverageReportersFlag(reporter))
+	args = append(args, cov.filterArgs()...)
+	return cov.runNPXCommand(args, cov.BuildStdout, cov.BuildStderr)
+}
+
+// filterArgs returns the Jest arguments which restrict the coverage
+// report to the files matched by the coverage filter. The arguments are
+// passed without quotes because they contain globs and regexes, which
+// would otherwise be matched including the quotes.
+func (cov *CoverageGenerator) filterArgs() []string {
+	if cov.Filter.Empty() {
+		return nil
+	}
+	var args []string
+	for _, glob := range cov.Filter.Include {
+		glob = strings.TrimSuffix(filepath.ToSlash(glob), "/")
+		base := path.Base(glob)
+		if !strings.Contains(base, ".") && !strings.Contains(base, "*") {
+			// The glob matches a directory
+			glob += "/**"
+		}
+		args = append(args, options.JazzerJSCollectCoverageFromFlag(glob))
+	}
+	for _, regex := range cov.Filter.IgnoreRegexes() {
+		args = append(args, options.JazzerJSCoveragePathIgnorePatternsFlag(regex))
+	}
+	return args
+}
+
+func parseLcovReport(path string) (*summary.CoverageSummary, error) {
+	reportFile, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer reportFile.Close()
+	return summary.ParseLcov(reportFile), nil
+}
+
+func (cov *CoverageGenerator) validateFuzzTest(testPathPattern, testNamePattern string) error {
 	// list all fuzz tests with the specified path and name patterns
 	args := []string{"jest", "--listTests"}
-	args = append(args, options.JazzerJSTestPathPatternFlag(cov.TestPathPattern))
-	args = append(args, options.JazzerJSTestNamePatternFlag(cov.TestNamePattern))
+	args = append(args, options.JazzerJSTestPathPatternFlag(testPathPattern))
+	args = append(args, options.JazzerJSTestNamePatternFlag(testNamePattern))
 
 	stdout := new(bytes.Buffer)
 	err := cov.runNPXCommand(args, stdout, stdout)
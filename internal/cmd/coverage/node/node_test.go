@@ -0,0 +1,18 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergedPatterns(t *testing.T) {
+	testPathPattern, testNamePattern := mergedPatterns([]string{`FuzzTestCase:"my fuzz test"`, `OtherFuzzTest:"other"`})
+	assert.Equal(t, "FuzzTestCase|OtherFuzzTest", testPathPattern)
+	assert.Equal(t, "my fuzz test|other", testNamePattern)
+
+	// The test name pattern is only used if all fuzz tests specify one
+	testPathPattern, testNamePattern = mergedPatterns([]string{`FuzzTestCase:"my fuzz test"`, "OtherFuzzTest"})
+	assert.Equal(t, "FuzzTestCase|OtherFuzzTest", testPathPattern)
+	assert.Equal(t, "", testNamePattern)
+}
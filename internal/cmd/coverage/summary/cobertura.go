@@ -0,0 +1,188 @@
+package summary
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/version"
+)
+
+const coberturaDoctype = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+type coberturaReport struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      int                `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+
+	coverage Coverage
+}
+
+type coberturaClass struct {
+	Name       string            `xml:"name,attr"`
+	Filename   string            `xml:"filename,attr"`
+	LineRate   string            `xml:"line-rate,attr"`
+	BranchRate string            `xml:"branch-rate,attr"`
+	Complexity int               `xml:"complexity,attr"`
+	Methods    []coberturaMethod `xml:"methods>method"`
+	Lines      []coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name       string          `xml:"name,attr"`
+	Signature  string          `xml:"signature,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity int             `xml:"complexity,attr"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int    `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr,omitempty"`
+}
+
+func rate(hit, found int) string {
+	if found == 0 {
+		return "1"
+	}
+	return fmt.Sprintf("%.4g", float64(hit)/float64(found))
+}
+
+// relativeFilename returns the filename relative to the first source
+// directory which contains it. Relative filenames are returned as is.
+func relativeFilename(filename string, sourceDirs []string) string {
+	if !filepath.IsAbs(filename) {
+		return filepath.ToSlash(filename)
+	}
+	for _, dir := range sourceDirs {
+		rel, err := filepath.Rel(dir, filename)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filename)
+}
+
+// WriteCobertura writes the coverage in the Cobertura XML format. Each
+// file is represented as a class of the package named after its
+// directory. The filenames are written relative to the specified
+// source directories, which are listed in the report.
+func (cs *CoverageSummary) WriteCobertura(w io.Writer, sourceDirs []string) error {
+	report := &coberturaReport{
+		LineRate:        rate(cs.Total.LinesHit, cs.Total.LinesFound),
+		BranchRate:      rate(cs.Total.BranchesHit, cs.Total.BranchesFound),
+		LinesCovered:    cs.Total.LinesHit,
+		LinesValid:      cs.Total.LinesFound,
+		BranchesCovered: cs.Total.BranchesHit,
+		BranchesValid:   cs.Total.BranchesFound,
+		Version:         version.Version,
+		Timestamp:       time.Now().Unix(),
+		Sources:         sourceDirs,
+	}
+
+	packages := make(map[string]*coberturaPackage)
+	for _, file := range cs.Files {
+		filename := relativeFilename(file.Filename, sourceDirs)
+		packageName := strings.ReplaceAll(path.Dir(filename), "/", ".")
+		if packageName == "." {
+			packageName = ""
+		}
+		pkg, ok := packages[packageName]
+		if !ok {
+			pkg = &coberturaPackage{Name: packageName}
+			packages[packageName] = pkg
+		}
+		pkg.coverage.LinesFound += file.Coverage.LinesFound
+		pkg.coverage.LinesHit += file.Coverage.LinesHit
+		pkg.coverage.BranchesFound += file.Coverage.BranchesFound
+		pkg.coverage.BranchesHit += file.Coverage.BranchesHit
+		pkg.Classes = append(pkg.Classes, newCoberturaClass(file, filename))
+	}
+
+	for _, pkg := range packages {
+		pkg.LineRate = rate(pkg.coverage.LinesHit, pkg.coverage.LinesFound)
+		pkg.BranchRate = rate(pkg.coverage.BranchesHit, pkg.coverage.BranchesFound)
+		sort.Slice(pkg.Classes, func(i, j int) bool {
+			return pkg.Classes[i].Filename < pkg.Classes[j].Filename
+		})
+		report.Packages = append(report.Packages, *pkg)
+	}
+	sort.Slice(report.Packages, func(i, j int) bool {
+		return report.Packages[i].Name < report.Packages[j].Name
+	})
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n%s\n", xml.Header, coberturaDoctype, out)
+	return errors.WithStack(err)
+}
+
+func newCoberturaClass(file *FileCoverage, filename string) coberturaClass {
+	class := coberturaClass{
+		Name:       path.Base(filename),
+		Filename:   filename,
+		LineRate:   rate(file.Coverage.LinesHit, file.Coverage.LinesFound),
+		BranchRate: rate(file.Coverage.BranchesHit, file.Coverage.BranchesFound),
+	}
+
+	var functions []string
+	for function := range file.FunctionHits {
+		functions = append(functions, function)
+	}
+	sort.Strings(functions)
+	for _, function := range functions {
+		lineRate := "0"
+		if file.FunctionHits[function] > 0 {
+			lineRate = "1"
+		}
+		class.Methods = append(class.Methods, coberturaMethod{
+			Name:       function,
+			LineRate:   lineRate,
+			BranchRate: "1",
+		})
+	}
+
+	var lines []int
+	for line := range file.LineHits {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	for _, line := range lines {
+		l := coberturaLine{Number: line, Hits: file.LineHits[line]}
+		if branches, ok := file.LineBranches[line]; ok && branches.Found > 0 {
+			l.Branch = true
+			l.ConditionCoverage = fmt.Sprintf("%d%% (%d/%d)", branches.Hit*100/branches.Found, branches.Hit, branches.Found)
+		}
+		class.Lines = append(class.Lines, l)
+	}
+	return class
+}
@@ -0,0 +1,76 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCobertura(t *testing.T) {
+	report := `SF:/project/src/foo.cpp
+FN:1,foo
+FN:5,bar
+FNDA:3,foo
+FNF:2
+FNH:1
+DA:1,3
+DA:2,0
+DA:5,0
+LF:3
+LH:1
+BRDA:1,0,0,3
+BRDA:1,0,1,0
+BRF:2
+BRH:1
+end_of_record
+SF:/project/main.cpp
+DA:1,1
+LF:1
+LH:1
+end_of_record
+`
+	summary := ParseLcov(strings.NewReader(report))
+
+	var out bytes.Buffer
+	err := summary.WriteCobertura(&out, []string{"/project"})
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`)
+	assert.Contains(t, out.String(), `line-rate="0.5" branch-rate="0.5" lines-covered="2" lines-valid="4" branches-covered="1" branches-valid="2"`)
+	assert.Contains(t, out.String(), `<source>/project</source>`)
+	assert.Contains(t, out.String(), `<package name="src" line-rate="0.3333" branch-rate="0.5" complexity="0">`)
+	assert.Contains(t, out.String(), `<class name="foo.cpp" filename="src/foo.cpp" line-rate="0.3333" branch-rate="0.5" complexity="0">`)
+	assert.Contains(t, out.String(), `<class name="main.cpp" filename="main.cpp" line-rate="1" branch-rate="1" complexity="0">`)
+	assert.Contains(t, out.String(), `<method name="bar" signature="" line-rate="0" branch-rate="1" complexity="0">`)
+	assert.Contains(t, out.String(), `<line number="1" hits="3" branch="true" condition-coverage="50% (1/2)">`)
+	assert.Contains(t, out.String(), `<line number="2" hits="0" branch="false">`)
+}
+
+func TestWriteJSON(t *testing.T) {
+	report := `SF:foo.cpp
+FN:1,foo
+FNDA:3,foo
+FNF:1
+FNH:1
+DA:1,3
+LF:1
+LH:1
+end_of_record
+`
+	summary := ParseLcov(strings.NewReader(report))
+
+	var out bytes.Buffer
+	err := summary.WriteJSON(&out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), `"lines_hit": 1`)
+	assert.Contains(t, out.String(), `"function_hits": {`)
+
+	parsed := &CoverageSummary{}
+	err = json.Unmarshal(out.Bytes(), parsed)
+	require.NoError(t, err)
+	assert.Equal(t, summary, parsed)
+}
@@ -11,33 +11,55 @@ import (
 )
 
 type CoverageSummary struct {
-	Total *Coverage
-	Files []*FileCoverage
+	Total *Coverage       `json:"total"`
+	Files []*FileCoverage `json:"files"`
+	// The coverage of the individual fuzz tests, only set if the
+	// coverage of multiple fuzz tests was merged
+	FuzzTests []*FuzzTestCoverage `json:"fuzz_tests,omitempty"`
+}
+
+type FuzzTestCoverage struct {
+	FuzzTest string    `json:"fuzz_test"`
+	Coverage *Coverage `json:"coverage"`
 }
 
 type FileCoverage struct {
-	Filename string
-	Coverage *Coverage
+	Filename string    `json:"filename"`
+	Coverage *Coverage `json:"coverage"`
+	// The execution counts of the instrumented lines by line number
+	LineHits map[int]int `json:"line_hits,omitempty"`
+	// The execution counts of the functions by function name
+	FunctionHits map[string]int `json:"function_hits,omitempty"`
+	// The start lines of the functions by function name
+	FunctionLines map[string]int `json:"function_lines,omitempty"`
+	// The found and hit branches of the instrumented lines by line
+	// number
+	LineBranches map[int]*Branches `json:"line_branches,omitempty"`
+}
+
+type Branches struct {
+	Found int `json:"found"`
+	Hit   int `json:"hit"`
 }
 
 type Coverage struct {
-	FunctionsFound int
-	FunctionsHit   int
-	BranchesFound  int
-	BranchesHit    int
-	LinesFound     int
-	LinesHit       int
+	FunctionsFound int `json:"functions_found"`
+	FunctionsHit   int `json:"functions_hit"`
+	BranchesFound  int `json:"branches_found"`
+	BranchesHit    int `json:"branches_hit"`
+	LinesFound     int `json:"lines_found"`
+	LinesHit       int `json:"lines_hit"`
 }
 
-func (cs *CoverageSummary) PrintTable(writer io.Writer) {
-	formatCell := func(hit, found int) string {
-		percent := 100.0
-		if found != 0 {
-			percent = (float64(hit) * 100) / float64(found)
-		}
-		return fmt.Sprintf("%d / %d %8s", hit, found, fmt.Sprintf("(%.1f%%)", percent))
+func formatCell(hit, found int) string {
+	percent := 100.0
+	if found != 0 {
+		percent = (float64(hit) * 100) / float64(found)
 	}
+	return fmt.Sprintf("%d / %d %8s", hit, found, fmt.Sprintf("(%.1f%%)", percent))
+}
 
+func (cs *CoverageSummary) PrintTable(writer io.Writer) {
 	// create table data for pterm table
 	tableData := pterm.TableData{{"File", "Functions Hit/Found", "Lines Hit/Found", "Branches Hit/Found"}}
 	for _, file := range cs.Files {
@@ -68,4 +90,47 @@ func (cs *CoverageSummary) PrintTable(writer io.Writer) {
 		log.Error(err, "Unable to print coverage table")
 	}
 	log.Print("\n")
+
+	if len(cs.FuzzTests) > 0 {
+		cs.printFuzzTestsTable(writer)
+	}
+}
+
+// printFuzzTestsTable prints the coverage of the individual fuzz tests
+// which the merged coverage report was created from
+func (cs *CoverageSummary) printFuzzTestsTable(writer io.Writer) {
+	tableData := pterm.TableData{{"Fuzz Test", "Functions Hit/Found", "Lines Hit/Found", "Branches Hit/Found"}}
+	for _, fuzzTest := range cs.FuzzTests {
+		tableData = append(tableData, []string{
+			fuzzTest.FuzzTest,
+			formatCell(fuzzTest.Coverage.FunctionsHit, fuzzTest.Coverage.FunctionsFound),
+			formatCell(fuzzTest.Coverage.LinesHit, fuzzTest.Coverage.LinesFound),
+			formatCell(fuzzTest.Coverage.BranchesHit, fuzzTest.Coverage.BranchesFound),
+		},
+		)
+	}
+	tableData = append(tableData, []string{"", "", "", ""})
+	tableData = append(tableData, []string{
+		"Merged",
+		formatCell(cs.Total.FunctionsHit, cs.Total.FunctionsFound),
+		formatCell(cs.Total.LinesHit, cs.Total.LinesFound),
+		formatCell(cs.Total.BranchesHit, cs.Total.BranchesFound),
+	},
+	)
+	table := pterm.DefaultTable.WithWriter(writer).WithHasHeader().WithData(tableData).WithRightAlignment()
+
+	log.Successf("Coverage per Fuzz Test:\n")
+	if err := table.Render(); err != nil {
+		log.Error(err, "Unable to print coverage table")
+	}
+	log.Print("\n")
+}
+
+// AddFuzzTest adds the coverage of an individual fuzz test to the
+// summary of the merged coverage
+func (cs *CoverageSummary) AddFuzzTest(fuzzTest string, fuzzTestSummary *CoverageSummary) {
+	cs.FuzzTests = append(cs.FuzzTests, &FuzzTestCoverage{
+		FuzzTest: fuzzTest,
+		Coverage: fuzzTestSummary.Total,
+	})
 }
@@ -36,3 +36,27 @@ end_of_record
 	assert.Contains(t, out, "0 / 0 (100.0%)")
 	assert.Contains(t, out, "3 / 22")
 }
+
+func TestCoverage_PrintTable_FuzzTests(t *testing.T) {
+	rPipe, wPipe, err := os.Pipe()
+	require.NoError(t, err)
+
+	merged := ParseLcov(strings.NewReader("SF:foo.cpp\nLH:3\nLF:4\nend_of_record\n"))
+	merged.AddFuzzTest("fuzz_test_1", ParseLcov(strings.NewReader("SF:foo.cpp\nLH:1\nLF:4\nend_of_record\n")))
+	merged.AddFuzzTest("fuzz_test_2", ParseLcov(strings.NewReader("SF:foo.cpp\nLH:2\nLF:4\nend_of_record\n")))
+	require.Len(t, merged.FuzzTests, 2)
+	assert.Equal(t, 1, merged.FuzzTests[0].Coverage.LinesHit)
+	merged.PrintTable(wPipe)
+
+	wPipe.Close()
+	pipeOut, err := io.ReadAll(rPipe)
+	require.NoError(t, err)
+	out := string(pipeOut)
+
+	assert.Contains(t, out, "fuzz_test_1")
+	assert.Contains(t, out, "fuzz_test_2")
+	assert.Contains(t, out, "1 / 4  (25.0%)")
+	assert.Contains(t, out, "2 / 4  (50.0%)")
+	assert.Contains(t, out, "Merged")
+	assert.Contains(t, out, "3 / 4  (75.0%)")
+}
@@ -0,0 +1,259 @@
+package summary
+
+import (
+	"bufio"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Filter selects the files of a coverage report via path globs. The
+// globs support "*", "?" and "**" and are relative to BaseDir unless
+// they are absolute. A glob which matches a directory matches all
+// files below that directory.
+type Filter struct {
+	Include []string `mapstructure:"include"`
+	Exclude []string `mapstructure:"exclude"`
+	// The directory relative to which the globs are interpreted,
+	// usually the project directory
+	BaseDir string `mapstructure:"-"`
+	// The directories relative to which relative file names of the
+	// report are interpreted, e.g. the Java source directories for the
+	// file names of JaCoCo reports (which are relative to the source
+	// directory). If empty, they are interpreted relative to BaseDir.
+	SourceDirs []string `mapstructure:"-"`
+
+	// The compiled include and exclude globs, see compile
+	includeRegexes []*globRegex
+	excludeRegexes []*globRegex
+}
+
+// globRegex is the regular expression of a glob, which matches the
+// glob's files and the files below them
+type globRegex struct {
+	*regexp.Regexp
+	// Whether the glob is absolute, else it's matched against the
+	// path relative to the base directory
+	absolute bool
+}
+
+// Empty returns true if the filter doesn't have any include or exclude
+// globs, i.e. if it matches all files
+func (f *Filter) Empty() bool {
+	return f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0)
+}
+
+// Validate returns an error if one of the globs is invalid
+func (f *Filter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, glob := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if strings.TrimSpace(glob) == "" {
+			return errors.New("coverage filter globs must not be empty")
+		}
+	}
+	return f.compile()
+}
+
+// compile compiles the include and exclude globs, unless that was
+// already done. Filters are only compiled once, so the globs must not
+// be changed after the filter was used.
+func (f *Filter) compile() error {
+	if f.includeRegexes != nil || f.excludeRegexes != nil {
+		return nil
+	}
+	include, err := compileGlobs(f.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := compileGlobs(f.Exclude)
+	if err != nil {
+		return err
+	}
+	f.includeRegexes, f.excludeRegexes = include, exclude
+	return nil
+}
+
+func compileGlobs(globs []string) ([]*globRegex, error) {
+	res := make([]*globRegex, 0, len(globs))
+	for _, glob := range globs {
+		slashGlob := filepath.ToSlash(glob)
+		re, err := regexp.Compile("^" + GlobToRegex(slashGlob) + "(/.*)?$")
+		if err != nil {
+			return nil, errors.Errorf("invalid coverage filter glob %q: %v", glob, err)
+		}
+		res = append(res, &globRegex{Regexp: re, absolute: strings.HasPrefix(slashGlob, "/")})
+	}
+	return res, nil
+}
+
+// Matches returns true if the file is matched by one of the include
+// globs (or if there are none) and by none of the exclude globs
+func (f *Filter) Matches(filename string) bool {
+	if f.Empty() {
+		return true
+	}
+	err := f.compile()
+	if err != nil {
+		// Invalid globs are rejected by Validate
+		return true
+	}
+	// A relative file name can be located in any of the source
+	// directories
+	filenames := []string{filename}
+	if !filepath.IsAbs(filename) && len(f.SourceDirs) > 0 {
+		filenames = nil
+		for _, dir := range f.SourceDirs {
+			filenames = append(filenames, filepath.Join(dir, filename))
+		}
+	}
+	matchesAny := func(regexes []*globRegex) bool {
+		for _, filename := range filenames {
+			if f.matchesAny(regexes, filename, f.relPath(filename)) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(f.includeRegexes) > 0 && !matchesAny(f.includeRegexes) {
+		return false
+	}
+	return !matchesAny(f.excludeRegexes)
+}
+
+func (f *Filter) matchesAny(regexes []*globRegex, filename, relPath string) bool {
+	for _, re := range regexes {
+		if re.absolute {
+			if re.MatchString(filepath.ToSlash(filename)) {
+				return true
+			}
+			continue
+		}
+		if re.MatchString(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// relPath returns the path of the file relative to the base directory
+// if the file is located below it and the file name otherwise
+func (f *Filter) relPath(filename string) string {
+	if f.BaseDir != "" && filepath.IsAbs(filename) {
+		rel, err := filepath.Rel(f.BaseDir, filename)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			filename = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filename), "./")
+}
+
+// IgnoreRegexes returns regular expressions which match the absolute
+// paths of the files excluded by the filter, which can be passed to
+// llvm-cov via -ignore-filename-regex
+func (f *Filter) IgnoreRegexes() []string {
+	if f == nil {
+		return nil
+	}
+	var res []string
+	for _, glob := range f.Exclude {
+		glob = filepath.ToSlash(glob)
+		prefix := ""
+		if !strings.HasPrefix(glob, "/") && f.BaseDir != "" {
+			prefix = regexp.QuoteMeta(filepath.ToSlash(f.BaseDir)) + "/"
+		}
+		res = append(res, "^"+prefix+GlobToRegex(glob)+"(/.*)?$")
+	}
+	return res
+}
+
+// GlobToRegex converts a path glob into an (unanchored) regular
+// expression. "**" matches any number of directories, "*" and "?"
+// match any characters or a single character except "/".
+func GlobToRegex(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// ApplyFilter removes the files which are not matched by the filter
+// from the summary and recalculates the total coverage from the
+// remaining files
+func (cs *CoverageSummary) ApplyFilter(f *Filter) {
+	if f.Empty() {
+		return
+	}
+	var files []*FileCoverage
+	total := &Coverage{}
+	for _, file := range cs.Files {
+		if !f.Matches(file.Filename) {
+			continue
+		}
+		files = append(files, file)
+		total.add(file.Coverage)
+	}
+	cs.Files = files
+	cs.Total = total
+}
+
+// FilterLcov removes the records of the files which are not matched by
+// the filter from an lcov report
+func FilterLcov(report string, f *Filter) string {
+	if f.Empty() {
+		return report
+	}
+	var sb strings.Builder
+	var record strings.Builder
+	keep := true
+	scanner := bufio.NewScanner(strings.NewReader(report))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "SF:") {
+			keep = f.Matches(strings.TrimPrefix(line, "SF:"))
+		}
+		record.WriteString(line + "\n")
+		if line == "end_of_record" {
+			if keep {
+				sb.WriteString(record.String())
+			}
+			record.Reset()
+			keep = true
+		}
+	}
+	if keep {
+		sb.WriteString(record.String())
+	}
+	return sb.String()
+}
+
+func (c *Coverage) add(other *Coverage) {
+	if other == nil {
+		return
+	}
+	c.FunctionsFound += other.FunctionsFound
+	c.FunctionsHit += other.FunctionsHit
+	c.BranchesFound += other.BranchesFound
+	c.BranchesHit += other.BranchesHit
+	c.LinesFound += other.LinesFound
+	c.LinesHit += other.LinesHit
+}
@@ -0,0 +1,93 @@
+package summary
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	f := &Filter{
+		Include: []string{"src", "lib/*.cpp"},
+		Exclude: []string{"src/generated", "**/*_test.cpp"},
+		BaseDir: "/project",
+	}
+
+	assert.True(t, f.Matches("/project/src/parser.cpp"))
+	assert.True(t, f.Matches("/project/src/a/b/parser.h"))
+	assert.True(t, f.Matches("src/parser.cpp"))
+	assert.True(t, f.Matches("/project/lib/util.cpp"))
+	assert.False(t, f.Matches("/project/lib/sub/util.cpp"))
+	assert.False(t, f.Matches("/project/src/generated/parser.pb.cc"))
+	assert.False(t, f.Matches("/project/src/parser_test.cpp"))
+	assert.False(t, f.Matches("/project/srcfoo/parser.cpp"))
+	assert.False(t, f.Matches("/usr/include/stdio.h"))
+
+	var empty *Filter
+	assert.True(t, empty.Matches("/usr/include/stdio.h"))
+}
+
+func TestFilter_IgnoreRegexes(t *testing.T) {
+	f := &Filter{
+		Exclude: []string{"third_party/**", "/usr/include"},
+		BaseDir: "/my.project",
+	}
+	regexes := f.IgnoreRegexes()
+	require.Len(t, regexes, 2)
+
+	re := regexp.MustCompile(regexes[0])
+	assert.True(t, re.MatchString("/my.project/third_party/zlib/inflate.c"))
+	assert.False(t, re.MatchString("/myXproject/third_party/zlib/inflate.c"))
+	assert.False(t, re.MatchString("/my.project/src/third_party.c"))
+
+	re = regexp.MustCompile(regexes[1])
+	assert.True(t, re.MatchString("/usr/include/stdio.h"))
+	assert.False(t, re.MatchString("/usr/include2/stdio.h"))
+}
+
+func TestCoverageSummary_ApplyFilter(t *testing.T) {
+	report := `SF:/project/src/foo.cpp
+FNH:1
+FNF:2
+LH:10
+LF:20
+end_of_record
+SF:/project/third_party/bar.cpp
+FNH:3
+FNF:4
+LH:30
+LF:40
+end_of_record
+`
+	f := &Filter{Exclude: []string{"third_party"}, BaseDir: "/project"}
+
+	summary := ParseLcov(strings.NewReader(report))
+	summary.ApplyFilter(f)
+	require.Len(t, summary.Files, 1)
+	assert.Equal(t, "/project/src/foo.cpp", summary.Files[0].Filename)
+	assert.Equal(t, &Coverage{FunctionsHit: 1, FunctionsFound: 2, LinesHit: 10, LinesFound: 20}, summary.Total)
+
+	filtered := FilterLcov(report, f)
+	assert.NotContains(t, filtered, "third_party")
+	assert.Equal(t, summary.Total, ParseLcov(strings.NewReader(filtered)).Total)
+}
+
+func TestFilter_MatchesWithSourceDirs(t *testing.T) {
+	// The file names of JaCoCo reports are relative to the source
+	// directory, the globs are relative to the project directory
+	f := &Filter{
+		Include:    []string{"src/main/java/com/example"},
+		Exclude:    []string{"src/main/java/com/example/generated", "**/*Stub.java"},
+		BaseDir:    "/project",
+		SourceDirs: []string{"/project/src/main/java", "/project/src/main/kotlin"},
+	}
+
+	assert.True(t, f.Matches("com/example/Parser.java"))
+	assert.False(t, f.Matches("com/example/generated/Proto.java"))
+	assert.False(t, f.Matches("com/example/ParserStub.java"))
+	assert.False(t, f.Matches("org/other/Util.java"))
+	assert.True(t, f.Matches("/project/src/main/java/com/example/Parser.java"))
+}
@@ -1,9 +1,15 @@
 package summary
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"code-intelligence.com/cifuzz/pkg/log"
 )
@@ -97,18 +103,158 @@ func ParseJacocoXML(in io.Reader) *CoverageSummary {
 
 	var currentFile *FileCoverage
 	for _, xmlPackage := range report.Packages {
+		files := make(map[string]*FileCoverage)
 		for _, sourcefile := range xmlPackage.Sourcefiles {
 			currentFile = &FileCoverage{
 				Filename: fmt.Sprintf("%s/%s", xmlPackage.Name, sourcefile.Name),
 				Coverage: &Coverage{},
+				LineHits: make(map[int]int),
 			}
 			for _, counter := range sourcefile.Counter {
 				countJacoco(summary.Total, &counter)
 				countJacoco(currentFile.Coverage, &counter)
 			}
+			// JaCoCo doesn't record execution counts, so we use the
+			// number of covered instructions of the line instead
+			for _, line := range sourcefile.Line {
+				nr, err := strconv.Atoi(line.Nr)
+				if err != nil {
+					log.Debugf("Parsing jacoco xml: invalid line number %s", line.Nr)
+					continue
+				}
+				coveredInstructions, err := strconv.Atoi(line.Ci)
+				if err != nil {
+					log.Debugf("Parsing jacoco xml: invalid number of covered instructions %s", line.Ci)
+					continue
+				}
+				currentFile.LineHits[nr] = coveredInstructions
+
+				missedBranches, _ := strconv.Atoi(line.Mb)
+				coveredBranches, _ := strconv.Atoi(line.Cb)
+				if missedBranches+coveredBranches > 0 {
+					if currentFile.LineBranches == nil {
+						currentFile.LineBranches = make(map[int]*Branches)
+					}
+					currentFile.LineBranches[nr] = &Branches{
+						Found: missedBranches + coveredBranches,
+						Hit:   coveredBranches,
+					}
+				}
+			}
+			files[sourcefile.Name] = currentFile
 			summary.Files = append(summary.Files, currentFile)
 		}
+
+		for _, class := range xmlPackage.Classes {
+			file, ok := files[class.Sourcefilename]
+			if !ok {
+				continue
+			}
+			for _, method := range class.Method {
+				for _, counter := range method.Counter {
+					if counter.Type != "METHOD" {
+						continue
+					}
+					if file.FunctionHits == nil {
+						file.FunctionHits = make(map[string]int)
+					}
+					name := strings.ReplaceAll(class.Name, "/", ".") + "." + method.Name + method.Desc
+					file.FunctionHits[name] = counter.Covered
+					if line, err := strconv.Atoi(method.Line); err == nil {
+						if file.FunctionLines == nil {
+							file.FunctionLines = make(map[string]int)
+						}
+						file.FunctionLines[name] = line
+					}
+				}
+			}
+		}
 	}
 
 	return summary
 }
+
+// FilterJacocoXML removes the source files which are not matched by the
+// filter and their classes from a JaCoCo XML report and recalculates
+// the counters of the packages and of the report. Packages without any
+// remaining source files are removed.
+func FilterJacocoXML(in io.Reader, out io.Writer, f *Filter) error {
+	report := &jacocoReport{}
+	err := xml.NewDecoder(in).Decode(report)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse JaCoCo XML report")
+	}
+
+	packages := report.Packages[:0]
+	report.Counter = nil
+	for _, p := range report.Packages {
+		sourcefiles := p.Sourcefiles[:0]
+		p.Counter = nil
+		for _, sourcefile := range p.Sourcefiles {
+			if !f.Matches(p.Name + "/" + sourcefile.Name) {
+				continue
+			}
+			sourcefiles = append(sourcefiles, sourcefile)
+			p.Counter = addJacocoCounters(p.Counter, sourcefile.Counter)
+		}
+		if len(sourcefiles) == 0 {
+			continue
+		}
+		p.Sourcefiles = sourcefiles
+
+		classes := p.Classes[:0]
+		for _, class := range p.Classes {
+			if f.Matches(p.Name + "/" + class.Sourcefilename) {
+				classes = append(classes, class)
+			}
+		}
+		p.Classes = classes
+
+		packages = append(packages, p)
+		report.Counter = addJacocoCounters(report.Counter, p.Counter)
+	}
+	report.Packages = packages
+
+	_, err = io.WriteString(out, xml.Header)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(xml.NewEncoder(out).Encode(report))
+}
+
+// FilterJacocoXMLFile applies the filter to the JaCoCo XML report at
+// the specified path, see FilterJacocoXML
+func FilterJacocoXMLFile(path string, f *Filter) error {
+	if f.Empty() {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var filtered bytes.Buffer
+	err = FilterJacocoXML(bytes.NewReader(content), &filtered, f)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(os.WriteFile(path, filtered.Bytes(), 0o644))
+}
+
+// addJacocoCounters adds the counters to the counters of the same type
+func addJacocoCounters(counters []jacocoCounter, other []jacocoCounter) []jacocoCounter {
+	for _, o := range other {
+		found := false
+		for i := range counters {
+			if counters[i].Type == o.Type {
+				counters[i].Missed += o.Missed
+				counters[i].Covered += o.Covered
+				found = true
+				break
+			}
+		}
+		if !found {
+			counters = append(counters, jacocoCounter{Type: o.Type, Missed: o.Missed, Covered: o.Covered})
+		}
+	}
+	return counters
+}
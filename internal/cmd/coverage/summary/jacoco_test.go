@@ -1,10 +1,12 @@
 package summary
 
 import (
+	"encoding/xml"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseJacoco(t *testing.T) {
@@ -57,3 +59,94 @@ func TestParseJacoco_Empty(t *testing.T) {
 	assert.Empty(t, summary.Total.LinesFound)
 	assert.Empty(t, summary.Total.FunctionsFound)
 }
+
+func TestParseJacoco_LinesAndMethods(t *testing.T) {
+	reportData := `
+<report name="maven-example">
+    <package name="com/example">
+        <class name="com/example/App" sourcefilename="App.java">
+            <method name="main" desc="([Ljava/lang/String;)V" line="3">
+                <counter type="METHOD" missed="0" covered="1"/>
+            </method>
+            <method name="unused" desc="()V" line="7">
+                <counter type="METHOD" missed="1" covered="0"/>
+            </method>
+        </class>
+        <sourcefile name="App.java">
+            <line nr="3" mi="0" ci="4" mb="1" cb="1"/>
+            <line nr="7" mi="2" ci="0" mb="0" cb="0"/>
+            <counter type="METHOD" missed="1" covered="1"/>
+        </sourcefile>
+    </package>
+</report>
+`
+	summary := ParseJacocoXML(strings.NewReader(reportData))
+	assert.Len(t, summary.Files, 1)
+	assert.Equal(t, map[int]int{3: 4, 7: 0}, summary.Files[0].LineHits)
+	assert.Equal(t, map[string]int{
+		"com.example.App.main([Ljava/lang/String;)V": 1,
+		"com.example.App.unused()V":                  0,
+	}, summary.Files[0].FunctionHits)
+	assert.Equal(t, map[int]*Branches{3: {Found: 2, Hit: 1}}, summary.Files[0].LineBranches)
+}
+
+func TestFilterJacocoXML(t *testing.T) {
+	reportData := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="maven-example">
+    <package name="com/example">
+        <class name="com/example/App" sourcefilename="App.java">
+            <counter type="LINE" missed="1" covered="3"/>
+        </class>
+        <class name="com/example/AppStub" sourcefilename="AppStub.java">
+            <counter type="LINE" missed="5" covered="0"/>
+        </class>
+        <sourcefile name="App.java">
+            <line nr="3" mi="0" ci="4" mb="1" cb="1"/>
+            <counter type="LINE" missed="1" covered="3"/>
+            <counter type="BRANCH" missed="1" covered="1"/>
+        </sourcefile>
+        <sourcefile name="AppStub.java">
+            <counter type="LINE" missed="5" covered="0"/>
+        </sourcefile>
+        <counter type="LINE" missed="6" covered="3"/>
+        <counter type="BRANCH" missed="1" covered="1"/>
+    </package>
+    <package name="com/example/generated">
+        <class name="com/example/generated/Proto" sourcefilename="Proto.java"/>
+        <sourcefile name="Proto.java">
+            <counter type="LINE" missed="10" covered="10"/>
+        </sourcefile>
+        <counter type="LINE" missed="10" covered="10"/>
+    </package>
+    <counter type="LINE" missed="16" covered="13"/>
+    <counter type="BRANCH" missed="1" covered="1"/>
+</report>
+`
+	f := &Filter{
+		Exclude:    []string{"src/main/java/com/example/generated", "**/*Stub.java"},
+		BaseDir:    "/project",
+		SourceDirs: []string{"/project/src/main/java"},
+	}
+	var filtered strings.Builder
+	err := FilterJacocoXML(strings.NewReader(reportData), &filtered, f)
+	require.NoError(t, err)
+	assert.NotContains(t, filtered.String(), "AppStub")
+	assert.NotContains(t, filtered.String(), "generated")
+
+	summary := ParseJacocoXML(strings.NewReader(filtered.String()))
+	require.Len(t, summary.Files, 1)
+	assert.Equal(t, "com/example/App.java", summary.Files[0].Filename)
+	assert.Equal(t, &Coverage{LinesFound: 4, LinesHit: 3, BranchesFound: 2, BranchesHit: 1}, summary.Total)
+	assert.Equal(t, map[int]int{3: 4}, summary.Files[0].LineHits)
+
+	report := &jacocoReport{}
+	require.NoError(t, xml.Unmarshal([]byte(filtered.String()), report))
+	require.Len(t, report.Packages, 1)
+	assert.Len(t, report.Packages[0].Classes, 1)
+	assert.Equal(t, []jacocoCounter{
+		{Type: "LINE", Missed: 1, Covered: 3},
+		{Type: "BRANCH", Missed: 1, Covered: 1},
+	}, report.Packages[0].Counter)
+	assert.Equal(t, report.Packages[0].Counter, report.Counter)
+}
@@ -0,0 +1,20 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// WriteJSON writes the coverage summary, including the line, branch
+// and function counts of each file, as JSON
+func (cs *CoverageSummary) WriteJSON(w io.Writer) error {
+	out, err := json.MarshalIndent(cs, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return errors.WithStack(err)
+}
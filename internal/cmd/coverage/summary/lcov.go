@@ -27,6 +27,96 @@ func count(c *Coverage, key string, value int) {
 	}
 }
 
+// parseLcovRecord parses the line and function coverage records of the
+// lcov tracefile format, which have the following formats:
+//
+//	DA:<line number>,<execution count>[,<checksum>]
+//	FN:<line number of function start>,[<line number of function end>,]<function name>
+//	FNDA:<execution count>,<function name>
+//	BRDA:<line number>,<block number>,<branch number>,<taken>
+func parseLcovRecord(file *FileCoverage, key string, value string) {
+	fields := strings.Split(value, ",")
+	if len(fields) < 2 {
+		log.Debugf("Parsing lcov: invalid value '%s' for key '%s'", value, key)
+		return
+	}
+
+	switch key {
+	case "DA":
+		line, err1 := strconv.Atoi(fields[0])
+		count, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			log.Debugf("Parsing lcov: invalid value '%s' for key '%s'", value, key)
+			return
+		}
+		if file.LineHits == nil {
+			file.LineHits = make(map[int]int)
+		}
+		file.LineHits[line] += count
+
+	case "FN":
+		// Demangled function names can contain commas, so the name is
+		// everything after the line number fields. The name can't
+		// consist of digits only, so a second numeric field is the end
+		// line.
+		nameStart := 1
+		if len(fields) > 2 {
+			if _, err := strconv.Atoi(fields[1]); err == nil {
+				nameStart = 2
+			}
+		}
+		name := strings.Join(fields[nameStart:], ",")
+		if file.FunctionHits == nil {
+			file.FunctionHits = make(map[string]int)
+		}
+		if _, ok := file.FunctionHits[name]; !ok {
+			file.FunctionHits[name] = 0
+		}
+		if line, err := strconv.Atoi(fields[0]); err == nil {
+			if file.FunctionLines == nil {
+				file.FunctionLines = make(map[string]int)
+			}
+			file.FunctionLines[name] = line
+		}
+
+	case "FNDA":
+		count, err := strconv.Atoi(fields[0])
+		if err != nil {
+			log.Debugf("Parsing lcov: invalid value '%s' for key '%s'", value, key)
+			return
+		}
+		if file.FunctionHits == nil {
+			file.FunctionHits = make(map[string]int)
+		}
+		file.FunctionHits[strings.Join(fields[1:], ",")] += count
+
+	case "BRDA":
+		if len(fields) < 4 {
+			log.Debugf("Parsing lcov: invalid value '%s' for key '%s'", value, key)
+			return
+		}
+		line, err := strconv.Atoi(fields[0])
+		if err != nil {
+			log.Debugf("Parsing lcov: invalid value '%s' for key '%s'", value, key)
+			return
+		}
+		if file.LineBranches == nil {
+			file.LineBranches = make(map[int]*Branches)
+		}
+		branches, ok := file.LineBranches[line]
+		if !ok {
+			branches = &Branches{}
+			file.LineBranches[line] = branches
+		}
+		branches.Found++
+		// The taken value is "-" if the basic block containing the
+		// branch was never executed
+		if taken, err := strconv.Atoi(fields[3]); err == nil && taken > 0 {
+			branches.Hit++
+		}
+	}
+}
+
 // ParseLcov takes a lcov tracefile report and turns it into
 // the `CoverageSummary` struct. The parsing is as forgiving
 // as possible. It will output debug/error logs instead of
@@ -78,9 +168,17 @@ func ParseLcov(in io.Reader) *CoverageSummary {
 				count(currentFile.Coverage, key, value)
 			}
 
+		// line, function and branch coverage
+		case "DA", "FN", "FNDA", "BRDA":
+			if currentFile == nil || len(parts) == 1 {
+				log.Debugf("Parsing lcov: Ignored key '%s' outside of a section", key)
+				break
+			}
+			parseLcovRecord(currentFile, key, parts[1])
+
 		// these keys are (currently) not relevant for cifuzz
 		// so we just ignore them
-		case "TN", "FN", "FNDA", "BRDA", "DA":
+		case "TN":
 			log.Debugf("Parsing lcov: Ignored key '%s'. Not implemented by now. ", key)
 
 		// this branch should only be reached if a key shows up
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseLcov(t *testing.T) {
@@ -131,3 +132,48 @@ func TestParseLcov_Empty(t *testing.T) {
 	assert.Empty(t, summary.Total.LinesFound)
 	assert.Empty(t, summary.Total.FunctionsFound)
 }
+
+func TestParseLcov_LinesAndFunctions(t *testing.T) {
+	report := `SF:foo.cpp
+FN:1,foo
+FN:5,9,bar
+FNDA:3,foo
+DA:1,3
+DA:2,0
+DA:5,0,checksum
+BRDA:1,0,0,3
+BRDA:1,0,1,0
+BRDA:5,0,0,-
+BRDA:5,0,1,-
+end_of_record
+`
+	summary := ParseLcov(strings.NewReader(report))
+	require.Len(t, summary.Files, 1)
+	assert.Equal(t, map[int]int{1: 3, 2: 0, 5: 0}, summary.Files[0].LineHits)
+	assert.Equal(t, map[string]int{"foo": 3, "bar": 0}, summary.Files[0].FunctionHits)
+	assert.Equal(t, map[string]int{"foo": 1, "bar": 5}, summary.Files[0].FunctionLines)
+	assert.Equal(t, map[int]*Branches{
+		1: {Found: 2, Hit: 1},
+		5: {Found: 2, Hit: 0},
+	}, summary.Files[0].LineBranches)
+}
+
+func TestParseLcov_FunctionNamesWithCommas(t *testing.T) {
+	report := `SF:foo.cpp
+FN:3,std::pair<int, int> foo<int, int>(int, int)
+FN:7,12,bar(char, char)
+FNDA:2,std::pair<int, int> foo<int, int>(int, int)
+FNDA:1,bar(char, char)
+end_of_record
+`
+	summary := ParseLcov(strings.NewReader(report))
+	require.Len(t, summary.Files, 1)
+	assert.Equal(t, map[string]int{
+		"std::pair<int, int> foo<int, int>(int, int)": 2,
+		"bar(char, char)": 1,
+	}, summary.Files[0].FunctionHits)
+	assert.Equal(t, map[string]int{
+		"std::pair<int, int> foo<int, int>(int, int)": 3,
+		"bar(char, char)": 7,
+	}, summary.Files[0].FunctionLines)
+}
@@ -0,0 +1,211 @@
+package summary
+
+import (
+	"encoding/json"
+	"io"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+type llvmCovCount struct {
+	Count   int `json:"count"`
+	Covered int `json:"covered"`
+}
+
+type llvmCovSummary struct {
+	Lines     llvmCovCount `json:"lines"`
+	Functions llvmCovCount `json:"functions"`
+	Branches  llvmCovCount `json:"branches"`
+}
+
+type llvmCovReport struct {
+	Data []struct {
+		Files []struct {
+			Filename string `json:"filename"`
+			// [line, column, count, has count, is region entry, is gap region]
+			Segments [][]any `json:"segments"`
+			// [line start, column start, line end, column end,
+			//  true count, false count, file ID, expanded file ID, kind]
+			Branches [][]any        `json:"branches"`
+			Summary  llvmCovSummary `json:"summary"`
+		} `json:"files"`
+		Functions []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+			// [line start, column start, line end, column end, count,
+			//  file ID, expanded file ID, kind]
+			Regions   [][]any  `json:"regions"`
+			Filenames []string `json:"filenames"`
+		} `json:"functions"`
+		Totals llvmCovSummary `json:"totals"`
+	} `json:"data"`
+}
+
+type llvmCovSegment struct {
+	line          int
+	count         int
+	hasCount      bool
+	isRegionEntry bool
+	isGapRegion   bool
+}
+
+func jsonInt(values []any, i int) int {
+	if i >= len(values) {
+		return 0
+	}
+	v, _ := values[i].(float64)
+	return int(v)
+}
+
+func jsonBool(values []any, i int) bool {
+	if i >= len(values) {
+		return false
+	}
+	switch v := values[i].(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	}
+	return false
+}
+
+func addLLVMCovSummary(c *Coverage, s *llvmCovSummary) {
+	c.LinesFound += s.Lines.Count
+	c.LinesHit += s.Lines.Covered
+	c.FunctionsFound += s.Functions.Count
+	c.FunctionsHit += s.Functions.Covered
+	c.BranchesFound += s.Branches.Count
+	c.BranchesHit += s.Branches.Covered
+}
+
+// llvmCovLineHits computes the execution counts of the lines from the
+// coverage segments the same way llvm-cov does for its line coverage
+func llvmCovLineHits(rawSegments [][]any) map[int]int {
+	var segments []*llvmCovSegment
+	for _, s := range rawSegments {
+		segments = append(segments, &llvmCovSegment{
+			line:          jsonInt(s, 0),
+			count:         jsonInt(s, 2),
+			hasCount:      jsonBool(s, 3),
+			isRegionEntry: jsonBool(s, 4),
+			isGapRegion:   jsonBool(s, 5),
+		})
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+
+	lineHits := make(map[int]int)
+	isStartOfRegion := func(s *llvmCovSegment) bool {
+		return !s.isGapRegion && s.hasCount && s.isRegionEntry
+	}
+	var wrapped *llvmCovSegment
+	next := 0
+	for line := segments[0].line; line <= segments[len(segments)-1].line; line++ {
+		var lineSegments []*llvmCovSegment
+		for next < len(segments) && segments[next].line == line {
+			lineSegments = append(lineSegments, segments[next])
+			next++
+		}
+
+		regionStarts := 0
+		for _, s := range lineSegments {
+			if isStartOfRegion(s) {
+				regionStarts++
+			}
+		}
+		startOfSkippedRegion := len(lineSegments) > 0 && !lineSegments[0].hasCount && lineSegments[0].isRegionEntry
+		mapped := !startOfSkippedRegion && ((wrapped != nil && wrapped.hasCount) || regionStarts > 0)
+		if mapped {
+			count := 0
+			if wrapped != nil {
+				count = wrapped.count
+			}
+			for _, s := range lineSegments {
+				if isStartOfRegion(s) && s.count > count {
+					count = s.count
+				}
+			}
+			lineHits[line] = count
+		}
+
+		if len(lineSegments) > 0 {
+			wrapped = lineSegments[len(lineSegments)-1]
+		}
+	}
+	return lineHits
+}
+
+// ParseLLVMCovJSON takes a report created by `llvm-cov export
+// -format=text` and turns it into the `CoverageSummary` struct. The
+// parsing is as forgiving as possible. It will output debug/error logs
+// instead of failing, with the goal to gather as much information as
+// possible
+func ParseLLVMCovJSON(in io.Reader) *CoverageSummary {
+	summary := &CoverageSummary{
+		Total: &Coverage{},
+	}
+
+	report := &llvmCovReport{}
+	err := json.NewDecoder(in).Decode(report)
+	if err != nil {
+		log.Debugf("Unable to parse llvm-cov JSON report: %v", err)
+		return summary
+	}
+
+	for _, data := range report.Data {
+		addLLVMCovSummary(summary.Total, &data.Totals)
+
+		files := make(map[string]*FileCoverage)
+		for _, f := range data.Files {
+			file := &FileCoverage{
+				Filename: f.Filename,
+				Coverage: &Coverage{},
+				LineHits: llvmCovLineHits(f.Segments),
+			}
+			addLLVMCovSummary(file.Coverage, &f.Summary)
+			for _, branch := range f.Branches {
+				if file.LineBranches == nil {
+					file.LineBranches = make(map[int]*Branches)
+				}
+				line := jsonInt(branch, 0)
+				branches, ok := file.LineBranches[line]
+				if !ok {
+					branches = &Branches{}
+					file.LineBranches[line] = branches
+				}
+				// Each branch has a true and a false outcome
+				branches.Found += 2
+				if jsonInt(branch, 4) > 0 {
+					branches.Hit++
+				}
+				if jsonInt(branch, 5) > 0 {
+					branches.Hit++
+				}
+			}
+			files[f.Filename] = file
+			summary.Files = append(summary.Files, file)
+		}
+
+		for _, function := range data.Functions {
+			if len(function.Filenames) == 0 {
+				continue
+			}
+			file, ok := files[function.Filenames[0]]
+			if !ok {
+				continue
+			}
+			if file.FunctionHits == nil {
+				file.FunctionHits = make(map[string]int)
+				file.FunctionLines = make(map[string]int)
+			}
+			file.FunctionHits[function.Name] += function.Count
+			if len(function.Regions) > 0 {
+				file.FunctionLines[function.Name] = jsonInt(function.Regions[0], 0)
+			}
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,64 @@
+package summary
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLLVMCovJSON(t *testing.T) {
+	report := `{
+  "type": "llvm.coverage.json.export",
+  "version": "2.0.1",
+  "data": [{
+    "files": [{
+      "filename": "/project/src/foo.c",
+      "segments": [
+        [1, 16, 5, true, true, false],
+        [2, 12, 3, true, true, true],
+        [3, 5, 3, true, true, false],
+        [3, 13, 2, true, false, false],
+        [4, 3, 2, true, true, false],
+        [5, 2, 0, false, false, false],
+        [7, 1, 0, false, true, false],
+        [9, 2, 0, false, false, false]
+      ],
+      "branches": [[2, 7, 2, 12, 3, 2, 0, 0, 4]],
+      "summary": {
+        "lines": {"count": 5, "covered": 5},
+        "functions": {"count": 1, "covered": 1},
+        "branches": {"count": 2, "covered": 2}
+      }
+    }],
+    "functions": [{
+      "name": "foo",
+      "count": 5,
+      "regions": [[1, 16, 5, 2, 5, 0, 0, 0]],
+      "filenames": ["/project/src/foo.c"]
+    }],
+    "totals": {
+      "lines": {"count": 5, "covered": 5},
+      "functions": {"count": 1, "covered": 1},
+      "branches": {"count": 2, "covered": 2}
+    }
+  }]
+}`
+	summary := ParseLLVMCovJSON(strings.NewReader(report))
+	assert.Equal(t, &Coverage{LinesFound: 5, LinesHit: 5, FunctionsFound: 1, FunctionsHit: 1, BranchesFound: 2, BranchesHit: 2}, summary.Total)
+	require.Len(t, summary.Files, 1)
+	file := summary.Files[0]
+	assert.Equal(t, "/project/src/foo.c", file.Filename)
+	// Line 7 is the start of a skipped region and therefore not mapped
+	assert.Equal(t, map[int]int{1: 5, 2: 5, 3: 3, 4: 2, 5: 2}, file.LineHits)
+	assert.Equal(t, map[int]*Branches{2: {Found: 2, Hit: 2}}, file.LineBranches)
+	assert.Equal(t, map[string]int{"foo": 5}, file.FunctionHits)
+	assert.Equal(t, map[string]int{"foo": 1}, file.FunctionLines)
+}
+
+func TestParseLLVMCovJSON_Invalid(t *testing.T) {
+	summary := ParseLLVMCovJSON(strings.NewReader("not json"))
+	assert.Empty(t, summary.Files)
+	assert.Equal(t, &Coverage{}, summary.Total)
+}
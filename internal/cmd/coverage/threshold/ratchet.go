@@ -0,0 +1,84 @@
+package threshold
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// LoadBaseline reads the coverage stored in a baseline file. If the file
+// doesn't exist, nil is returned.
+func LoadBaseline(path string) (*Measurement, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	baseline := &Measurement{}
+	err = json.Unmarshal(content, baseline)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse coverage baseline %s", path)
+	}
+	return baseline, nil
+}
+
+// SaveBaseline stores the coverage in the baseline file. The coverage
+// of the baseline never decreases: If the measured coverage is lower
+// than the coverage of the previous baseline (which is within the
+// tolerance of the ratchet checks), the previous value is kept.
+// Returns true if the baseline was changed.
+func SaveBaseline(path string, m *Measurement, previous *Measurement) (bool, error) {
+	baseline := &Measurement{Total: m.Total, Paths: m.Paths}
+	if previous != nil {
+		baseline = &Measurement{
+			Total: maxCoverage(m.Total, previous.Total),
+			Paths: make(map[string]*Coverage),
+		}
+		for p, c := range m.Paths {
+			baseline.Paths[p] = maxCoverage(c, previous.Paths[p])
+		}
+		if len(baseline.Paths) == 0 {
+			baseline.Paths = nil
+		}
+		if reflect.DeepEqual(baseline, previous) {
+			return false, nil
+		}
+	}
+
+	content, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	err = os.WriteFile(path, append(content, '\n'), 0o644)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+func maxCoverage(c *Coverage, previous *Coverage) *Coverage {
+	if previous == nil {
+		return c
+	}
+	return &Coverage{
+		LineCoverage:   maxPercent(c.LineCoverage, previous.LineCoverage),
+		BranchCoverage: maxPercent(c.BranchCoverage, previous.BranchCoverage),
+	}
+}
+
+// maxPercent returns the higher coverage, ignoring missing data
+func maxPercent(p *float64, previous *float64) *float64 {
+	if p == nil {
+		return previous
+	}
+	if previous == nil {
+		return p
+	}
+	m := math.Max(*p, *previous)
+	return &m
+}
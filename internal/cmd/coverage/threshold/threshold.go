@@ -0,0 +1,278 @@
+// Package threshold checks the coverage of a coverage report against
+// the minimum line and branch coverage configured for the project and
+// against a stored baseline.
+package threshold
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-zglob"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+// Options specifies the coverage thresholds. They can be set in the
+// `coverage-thresholds` section of the project config. Thresholds are
+// percentages, zero means that no threshold is checked.
+type Options struct {
+	MinLineCoverage   float64 `mapstructure:"min-line-coverage"`
+	MinBranchCoverage float64 `mapstructure:"min-branch-coverage"`
+	// Thresholds for the files below particular paths
+	Paths []*PathRule `mapstructure:"paths"`
+	// A file storing the coverage of a previous run. If set, the
+	// check fails if the coverage decreased compared to the baseline.
+	RatchetBaseline string `mapstructure:"ratchet-baseline"`
+}
+
+// PathRule specifies the thresholds for the files which are in the
+// directory Path (relative to the project directory), are the file Path
+// or match the glob pattern Path
+type PathRule struct {
+	Path              string  `mapstructure:"path"`
+	MinLineCoverage   float64 `mapstructure:"min-line-coverage"`
+	MinBranchCoverage float64 `mapstructure:"min-branch-coverage"`
+}
+
+// Enabled returns true if any threshold or a ratchet baseline is
+// configured
+func (opts *Options) Enabled() bool {
+	return opts != nil && (opts.MinLineCoverage > 0 || opts.MinBranchCoverage > 0 ||
+		len(opts.Paths) > 0 || opts.RatchetBaseline != "")
+}
+
+// ResolvePaths makes the path of the ratchet baseline relative to the
+// specified directory, which is usually the project directory.
+func (opts *Options) ResolvePaths(dir string) {
+	if opts.RatchetBaseline != "" && !filepath.IsAbs(opts.RatchetBaseline) {
+		opts.RatchetBaseline = filepath.Join(dir, opts.RatchetBaseline)
+	}
+}
+
+// Coverage is the line and branch coverage in percent. A metric is nil
+// if the report contains no lines or branches of the files, e.g. if the
+// coverage tool doesn't measure branch coverage.
+type Coverage struct {
+	LineCoverage   *float64 `json:"line_coverage"`
+	BranchCoverage *float64 `json:"branch_coverage"`
+}
+
+func (c *Coverage) metric(metric string) *float64 {
+	if metric == MetricBranches {
+		return c.BranchCoverage
+	}
+	return c.LineCoverage
+}
+
+// Measurement is the coverage of the whole report and of the paths of
+// the path rules. Paths which don't match any file are omitted.
+type Measurement struct {
+	Total *Coverage            `json:"total"`
+	Paths map[string]*Coverage `json:"paths,omitempty"`
+}
+
+const totalName = "Total"
+
+// Metrics of the checks
+const (
+	MetricLines    = "lines"
+	MetricBranches = "branches"
+)
+
+// Check is the result of checking a coverage metric of the whole report
+// (Path is "Total") or of a path against a threshold. For ratchet
+// checks, the required value is the coverage of the baseline.
+type Check struct {
+	Path     string  `json:"path"`
+	Metric   string  `json:"metric"`
+	Required float64 `json:"required"`
+	// Nil if no file of the report matched the path or if the matching
+	// files have no data for the metric
+	Actual *float64 `json:"actual"`
+	// True if files matched the path, but the report has no data for
+	// the metric. The check fails in that case, because the coverage
+	// can't be verified.
+	NoData  bool `json:"no_data,omitempty"`
+	Ratchet bool `json:"ratchet,omitempty"`
+	Passed  bool `json:"passed"`
+}
+
+type Result struct {
+	Checks []*Check `json:"checks"`
+}
+
+// Ratchet checks allow for a small decrease of the coverage to avoid
+// failing due to rounding
+const ratchetTolerance = 0.01
+
+// percent returns the coverage in percent or nil if nothing was found
+func percent(hit, found int) *float64 {
+	if found == 0 {
+		return nil
+	}
+	p := float64(hit) * 100 / float64(found)
+	return &p
+}
+
+func cleanRulePath(p string) string {
+	return strings.TrimSuffix(path.Clean(filepath.ToSlash(p)), "/")
+}
+
+func matches(rulePath string, filename string) bool {
+	if filename == rulePath || strings.HasPrefix(filename, rulePath+"/") {
+		return true
+	}
+	matched, err := zglob.Match(rulePath, filename)
+	return err == nil && matched
+}
+
+// Measure returns the coverage of the report and of the paths of the
+// path rules. The filenames of the report are matched relative to the
+// project directory.
+func Measure(cs *summary.CoverageSummary, opts *Options, projectDir string) *Measurement {
+	m := &Measurement{
+		Total: &Coverage{
+			LineCoverage:   percent(cs.Total.LinesHit, cs.Total.LinesFound),
+			BranchCoverage: percent(cs.Total.BranchesHit, cs.Total.BranchesFound),
+		},
+	}
+
+	for _, rule := range opts.Paths {
+		rulePath := cleanRulePath(rule.Path)
+		var c summary.Coverage
+		found := false
+		for _, file := range cs.Files {
+			filename := file.Filename
+			if filepath.IsAbs(filename) {
+				rel, err := filepath.Rel(projectDir, filename)
+				if err == nil {
+					filename = rel
+				}
+			}
+			if !matches(rulePath, filepath.ToSlash(filename)) {
+				continue
+			}
+			found = true
+			c.LinesFound += file.Coverage.LinesFound
+			c.LinesHit += file.Coverage.LinesHit
+			c.BranchesFound += file.Coverage.BranchesFound
+			c.BranchesHit += file.Coverage.BranchesHit
+		}
+		if found {
+			if m.Paths == nil {
+				m.Paths = make(map[string]*Coverage)
+			}
+			m.Paths[rulePath] = &Coverage{
+				LineCoverage:   percent(c.LinesHit, c.LinesFound),
+				BranchCoverage: percent(c.BranchesHit, c.BranchesFound),
+			}
+		}
+	}
+	return m
+}
+
+// Evaluate checks the measured coverage against the thresholds and, if
+// a baseline is specified, against the coverage of the baseline
+func Evaluate(m *Measurement, opts *Options, baseline *Measurement) *Result {
+	result := &Result{}
+	addCheck := func(name string, metric string, required float64, actual *Coverage, ratchet bool) {
+		check := &Check{Path: name, Metric: metric, Required: required, Ratchet: ratchet}
+		if actual != nil {
+			check.Actual = actual.metric(metric)
+			check.NoData = check.Actual == nil
+		}
+		if check.Actual != nil {
+			tolerance := 0.0
+			if ratchet {
+				tolerance = ratchetTolerance
+			}
+			check.Passed = *check.Actual+tolerance >= required
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	addThresholds := func(name string, minLines, minBranches float64, actual *Coverage) {
+		if minLines > 0 {
+			addCheck(name, MetricLines, minLines, actual, false)
+		}
+		if minBranches > 0 {
+			addCheck(name, MetricBranches, minBranches, actual, false)
+		}
+	}
+
+	addThresholds(totalName, opts.MinLineCoverage, opts.MinBranchCoverage, m.Total)
+	for _, rule := range opts.Paths {
+		rulePath := cleanRulePath(rule.Path)
+		addThresholds(rulePath, rule.MinLineCoverage, rule.MinBranchCoverage, m.Paths[rulePath])
+	}
+
+	if baseline != nil {
+		addRatchet := func(name string, old *Coverage, actual *Coverage) {
+			// Metrics without data in the baseline can't decrease
+			for _, metric := range []string{MetricLines, MetricBranches} {
+				if required := old.metric(metric); required != nil {
+					addCheck(name, metric, *required, actual, true)
+				}
+			}
+		}
+		if baseline.Total != nil {
+			addRatchet(totalName, baseline.Total, m.Total)
+		}
+		var paths []string
+		for p := range baseline.Paths {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			addRatchet(p, baseline.Paths[p], m.Paths[p])
+		}
+	}
+	return result
+}
+
+// Failed returns the checks which failed
+func (r *Result) Failed() []*Check {
+	var failed []*Check
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// PrintTable prints the checks and whether they passed or failed
+func (r *Result) PrintTable(w io.Writer) error {
+	if len(r.Checks) == 0 {
+		return nil
+	}
+	data := pterm.TableData{{"Path", "Metric", "Required", "Actual", "Result"}}
+	for _, check := range r.Checks {
+		metric := check.Metric
+		if check.Ratchet {
+			metric += " (baseline)"
+		}
+		actual := "no files"
+		if check.Actual != nil {
+			actual = fmt.Sprintf("%.1f%%", *check.Actual)
+		} else if check.NoData {
+			actual = "no data"
+		}
+		status := pterm.Green("pass")
+		if !check.Passed {
+			status = pterm.Red("FAIL")
+		}
+		data = append(data, []string{check.Path, metric, fmt.Sprintf("%.1f%%", check.Required), actual, status})
+	}
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(data).Srender()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, _ = fmt.Fprintln(w, table)
+	return nil
+}
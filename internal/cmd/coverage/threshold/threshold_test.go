@@ -0,0 +1,131 @@
+package threshold
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+func testSummary() *summary.CoverageSummary {
+	return &summary.CoverageSummary{
+		Total: &summary.Coverage{LinesFound: 40, LinesHit: 30, BranchesFound: 20, BranchesHit: 10},
+		Files: []*summary.FileCoverage{
+			{
+				Filename: "/project/src/parser/parser.c",
+				Coverage: &summary.Coverage{LinesFound: 20, LinesHit: 19, BranchesFound: 10, BranchesHit: 8},
+			},
+			{
+				Filename: "/project/src/util.c",
+				Coverage: &summary.Coverage{LinesFound: 20, LinesHit: 11, BranchesFound: 10, BranchesHit: 2},
+			},
+		},
+	}
+}
+
+func percentage(p float64) *float64 {
+	return &p
+}
+
+func TestMeasure(t *testing.T) {
+	opts := &Options{Paths: []*PathRule{
+		{Path: "src/parser/"},
+		{Path: "src/*.c"},
+		{Path: "does/not/exist"},
+	}}
+	m := Measure(testSummary(), opts, "/project")
+	assert.Equal(t, &Coverage{LineCoverage: percentage(75), BranchCoverage: percentage(50)}, m.Total)
+	assert.Equal(t, map[string]*Coverage{
+		"src/parser": {LineCoverage: percentage(95), BranchCoverage: percentage(80)},
+		"src/*.c":    {LineCoverage: percentage(55), BranchCoverage: percentage(20)},
+	}, m.Paths)
+}
+
+func TestEvaluate(t *testing.T) {
+	opts := &Options{
+		MinLineCoverage: 70,
+		Paths: []*PathRule{
+			{Path: "src/parser", MinLineCoverage: 90, MinBranchCoverage: 90},
+			{Path: "does/not/exist", MinLineCoverage: 10},
+		},
+	}
+	result := Evaluate(Measure(testSummary(), opts, "/project"), opts, nil)
+	require.Len(t, result.Checks, 4)
+	assert.True(t, result.Checks[0].Passed)
+	assert.True(t, result.Checks[1].Passed)
+	assert.Equal(t, "src/parser", result.Checks[2].Path)
+	assert.Equal(t, MetricBranches, result.Checks[2].Metric)
+	assert.False(t, result.Checks[2].Passed)
+	assert.Nil(t, result.Checks[3].Actual)
+	assert.False(t, result.Checks[3].Passed)
+	assert.Len(t, result.Failed(), 2)
+}
+
+func TestEvaluate_NoBranchData(t *testing.T) {
+	// The report doesn't contain branch coverage, e.g. because the
+	// coverage tool doesn't measure it
+	cs := testSummary()
+	cs.Total.BranchesFound = 0
+	cs.Total.BranchesHit = 0
+	opts := &Options{MinLineCoverage: 70, MinBranchCoverage: 10}
+
+	m := Measure(cs, opts, "/project")
+	assert.Nil(t, m.Total.BranchCoverage)
+	result := Evaluate(m, opts, nil)
+	require.Len(t, result.Checks, 2)
+	assert.True(t, result.Checks[0].Passed)
+	assert.Equal(t, MetricBranches, result.Checks[1].Metric)
+	assert.Nil(t, result.Checks[1].Actual)
+	assert.True(t, result.Checks[1].NoData)
+	assert.False(t, result.Checks[1].Passed)
+
+	// Metrics without data in the ratchet baseline aren't checked
+	ratchetOpts := &Options{RatchetBaseline: "baseline.json"}
+	result = Evaluate(m, ratchetOpts, m)
+	require.Len(t, result.Checks, 1)
+	assert.Equal(t, MetricLines, result.Checks[0].Metric)
+	assert.True(t, result.Checks[0].Passed)
+
+	// Missing data fails the ratchet check if the baseline has data
+	result = Evaluate(m, ratchetOpts, Measure(testSummary(), ratchetOpts, "/project"))
+	require.Len(t, result.Failed(), 1)
+	assert.Equal(t, MetricBranches, result.Failed()[0].Metric)
+	assert.True(t, result.Failed()[0].NoData)
+}
+
+func TestRatchet(t *testing.T) {
+	baselinePath := filepath.Join(t.TempDir(), "baseline.json")
+	opts := &Options{RatchetBaseline: baselinePath}
+
+	// Without an existing baseline, the check passes and the baseline
+	// is created
+	baseline, err := LoadBaseline(baselinePath)
+	require.NoError(t, err)
+	require.Nil(t, baseline)
+	m := Measure(testSummary(), opts, "/project")
+	assert.Empty(t, Evaluate(m, opts, baseline).Failed())
+	changed, err := SaveBaseline(baselinePath, m, baseline)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// The same coverage doesn't change the baseline
+	baseline, err = LoadBaseline(baselinePath)
+	require.NoError(t, err)
+	assert.Equal(t, m, baseline)
+	assert.Empty(t, Evaluate(m, opts, baseline).Failed())
+	changed, err = SaveBaseline(baselinePath, m, baseline)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// A decreased coverage fails the ratchet check
+	decreased := testSummary()
+	decreased.Total.BranchesHit = 9
+	result := Evaluate(Measure(decreased, opts, "/project"), opts, baseline)
+	require.Len(t, result.Failed(), 1)
+	assert.Equal(t, MetricBranches, result.Failed()[0].Metric)
+	assert.True(t, result.Failed()[0].Ratchet)
+	assert.Equal(t, 50.0, result.Failed()[0].Required)
+}
@@ -0,0 +1,128 @@
+package timeline
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The size of the plot area of the chart in pixels
+const (
+	chartWidth  = 720
+	chartHeight = 300
+	chartMargin = 50
+)
+
+var htmlTemplate = template.Must(template.New("timeline").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage Timeline</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-top: 1em; }
+td, th { padding: 0.2em 1em; text-align: right; }
+.axis { stroke: #888; }
+.grid { stroke: #eee; }
+.label { font-size: 11px; fill: #555; }
+.lines { stroke: #1a7f37; fill: none; stroke-width: 2; }
+.branches { stroke: #0969da; fill: none; stroke-width: 2; }
+.legend-lines { color: #1a7f37; }
+.legend-branches { color: #0969da; }
+</style>
+</head>
+<body>
+<h1>Coverage Timeline</h1>
+<p>
+<span class="legend-lines">&#9632; Line coverage</span>
+<span class="legend-branches">&#9632; Branch coverage</span>
+</p>
+<svg width="{{.Width}}" height="{{.Height}}">
+{{range .YTicks}}<line class="grid" x1="{{$.Left}}" y1="{{.Y}}" x2="{{$.Right}}" y2="{{.Y}}"/>
+<text class="label" x="{{$.TickX}}" y="{{.Y}}" text-anchor="end" dominant-baseline="middle">{{.Label}}</text>
+{{end}}<line class="axis" x1="{{.Left}}" y1="{{.Bottom}}" x2="{{.Right}}" y2="{{.Bottom}}"/>
+<line class="axis" x1="{{.Left}}" y1="{{.Top}}" x2="{{.Left}}" y2="{{.Bottom}}"/>
+{{range .XTicks}}<text class="label" x="{{.X}}" y="{{$.XLabelY}}" text-anchor="middle">{{.Label}}</text>
+{{end}}<text class="label" x="{{.CenterX}}" y="{{.Height}}" text-anchor="middle" dy="-4">Inputs</text>
+<polyline class="lines" points="{{.LinePoints}}"/>
+<polyline class="branches" points="{{.BranchPoints}}"/>
+</svg>
+<table>
+<tr><th>Chunk</th><th>Inputs</th><th>Newest Input</th><th>Lines Hit/Found</th><th>Branches Hit/Found</th></tr>
+{{range .Points}}<tr><td>{{.Chunk}}</td><td>{{.Inputs}}</td><td>{{if not .Time.IsZero}}{{.Time.Format "2006-01-02 15:04:05"}}{{end}}</td><td>{{.LinesHit}} / {{.LinesFound}} ({{printf "%.1f" .LineCoverage}}%)</td><td>{{.BranchesHit}} / {{.BranchesFound}} ({{printf "%.1f" .BranchCoverage}}%)</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type tick struct {
+	X, Y  int
+	Label string
+}
+
+// WriteHTML writes an HTML page with a chart of the line and branch
+// coverage over the number of replayed inputs
+func (t *Timeline) WriteHTML(w io.Writer) error {
+	left, top := chartMargin, chartMargin/2
+	right, bottom := left+chartWidth, top+chartHeight
+
+	maxInputs := 0
+	for _, p := range t.Points {
+		if p.Inputs > maxInputs {
+			maxInputs = p.Inputs
+		}
+	}
+	x := func(inputs int) int {
+		if maxInputs == 0 {
+			return left
+		}
+		return left + inputs*chartWidth/maxInputs
+	}
+	y := func(percent float64) int {
+		return bottom - int(percent*chartHeight/100)
+	}
+
+	var linePoints, branchPoints []string
+	for _, p := range t.Points {
+		linePoints = append(linePoints, fmt.Sprintf("%d,%d", x(p.Inputs), y(p.LineCoverage)))
+		branchPoints = append(branchPoints, fmt.Sprintf("%d,%d", x(p.Inputs), y(p.BranchCoverage)))
+	}
+
+	var yTicks []tick
+	for percent := 0; percent <= 100; percent += 20 {
+		yTicks = append(yTicks, tick{Y: y(float64(percent)), Label: fmt.Sprintf("%d%%", percent)})
+	}
+	var xTicks []tick
+	for i := 0; i <= 4; i++ {
+		inputs := maxInputs * i / 4
+		xTicks = append(xTicks, tick{X: x(inputs), Label: fmt.Sprint(inputs)})
+	}
+
+	data := struct {
+		*Timeline
+		Width, Height            int
+		Left, Right, Top, Bottom int
+		TickX, XLabelY, CenterX  int
+		YTicks, XTicks           []tick
+		LinePoints, BranchPoints string
+	}{
+		Timeline:     t,
+		Width:        right + chartMargin/2,
+		Height:       bottom + chartMargin,
+		Left:         left,
+		Right:        right,
+		Top:          top,
+		Bottom:       bottom,
+		TickX:        left - 6,
+		XLabelY:      bottom + 16,
+		CenterX:      (left + right) / 2,
+		YTicks:       yTicks,
+		XTicks:       xTicks,
+		LinePoints:   strings.Join(linePoints, " "),
+		BranchPoints: strings.Join(branchPoints, " "),
+	}
+	return errors.WithStack(htmlTemplate.Execute(w, data))
+}
@@ -0,0 +1,173 @@
+package timeline
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+// Input is an input of the corpus of a fuzz test
+type Input struct {
+	Path string
+	// The modification time of the input, which is the time at which
+	// the fuzzer added it to the generated corpus
+	Time time.Time
+}
+
+// Point is the coverage after replaying the inputs of a chunk and all
+// previous chunks
+type Point struct {
+	Chunk int `json:"chunk"`
+	// The number of inputs replayed so far
+	Inputs int `json:"inputs"`
+	// The creation time of the newest input replayed so far, zero if
+	// no input was replayed
+	Time           time.Time `json:"time"`
+	LinesHit       int       `json:"lines_hit"`
+	LinesFound     int       `json:"lines_found"`
+	BranchesHit    int       `json:"branches_hit"`
+	BranchesFound  int       `json:"branches_found"`
+	LineCoverage   float64   `json:"line_coverage"`
+	BranchCoverage float64   `json:"branch_coverage"`
+}
+
+// Timeline records how the coverage grew while the corpus was
+// generated
+type Timeline struct {
+	Points []*Point `json:"points"`
+}
+
+// ListInputs returns the inputs in the directories, ordered by their
+// creation time
+func ListInputs(dirs []string) ([]*Input, error) {
+	var inputs []*Input
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			inputs = append(inputs, &Input{Path: path, Time: info.ModTime()})
+			return nil
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	sort.SliceStable(inputs, func(i, j int) bool {
+		if inputs[i].Time.Equal(inputs[j].Time) {
+			return inputs[i].Path < inputs[j].Path
+		}
+		return inputs[i].Time.Before(inputs[j].Time)
+	})
+	return inputs, nil
+}
+
+// Split splits the inputs into the specified number of chunks of about
+// the same size, keeping their order. If there are fewer inputs than
+// chunks, the last chunks are empty.
+func Split(inputs []*Input, chunks int) [][]*Input {
+	res := make([][]*Input, chunks)
+	for i := range res {
+		if len(inputs) < chunks {
+			// Use one input per chunk
+			if i < len(inputs) {
+				res[i] = inputs[i : i+1]
+			}
+			continue
+		}
+		res[i] = inputs[i*len(inputs)/chunks : (i+1)*len(inputs)/chunks]
+	}
+	return res
+}
+
+// Add adds the coverage after replaying the specified number of inputs,
+// the newest of which was created at the specified time
+func (t *Timeline) Add(inputs int, newest time.Time, c *summary.Coverage) {
+	t.Points = append(t.Points, &Point{
+		Chunk:          len(t.Points),
+		Inputs:         inputs,
+		Time:           newest,
+		LinesHit:       c.LinesHit,
+		LinesFound:     c.LinesFound,
+		BranchesHit:    c.BranchesHit,
+		BranchesFound:  c.BranchesFound,
+		LineCoverage:   percent(c.LinesHit, c.LinesFound),
+		BranchCoverage: percent(c.BranchesHit, c.BranchesFound),
+	})
+}
+
+// Write writes the timeline to the file as CSV if it has the .csv
+// extension and as JSON otherwise
+func (t *Timeline) Write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = t.WriteCSV(f)
+	} else {
+		err = t.WriteJSON(f)
+	}
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(f.Close())
+}
+
+// WriteJSON writes the timeline as JSON
+func (t *Timeline) WriteJSON(w io.Writer) error {
+	out, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return errors.WithStack(err)
+}
+
+// WriteCSV writes the timeline as CSV with a header row
+func (t *Timeline) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{
+		"chunk", "inputs", "time",
+		"lines_hit", "lines_found", "line_coverage",
+		"branches_hit", "branches_found", "branch_coverage",
+	}}
+	for _, p := range t.Points {
+		timestamp := ""
+		if !p.Time.IsZero() {
+			timestamp = p.Time.UTC().Format(time.RFC3339)
+		}
+		records = append(records, []string{
+			fmt.Sprint(p.Chunk), fmt.Sprint(p.Inputs), timestamp,
+			fmt.Sprint(p.LinesHit), fmt.Sprint(p.LinesFound), fmt.Sprintf("%.2f", p.LineCoverage),
+			fmt.Sprint(p.BranchesHit), fmt.Sprint(p.BranchesFound), fmt.Sprintf("%.2f", p.BranchCoverage),
+		})
+	}
+	return errors.WithStack(cw.WriteAll(records))
+}
+
+func percent(hit, found int) float64 {
+	if found == 0 {
+		return 0
+	}
+	return float64(hit) * 100 / float64(found)
+}
@@ -0,0 +1,83 @@
+package timeline
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmd/coverage/summary"
+)
+
+func TestListInputs(t *testing.T) {
+	seedCorpus := t.TempDir()
+	generatedCorpus := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	files := map[string]time.Time{
+		filepath.Join(generatedCorpus, "b"): start.Add(2 * time.Minute),
+		filepath.Join(seedCorpus, "a"):      start,
+		filepath.Join(generatedCorpus, "c"): start.Add(time.Minute),
+	}
+	for path, modTime := range files {
+		require.NoError(t, os.WriteFile(path, []byte("x"), 0o644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	inputs, err := ListInputs([]string{seedCorpus, generatedCorpus})
+	require.NoError(t, err)
+	require.Len(t, inputs, 3)
+	assert.Equal(t, filepath.Join(seedCorpus, "a"), inputs[0].Path)
+	assert.Equal(t, filepath.Join(generatedCorpus, "c"), inputs[1].Path)
+	assert.Equal(t, filepath.Join(generatedCorpus, "b"), inputs[2].Path)
+}
+
+func TestSplit(t *testing.T) {
+	var inputs []*Input
+	for i := 0; i < 10; i++ {
+		inputs = append(inputs, &Input{Path: string(rune('a' + i))})
+	}
+
+	chunks := Split(inputs, 3)
+	require.Len(t, chunks, 3)
+	assert.Len(t, chunks[0], 3)
+	assert.Len(t, chunks[1], 3)
+	assert.Len(t, chunks[2], 4)
+	assert.Equal(t, "a", chunks[0][0].Path)
+	assert.Equal(t, "j", chunks[2][3].Path)
+
+	chunks = Split(inputs[:2], 4)
+	require.Len(t, chunks, 4)
+	assert.Len(t, chunks[0], 1)
+	assert.Len(t, chunks[1], 1)
+	assert.Empty(t, chunks[2])
+	assert.Empty(t, chunks[3])
+}
+
+func TestTimeline_Write(t *testing.T) {
+	tl := &Timeline{}
+	tl.Add(0, time.Time{}, &summary.Coverage{LinesHit: 10, LinesFound: 100})
+	newest := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tl.Add(42, newest, &summary.Coverage{LinesHit: 50, LinesFound: 100, BranchesHit: 1, BranchesFound: 4})
+
+	var csv bytes.Buffer
+	require.NoError(t, tl.WriteCSV(&csv))
+	assert.Equal(t, `chunk,inputs,time,lines_hit,lines_found,line_coverage,branches_hit,branches_found,branch_coverage
+0,0,,10,100,10.00,0,0,0.00
+1,42,2023-05-01T12:00:00Z,50,100,50.00,1,4,25.00
+`, csv.String())
+
+	jsonPath := filepath.Join(t.TempDir(), "timeline.json")
+	require.NoError(t, tl.Write(jsonPath))
+	content, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"line_coverage": 50`)
+
+	var html bytes.Buffer
+	require.NoError(t, tl.WriteHTML(&html))
+	assert.Contains(t, html.String(), `<polyline class="lines" points="50,295 770,175"/>`)
+	assert.Contains(t, html.String(), "2023-05-01 12:00:00")
+}
@@ -3,24 +3,32 @@ package execute
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/pterm/pterm"
 	"github.com/pterm/pterm/putils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"code-intelligence.com/cifuzz/internal/bundler/archive"
 	runCmd "code-intelligence.com/cifuzz/internal/cmd/run"
 	"code-intelligence.com/cifuzz/internal/cmd/run/reporthandler"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/container"
+	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/pkg/runner/jazzer"
+	"code-intelligence.com/cifuzz/pkg/runner/jazzerjs"
 	"code-intelligence.com/cifuzz/pkg/runner/libfuzzer"
 	"code-intelligence.com/cifuzz/util/fileutil"
 )
 
 type executeOpts struct {
-	name string
+	name       string
+	trustedKey string
+	outputDir  string
 }
 
 type executeCmd struct {
@@ -29,14 +37,30 @@ type executeCmd struct {
 }
 
 func New() *cobra.Command {
+	var bindFlags func()
+	var outputDir string
+
 	cmd := &cobra.Command{
 		Use:   "execute",
 		Short: "Execute a fuzz test bundle locally",
 		Long: `This command executes a cifuzz fuzz test bundle locally.
 It can be used as an experimental alternative to cifuzz_runner.
-I is currently only intended for use with the 'cifuzz container' subcommand.`,
+I is currently only intended for use with the 'cifuzz container' subcommand.
+
+If a trusted public key is specified via --trusted-key or the
+CIFUZZ_TRUSTED_KEY environment variable, the bundle is only executed if
+it is signed with the corresponding private key and its files match the
+checksums of its content manifest.
+
+If an output directory is specified via --output-dir, the findings are
+stored in its .cifuzz-findings directory and the generated corpus in its
+corpus directory, so that they can be copied out of the container.`,
 		Example: "cifuzz execute <bundle.tar.gz>",
 		Args:    cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			bindFlags()
+			return nil
+		},
 		RunE: func(c *cobra.Command, args []string) error {
 			// If there are no arguments provided, provide a helpful message and list all available fuzzers.
 			if len(args) == 0 {
@@ -68,19 +92,26 @@ I is currently only intended for use with the 'cifuzz container' subcommand.`,
 				}
 				return nil
 			}
-			opts := &executeOpts{name: args[0]}
+			opts := &executeOpts{name: args[0], trustedKey: viper.GetString("trusted-key"), outputDir: outputDir}
 
 			cmd := executeCmd{Command: c, opts: opts}
 			return cmd.run()
 		},
 	}
 
+	bindFlags = cmdutils.AddFlags(cmd, cmdutils.AddTrustedKeyFlag)
+	cmd.Flags().StringVar(&outputDir, "output-dir", "", "Absolute path of the directory in which the findings and the generated corpus are stored.")
 	cmdutils.DisableConfigCheck(cmd)
 
 	return cmd
 }
 
 func (c *executeCmd) run() error {
+	err := verifyBundle(c.opts.trustedKey)
+	if err != nil {
+		return err
+	}
+
 	metadata, err := getMetadata()
 	if err != nil {
 		return err
@@ -91,13 +122,98 @@ func (c *executeCmd) run() error {
 		return err
 	}
 
-	runner, err := buildRunner(fuzzer)
+	if fuzzer.Engine == "JAVASCRIPT_LIBFUZZER" {
+		err = prepareNodeProject(fuzzer)
+		if err != nil {
+			return err
+		}
+	}
+
+	runner, err := buildRunner(fuzzer, c.opts.outputDir)
 	if err != nil {
 		return err
 	}
 	return runCmd.ExecuteRunner(runner)
 }
 
+// verifyBundle checks that the unpacked bundle in the current directory
+// is signed with the private key of the trusted key and was not
+// modified. Nothing is checked if no trusted key is specified.
+func verifyBundle(trustedKey string) error {
+	key, err := archive.LoadPublicKey(trustedKey, "")
+	if err != nil {
+		return errors.WithMessage(err, "Failed to load the trusted key")
+	}
+	if key == nil {
+		return nil
+	}
+	// The files of the build context of the fuzz container image are
+	// not part of the bundle
+	err = archive.VerifyDir(".", key, container.BuildContextFiles...)
+	if err != nil {
+		return errors.WithMessage(err, "Rejecting the bundle")
+	}
+	log.Debug("Verified the signature of the bundle")
+	return nil
+}
+
+// prepareNodeProject changes the working directory to the Node.js project
+// in the bundle, from which jest has to be run, and installs the
+// dependencies of the project from its lock file if the bundle doesn't
+// contain its node_modules directory.
+func prepareNodeProject(fuzzer *archive.Fuzzer) error {
+	if len(fuzzer.RuntimePaths) == 0 {
+		return errors.Errorf("fuzzer '%s' doesn't specify the directory of the Node.js project", getFuzzerName(fuzzer))
+	}
+	err := os.Chdir(fuzzer.RuntimePaths[0])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	exists, err := fileutil.Exists("node_modules")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if exists {
+		return nil
+	}
+
+	packageManager, err := nodePackageManager()
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	if packageManager == "yarn" {
+		cmd = exec.Command("yarn", "install", "--frozen-lockfile")
+	} else {
+		cmd = exec.Command("npm", "ci")
+	}
+	// Print the output of the package manager to stderr, like the
+	// output of the fuzzer
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	log.Infof("Installing the dependencies of the Node.js project")
+	log.Debugf("Command: %s", cmd.String())
+	err = cmd.Run()
+	if err != nil {
+		return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return nil
+}
+
+// nodePackageManager returns the package manager of the Node.js project
+// in the current directory, which is determined by its lock file
+func nodePackageManager() (string, error) {
+	hasYarnLock, err := fileutil.Exists("yarn.lock")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if hasYarnLock {
+		return "yarn", nil
+	}
+	return "npm", nil
+}
+
 // getMetadata returns the bundle metadata from the bundle.yaml file.
 func getMetadata() (*archive.Metadata, error) {
 	exists, err := fileutil.Exists(archive.MetadataFileName)
@@ -143,10 +259,18 @@ func findFuzzer(nameToFind string, bundleMetadata *archive.Metadata) (*archive.F
 	return nil, errors.Errorf("fuzzer '%s' not found in a bundle metadata file", nameToFind)
 }
 
-func buildRunner(fuzzer *archive.Fuzzer) (runCmd.Runner, error) {
+func buildRunner(fuzzer *archive.Fuzzer, outputDir string) (runCmd.Runner, error) {
 	// TODO: create or get real directory for seed corpus
 	corpusDirName := "corpus"
 	seedDirName := "seed"
+	findingsProjectDir := fuzzer.ProjectDir
+	if outputDir != "" {
+		// Store the findings and the generated corpus in the output
+		// directory, from which 'cifuzz container run' copies them to
+		// the project on the host
+		corpusDirName = filepath.Join(outputDir, "corpus")
+		findingsProjectDir = outputDir
+	}
 	err := os.MkdirAll(seedDirName, 0o755)
 	if err != nil {
 		return nil, err
@@ -160,7 +284,7 @@ func buildRunner(fuzzer *archive.Fuzzer) (runCmd.Runner, error) {
 	reportHandler, err := reporthandler.NewReportHandler(
 		getFuzzerName(fuzzer),
 		&reporthandler.ReportHandlerOptions{
-			ProjectDir:           fuzzer.ProjectDir,
+			ProjectDir:           findingsProjectDir,
 			PrintJSON:            false,
 			ManagedSeedCorpusDir: seedDirName,
 		})
@@ -197,6 +321,23 @@ func buildRunner(fuzzer *archive.Fuzzer) (runCmd.Runner, error) {
 			LibfuzzerOptions: runnerOpts,
 		}
 		runner = jazzer.NewRunner(runnerOpts)
+	case "JAVASCRIPT_LIBFUZZER":
+		// The fuzz test is specified as "<test file>" or, for fuzz test
+		// files containing multiple fuzz tests, as "<test file>:<name>"
+		testPathPattern, testNamePattern, _ := strings.Cut(fuzzer.Name, ":")
+		// The working directory was changed to the Node.js project by
+		// prepareNodeProject
+		packageManager, err := nodePackageManager()
+		if err != nil {
+			return nil, err
+		}
+		runnerOpts := &jazzerjs.RunnerOptions{
+			TestPathPattern:  testPathPattern,
+			TestNamePattern:  strings.ReplaceAll(testNamePattern, "\"", ""),
+			LibfuzzerOptions: runnerOpts,
+			PackageManager:   packageManager,
+		}
+		runner = jazzerjs.NewRunner(runnerOpts)
 	default:
 		runner = libfuzzer.NewRunner(runnerOpts)
 	}
@@ -1,13 +1,23 @@
 package execute
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/internal/cmd/run/reporthandler"
+	"code-intelligence.com/cifuzz/internal/testutil"
 	"code-intelligence.com/cifuzz/pkg/runner/jazzer"
+	"code-intelligence.com/cifuzz/pkg/runner/jazzerjs"
 	"code-intelligence.com/cifuzz/pkg/runner/libfuzzer"
 )
 
@@ -160,7 +170,7 @@ func Test_buildRunnerJazzerRunner(t *testing.T) {
 	runner, err := buildRunner(&archive.Fuzzer{
 		Name:   "a-fuzzer",
 		Engine: "JAVA_LIBFUZZER",
-	})
+	}, "")
 	require.NoError(t, err)
 	v, ok := runner.(*jazzer.Runner)
 	require.Equal(t, true, ok)
@@ -172,9 +182,107 @@ func Test_buildRunnerLibfuzzerRunner(t *testing.T) {
 		Target: "b-fuzzer",
 		Path:   "fuzzTarget",
 		Engine: "LIBFUZZER",
-	})
+	}, "")
 	require.NoError(t, err)
 	v, ok := runner.(*libfuzzer.Runner)
 	require.Equal(t, true, ok)
 	require.Equal(t, "fuzzTarget", v.RunnerOptions.FuzzTarget)
 }
+
+func Test_buildRunnerOutputDir(t *testing.T) {
+	outputDir := testutil.MkdirTemp(t, "", "execute-output-dir-test-")
+	runner, err := buildRunner(&archive.Fuzzer{
+		Target: "b-fuzzer",
+		Path:   "fuzzTarget",
+		Engine: "LIBFUZZER",
+	}, outputDir)
+	require.NoError(t, err)
+	v, ok := runner.(*libfuzzer.Runner)
+	require.Equal(t, true, ok)
+	// The generated corpus is stored in the output directory, from which
+	// it's copied out of the container
+	require.Equal(t, filepath.Join(outputDir, "corpus"), v.RunnerOptions.GeneratedCorpusDir)
+	require.DirExists(t, v.RunnerOptions.GeneratedCorpusDir)
+	// The findings are stored in the .cifuzz-findings directory of the
+	// output directory
+	reportHandler, ok := v.RunnerOptions.ReportHandler.(*reporthandler.ReportHandler)
+	require.Equal(t, true, ok)
+	require.Equal(t, outputDir, reportHandler.ProjectDir)
+}
+
+func Test_buildRunnerJazzerJSRunner(t *testing.T) {
+	_, cleanup := testutil.ChdirToTempDir("execute-build-runner-jazzerjs-test-")
+	defer cleanup()
+
+	fuzzer := &archive.Fuzzer{
+		Name:   `FuzzTestCase:"My fuzz test"`,
+		Engine: "JAVASCRIPT_LIBFUZZER",
+	}
+	runner, err := buildRunner(fuzzer, "")
+	require.NoError(t, err)
+	v, ok := runner.(*jazzerjs.Runner)
+	require.Equal(t, true, ok)
+	require.Equal(t, "FuzzTestCase", v.RunnerOptions.TestPathPattern)
+	require.Equal(t, "My fuzz test", v.RunnerOptions.TestNamePattern)
+	require.Equal(t, "npm", v.RunnerOptions.PackageManager)
+
+	// The package manager is determined by the lock file of the project
+	err = os.WriteFile("yarn.lock", nil, 0o644)
+	require.NoError(t, err)
+	runner, err = buildRunner(fuzzer, "")
+	require.NoError(t, err)
+	require.Equal(t, "yarn", runner.(*jazzerjs.Runner).RunnerOptions.PackageManager)
+}
+
+func Test_prepareNodeProject(t *testing.T) {
+	tempDir, cleanup := testutil.ChdirToTempDir("execute-prepare-node-project-test-")
+	defer cleanup()
+
+	fuzzer := &archive.Fuzzer{
+		Name:   "FuzzTestCase",
+		Engine: "JAVASCRIPT_LIBFUZZER",
+	}
+	require.Error(t, prepareNodeProject(fuzzer))
+
+	// If the bundle contains the node_modules directory, no dependencies
+	// have to be installed
+	err := os.MkdirAll(filepath.Join("project", "node_modules"), 0o755)
+	require.NoError(t, err)
+	fuzzer.RuntimePaths = []string{"project"}
+	require.NoError(t, prepareNodeProject(fuzzer))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	expectedWd, err := filepath.EvalSymlinks(filepath.Join(tempDir, "project"))
+	require.NoError(t, err)
+	wd, err = filepath.EvalSymlinks(wd)
+	require.NoError(t, err)
+	require.Equal(t, expectedWd, wd)
+}
+
+func Test_verifyBundle(t *testing.T) {
+	_, cleanup := testutil.ChdirToTempDir("execute-verify-bundle-test-")
+	defer cleanup()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	trustedKey := base64.StdEncoding.EncodeToString(publicKey)
+
+	// Without a trusted key, unsigned bundles are accepted
+	require.NoError(t, verifyBundle(""))
+	require.ErrorIs(t, verifyBundle(trustedKey), archive.ErrUnsigned)
+
+	err = os.WriteFile(archive.MetadataFileName, []byte("fuzzers: []\n"), 0o644)
+	require.NoError(t, err)
+	checksum := sha256.Sum256([]byte("fuzzers: []\n"))
+	manifest := []byte(fmt.Sprintf("%x  %s\n", checksum, archive.MetadataFileName))
+	err = os.WriteFile(archive.ContentManifestFileName, manifest, 0o644)
+	require.NoError(t, err)
+	err = os.WriteFile(archive.SignatureFileName, archive.Sign(manifest, privateKey), 0o644)
+	require.NoError(t, err)
+	require.NoError(t, verifyBundle(trustedKey))
+
+	err = os.WriteFile(archive.MetadataFileName, []byte("fuzzers: [{}]\n"), 0o644)
+	require.NoError(t, err)
+	require.Error(t, verifyBundle(trustedKey))
+}
@@ -0,0 +1,289 @@
+package bisect
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"code-intelligence.com/cifuzz/internal/cmd/run"
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/completion"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
+	"code-intelligence.com/cifuzz/pkg/vcs"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+const (
+	termGood = "good"
+	termBad  = "bad"
+	termSkip = "skip"
+)
+
+type options struct {
+	BuildSystem      string         `mapstructure:"build-system"`
+	BuildCommand     string         `mapstructure:"build-command"`
+	CleanCommand     string         `mapstructure:"clean-command"`
+	NumBuildJobs     uint           `mapstructure:"build-jobs"`
+	ErrorIDRules     []errorid.Rule `mapstructure:"error-id-rules"`
+	ErrorIDRulesFile string         `mapstructure:"error-id-rules-file"`
+	ProjectDir       string         `mapstructure:"project-dir"`
+	ConfigDir        string         `mapstructure:"config-dir"`
+
+	Good string
+	Bad  string
+}
+
+type bisectCmd struct {
+	*cobra.Command
+	opts       *options
+	classifier *errorid.Classifier
+
+	// testCommit returns the git bisect term for the commit which is
+	// checked out in projectDir. It's a field so that it can be
+	// replaced in tests.
+	testCommit func(projectDir string, f *finding.Finding, input string) string
+}
+
+func New() *cobra.Command {
+	return newWithOptions(&options{})
+}
+
+func newWithOptions(opts *options) *cobra.Command {
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "bisect [flags] <name> --good <ref>",
+		Short: "Find the commit which introduced a finding",
+		Long: `This command finds the commit which introduced a finding via git bisect.
+
+In a temporary Git worktree, the fuzz test of the finding is built for
+each commit which git bisect selects between the --good and the --bad
+commit. The commit is considered bad if the crashing input of the
+finding produces a finding with the same error ID, else it's considered
+good. The error IDs are determined using the error ID rules of the
+project, so the error ID of the finding is updated if it doesn't match
+the rules anymore. Commits for which the fuzz test can't be built are skipped.
+Before bisecting, the --bad commit is checked to be bad and the --good
+commit to be good.
+
+The first bad commit is printed and stored in the finding.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completion.ValidFindings,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind viper keys to flags. We can't do this in the New
+			// function, because that would re-bind viper keys which
+			// were bound to the flags of other commands before.
+			bindFlags()
+			err := config.FindAndParseProjectConfig(opts)
+			if err != nil {
+				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			classifier, err := errorid.NewProjectClassifier(opts.ProjectDir, opts.ErrorIDRules, opts.ErrorIDRulesFile)
+			if err != nil {
+				log.Errorf(err, "Failed to load error ID rules: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			cmd := &bisectCmd{Command: c, opts: opts, classifier: classifier}
+			cmd.testCommit = cmd.reproduceInCommit
+			return cmd.run(args[0])
+		},
+	}
+
+	// Note: If a flag should be configurable via viper as well (i.e.
+	//       via cifuzz.yaml and CIFUZZ_* environment variables), bind
+	//       it to viper in the PreRun function.
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddBuildCommandFlag,
+		cmdutils.AddCleanCommandFlag,
+		cmdutils.AddBuildJobsFlag,
+		cmdutils.AddProjectDirFlag,
+	)
+	cmd.Flags().StringVar(&opts.Good, "good", "", "A `ref` of a commit in which the finding is not reproducible.")
+	cmd.Flags().StringVar(&opts.Bad, "bad", "HEAD", "A `ref` of a commit in which the finding is reproducible.")
+	cmdutils.MarkFlagsRequired(cmd, "good")
+
+	return cmd
+}
+
+func (c *bisectCmd) run(findingName string) error {
+	f, err := finding.LoadFinding(c.opts.ProjectDir, findingName, nil)
+	if finding.IsNotExistError(err) {
+		log.Errorf(err, "Finding %s does not exist", findingName)
+		return cmdutils.WrapSilentError(err)
+	}
+	if err != nil {
+		return err
+	}
+	// The reproduced findings are classified with the rules of the
+	// project, so the finding must be as well for the error IDs to be
+	// comparable
+	if c.classifier != nil {
+		c.classifier.Classify(f)
+	}
+	if f.FuzzTest == "" || f.InputFile == "" || f.MoreDetails == nil || f.MoreDetails.ID == "" {
+		err = errors.Errorf("Finding %s can't be bisected because it has no fuzz test, crashing input or error ID", findingName)
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+	input := filepath.Join(c.opts.ProjectDir, f.InputFile)
+
+	good, err := vcs.GitRevParse(c.opts.ProjectDir, c.opts.Good)
+	if err != nil {
+		return cmdutils.WrapIncorrectUsageError(errors.Errorf("Invalid --good commit %q", c.opts.Good))
+	}
+	bad, err := vcs.GitRevParse(c.opts.ProjectDir, c.opts.Bad)
+	if err != nil {
+		return cmdutils.WrapIncorrectUsageError(errors.Errorf("Invalid --bad commit %q", c.opts.Bad))
+	}
+
+	// The project directory might be a subdirectory of the repository
+	topLevel, err := vcs.GitTopLevel(c.opts.ProjectDir)
+	if err != nil {
+		return err
+	}
+	relProjectDir, err := filepath.Rel(topLevel, c.opts.ProjectDir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "cifuzz-bisect-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tempDir)
+	worktree := filepath.Join(tempDir, "worktree")
+	err = vcs.GitWorktreeAdd(c.opts.ProjectDir, worktree, bad)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := vcs.GitWorktreeRemove(c.opts.ProjectDir, worktree)
+		if err != nil {
+			log.Error(err)
+		}
+	}()
+	worktreeProjectDir := filepath.Join(worktree, relProjectDir)
+
+	// The first bad commit is meaningless if the finding is not
+	// reproducible in the bad commit or already in the good commit
+	err = c.checkEndpoint(worktreeProjectDir, f, input, c.opts.Bad, termBad)
+	if err != nil {
+		return err
+	}
+	err = vcs.GitCheckout(worktree, good)
+	if err != nil {
+		return err
+	}
+	err = c.checkEndpoint(worktreeProjectDir, f, input, c.opts.Good, termGood)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Bisecting finding %s between %s (good) and %s (bad)", findingName, c.opts.Good, c.opts.Bad)
+	firstBadCommit, err := vcs.GitBisectStart(worktree, bad, good)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := vcs.GitBisectReset(worktree)
+		if err != nil {
+			log.Error(err)
+		}
+	}()
+
+	for firstBadCommit == "" {
+		commit, err := vcs.GitRevParse(worktree, "HEAD")
+		if err != nil {
+			return err
+		}
+		term := c.testCommit(worktreeProjectDir, f, input)
+		log.Infof("Commit %s is %s", commit, term)
+		firstBadCommit, err = vcs.GitBisectMark(worktree, term)
+		if err != nil {
+			log.Error(err)
+			return cmdutils.WrapSilentError(err)
+		}
+	}
+
+	log.Successf("The first bad commit is %s", pterm.Style{pterm.Reset, pterm.Bold}.Sprint(firstBadCommit))
+	_, _ = fmt.Fprintln(c.OutOrStdout(), firstBadCommit)
+
+	f.FirstBadCommit = firstBadCommit
+	return f.Save(c.opts.ProjectDir)
+}
+
+// checkEndpoint checks that the checked out commit, which was specified
+// as ref, has the expected git bisect term
+func (c *bisectCmd) checkEndpoint(projectDir string, f *finding.Finding, input, ref, expectedTerm string) error {
+	log.Infof("Checking that the --%s commit %s is %s", expectedTerm, ref, expectedTerm)
+	term := c.testCommit(projectDir, f, input)
+	if term == expectedTerm {
+		return nil
+	}
+
+	var err error
+	switch {
+	case term == termSkip:
+		err = errors.Errorf("The fuzz test could not be built or executed in the --%s commit %s", expectedTerm, ref)
+	case expectedTerm == termBad:
+		err = errors.Errorf("Finding %s is not reproducible in the --bad commit %s", f.Name, ref)
+	default:
+		err = errors.Errorf("Finding %s is already reproducible in the --good commit %s", f.Name, ref)
+	}
+	log.Error(err)
+	return cmdutils.WrapSilentError(err)
+}
+
+// reproduceInCommit builds the fuzz test in the checked out commit and
+// returns the git bisect term for that commit.
+func (c *bisectCmd) reproduceInCommit(projectDir string, f *finding.Finding, input string) string {
+	var buildOutput io.Writer = io.Discard
+	if viper.GetBool("verbose") {
+		buildOutput = c.ErrOrStderr()
+	}
+	reproducer, err := run.NewReproducer(&run.ReproducerOptions{
+		ProjectDir:   projectDir,
+		BuildSystem:  c.opts.BuildSystem,
+		BuildCommand: c.opts.BuildCommand,
+		CleanCommand: c.opts.CleanCommand,
+		NumBuildJobs: c.opts.NumBuildJobs,
+		FuzzTest:     f.FuzzTest,
+		WorkDir:      projectDir,
+		Classifier:   c.classifier,
+		Stdout:       buildOutput,
+		Stderr:       buildOutput,
+	})
+	if err != nil {
+		log.Warnf("Skipping commit: %v", err)
+		return termSkip
+	}
+	defer reproducer.Cleanup()
+
+	err = reproducer.Build()
+	if err != nil {
+		log.Warnf("Skipping commit because the fuzz test could not be built: %v", err)
+		return termSkip
+	}
+	findings, err := reproducer.Reproduce(input)
+	if err != nil {
+		log.Warnf("Skipping commit because the fuzz test could not be executed: %v", err)
+		return termSkip
+	}
+	if f.IsReproducedBy(findings) {
+		return termBad
+	}
+	return termGood
+}
@@ -0,0 +1,159 @@
+package bisect
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/testutil"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/vcs"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+func TestBisect_FindingDoesNotExist(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-bisect-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	_, stdErr, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding", "--good", "HEAD~")
+	require.Error(t, err)
+	assert.Contains(t, stdErr, "Finding my_finding does not exist")
+}
+
+func TestBisect_FindingWithoutInput(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-bisect-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+	f := &finding.Finding{Name: "my_finding", FuzzTest: "my_fuzz_test"}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+
+	_, stdErr, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding", "--good", "HEAD~")
+	require.Error(t, err)
+	assert.Contains(t, stdErr, "can't be bisected")
+}
+
+// createBisectProject creates a project in a Git repository with five
+// commits. The third commit adds a file which introduces the bug of
+// the returned finding.
+func createBisectProject(t *testing.T) (string, *finding.Finding, []string) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-bisect-")
+	runGit(t, projectDir, "init")
+	runGit(t, projectDir, "config", "user.email", "you@example.com")
+	runGit(t, projectDir, "config", "user.name", "Your Name")
+
+	var commits []string
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("file-%d", i)
+		if i == 2 {
+			name = "bug"
+		}
+		err := fileutil.Touch(filepath.Join(projectDir, name))
+		require.NoError(t, err)
+		runGit(t, projectDir, "add", ".")
+		runGit(t, projectDir, "commit", "-m", "Add "+name)
+		commit, err := vcs.GitRevParse(projectDir, "HEAD")
+		require.NoError(t, err)
+		commits = append(commits, commit)
+	}
+
+	f := &finding.Finding{
+		Name:        "my_finding",
+		FuzzTest:    "my_fuzz_test",
+		InputFile:   "crashing-input",
+		MoreDetails: &finding.ErrorDetails{ID: "heap_buffer_overflow"},
+	}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+	return projectDir, f, commits
+}
+
+// newTestBisectCmd returns a bisect command which considers commits bad
+// if the bug file exists, instead of reproducing the finding
+func newTestBisectCmd(opts *options, stdout io.Writer) *bisectCmd {
+	cmd := &bisectCmd{Command: &cobra.Command{}, opts: opts}
+	cmd.SetOut(stdout)
+	cmd.testCommit = func(projectDir string, f *finding.Finding, input string) string {
+		exists, err := fileutil.Exists(filepath.Join(projectDir, "bug"))
+		if err != nil {
+			return termSkip
+		}
+		if exists {
+			return termBad
+		}
+		return termGood
+	}
+	return cmd
+}
+
+func TestBisect(t *testing.T) {
+	projectDir, f, commits := createBisectProject(t)
+	var stdout bytes.Buffer
+	cmd := newTestBisectCmd(&options{
+		ProjectDir: projectDir,
+		Good:       commits[0],
+		Bad:        "HEAD",
+	}, &stdout)
+
+	err := cmd.run(f.Name)
+	require.NoError(t, err)
+	assert.Equal(t, commits[2]+"\n", stdout.String())
+
+	f, err = finding.LoadFinding(projectDir, f.Name, nil)
+	require.NoError(t, err)
+	assert.Equal(t, commits[2], f.FirstBadCommit)
+
+	// The worktree was removed and the project was not changed
+	assert.NoFileExists(t, filepath.Join(projectDir, ".git", "worktrees"))
+	head, err := vcs.GitRevParse(projectDir, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, commits[4], head)
+}
+
+func TestBisect_InvalidEndpoints(t *testing.T) {
+	projectDir, f, commits := createBisectProject(t)
+
+	for _, tc := range []struct {
+		good, bad string
+		errMsg    string
+	}{
+		{commits[0], commits[1], "is not reproducible in the --bad commit"},
+		{commits[3], commits[4], "is already reproducible in the --good commit"},
+	} {
+		cmd := newTestBisectCmd(&options{
+			ProjectDir: projectDir,
+			Good:       tc.good,
+			Bad:        tc.bad,
+		}, io.Discard)
+		err := cmd.run(f.Name)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), tc.errMsg)
+	}
+
+	f, err := finding.LoadFinding(projectDir, f.Name, nil)
+	require.NoError(t, err)
+	assert.Empty(t, f.FirstBadCommit)
+}
+
+func runGit(t *testing.T, repo string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repo
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	require.NoError(t, err)
+}
@@ -0,0 +1,230 @@
+package checkflaky
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"code-intelligence.com/cifuzz/internal/cmd/run"
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/completion"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
+	"code-intelligence.com/cifuzz/util/stringutil"
+)
+
+type options struct {
+	BuildSystem      string         `mapstructure:"build-system"`
+	BuildCommand     string         `mapstructure:"build-command"`
+	CleanCommand     string         `mapstructure:"clean-command"`
+	NumBuildJobs     uint           `mapstructure:"build-jobs"`
+	EngineArgs       []string       `mapstructure:"engine-args"`
+	ErrorIDRules     []errorid.Rule `mapstructure:"error-id-rules"`
+	ErrorIDRulesFile string         `mapstructure:"error-id-rules-file"`
+	PrintJSON        bool           `mapstructure:"print-json"`
+	ProjectDir       string         `mapstructure:"project-dir"`
+	ConfigDir        string         `mapstructure:"config-dir"`
+
+	Runs uint
+}
+
+type checkFlakyCmd struct {
+	*cobra.Command
+	opts       *options
+	classifier *errorid.Classifier
+}
+
+func New() *cobra.Command {
+	return newWithOptions(&options{})
+}
+
+func newWithOptions(opts *options) *cobra.Command {
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "check-flaky [flags] [<name>...]",
+		Short: "Check whether findings are flaky",
+		Long: `This command checks whether findings are flaky, i.e. whether their
+crashing input doesn't reproduce them reliably.
+
+The fuzz test of each finding is built and executed on the crashing
+input of the finding the number of times specified via --runs, each
+time in a new process. The ratio of runs which reproduced the finding
+is stored in the finding. If not all runs reproduced the finding, it's
+marked as flaky. The error IDs are determined using the error ID rules
+of the project, so the error ID of the finding is updated if it doesn't
+match the rules anymore.
+
+If no finding names are specified, all findings of the project are
+checked.`,
+		ValidArgsFunction: completion.ValidFindings,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind viper keys to flags. We can't do this in the New
+			// function, because that would re-bind viper keys which
+			// were bound to the flags of other commands before.
+			bindFlags()
+			err := config.FindAndParseProjectConfig(opts)
+			if err != nil {
+				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			if opts.Runs == 0 {
+				return cmdutils.WrapIncorrectUsageError(errors.New("The number of runs must be greater than 0"))
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			classifier, err := errorid.NewProjectClassifier(opts.ProjectDir, opts.ErrorIDRules, opts.ErrorIDRulesFile)
+			if err != nil {
+				log.Errorf(err, "Failed to load error ID rules: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			cmd := checkFlakyCmd{Command: c, opts: opts, classifier: classifier}
+			return cmd.run(args)
+		},
+	}
+
+	// Note: If a flag should be configurable via viper as well (i.e.
+	//       via cifuzz.yaml and CIFUZZ_* environment variables), bind
+	//       it to viper in the PreRun function.
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddBuildCommandFlag,
+		cmdutils.AddCleanCommandFlag,
+		cmdutils.AddBuildJobsFlag,
+		cmdutils.AddEngineArgFlag,
+		cmdutils.AddPrintJSONFlag,
+		cmdutils.AddProjectDirFlag,
+	)
+	cmd.Flags().UintVar(&opts.Runs, "runs", 10, "The `number` of times the crashing input of each finding is executed.")
+
+	return cmd
+}
+
+func (c *checkFlakyCmd) run(names []string) error {
+	findings, err := c.loadFindings(names)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		log.Print("This project doesn't have any findings yet")
+		return nil
+	}
+
+	// Group the findings by fuzz test, so that each fuzz test is only
+	// built once
+	var fuzzTests []string
+	findingsByFuzzTest := make(map[string][]*finding.Finding)
+	var failed []string
+	for _, f := range findings {
+		if f.FuzzTest == "" || f.InputFile == "" {
+			log.Warnf("Finding %s can't be checked because it has no fuzz test or crashing input", f.Name)
+			failed = append(failed, f.Name)
+			continue
+		}
+		if _, ok := findingsByFuzzTest[f.FuzzTest]; !ok {
+			fuzzTests = append(fuzzTests, f.FuzzTest)
+		}
+		findingsByFuzzTest[f.FuzzTest] = append(findingsByFuzzTest[f.FuzzTest], f)
+	}
+
+	checked := []*finding.Finding{}
+	for _, fuzzTest := range fuzzTests {
+		res, err := c.checkFuzzTest(fuzzTest, findingsByFuzzTest[fuzzTest])
+		if err != nil {
+			log.Errorf(err, "Failed to check findings of fuzz test %s: %v", fuzzTest, err.Error())
+			for _, f := range findingsByFuzzTest[fuzzTest] {
+				failed = append(failed, f.Name)
+			}
+			continue
+		}
+		checked = append(checked, res...)
+	}
+
+	if c.opts.PrintJSON {
+		s, err := stringutil.ToJSONString(checked)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(c.OutOrStdout(), s)
+	}
+
+	if len(failed) > 0 {
+		err = errors.Errorf("Failed to check %d findings for flakiness", len(failed))
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+	return nil
+}
+
+func (c *checkFlakyCmd) loadFindings(names []string) ([]*finding.Finding, error) {
+	if len(names) == 0 {
+		return finding.ListFindings(c.opts.ProjectDir, nil)
+	}
+	var findings []*finding.Finding
+	for _, name := range names {
+		f, err := finding.LoadFinding(c.opts.ProjectDir, name, nil)
+		if finding.IsNotExistError(err) {
+			log.Errorf(err, "Finding %s does not exist", name)
+			return nil, cmdutils.WrapSilentError(err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// checkFuzzTest builds the fuzz test and checks the reproducibility of
+// the findings, which must all belong to that fuzz test.
+func (c *checkFlakyCmd) checkFuzzTest(fuzzTest string, findings []*finding.Finding) ([]*finding.Finding, error) {
+	var buildOutput io.Writer = io.Discard
+	if viper.GetBool("verbose") {
+		buildOutput = c.ErrOrStderr()
+	}
+	reproducer, err := run.NewReproducer(&run.ReproducerOptions{
+		ProjectDir:   c.opts.ProjectDir,
+		BuildSystem:  c.opts.BuildSystem,
+		BuildCommand: c.opts.BuildCommand,
+		CleanCommand: c.opts.CleanCommand,
+		NumBuildJobs: c.opts.NumBuildJobs,
+		FuzzTest:     fuzzTest,
+		EngineArgs:   c.opts.EngineArgs,
+		Classifier:   c.classifier,
+		Stdout:       buildOutput,
+		Stderr:       buildOutput,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer reproducer.Cleanup()
+
+	log.Infof("Building %s", fuzzTest)
+	err = reproducer.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range findings {
+		// The reproduced findings are classified with the rules of the
+		// project, so the finding must be as well for the error IDs to
+		// be comparable
+		c.classifier.Classify(f)
+		log.Infof("Checking finding %s for flakiness (%d runs)", f.Name, c.opts.Runs)
+		f.Reproducibility, err = reproducer.CheckReproducibility(f, c.opts.Runs)
+		if err != nil {
+			return nil, err
+		}
+		err = f.Save(c.opts.ProjectDir)
+		if err != nil {
+			return nil, err
+		}
+		cmdutils.PrintReproducibility(f)
+	}
+	return findings, nil
+}
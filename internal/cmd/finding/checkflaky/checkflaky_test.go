@@ -0,0 +1,64 @@
+package checkflaky
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/testutil"
+	"code-intelligence.com/cifuzz/pkg/finding"
+)
+
+func TestCheckFlaky_NoFindings(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-check-flaky-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	_, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin)
+	require.NoError(t, err)
+}
+
+func TestCheckFlaky_FindingDoesNotExist(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-check-flaky-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	_, stdErr, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding")
+	require.Error(t, err)
+	assert.Contains(t, stdErr, "Finding my_finding does not exist")
+}
+
+func TestCheckFlaky_FindingWithoutInput(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-check-flaky-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+	f := &finding.Finding{Name: "my_finding", FuzzTest: "my_fuzz_test"}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+
+	_, stdErr, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding")
+	require.Error(t, err)
+	assert.Contains(t, stdErr, "can't be checked")
+}
+
+func TestCheckFlaky_InvalidRuns(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-check-flaky-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	cmd := newWithOptions(opts)
+	cmd.SilenceUsage = true
+	_, _, err := cmdutils.ExecuteCommand(t, cmd, os.Stdin, "--runs", "0")
+	require.Error(t, err)
+}
@@ -1,10 +1,15 @@
 package finding
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/pterm/pterm"
@@ -13,6 +18,11 @@ import (
 	"golang.org/x/term"
 
 	"code-intelligence.com/cifuzz/internal/api"
+	"code-intelligence.com/cifuzz/internal/cmd/finding/bisect"
+	"code-intelligence.com/cifuzz/internal/cmd/finding/checkflaky"
+	"code-intelligence.com/cifuzz/internal/cmd/finding/input"
+	"code-intelligence.com/cifuzz/internal/cmd/finding/reclassify"
+	"code-intelligence.com/cifuzz/internal/cmd/finding/symbolize"
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/cmdutils/auth"
 	"code-intelligence.com/cifuzz/internal/completion"
@@ -20,6 +30,9 @@ import (
 	"code-intelligence.com/cifuzz/pkg/finding"
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/pkg/messaging"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+	"code-intelligence.com/cifuzz/util/sliceutil"
 	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
@@ -29,11 +42,36 @@ type options struct {
 	ConfigDir   string `mapstructure:"config-dir"`
 	Interactive bool   `mapstructure:"interactive"`
 	Server      string `mapstructure:"server"`
+	Baseline    string `mapstructure:"baseline"`
+
+	ErrorDetailsFile string         `mapstructure:"error-details-file"`
+	ErrorIDRules     []errorid.Rule `mapstructure:"error-id-rules"`
+	ErrorIDRulesFile string         `mapstructure:"error-id-rules-file"`
+
+	// Options to select, sort and format the listed findings
+	FuzzTest     string
+	ErrorID      string
+	Severity     string
+	MinScore     float32
+	CreatedAfter string
+	SourceFile   string
+	SortBy       string
+	Reverse      bool
+	Format       string
+
+	query *finding.Query
 }
 
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
 type findingCmd struct {
 	*cobra.Command
-	opts *options
+	opts       *options
+	classifier *errorid.Classifier
 }
 
 func New() *cobra.Command {
@@ -70,12 +108,26 @@ func newWithOptions(opts *options) *cobra.Command {
 			}
 			opts.Server = viper.GetString("server")
 
-			var err error
+			if opts.Baseline != "" && len(args) > 0 {
+				msg := "The --baseline flag can't be used together with a <name> argument"
+				return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+			}
+
+			err := opts.validateListOptions(c, len(args) > 0)
+			if err != nil {
+				return cmdutils.WrapIncorrectUsageError(err)
+			}
+
 			opts.Server, err = api.ValidateAndNormalizeServerURL(opts.Server)
 			if err != nil {
 				return err
 			}
-			cmd := findingCmd{Command: c, opts: opts}
+			classifier, err := errorid.NewProjectClassifier(opts.ProjectDir, opts.ErrorIDRules, opts.ErrorIDRulesFile)
+			if err != nil {
+				log.Errorf(err, "Failed to load error ID rules: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			cmd := findingCmd{Command: c, opts: opts, classifier: classifier}
 			return cmd.run(args)
 		},
 	}
@@ -88,7 +140,41 @@ func newWithOptions(opts *options) *cobra.Command {
 		cmdutils.AddProjectDirFlag,
 		cmdutils.AddInteractiveFlag,
 		cmdutils.AddServerFlag,
+		cmdutils.AddBaselineFlag,
 	)
+	cmd.Flags().StringVar(&opts.FuzzTest, "fuzz-test", "", "Only list findings of the specified `fuzz test`.")
+	cmd.Flags().StringVar(&opts.ErrorID, "error-id", "", "Only list findings with the specified error `ID` (e.g. heap_buffer_overflow).")
+	cmd.Flags().StringVar(&opts.Severity, "severity", "",
+		"Only list findings with at least the specified severity `level` (low, medium, high or critical).")
+	cmd.Flags().Float32Var(&opts.MinScore, "min-score", 0, "Only list findings with at least the specified severity `score`.")
+	cmd.Flags().StringVar(&opts.CreatedAfter, "created-after", "",
+		"Only list findings created after the specified `time`, either a date (2006-01-02),\n"+
+			"a timestamp (2006-01-02T15:04:05Z07:00) or a duration before now (e.g. 7d or 12h).")
+	cmd.Flags().StringVar(&opts.SourceFile, "source-file", "",
+		"Only list findings with the specified source `file` in the stack trace.\n"+
+			"The file can be specified by a path relative to the project directory, a file name or a glob pattern.")
+	cmd.Flags().StringVar(&opts.SortBy, "sort-by", finding.SortByCreated,
+		"Sort the listed findings by `key`, one of: "+strings.Join(finding.SortKeys, ", ")+".")
+	cmd.Flags().BoolVar(&opts.Reverse, "reverse", false, "Reverse the sort order of the listed findings.")
+	cmd.Flags().StringVar(&opts.Format, "format", "",
+		"Output `format` of the listed findings, one of: table, json, csv (default \"table\", or \"json\" with --json).")
+	flagCompletions := map[string][]string{
+		"format":   {formatTable, formatJSON, formatCSV},
+		"severity": {"low", "medium", "high", "critical"},
+		"sort-by":  finding.SortKeys,
+	}
+	for name, values := range flagCompletions {
+		err := cmd.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	cmd.AddCommand(bisect.New())
+	cmd.AddCommand(checkflaky.New())
+	cmd.AddCommand(input.New())
+	cmd.AddCommand(reclassify.New())
+	cmd.AddCommand(symbolize.New())
 
 	return cmd
 }
@@ -110,6 +196,10 @@ func (cmd *findingCmd) run(args []string) error {
 		return err
 	}
 
+	if cmd.opts.Baseline != "" {
+		return cmd.compareWithBaseline(errorDetails)
+	}
+
 	if len(args) == 0 {
 		// If called without arguments, `cifuzz findings` lists short
 		// descriptions of all findings
@@ -117,17 +207,30 @@ func (cmd *findingCmd) run(args []string) error {
 		if err != nil {
 			return err
 		}
+		cmd.classify(findings, errorDetails)
+		findings = cmd.opts.query.Filter(findings)
+		err = finding.SortFindings(findings, cmd.opts.SortBy, cmd.opts.Reverse)
+		if err != nil {
+			return err
+		}
 
-		if cmd.opts.PrintJSON {
+		switch cmd.opts.Format {
+		case formatJSON:
 			s, err := stringutil.ToJSONString(findings)
 			if err != nil {
 				return err
 			}
 			_, _ = fmt.Fprintln(cmd.OutOrStdout(), s)
 			return nil
+		case formatCSV:
+			return printFindingsCSV(cmd.OutOrStdout(), findings)
 		}
 
 		if len(findings) == 0 {
+			if *cmd.opts.query != (finding.Query{}) {
+				log.Print("No findings match the specified filters")
+				return nil
+			}
 			log.Print("This project doesn't have any findings yet")
 			return nil
 		}
@@ -146,23 +249,20 @@ func (cmd *findingCmd) run(args []string) error {
 
 		for _, f := range findings {
 			score := "n/a"
-			locationInfo := "n/a"
-			// add location (file, function, line) if available
-			if len(f.ShortDescriptionColumns()) > 1 {
-				locationInfo = f.ShortDescriptionColumns()[1]
+			name := f.Name
+			if f.IsFlaky() {
+				name += " " + pterm.Yellow("(flaky)")
+			}
+			locationInfo := findingLocation(f)
+			// check if we have a severity and if we have a severity score
+			if f.MoreDetails != nil && f.MoreDetails.Severity != nil {
+				colorFunc := getColorFunctionForSeverity(f.MoreDetails.Severity.Score)
+				score = colorFunc(fmt.Sprintf("%.1f", f.MoreDetails.Severity.Score))
 			}
 			if authenticated {
-				// check if MoreDetails exists to avoid nil pointer errors
-				if f.MoreDetails != nil {
-					// check if we have a severity and if we have a severity score
-					if f.MoreDetails.Severity != nil {
-						colorFunc := getColorFunctionForSeverity(f.MoreDetails.Severity.Score)
-						score = colorFunc(fmt.Sprintf("%.1f", f.MoreDetails.Severity.Score))
-					}
-				}
 				data = append(data, []string{
 					score,
-					f.Name,
+					name,
 					// FIXME: replace f.ShortDescriptionColumns()[0] with
 					// f.MoreDetails.Name once we cover all bugs with our
 					// error-details.json
@@ -174,7 +274,7 @@ func (cmd *findingCmd) run(args []string) error {
 			} else {
 				data = append(data, []string{
 					score,
-					f.Name,
+					name,
 					f.ShortDescriptionColumns()[0],
 					locationInfo,
 				})
@@ -203,11 +303,12 @@ func (cmd *findingCmd) run(args []string) error {
 	if err != nil {
 		return err
 	}
+	cmd.classify([]*finding.Finding{f}, errorDetails)
 	return cmd.printFinding(f)
 }
 
 func (cmd *findingCmd) printFinding(f *finding.Finding) error {
-	if cmd.opts.PrintJSON {
+	if cmd.opts.Format == formatJSON {
 		s, err := stringutil.ToJSONString(f)
 		if err != nil {
 			return err
@@ -219,20 +320,186 @@ func (cmd *findingCmd) printFinding(f *finding.Finding) error {
 	} else {
 		s := pterm.Style{pterm.Reset, pterm.Bold}.Sprint(f.ShortDescriptionWithName())
 		s += fmt.Sprintf("\nDate: %s\n", f.CreatedAt)
+		if f.Reproducibility != nil {
+			reproducibility := cmdutils.ReproducibilityString(f.Reproducibility)
+			if f.IsFlaky() {
+				reproducibility += " " + pterm.Yellow("(flaky)")
+			}
+			s += fmt.Sprintf("Reproducibility: %s\n", reproducibility)
+		}
 		s += fmt.Sprintf("\n  %s\n", strings.Join(f.Logs, "\n  "))
+		s += sanitizerDetailsString(f.SanitizerDetails)
 		_, err := fmt.Fprint(cmd.OutOrStdout(), s)
 		if err != nil {
 			return err
 		}
 
-		// only show more details if the user is authenticated
-		if auth, err := auth.GetAuthStatus(cmd.opts.Server); err == nil && auth {
-			PrintMoreDetails(f)
+		PrintMoreDetails(f)
+	}
+	return nil
+}
+
+// validateListOptions checks the options which select, sort and format
+// the listed findings and sets the query and the output format.
+func (opts *options) validateListOptions(cmd *cobra.Command, hasNameArg bool) error {
+	listFlags := []string{"fuzz-test", "error-id", "severity", "min-score", "created-after", "source-file", "sort-by", "reverse"}
+	if hasNameArg {
+		for _, name := range listFlags {
+			if cmd.Flags().Changed(name) {
+				return errors.Errorf("The --%s flag can't be used together with a <name> argument", name)
+			}
+		}
+	}
+
+	switch opts.Format {
+	case "":
+		opts.Format = formatTable
+		if opts.PrintJSON {
+			opts.Format = formatJSON
+		}
+	case formatTable, formatJSON, formatCSV:
+	default:
+		return errors.Errorf("Invalid format %q (valid formats: table, json, csv)", opts.Format)
+	}
+	if opts.Format == formatCSV && (hasNameArg || opts.Baseline != "") {
+		return errors.New("The csv format can only be used to list findings")
+	}
+
+	if !sliceutil.Contains(finding.SortKeys, opts.SortBy) {
+		return errors.Errorf("Invalid sort key %q (valid keys: %s)", opts.SortBy, strings.Join(finding.SortKeys, ", "))
+	}
+
+	opts.query = &finding.Query{
+		FuzzTest:         opts.FuzzTest,
+		ErrorID:          opts.ErrorID,
+		MinSeverityScore: opts.MinScore,
+		SourceFile:       opts.SourceFile,
+	}
+	if opts.Severity != "" {
+		level, err := finding.ParseSeverityLevel(opts.Severity)
+		if err != nil {
+			return err
 		}
+		opts.query.MinSeverityLevel = level
+	}
+	if opts.CreatedAfter != "" {
+		createdAfter, err := parseTime(opts.CreatedAfter, time.Now())
+		if err != nil {
+			return err
+		}
+		opts.query.CreatedAfter = createdAfter
 	}
 	return nil
 }
 
+// parseTime parses a date, an RFC 3339 timestamp or a duration, which
+// can also be specified in days (e.g. "7d"), before now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if days, found := strings.CutSuffix(s, "d"); found {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err == nil {
+			return now.AddDate(0, 0, -int(n)), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, errors.Errorf("Invalid time %q, expected a date (2006-01-02), a timestamp (2006-01-02T15:04:05Z07:00) or a duration (e.g. 7d or 12h)", s)
+}
+
+// findingLocation returns the location (file, function, line) of the
+// finding, if available.
+func findingLocation(f *finding.Finding) string {
+	if len(f.ShortDescriptionColumns()) > 1 {
+		return f.ShortDescriptionColumns()[1]
+	}
+	return "n/a"
+}
+
+// printFindingsCSV prints the findings as CSV with a header row.
+func printFindingsCSV(out io.Writer, findings []*finding.Finding) error {
+	w := csv.NewWriter(out)
+	err := w.Write([]string{"name", "created_at", "fuzz_test", "error_id", "severity_level", "severity_score", "description", "location", "reproducibility", "flaky"})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, f := range findings {
+		var errorID, severityLevel, severityScore string
+		if f.MoreDetails != nil {
+			errorID = f.MoreDetails.ID
+			if f.MoreDetails.Severity != nil {
+				severityLevel = string(f.MoreDetails.Severity.Level)
+				severityScore = fmt.Sprintf("%.1f", f.MoreDetails.Severity.Score)
+			}
+		}
+		location := findingLocation(f)
+		var reproducibility, flaky string
+		if f.Reproducibility != nil {
+			reproducibility = strconv.FormatFloat(f.Reproducibility.Ratio, 'f', 2, 64)
+			flaky = strconv.FormatBool(f.Reproducibility.Flaky)
+		}
+		var createdAt string
+		if !f.CreatedAt.IsZero() {
+			createdAt = f.CreatedAt.Format(time.RFC3339)
+		}
+		err = w.Write([]string{
+			f.Name,
+			createdAt,
+			f.FuzzTest,
+			errorID,
+			severityLevel,
+			severityScore,
+			f.ShortDescriptionColumns()[0],
+			location,
+			reproducibility,
+			flaky,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	w.Flush()
+	return errors.WithStack(w.Error())
+}
+
+// compareWithBaseline classifies the findings of the project as new or
+// still present compared to the baseline findings. The crashing inputs
+// are not executed, so baseline findings which don't exist in the
+// project are classified as unverified instead of resolved.
+func (cmd *findingCmd) compareWithBaseline(errorDetails *[]finding.ErrorDetails) error {
+	findings, err := finding.ListFindings(cmd.opts.ProjectDir, errorDetails)
+	if err != nil {
+		return err
+	}
+	baseline, err := finding.LoadBaseline(cmd.opts.ProjectDir, cmd.opts.Baseline, errorDetails)
+	if err != nil {
+		log.Errorf(err, "Failed to load baseline: %v", err.Error())
+		return cmdutils.WrapSilentError(err)
+	}
+	cmd.classify(findings, errorDetails)
+	cmd.classify(baseline, errorDetails)
+	comparison := finding.CompareWithBaseline(cmd.opts.query.Filter(findings), cmd.opts.query.Filter(baseline), nil)
+	return cmdutils.PrintBaselineComparison(cmd.OutOrStdout(), comparison, cmd.opts.Format == formatJSON)
+}
+
+// classify determines the error IDs of the findings via the error ID
+// rules of the project, so that findings which were stored before the
+// rules were added or changed are shown with their current error IDs.
+// The findings are not saved, that's done by `cifuzz finding reclassify`.
+func (cmd *findingCmd) classify(findings []*finding.Finding, errorDetails *[]finding.ErrorDetails) {
+	for _, f := range findings {
+		if cmd.classifier.Classify(f) {
+			f.EnhanceWithErrorDetails(errorDetails)
+		}
+	}
+}
+
 func PrintMoreDetails(f *finding.Finding) {
 	if f.MoreDetails == nil {
 		return
@@ -287,6 +554,67 @@ func PrintMoreDetails(f *finding.Finding) {
 	}
 }
 
+// sanitizerDetailsString returns the structured information from the
+// sanitizer report in separate sections.
+func sanitizerDetailsString(d *finding.SanitizerDetails) string {
+	if d == nil {
+		return ""
+	}
+	var s string
+	section := func(title string, lines ...string) {
+		s += fmt.Sprintf("\n%s\n  %s\n", pterm.Blue(title+":"), strings.Join(lines, "\n  "))
+	}
+
+	if d.Summary != "" {
+		section("Summary", d.Summary)
+	} else if d.Sanitizer != "" {
+		section("Sanitizer", d.Sanitizer)
+	}
+	if d.UBSanMessage != "" {
+		section("Undefined behavior", d.UBSanMessage)
+	}
+	if d.Access != nil {
+		section("Memory access", memoryAccessString(d.Access))
+	}
+	if len(d.FreeStack) > 0 {
+		section("Freed at", stackTraceLines(d.FreeStack)...)
+	}
+	if len(d.AllocationStack) > 0 {
+		section("Allocated at", stackTraceLines(d.AllocationStack)...)
+	}
+	return s
+}
+
+func memoryAccessString(a *finding.MemoryAccess) string {
+	s := "Access"
+	if a.Type != "" {
+		s = a.Type
+	}
+	if a.Size != 0 {
+		s += fmt.Sprintf(" of %d bytes", a.Size)
+	}
+	if a.Address != "" {
+		s += " at " + a.Address
+	}
+	return s
+}
+
+func stackTraceLines(frames []*stacktrace.StackFrame) []string {
+	var lines []string
+	for _, frame := range frames {
+		location := fmt.Sprintf("%s:%d", frame.SourceFile, frame.Line)
+		if frame.Column != 0 {
+			location += fmt.Sprintf(":%d", frame.Column)
+		}
+		if frame.Function != "" {
+			lines = append(lines, fmt.Sprintf("#%d %s in %s", frame.FrameNumber, frame.Function, location))
+		} else {
+			lines = append(lines, fmt.Sprintf("#%d %s", frame.FrameNumber, location))
+		}
+	}
+	return lines
+}
+
 func getColorFunctionForSeverity(severity float32) func(a ...interface{}) string {
 	switch {
 	case severity >= 7.0:
@@ -320,26 +648,47 @@ func wrapLongStringToMultiline(s string, maxLineLength int) string {
 }
 
 // checkForErrorDetails tries to get error details from the API.
-// If the API is available and the user is logged in, it returns the error details.
-// If the API is not available or the user is not logged in, it returns nil.
+// If the API is available and the user is logged in, the error details
+// from the API are used in addition to the error details shipped with
+// cifuzz, else only the latter are used. In both cases, the error
+// details can be overridden by the error details file of the project.
 func (cmd *findingCmd) checkForErrorDetails() (*[]finding.ErrorDetails, error) {
-	var errorDetails []finding.ErrorDetails
-	var err error
+	remoteErrorDetails, err := cmd.remoteErrorDetails()
+	if err != nil {
+		return nil, err
+	}
 
+	errorDetailsFile := cmd.opts.ErrorDetailsFile
+	if errorDetailsFile != "" && !filepath.IsAbs(errorDetailsFile) {
+		errorDetailsFile = filepath.Join(cmd.opts.ProjectDir, errorDetailsFile)
+	}
+	errorDetails, err := finding.ResolveErrorDetails(remoteErrorDetails, errorDetailsFile)
+	if err != nil {
+		log.Errorf(err, "Failed to load error details: %v", err.Error())
+		return nil, cmdutils.WrapSilentError(err)
+	}
+	return &errorDetails, nil
+}
+
+func (cmd *findingCmd) remoteErrorDetails() ([]finding.ErrorDetails, error) {
 	token := auth.GetToken(cmd.opts.Server)
+	if token == "" {
+		log.Debug("No API token available, using built-in error details")
+		return nil, nil
+	}
 	log.Debugf("Checking for error details on server %s", cmd.opts.Server)
 
 	apiClient := api.NewClient(cmd.opts.Server, cmd.Command.Root().Version)
-	errorDetails, err = apiClient.GetErrorDetails(token)
+	errorDetails, err := apiClient.GetErrorDetails(token)
 	if err != nil {
 		var connErr *api.ConnectionError
 		if !errors.As(err, &connErr) {
 			return nil, err
 		} else {
-			log.Warn("Skipping error details.")
+			log.Warn("Skipping remote error details.")
 			log.Debugf("Connection error: %v (continiung gracefully)", connErr)
 			return nil, nil
 		}
 	}
-	return &errorDetails, nil
+	return errorDetails, nil
 }
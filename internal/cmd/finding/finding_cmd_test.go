@@ -1,10 +1,16 @@
 package finding
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pterm/pterm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -12,6 +18,8 @@ import (
 	"code-intelligence.com/cifuzz/internal/cmdutils"
 	"code-intelligence.com/cifuzz/internal/testutil"
 	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
 	"code-intelligence.com/cifuzz/util/stringutil"
 )
 
@@ -200,3 +208,307 @@ func TestPrintFinding_Authenticated(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, stdErr, "cifuzz found more extensive information about this finding:")
 }
+
+func TestListFindings_Baseline(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-list-findings-baseline-")
+	baselineDir := testutil.MkdirTemp(t, "", "test-list-findings-baseline-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	oldFinding := &finding.Finding{
+		Name:        "old_finding",
+		Details:     "heap-buffer-overflow on address 0x602000000e31",
+		MoreDetails: &finding.ErrorDetails{ID: "heap_buffer_overflow"},
+	}
+	err := oldFinding.Save(projectDir)
+	require.NoError(t, err)
+	err = oldFinding.Save(baselineDir)
+	require.NoError(t, err)
+
+	// Without new findings, the command succeeds
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin,
+		"--json", "--interactive=false", "--baseline", baselineDir)
+	require.NoError(t, err)
+	var comparison finding.BaselineComparison
+	err = json.Unmarshal([]byte(stdOut), &comparison)
+	require.NoError(t, err)
+	assert.Empty(t, comparison.New)
+	require.Len(t, comparison.StillPresent, 1)
+	assert.Equal(t, oldFinding.Name, comparison.StillPresent[0].Name)
+
+	// With a new finding, the command fails
+	newFinding := &finding.Finding{
+		Name:        "new_finding",
+		Details:     "attempting double-free on 0x6020000422b0 in thread T0:",
+		MoreDetails: &finding.ErrorDetails{ID: "double_free"},
+	}
+	err = newFinding.Save(projectDir)
+	require.NoError(t, err)
+	cmd := newWithOptions(opts)
+	// Don't print the usage message to stdout on error
+	cmd.SilenceUsage = true
+	stdOut, _, err = cmdutils.ExecuteCommand(t, cmd, os.Stdin,
+		"--json", "--interactive=false", "--baseline", baselineDir)
+	require.Error(t, err)
+	err = json.Unmarshal([]byte(stdOut), &comparison)
+	require.NoError(t, err)
+	require.Len(t, comparison.New, 1)
+	assert.Equal(t, newFinding.Name, comparison.New[0].Name)
+}
+
+func TestPrintFinding_BuiltinErrorDetails(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-print-finding-builtin-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	f := &finding.Finding{
+		Name:        "test_finding",
+		Details:     "heap-buffer-overflow on address 0x602000000e31",
+		MoreDetails: &finding.ErrorDetails{ID: "heap_buffer_overflow"},
+	}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+
+	// Without an API token, the built-in error details are used
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, f.Name, "--json", "--interactive=false")
+	require.NoError(t, err)
+	var printed finding.Finding
+	err = json.Unmarshal([]byte(stdOut), &printed)
+	require.NoError(t, err)
+	assert.Equal(t, "Heap Buffer Overflow", printed.MoreDetails.Name)
+	require.NotNil(t, printed.MoreDetails.CweDetails)
+	assert.EqualValues(t, 122, printed.MoreDetails.CweDetails.ID)
+
+	_, stdErr, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, f.Name, "--interactive=false")
+	require.NoError(t, err)
+	assert.Contains(t, stdErr, "cifuzz found more extensive information about this finding:")
+
+	// The error details can be overridden by the project
+	err = os.WriteFile(filepath.Join(projectDir, "error-details.yaml"), []byte(`
+error_details:
+  - id: heap_buffer_overflow
+    mitigation: Use our bounds-checked buffer class.
+`), 0o644)
+	require.NoError(t, err)
+	opts.ErrorDetailsFile = "error-details.yaml"
+	stdOut, _, err = cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, f.Name, "--json", "--interactive=false")
+	require.NoError(t, err)
+	err = json.Unmarshal([]byte(stdOut), &printed)
+	require.NoError(t, err)
+	assert.Equal(t, "Heap Buffer Overflow", printed.MoreDetails.Name)
+	assert.Equal(t, "Use our bounds-checked buffer class.", printed.MoreDetails.Mitigation)
+}
+
+func TestListFindings_ErrorIDRules(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-list-findings-rules-")
+	opts := &options{
+		ProjectDir:   projectDir,
+		ConfigDir:    projectDir,
+		ErrorIDRules: []errorid.Rule{{ID: "custom_assertion", Substrings: []string{"MY_ASSERT"}, MatchLogs: true}},
+	}
+
+	// The finding was stored before the error ID rule was added
+	f := &finding.Finding{
+		Name:        "test_finding",
+		Details:     "deadly signal",
+		Logs:        []string{"MY_ASSERT(ptr != nullptr) failed"},
+		MoreDetails: &finding.ErrorDetails{ID: "deadly_signal"},
+	}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "--json", "--interactive=false", "--error-id", "custom_assertion")
+	require.NoError(t, err)
+	var listed []*finding.Finding
+	err = json.Unmarshal([]byte(stdOut), &listed)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "custom_assertion", listed[0].MoreDetails.ID)
+
+	stdOut, _, err = cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, f.Name, "--json", "--interactive=false")
+	require.NoError(t, err)
+	var printed finding.Finding
+	err = json.Unmarshal([]byte(stdOut), &printed)
+	require.NoError(t, err)
+	assert.Equal(t, "custom_assertion", printed.MoreDetails.ID)
+
+	// The stored finding is not changed
+	stored, err := finding.LoadFinding(projectDir, f.Name, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "deadly_signal", stored.MoreDetails.ID)
+}
+
+func TestListFindings_FilterAndFormat(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-list-findings-filter-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	now := time.Now().Truncate(time.Second)
+	findings := []*finding.Finding{
+		{
+			Name:        "heap_overflow",
+			Type:        finding.ErrorTypeCrash,
+			Details:     "heap-buffer-overflow on address 0x602000000e31",
+			FuzzTest:    "parser_fuzz_test",
+			CreatedAt:   now.Add(-time.Hour),
+			MoreDetails: &finding.ErrorDetails{ID: "heap_buffer_overflow"},
+		},
+		{
+			Name:        "old_leak",
+			Type:        finding.ErrorTypeCrash,
+			Details:     "detected memory leaks",
+			FuzzTest:    "api_fuzz_test",
+			CreatedAt:   now.AddDate(0, 0, -30),
+			MoreDetails: &finding.ErrorDetails{ID: "memory_leak"},
+		},
+	}
+	for _, f := range findings {
+		err := f.Save(projectDir)
+		require.NoError(t, err)
+	}
+
+	listNames := func(args ...string) []string {
+		args = append([]string{"--format", "json", "--interactive=false"}, args...)
+		stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, args...)
+		require.NoError(t, err)
+		var listed []*finding.Finding
+		err = json.Unmarshal([]byte(stdOut), &listed)
+		require.NoError(t, err)
+		names := []string{}
+		for _, f := range listed {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"heap_overflow", "old_leak"}, listNames())
+	assert.Equal(t, []string{"old_leak"}, listNames("--fuzz-test", "api_fuzz_test"))
+	assert.Equal(t, []string{"heap_overflow"}, listNames("--error-id", "heap_buffer_overflow"))
+	// The severity is taken from the built-in error details
+	assert.Equal(t, []string{"heap_overflow"}, listNames("--severity", "high"))
+	assert.Equal(t, []string{"heap_overflow"}, listNames("--min-score", "9"))
+	assert.Equal(t, []string{"heap_overflow"}, listNames("--created-after", "7d"))
+	assert.Equal(t, []string{"old_leak", "heap_overflow"}, listNames("--sort-by", "name", "--reverse"))
+
+	// Check the CSV output
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin,
+		"--format", "csv", "--interactive=false", "--sort-by", "severity")
+	require.NoError(t, err)
+	records, err := csv.NewReader(strings.NewReader(stdOut)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "name", records[0][0])
+	assert.Equal(t, []string{
+		"heap_overflow",
+		now.Add(-time.Hour).Format(time.RFC3339),
+		"parser_fuzz_test",
+		"heap_buffer_overflow",
+		"CRITICAL",
+		"9.8",
+		"heap buffer overflow",
+		"n/a",
+		"",
+		"",
+	}, records[1])
+	assert.Equal(t, "old_leak", records[2][0])
+
+	// Check that invalid options are rejected
+	for _, args := range [][]string{
+		{"--format", "xml"},
+		{"--severity", "very-high"},
+		{"--sort-by", "color"},
+		{"--created-after", "yesterday"},
+		{"heap_overflow", "--fuzz-test", "api_fuzz_test"},
+	} {
+		cmd := newWithOptions(&options{ProjectDir: projectDir, ConfigDir: projectDir})
+		cmd.SilenceUsage = true
+		_, _, err = cmdutils.ExecuteCommand(t, cmd, os.Stdin, append(args, "--interactive=false")...)
+		assert.Errorf(t, err, "Expected an error for %v", args)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2023, 8, 15, 12, 0, 0, 0, time.UTC)
+
+	parsed, err := parseTime("2023-08-01T10:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC), parsed)
+
+	parsed, err = parseTime("2023-08-01", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 8, 1, 0, 0, 0, 0, time.Local), parsed)
+
+	parsed, err = parseTime("7d", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 8, 8, 12, 0, 0, 0, time.UTC), parsed)
+
+	parsed, err = parseTime("12h", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC), parsed)
+
+	_, err = parseTime("last week", now)
+	require.Error(t, err)
+}
+
+func TestPrintFinding_Flaky(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-print-finding-flaky-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+	f := &finding.Finding{
+		Name:            "flaky_finding",
+		Reproducibility: finding.NewReproducibility(10, 3),
+	}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, f.Name, "--interactive=false")
+	require.NoError(t, err)
+	assert.Contains(t, pterm.RemoveColorFromString(stdOut), "Reproducibility: 3/10 runs (30%) (flaky)")
+
+	stdOut, _, err = cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "--format", "csv", "--interactive=false")
+	require.NoError(t, err)
+	records, err := csv.NewReader(strings.NewReader(stdOut)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"0.30", "true"}, records[1][8:])
+}
+
+func TestPrintFinding_SanitizerDetails(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-print-finding-sanitizer-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+	f := &finding.Finding{
+		Name: "uaf_finding",
+		SanitizerDetails: &finding.SanitizerDetails{
+			Sanitizer: "AddressSanitizer",
+			Access:    &finding.MemoryAccess{Address: "0x602000000010", Size: 4, Type: finding.AccessTypeRead},
+			FreeStack: []*stacktrace.StackFrame{
+				{SourceFile: "src/uaf.cpp", Line: 5, Column: 3, FrameNumber: 1, Function: "release"},
+			},
+			AllocationStack: []*stacktrace.StackFrame{
+				{SourceFile: "src/uaf.cpp", Line: 18, FrameNumber: 1, Function: "LLVMFuzzerTestOneInput"},
+			},
+			Summary: "AddressSanitizer: heap-use-after-free src/uaf.cpp:10:3 in use(int*)",
+		},
+	}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, f.Name, "--interactive=false")
+	require.NoError(t, err)
+	stdOut = pterm.RemoveColorFromString(stdOut)
+	assert.Contains(t, stdOut, "Summary:\n  AddressSanitizer: heap-use-after-free src/uaf.cpp:10:3 in use(int*)")
+	assert.Contains(t, stdOut, "Memory access:\n  READ of 4 bytes at 0x602000000010")
+	assert.Contains(t, stdOut, "Freed at:\n  #1 release in src/uaf.cpp:5:3")
+	assert.Contains(t, stdOut, "Allocated at:\n  #1 LLVMFuzzerTestOneInput in src/uaf.cpp:18")
+}
@@ -0,0 +1,186 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/completion"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/inputview"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/sliceutil"
+)
+
+type options struct {
+	ProjectDir string             `mapstructure:"project-dir"`
+	ConfigDir  string             `mapstructure:"config-dir"`
+	InputView  *inputview.Options `mapstructure:"input-view"`
+
+	flags inputview.Options
+	Save  bool
+}
+
+type inputCmd struct {
+	*cobra.Command
+	opts *options
+}
+
+func New() *cobra.Command {
+	return newWithOptions(&options{})
+}
+
+func newWithOptions(opts *options) *cobra.Command {
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "input [flags] <name>",
+		Short: "Show the crashing input of a finding",
+		Long: `This command shows the crashing input of a finding in a human
+readable form. The following formats are supported:
+
+  hexdump     An annotated hexdump of the input (default)
+  fdp         Replays the specified calls of the FuzzedDataProvider of
+              LLVM (used by C/C++ fuzz tests) and shows which bytes
+              were consumed as which values
+  jazzer-fdp  Like fdp, but for the FuzzedDataProvider of Jazzer
+              (used by Java fuzz tests)
+  protobuf    Decodes the input of a libprotobuf-mutator fuzz test as
+              a protobuf message, using a .proto file (requires protoc)
+              or a descriptor set
+
+The FuzzedDataProvider calls are specified in the order in which the
+fuzz test executes them, separated by semicolons or newlines. A call
+followed by "*" is repeated until all data is consumed, for example:
+
+    cifuzz finding input my_finding --format fdp \
+      --calls 'ConsumeIntegral<uint8_t>(); ConsumeRandomLengthString(16)*'
+
+The defaults of these options can be configured in the "input-view"
+section of cifuzz.yaml, which is also used to render the inputs which
+are stored in new findings.
+
+With --save, the rendering is stored in the finding.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completion.ValidFindings,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind viper keys to flags. We can't do this in the New
+			// function, because that would re-bind viper keys which
+			// were bound to the flags of other commands before.
+			bindFlags()
+			err := config.FindAndParseProjectConfig(opts)
+			if err != nil {
+				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			if opts.InputView == nil {
+				opts.InputView = &inputview.Options{}
+			}
+			opts.InputView.ResolvePaths(opts.ProjectDir)
+
+			// Flags take precedence over the config file
+			flags := cmd.Flags()
+			if flags.Changed("format") {
+				opts.InputView.Format = opts.flags.Format
+			}
+			if flags.Changed("calls") {
+				opts.InputView.Calls = opts.flags.Calls
+				opts.InputView.CallsFile = ""
+			}
+			if flags.Changed("calls-file") {
+				opts.InputView.CallsFile = opts.flags.CallsFile
+				if !flags.Changed("calls") {
+					opts.InputView.Calls = ""
+				}
+			}
+			if flags.Changed("proto") {
+				opts.InputView.Proto = opts.flags.Proto
+			}
+			if flags.Changed("proto-include-dir") {
+				opts.InputView.ProtoIncludeDirs = opts.flags.ProtoIncludeDirs
+			}
+			if flags.Changed("proto-message") {
+				opts.InputView.ProtoMessage = opts.flags.ProtoMessage
+			}
+			if flags.Changed("max-bytes") {
+				opts.InputView.MaxBytes = opts.flags.MaxBytes
+			}
+
+			if opts.InputView.Format != "" && !sliceutil.Contains(inputview.Formats, opts.InputView.Format) {
+				msg := fmt.Sprintf("Unsupported format %q, supported formats are: %s",
+					opts.InputView.Format, strings.Join(inputview.Formats, ", "))
+				return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			cmd := inputCmd{Command: c, opts: opts}
+			return cmd.run(args[0])
+		},
+	}
+
+	// Note: If a flag should be configurable via viper as well (i.e.
+	//       via cifuzz.yaml and CIFUZZ_* environment variables), bind
+	//       it to viper in the PreRun function.
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddProjectDirFlag,
+	)
+	cmd.Flags().StringVar(&opts.flags.Format, "format", "", "The `format` in which the input is shown: "+strings.Join(inputview.Formats, ", ")+".")
+	cmd.Flags().StringVar(&opts.flags.Calls, "calls", "", "The FuzzedDataProvider `calls` executed by the fuzz test.")
+	cmd.Flags().StringVar(&opts.flags.CallsFile, "calls-file", "", "A `file` containing the FuzzedDataProvider calls executed by the fuzz test.")
+	cmd.Flags().StringVar(&opts.flags.Proto, "proto", "", "A .proto `file` or descriptor set defining the message type of the input.")
+	cmd.Flags().StringArrayVar(&opts.flags.ProtoIncludeDirs, "proto-include-dir", nil, "A `directory` in which protoc searches for imports (can be specified multiple times).")
+	cmd.Flags().StringVar(&opts.flags.ProtoMessage, "proto-message", "", "The full `name` of the message type of the input.")
+	cmd.Flags().IntVar(&opts.flags.MaxBytes, "max-bytes", 0, "The maximum `number` of bytes shown by the hexdump (0 means unlimited).")
+	cmd.Flags().BoolVar(&opts.Save, "save", false, "Store the rendered input in the finding.")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(inputview.Formats, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+func (c *inputCmd) run(findingName string) error {
+	f, err := finding.LoadFinding(c.opts.ProjectDir, findingName, nil)
+	if finding.IsNotExistError(err) {
+		log.Errorf(err, "Finding %s does not exist", findingName)
+		return cmdutils.WrapSilentError(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	data := f.InputData
+	if f.InputFile != "" {
+		data, err = os.ReadFile(filepath.Join(c.opts.ProjectDir, f.InputFile))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if data == nil {
+		err = errors.Errorf("Finding %s has no crashing input", findingName)
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	res, err := inputview.Render(data, c.opts.InputView)
+	if err != nil {
+		log.Errorf(err, "Failed to render the crashing input: %v", err.Error())
+		return cmdutils.WrapSilentError(err)
+	}
+	_, _ = fmt.Fprint(c.OutOrStdout(), res)
+
+	if c.opts.Save {
+		f.HumanReadableInput = res
+		err = f.Save(c.opts.ProjectDir)
+		if err != nil {
+			return err
+		}
+		log.Successf("Stored the rendered input in finding %s", findingName)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/testutil"
+	"code-intelligence.com/cifuzz/pkg/finding"
+)
+
+func saveFinding(t *testing.T, projectDir string, input []byte) {
+	inputFile := filepath.Join(".cifuzz-findings", "my_finding", "crashing-input")
+	err := os.MkdirAll(filepath.Join(projectDir, filepath.Dir(inputFile)), 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(projectDir, inputFile), input, 0o644)
+	require.NoError(t, err)
+
+	f := &finding.Finding{Name: "my_finding", InputFile: inputFile}
+	err = f.Save(projectDir)
+	require.NoError(t, err)
+}
+
+func TestInput_FindingDoesNotExist(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-finding-input-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	_, stdErr, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding")
+	require.Error(t, err)
+	assert.Contains(t, stdErr, "Finding my_finding does not exist")
+}
+
+func TestInput_Hexdump(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-finding-input-")
+	saveFinding(t, projectDir, []byte("FUZZ"))
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding")
+	require.NoError(t, err)
+	assert.Contains(t, stdOut, "46 55 5a 5a")
+	assert.Contains(t, stdOut, "|FUZZ|")
+}
+
+func TestInput_FDPAndSave(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-finding-input-")
+	saveFinding(t, projectDir, []byte("abc\x01"))
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	args := []string{"my_finding", "--format", "fdp", "--calls", "ConsumeBool(); ConsumeRemainingBytesAsString()", "--save"}
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, args...)
+	require.NoError(t, err)
+	assert.Contains(t, stdOut, "ConsumeBool() = true")
+	assert.Contains(t, stdOut, `ConsumeRemainingBytesAsString() = "abc"`)
+
+	f, err := finding.LoadFinding(projectDir, "my_finding", nil)
+	require.NoError(t, err)
+	assert.Equal(t, stdOut, strings.TrimSpace(f.HumanReadableInput))
+}
+
+func TestInput_UnsupportedFormat(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-finding-input-")
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	cmd := newWithOptions(opts)
+	cmd.SilenceUsage = true
+	_, _, err := cmdutils.ExecuteCommand(t, cmd, os.Stdin, "my_finding", "--format", "foo")
+	require.Error(t, err)
+}
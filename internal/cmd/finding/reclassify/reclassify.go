@@ -0,0 +1,171 @@
+package reclassify
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/completion"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
+	"code-intelligence.com/cifuzz/util/stringutil"
+)
+
+type options struct {
+	ErrorIDRules     []errorid.Rule `mapstructure:"error-id-rules"`
+	ErrorIDRulesFile string         `mapstructure:"error-id-rules-file"`
+	PrintJSON        bool           `mapstructure:"print-json"`
+	ProjectDir       string         `mapstructure:"project-dir"`
+	ConfigDir        string         `mapstructure:"config-dir"`
+
+	DryRun bool
+}
+
+type reclassifyCmd struct {
+	*cobra.Command
+	opts       *options
+	classifier *errorid.Classifier
+}
+
+// Change describes the reclassification of a finding
+type Change struct {
+	Name       string `json:"name"`
+	OldErrorID string `json:"old_error_id"`
+	NewErrorID string `json:"new_error_id"`
+}
+
+func New() *cobra.Command {
+	return newWithOptions(&options{})
+}
+
+func newWithOptions(opts *options) *cobra.Command {
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "reclassify [flags] [<name>...]",
+		Short: "Determine the error IDs of stored findings again",
+		Long: `This command determines the error IDs of stored findings again, using
+the error ID rules of the project (configured via "error-id-rules" and
+"error-id-rules-file" in cifuzz.yaml) and the built-in rules of cifuzz.
+
+This is useful after adding or changing error ID rules, to apply them
+to the findings which were found before. The error details of the
+findings are updated according to their new error IDs.
+
+If no finding names are specified, all findings of the project are
+reclassified.`,
+		ValidArgsFunction: completion.ValidFindings,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind viper keys to flags. We can't do this in the New
+			// function, because that would re-bind viper keys which
+			// were bound to the flags of other commands before.
+			bindFlags()
+			err := config.FindAndParseProjectConfig(opts)
+			if err != nil {
+				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			classifier, err := errorid.NewProjectClassifier(opts.ProjectDir, opts.ErrorIDRules, opts.ErrorIDRulesFile)
+			if err != nil {
+				log.Errorf(err, "Failed to load error ID rules: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			cmd := reclassifyCmd{Command: c, opts: opts, classifier: classifier}
+			return cmd.run(args)
+		},
+	}
+
+	// Note: If a flag should be configurable via viper as well (i.e.
+	//       via cifuzz.yaml and CIFUZZ_* environment variables), bind
+	//       it to viper in the PreRun function.
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddPrintJSONFlag,
+		cmdutils.AddProjectDirFlag,
+	)
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Only print the changed error IDs without updating the findings.")
+
+	return cmd
+}
+
+func (c *reclassifyCmd) run(names []string) error {
+	findings, err := c.loadFindings(names)
+	if err != nil {
+		return err
+	}
+
+	changes := []*Change{}
+	for _, f := range findings {
+		var oldID string
+		if f.MoreDetails != nil {
+			oldID = f.MoreDetails.ID
+		}
+		newID := c.classifier.ForFinding(f)
+		if newID == "" || newID == oldID {
+			continue
+		}
+		changes = append(changes, &Change{Name: f.Name, OldErrorID: oldID, NewErrorID: newID})
+
+		if c.opts.DryRun {
+			continue
+		}
+		// The error details of the old error ID don't apply anymore.
+		// The details of the new error ID are added when the finding
+		// is loaded.
+		f.MoreDetails = &finding.ErrorDetails{ID: newID}
+		err = f.Save(c.opts.ProjectDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.opts.PrintJSON {
+		s, err := stringutil.ToJSONString(changes)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(c.OutOrStdout(), s)
+		return nil
+	}
+
+	for _, change := range changes {
+		oldID := change.OldErrorID
+		if oldID == "" {
+			oldID = "<none>"
+		}
+		_, _ = fmt.Fprintf(c.OutOrStdout(), "%s: %s -> %s\n", change.Name, oldID, change.NewErrorID)
+	}
+	switch {
+	case len(changes) == 0:
+		log.Printf("The error IDs of all %d findings are up to date", len(findings))
+	case c.opts.DryRun:
+		log.Printf("%d of %d findings would be reclassified", len(changes), len(findings))
+	default:
+		log.Successf("Reclassified %d of %d findings", len(changes), len(findings))
+	}
+	return nil
+}
+
+func (c *reclassifyCmd) loadFindings(names []string) ([]*finding.Finding, error) {
+	if len(names) == 0 {
+		return finding.ListFindings(c.opts.ProjectDir, nil)
+	}
+	var findings []*finding.Finding
+	for _, name := range names {
+		f, err := finding.LoadFinding(c.opts.ProjectDir, name, nil)
+		if finding.IsNotExistError(err) {
+			log.Errorf(err, "Finding %s does not exist", name)
+			return nil, cmdutils.WrapSilentError(err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
@@ -0,0 +1,114 @@
+package reclassify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/testutil"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
+)
+
+func saveFindings(t *testing.T, projectDir string) {
+	findings := []*finding.Finding{
+		{
+			Name:        "custom_finding",
+			Details:     "Security Issue: Custom Sanitizer",
+			MoreDetails: &finding.ErrorDetails{ID: "jazzer_security_issue", Name: "Jazzer Security Issue"},
+		},
+		{
+			Name:        "other_finding",
+			Details:     "heap-use-after-free on address 0x01",
+			MoreDetails: &finding.ErrorDetails{ID: "heap_use_after_free"},
+		},
+	}
+	for _, f := range findings {
+		err := f.Save(projectDir)
+		require.NoError(t, err)
+	}
+}
+
+func TestReclassify(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-reclassify-")
+	saveFindings(t, projectDir)
+	opts := &options{
+		ProjectDir:   projectDir,
+		ConfigDir:    projectDir,
+		ErrorIDRules: []errorid.Rule{{ID: "custom_sanitizer", Substrings: []string{"Custom Sanitizer"}}},
+	}
+
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin)
+	require.NoError(t, err)
+	assert.Equal(t, "custom_finding: jazzer_security_issue -> custom_sanitizer", stdOut)
+
+	f, err := finding.LoadFinding(projectDir, "custom_finding", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &finding.ErrorDetails{ID: "custom_sanitizer"}, f.MoreDetails)
+
+	f, err = finding.LoadFinding(projectDir, "other_finding", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "heap_use_after_free", f.MoreDetails.ID)
+}
+
+func TestReclassify_DryRun(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-reclassify-")
+	saveFindings(t, projectDir)
+	err := os.WriteFile(filepath.Join(projectDir, "rules.yaml"), []byte(`
+rules:
+  - id: custom_sanitizer
+    substrings: ["Custom Sanitizer"]
+`), 0o644)
+	require.NoError(t, err)
+	opts := &options{
+		ProjectDir:       projectDir,
+		ConfigDir:        projectDir,
+		ErrorIDRulesFile: "rules.yaml",
+	}
+
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "custom_finding", "--dry-run")
+	require.NoError(t, err)
+	assert.Equal(t, "custom_finding: jazzer_security_issue -> custom_sanitizer", stdOut)
+
+	f, err := finding.LoadFinding(projectDir, "custom_finding", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "jazzer_security_issue", f.MoreDetails.ID)
+}
+
+func TestReclassify_InvalidRules(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-reclassify-")
+	opts := &options{
+		ProjectDir:   projectDir,
+		ConfigDir:    projectDir,
+		ErrorIDRules: []errorid.Rule{{ID: "invalid", Regexes: []string{"("}}},
+	}
+
+	_, stdErr, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin)
+	require.Error(t, err)
+	assert.Contains(t, stdErr, "Failed to load error ID rules")
+}
+
+func TestReclassify_RulesFromConfig(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-reclassify-")
+	saveFindings(t, projectDir)
+	config := `
+error-id-rules:
+  - id: custom_sanitizer
+    substrings: ["Custom Sanitizer"]
+    priority: 1
+`
+	err := os.WriteFile(filepath.Join(projectDir, "cifuzz.yaml"), []byte(config), 0o644)
+	require.NoError(t, err)
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+	}
+
+	stdOut, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "--dry-run")
+	require.NoError(t, err)
+	assert.Equal(t, "custom_finding: jazzer_security_issue -> custom_sanitizer", stdOut)
+}
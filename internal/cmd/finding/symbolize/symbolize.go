@@ -0,0 +1,324 @@
+package symbolize
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/completion"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+	"code-intelligence.com/cifuzz/pkg/parser/sanitizer"
+	"code-intelligence.com/cifuzz/pkg/runfiles"
+	"code-intelligence.com/cifuzz/pkg/symbolize"
+	"code-intelligence.com/cifuzz/util/fileutil"
+	"code-intelligence.com/cifuzz/util/stringutil"
+)
+
+const (
+	symbolizerAuto  = "auto"
+	symbolizerLLVM  = "llvm"
+	symbolizerDWARF = "dwarf"
+)
+
+var symbolizers = []string{symbolizerAuto, symbolizerLLVM, symbolizerDWARF}
+
+type options struct {
+	PrintJSON  bool   `mapstructure:"print-json"`
+	ProjectDir string `mapstructure:"project-dir"`
+	ConfigDir  string `mapstructure:"config-dir"`
+
+	BuildDir   string
+	Symbolizer string
+}
+
+type symbolizeCmd struct {
+	*cobra.Command
+	opts       *options
+	symbolizer symbolize.Symbolizer
+	// Maps the base names of the files in the build directory to their
+	// paths, populated on first use
+	buildFiles map[string][]string
+}
+
+func New() *cobra.Command {
+	return newWithOptions(&options{})
+}
+
+func newWithOptions(opts *options) *cobra.Command {
+	var bindFlags func()
+
+	cmd := &cobra.Command{
+		Use:   "symbolize [flags] [<name>...]",
+		Short: "Resolve the source locations of stack frames of findings",
+		Long: `This command resolves the source locations of the stack frames of
+findings which were printed without source location, for example
+because llvm-symbolizer was not available or the binary was stripped
+when the finding was found, or because the finding was imported from a
+bundle which was run elsewhere.
+
+The modules (executables and shared libraries) of the stack frames are
+searched in the build directory if --build-dir is specified, which
+takes precedence over the module paths from the logs (for example if
+the binaries at those paths are stripped). Otherwise, modules which
+don't exist at the paths from the logs are searched in the project
+directory. The modules are symbolized via llvm-symbolizer or, if
+llvm-symbolizer is not available, via the DWARF debug information of
+the modules. The binaries must be built with debug information and from
+the same sources as the ones which produced the finding.
+
+The logs and the stack trace of the findings are updated. The original
+logs are kept in the finding.
+
+If no finding names are specified, all findings of the project are
+symbolized.`,
+		ValidArgsFunction: completion.ValidFindings,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind viper keys to flags. We can't do this in the New
+			// function, because that would re-bind viper keys which
+			// were bound to the flags of other commands before.
+			bindFlags()
+			err := config.FindAndParseProjectConfig(opts)
+			if err != nil {
+				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
+				return cmdutils.WrapSilentError(err)
+			}
+			if !stringutil.Contains(symbolizers, opts.Symbolizer) {
+				msg := fmt.Sprintf("Invalid symbolizer %q, valid values are: %s", opts.Symbolizer, strings.Join(symbolizers, ", "))
+				return cmdutils.WrapIncorrectUsageError(errors.New(msg))
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			cmd := symbolizeCmd{Command: c, opts: opts}
+			return cmd.run(args)
+		},
+	}
+
+	// Note: If a flag should be configurable via viper as well (i.e.
+	//       via cifuzz.yaml and CIFUZZ_* environment variables), bind
+	//       it to viper in the PreRun function.
+	bindFlags = cmdutils.AddFlags(cmd,
+		cmdutils.AddPrintJSONFlag,
+		cmdutils.AddProjectDirFlag,
+	)
+	cmd.Flags().StringVar(&opts.BuildDir, "build-dir", "", "The `directory` in which the modules of the stack frames are searched before the paths from the logs.")
+	cmd.Flags().StringVar(&opts.Symbolizer, "symbolizer", symbolizerAuto, "The `symbolizer` to use: "+strings.Join(symbolizers, ", ")+".")
+	_ = cmd.RegisterFlagCompletionFunc("symbolizer", cobra.FixedCompletions(symbolizers, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+func (c *symbolizeCmd) run(names []string) error {
+	var err error
+	c.symbolizer, err = c.newSymbolizer()
+	if err != nil {
+		log.Error(err)
+		return cmdutils.WrapSilentError(err)
+	}
+
+	findings, err := c.loadFindings(names)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		log.Print("This project doesn't have any findings yet")
+		return nil
+	}
+
+	symbolized := []*finding.Finding{}
+	for _, f := range findings {
+		ok, err := c.symbolizeFinding(f)
+		if err != nil {
+			log.Errorf(err, "Failed to symbolize finding %s: %v", f.Name, err.Error())
+			return cmdutils.WrapSilentError(err)
+		}
+		if ok {
+			symbolized = append(symbolized, f)
+		}
+	}
+
+	if c.opts.PrintJSON {
+		s, err := stringutil.ToJSONString(symbolized)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(c.OutOrStdout(), s)
+	}
+	return nil
+}
+
+// symbolizeFinding symbolizes the logs of the finding and saves it if
+// any frames were symbolized.
+func (c *symbolizeCmd) symbolizeFinding(f *finding.Finding) (bool, error) {
+	res, err := symbolize.Logs(f.Logs, c.symbolizer, c.resolveModule)
+	if err != nil {
+		return false, err
+	}
+	if res.NumRawFrames == 0 {
+		log.Infof("Finding %s has no stack frames without source location", f.Name)
+		return false, nil
+	}
+	if res.NumSymbolized == 0 {
+		log.Warnf("None of the %d stack frames of finding %s could be symbolized", res.NumRawFrames, f.Name)
+		return false, nil
+	}
+
+	if f.OriginalLogs == nil {
+		f.OriginalLogs = f.Logs
+	}
+	f.Logs = res.Logs
+
+	parserOpts := &stacktrace.ParserOptions{ProjectDir: c.opts.ProjectDir}
+	f.StackTrace, err = stacktrace.NewParser(parserOpts).Parse(f.Logs)
+	if err != nil {
+		return false, err
+	}
+	f.SanitizerDetails, err = sanitizer.ParseDetails(f.Logs, parserOpts)
+	if err != nil {
+		return false, err
+	}
+
+	err = f.Save(c.opts.ProjectDir)
+	if err != nil {
+		return false, err
+	}
+	log.Successf("Symbolized %d of %d stack frames of finding %s", res.NumSymbolized, res.NumRawFrames, f.Name)
+	return true, nil
+}
+
+func (c *symbolizeCmd) newSymbolizer() (symbolize.Symbolizer, error) {
+	if c.opts.Symbolizer == symbolizerDWARF {
+		return &symbolize.DWARFSymbolizer{}, nil
+	}
+	path, err := runfiles.Finder.LLVMSymbolizerPath()
+	if err == nil {
+		return &symbolize.LLVMSymbolizer{Path: path}, nil
+	}
+	if c.opts.Symbolizer == symbolizerLLVM {
+		return nil, errors.Wrap(err, "llvm-symbolizer not found")
+	}
+	log.Debugf("llvm-symbolizer not found, using the DWARF symbolizer: %v", err)
+	return &symbolize.DWARFSymbolizer{}, nil
+}
+
+// resolveModule returns the path of the module on this system. If the
+// build directory was specified, the module is searched there first,
+// because the module at the path from the logs might be a stripped
+// binary. Otherwise, modules which don't exist at the path from the
+// logs are searched in the project directory.
+func (c *symbolizeCmd) resolveModule(module string) string {
+	exists, err := fileutil.Exists(module)
+	existsLocally := err == nil && exists
+
+	if c.opts.BuildDir == "" && existsLocally {
+		return module
+	}
+
+	buildDir := c.opts.BuildDir
+	if buildDir == "" {
+		buildDir = c.opts.ProjectDir
+	}
+	path := c.findInBuildDir(module, buildDir)
+	if path != "" {
+		log.Debugf("Using %s for module %s", path, module)
+		return path
+	}
+	if existsLocally {
+		return module
+	}
+	log.Warnf("Module %s not found in %s", module, buildDir)
+	return ""
+}
+
+// findInBuildDir searches the module by its base name in the build
+// directory. If multiple files have the same base name, the one whose
+// path shares the most trailing path components with the module path
+// is used.
+func (c *symbolizeCmd) findInBuildDir(module string, buildDir string) string {
+	if c.buildFiles == nil {
+		c.buildFiles = make(map[string][]string)
+		err := filepath.WalkDir(buildDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Skip directories which can't be read
+				return nil
+			}
+			if d.IsDir() && (d.Name() == ".git" || d.Name() == ".cifuzz-findings" || d.Name() == ".cifuzz-corpus") {
+				return filepath.SkipDir
+			}
+			if d.Type().IsRegular() {
+				c.buildFiles[d.Name()] = append(c.buildFiles[d.Name()], path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Debugf("Failed to search the build directory: %v", err)
+		}
+	}
+
+	// The module path was printed on the system the fuzz test was run
+	// on, which is not necessarily the same as this one
+	moduleComponents := strings.Split(strings.ReplaceAll(module, "\\", "/"), "/")
+	base := moduleComponents[len(moduleComponents)-1]
+	candidates := c.buildFiles[base]
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	var best []string
+	bestScore := 0
+	for _, candidate := range candidates {
+		rel, err := filepath.Rel(buildDir, candidate)
+		if err != nil {
+			rel = candidate
+		}
+		score := commonSuffixLen(moduleComponents, strings.Split(filepath.ToSlash(rel), "/"))
+		if score > bestScore {
+			best = []string{candidate}
+			bestScore = score
+		} else if score == bestScore {
+			best = append(best, candidate)
+		}
+	}
+	if len(best) > 1 {
+		log.Warnf("Module %s is ambiguous, found %d files with the same path suffix in %s:\n  %s\nUsing %s",
+			module, len(best), buildDir, strings.Join(best, "\n  "), best[0])
+	}
+	return best[0]
+}
+
+// commonSuffixLen returns the number of equal trailing elements of a
+// and b.
+func commonSuffixLen(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+func (c *symbolizeCmd) loadFindings(names []string) ([]*finding.Finding, error) {
+	if len(names) == 0 {
+		return finding.ListFindings(c.opts.ProjectDir, nil)
+	}
+	var findings []*finding.Finding
+	for _, name := range names {
+		f, err := finding.LoadFinding(c.opts.ProjectDir, name, nil)
+		if finding.IsNotExistError(err) {
+			log.Errorf(err, "Finding %s does not exist", name)
+			return nil, cmdutils.WrapSilentError(err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
@@ -0,0 +1,176 @@
+package symbolize
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/internal/testutil"
+	"code-intelligence.com/cifuzz/pkg/finding"
+)
+
+const testSource = `int foo(int x) {
+  return x * 2;
+}
+
+int main(int argc, char **argv) {
+  return foo(argc);
+}
+`
+
+// buildTestBinary compiles the test source with debug information in
+// the build directory and returns the address of the function foo.
+func buildTestBinary(t *testing.T, buildDir string) uint64 {
+	if runtime.GOOS != "linux" {
+		t.Skip("The test binary is only built on Linux")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("No C compiler found")
+	}
+
+	src := filepath.Join(buildDir, "test.c")
+	err = os.WriteFile(src, []byte(testSource), 0o644)
+	require.NoError(t, err)
+	binary := filepath.Join(buildDir, "my_fuzz_test")
+	out, err := exec.Command(cc, "-g", "-O0", "-o", binary, src).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	file, err := elf.Open(binary)
+	require.NoError(t, err)
+	defer file.Close()
+	symbols, err := file.Symbols()
+	require.NoError(t, err)
+	for _, symbol := range symbols {
+		if symbol.Name == "foo" {
+			return symbol.Value
+		}
+	}
+	require.FailNow(t, "Symbol foo not found")
+	return 0
+}
+
+func TestSymbolize(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-symbolize-")
+	address := buildTestBinary(t, projectDir)
+
+	// The module path is the one of the system the fuzz test was run on
+	logs := []string{
+		"==16==ERROR: AddressSanitizer: heap-buffer-overflow on address 0x602000000010",
+		fmt.Sprintf("    #0 0x55a0e3c5c1b2  (/out/my_fuzz_test+0x%x)", address),
+		"    #1 0x55a0e3c5c1c3  (/out/libunknown.so+0x1c3)",
+	}
+	f := &finding.Finding{Name: "my_finding", Logs: logs}
+	err := f.Save(projectDir)
+	require.NoError(t, err)
+
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+		Symbolizer: symbolizerDWARF,
+	}
+	_, _, err = cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding")
+	require.NoError(t, err)
+
+	f, err = finding.LoadFinding(projectDir, "my_finding", nil)
+	require.NoError(t, err)
+	assert.Equal(t, logs, f.OriginalLogs)
+	require.Len(t, f.Logs, 3)
+	assert.Regexp(t, `^    #0 0x55a0e3c5c1b2 in foo .*test\.c:1`, f.Logs[1])
+	// Frames which can't be symbolized are kept unchanged
+	assert.Equal(t, logs[2], f.Logs[2])
+	require.Len(t, f.StackTrace, 1)
+	assert.Equal(t, "foo", f.StackTrace[0].Function)
+	assert.Equal(t, "test.c", f.StackTrace[0].SourceFile)
+	assert.Equal(t, uint32(1), f.StackTrace[0].Line)
+
+	// Symbolizing again keeps the original logs
+	_, _, err = cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "my_finding")
+	require.NoError(t, err)
+	f, err = finding.LoadFinding(projectDir, "my_finding", nil)
+	require.NoError(t, err)
+	assert.Equal(t, logs, f.OriginalLogs)
+}
+
+func TestSymbolize_InvalidSymbolizer(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-symbolize-")
+	opts := &options{ProjectDir: projectDir, ConfigDir: projectDir}
+	_, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "--symbolizer", "foo")
+	require.Error(t, err)
+	var usageErr *cmdutils.IncorrectUsageError
+	assert.ErrorAs(t, err, &usageErr)
+}
+
+func TestSymbolize_NotExist(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-symbolize-")
+	opts := &options{ProjectDir: projectDir, ConfigDir: projectDir}
+	_, _, err := cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "foo")
+	require.Error(t, err)
+	var silentErr *cmdutils.SilentError
+	assert.ErrorAs(t, err, &silentErr)
+}
+
+func TestSymbolize_BuildDirPreferredOverStrippedModule(t *testing.T) {
+	projectDir := testutil.BootstrapEmptyProject(t, "test-symbolize-")
+	buildDir := filepath.Join(projectDir, "build")
+	err := os.Mkdir(buildDir, 0o755)
+	require.NoError(t, err)
+	address := buildTestBinary(t, buildDir)
+	strip, err := exec.LookPath("strip")
+	if err != nil {
+		t.Skip("strip not found")
+	}
+
+	// The stripped binary still exists at the path from the logs
+	runDir := testutil.MkdirTemp(t, "", "test-symbolize-run-")
+	stripped := filepath.Join(runDir, "my_fuzz_test")
+	out, err := exec.Command(strip, "-o", stripped, filepath.Join(buildDir, "my_fuzz_test")).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	logs := []string{
+		"==16==ERROR: AddressSanitizer: heap-buffer-overflow on address 0x602000000010",
+		fmt.Sprintf("    #0 0x55a0e3c5c1b2  (%s+0x%x)", stripped, address),
+	}
+	f := &finding.Finding{Name: "my_finding", Logs: logs}
+	err = f.Save(projectDir)
+	require.NoError(t, err)
+
+	opts := &options{
+		ProjectDir: projectDir,
+		ConfigDir:  projectDir,
+		Symbolizer: symbolizerDWARF,
+	}
+	_, _, err = cmdutils.ExecuteCommand(t, newWithOptions(opts), os.Stdin, "--build-dir", buildDir, "my_finding")
+	require.NoError(t, err)
+
+	f, err = finding.LoadFinding(projectDir, "my_finding", nil)
+	require.NoError(t, err)
+	require.Len(t, f.Logs, 2)
+	assert.Regexp(t, `^    #0 0x55a0e3c5c1b2 in foo .*test\.c:1`, f.Logs[1])
+}
+
+func TestFindInBuildDir_PrefersMatchingRelativePath(t *testing.T) {
+	buildDir := testutil.MkdirTemp(t, "", "test-symbolize-build-")
+	for _, dir := range []string{"a/bin", "b/bin"} {
+		err := os.MkdirAll(filepath.Join(buildDir, dir), 0o755)
+		require.NoError(t, err)
+		err = os.WriteFile(filepath.Join(buildDir, dir, "my_fuzz_test"), nil, 0o644)
+		require.NoError(t, err)
+	}
+
+	c := &symbolizeCmd{opts: &options{}}
+	path := c.findInBuildDir("/out/b/bin/my_fuzz_test", buildDir)
+	assert.Equal(t, filepath.Join(buildDir, "b", "bin", "my_fuzz_test"), path)
+	path = c.findInBuildDir(`C:\out\a\bin\my_fuzz_test`, buildDir)
+	assert.Equal(t, filepath.Join(buildDir, "a", "bin", "my_fuzz_test"), path)
+	path = c.findInBuildDir("/out/libfoo.so", buildDir)
+	assert.Empty(t, path)
+}
@@ -21,7 +21,9 @@ import (
 	"code-intelligence.com/cifuzz/internal/names"
 	"code-intelligence.com/cifuzz/pkg/desktop"
 	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/inputview"
 	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
 	"code-intelligence.com/cifuzz/pkg/report"
 	"code-intelligence.com/cifuzz/util/fileutil"
 	"code-intelligence.com/cifuzz/util/stringutil"
@@ -34,6 +36,13 @@ type ReportHandlerOptions struct {
 	UserSeedCorpusDirs   []string
 	BuildSystem          string
 	PrintJSON            bool
+	// Specifies how the crashing input is rendered in the
+	// HumanReadableInput field of findings. If nil, the field is not
+	// set, the input can still be rendered via 'cifuzz finding input'.
+	InputView *inputview.Options
+	// Classifies findings with the error ID rules of the project. If
+	// nil, the error IDs determined by the built-in rules are kept.
+	Classifier *errorid.Classifier
 }
 
 type ReportHandler struct {
@@ -171,6 +180,10 @@ func (h *ReportHandler) handleFinding(f *finding.Finding, print bool) error {
 
 	f.CreatedAt = time.Now()
 
+	if h.Classifier != nil {
+		h.Classifier.Classify(f)
+	}
+
 	// Generate a name for the finding. The name is chosen deterministically,
 	// based on:
 	// * Parts of the stack trace: The function name, source file name,
@@ -210,6 +223,7 @@ func (h *ReportHandler) handleFinding(f *finding.Finding, print bool) error {
 	}
 
 	f.FuzzTest = h.FuzzTest
+	f.HumanReadableInput = h.renderInput(f)
 
 	// Do not mutate f after this call.
 	err = f.Save(h.ProjectDir)
@@ -227,6 +241,40 @@ func (h *ReportHandler) handleFinding(f *finding.Finding, print bool) error {
 	return nil
 }
 
+// renderInput returns the crashing input of the finding rendered as
+// configured in the input view options, an empty string if they're not
+// set. If the input can't be rendered that way, it falls back to a
+// hexdump.
+func (h *ReportHandler) renderInput(f *finding.Finding) string {
+	if h.InputView == nil {
+		return ""
+	}
+	data := f.InputData
+	if f.InputFile != "" {
+		bytes, err := os.ReadFile(filepath.Join(h.ProjectDir, f.InputFile))
+		if err == nil {
+			data = bytes
+		} else {
+			log.Debugf("Failed to read input file of finding %s: %v", f.Name, err)
+		}
+	}
+	if len(data) == 0 {
+		return ""
+	}
+
+	defaultOpts := &inputview.Options{Format: inputview.FormatHexdump, MaxBytes: inputview.DefaultMaxBytes}
+	opts := h.InputView
+	if opts.Format == "" {
+		opts = defaultOpts
+	}
+	res, err := inputview.Render(data, opts)
+	if err != nil {
+		log.Warnf("Failed to render the crashing input of finding %s as %s: %v", f.Name, opts.Format, err)
+		res, _ = inputview.Render(data, defaultOpts)
+	}
+	return res
+}
+
 func (h *ReportHandler) PrintFindingInstruction() {
 	log.Note(`
 Use 'cifuzz finding <finding name>' for details on a finding.
@@ -14,7 +14,9 @@ import (
 	"code-intelligence.com/cifuzz/internal/cmd/run/reporthandler/metrics"
 	"code-intelligence.com/cifuzz/internal/testutil"
 	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/inputview"
 	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
 	"code-intelligence.com/cifuzz/pkg/report"
 )
 
@@ -110,6 +112,41 @@ func TestReportHandler_Finding(t *testing.T) {
 
 	expectedOutputs := []string{findingReport.Finding.Name}
 	checkOutput(t, logOutput, expectedOutputs...)
+
+	// The rendered input is only stored in the finding if the input
+	// view is configured
+	assert.Empty(t, findingReport.Finding.HumanReadableInput)
+
+	h, err = NewReportHandler("", &ReportHandlerOptions{
+		ProjectDir:           testDir,
+		ManagedSeedCorpusDir: "seed_corpus",
+		InputView:            &inputview.Options{Format: inputview.FormatHexdump},
+	})
+	require.NoError(t, err)
+	findingReport = &report.Report{
+		Status: report.RunStatusRunning,
+		Finding: &finding.Finding{
+			InputFile: testfile,
+		},
+	}
+	err = h.Handle(findingReport)
+	require.NoError(t, err)
+	assert.Contains(t, findingReport.Finding.HumanReadableInput, "|TEST|")
+}
+
+func TestReportHandler_Classifier(t *testing.T) {
+	classifier, err := errorid.NewClassifier([]errorid.Rule{{ID: "custom_error", Substrings: []string{"custom"}}})
+	require.NoError(t, err)
+	h, err := NewReportHandler("", &ReportHandlerOptions{ProjectDir: testDir, Classifier: classifier})
+	require.NoError(t, err)
+
+	f := &finding.Finding{
+		Details:     "custom Security Issue",
+		MoreDetails: &finding.ErrorDetails{ID: "jazzer_security_issue"},
+	}
+	err = h.Handle(&report.Report{Status: report.RunStatusRunning, Finding: f})
+	require.NoError(t, err)
+	assert.Equal(t, "custom_error", f.MoreDetails.ID)
 }
 
 func TestReportHandler_CorpusDirs(t *testing.T) {
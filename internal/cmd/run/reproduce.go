@@ -0,0 +1,248 @@
+package run
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/build"
+	"code-intelligence.com/cifuzz/internal/config"
+	"code-intelligence.com/cifuzz/internal/ldd"
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
+	"code-intelligence.com/cifuzz/pkg/report"
+	"code-intelligence.com/cifuzz/pkg/runner/jazzer"
+	"code-intelligence.com/cifuzz/pkg/runner/libfuzzer"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+type ReproducerOptions struct {
+	ProjectDir   string
+	BuildSystem  string
+	BuildCommand string
+	CleanCommand string
+	NumBuildJobs uint
+	// The fuzz test as stored in the finding, for Maven and Gradle
+	// projects optionally including the target method, separated by
+	// "::".
+	FuzzTest string
+	// Additional arguments which are passed to the fuzzing engine,
+	// e.g. timeouts and memory limits which are needed to reproduce
+	// findings
+	EngineArgs []string
+	EnvVars    []string
+	// The directory in which the build commands and the fuzz test are
+	// executed, the current working directory if empty. Set this instead
+	// of changing the working directory of the process.
+	WorkDir string
+	// The classifier which determines the error IDs of the reproduced
+	// findings. It must be the one which was used for the findings that
+	// are checked, so that their error IDs can be compared.
+	Classifier *errorid.Classifier
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// A Reproducer builds a fuzz test and executes it on single inputs to
+// check which findings those inputs trigger. In contrast to a fuzzing
+// run, nothing is stored in the project directory.
+type Reproducer struct {
+	*ReproducerOptions
+
+	cmd         *runCmd
+	buildResult *build.Result
+	tempDir     string
+}
+
+func NewReproducer(opts *ReproducerOptions) (*Reproducer, error) {
+	if opts.BuildSystem == config.BuildSystemNodeJS {
+		return nil, errors.New("Reproducing findings is not supported for Node.js projects yet")
+	}
+
+	tempDir, err := os.MkdirTemp("", "cifuzz-reproduce-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	runOpts := &runOptions{
+		ProjectDir:   opts.ProjectDir,
+		BuildSystem:  opts.BuildSystem,
+		BuildCommand: opts.BuildCommand,
+		CleanCommand: opts.CleanCommand,
+		NumBuildJobs: opts.NumBuildJobs,
+		fuzzTest:     opts.FuzzTest,
+		workDir:      opts.WorkDir,
+		buildStdout:  opts.Stdout,
+		buildStderr:  opts.Stderr,
+	}
+	if runOpts.buildStdout == nil {
+		runOpts.buildStdout = os.Stderr
+	}
+	if runOpts.buildStderr == nil {
+		runOpts.buildStderr = os.Stderr
+	}
+	if opts.BuildSystem == config.BuildSystemMaven || opts.BuildSystem == config.BuildSystemGradle {
+		runOpts.fuzzTest, runOpts.targetMethod = splitJVMFuzzTest(opts.FuzzTest)
+	}
+
+	return &Reproducer{
+		ReproducerOptions: opts,
+		cmd:               &runCmd{opts: runOpts, tempDir: tempDir},
+		tempDir:           tempDir,
+	}, nil
+}
+
+// newReproducerForBuild returns a reproducer for the fuzz test which
+// was already built by the run command. The reproducer uses the
+// temporary directory of the run command, so it must not be cleaned up
+// separately.
+func (c *runCmd) newReproducerForBuild(buildResult *build.Result, output io.Writer) *Reproducer {
+	return &Reproducer{
+		ReproducerOptions: &ReproducerOptions{
+			ProjectDir:  c.opts.ProjectDir,
+			BuildSystem: c.opts.BuildSystem,
+			FuzzTest:    c.opts.fuzzTest,
+			EngineArgs:  c.opts.EngineArgs,
+			Classifier:  c.reportHandler.Classifier,
+			Stdout:      output,
+			Stderr:      output,
+		},
+		cmd:         c,
+		buildResult: buildResult,
+		tempDir:     c.tempDir,
+	}
+}
+
+// Build builds the fuzz test with the builder of the configured build
+// system. It has to be called before Reproduce.
+func (r *Reproducer) Build() error {
+	var err error
+	r.buildResult, err = r.cmd.buildFuzzTest()
+	return err
+}
+
+// Reproduce executes the fuzz test in a new process on the specified
+// input and returns the findings which were reported.
+func (r *Reproducer) Reproduce(input string) ([]*finding.Finding, error) {
+	if r.buildResult == nil {
+		return nil, errors.New("The fuzz test has to be built before reproducing findings")
+	}
+
+	// Copy the input to an otherwise empty directory which is passed
+	// as the only corpus directory to the fuzzer, so that no other
+	// inputs are executed
+	inputDir, err := os.MkdirTemp(r.tempDir, "input-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(inputDir)
+	err = copy.Copy(input, filepath.Join(inputDir, filepath.Base(input)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	generatedCorpusDir, err := os.MkdirTemp(r.tempDir, "corpus-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(generatedCorpusDir)
+
+	var libraryPaths []string
+	if runtime.GOOS != "windows" && r.buildResult.Executable != "" {
+		libraryPaths, err = ldd.LibraryPaths(r.buildResult.Executable)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	handler := &findingCollector{classifier: r.Classifier}
+	runnerOpts := &libfuzzer.RunnerOptions{
+		// Only execute the inputs from the corpus directories
+		EngineArgs:         append(append([]string{}, r.EngineArgs...), "-runs=0"),
+		EnvVars:            append([]string{"NO_CIFUZZ=1"}, r.EnvVars...),
+		FuzzTarget:         r.buildResult.Executable,
+		LibraryDirs:        libraryPaths,
+		GeneratedCorpusDir: generatedCorpusDir,
+		ProjectDir:         r.ProjectDir,
+		ReadOnlyBindings:   []string{r.buildResult.BuildDir},
+		ReportHandler:      handler,
+		SeedCorpusDirs:     []string{inputDir},
+		LogOutput:          r.Stderr,
+		WorkDir:            r.WorkDir,
+	}
+
+	var runner Runner
+	switch r.BuildSystem {
+	case config.BuildSystemMaven, config.BuildSystemGradle:
+		runner = jazzer.NewRunner(&jazzer.RunnerOptions{
+			TargetClass:      r.cmd.opts.fuzzTest,
+			TargetMethod:     r.cmd.opts.targetMethod,
+			ClassPaths:       r.buildResult.RuntimeDeps,
+			LibfuzzerOptions: runnerOpts,
+		})
+	default:
+		runner = libfuzzer.NewRunner(runnerOpts)
+	}
+
+	err = ExecuteRunner(runner)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Reproducing %s reported %d findings", input, len(handler.findings))
+	return handler.findings, nil
+}
+
+// CheckReproducibility executes the fuzz test on the crashing input of
+// the finding the specified number of times, each time in a new
+// process, and returns how many runs reproduced the finding.
+func (r *Reproducer) CheckReproducibility(f *finding.Finding, runs uint) (*finding.Reproducibility, error) {
+	if f.InputFile == "" {
+		return nil, errors.Errorf("Finding %s has no crashing input", f.Name)
+	}
+	input := filepath.Join(r.ProjectDir, f.InputFile)
+
+	var reproduced uint
+	for i := uint(0); i < runs; i++ {
+		findings, err := r.Reproduce(input)
+		if err != nil {
+			return nil, err
+		}
+		if f.IsReproducedBy(findings) {
+			reproduced++
+		}
+	}
+	return finding.NewReproducibility(runs, reproduced), nil
+}
+
+// Cleanup removes the temporary files created by the reproducer.
+func (r *Reproducer) Cleanup() {
+	fileutil.Cleanup(r.tempDir)
+}
+
+// findingCollector is a report handler which only stores the findings,
+// after determining their error IDs via the classifier, if any.
+type findingCollector struct {
+	classifier *errorid.Classifier
+	findings   []*finding.Finding
+}
+
+func (h *findingCollector) Handle(r *report.Report) error {
+	if r.Finding != nil {
+		if h.classifier != nil {
+			h.classifier.Classify(r.Finding)
+		}
+		h.findings = append(h.findings, r.Finding)
+	}
+	return nil
+}
+
+func splitJVMFuzzTest(fuzzTest string) (string, string) {
+	class, method, _ := strings.Cut(fuzzTest, "::")
+	return class, method
+}
@@ -39,8 +39,10 @@ import (
 	"code-intelligence.com/cifuzz/pkg/dependencies"
 	"code-intelligence.com/cifuzz/pkg/dialog"
 	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/inputview"
 	"code-intelligence.com/cifuzz/pkg/log"
 	"code-intelligence.com/cifuzz/pkg/messaging"
+	"code-intelligence.com/cifuzz/pkg/parser/errorid"
 	"code-intelligence.com/cifuzz/pkg/report"
 	"code-intelligence.com/cifuzz/pkg/runner/jazzer"
 	"code-intelligence.com/cifuzz/pkg/runner/jazzerjs"
@@ -51,20 +53,26 @@ import (
 )
 
 type runOptions struct {
-	BuildSystem           string        `mapstructure:"build-system"`
-	BuildCommand          string        `mapstructure:"build-command"`
-	CleanCommand          string        `mapstructure:"clean-command"`
-	NumBuildJobs          uint          `mapstructure:"build-jobs"`
-	Dictionary            string        `mapstructure:"dict"`
-	EngineArgs            []string      `mapstructure:"engine-args"`
-	SeedCorpusDirs        []string      `mapstructure:"seed-corpus-dirs"`
-	Timeout               time.Duration `mapstructure:"timeout"`
-	Interactive           bool          `mapstructure:"interactive"`
-	Server                string        `mapstructure:"server"`
-	Project               string        `mapstructure:"project"`
-	UseSandbox            bool          `mapstructure:"use-sandbox"`
-	PrintJSON             bool          `mapstructure:"print-json"`
-	BuildOnly             bool          `mapstructure:"build-only"`
+	BuildSystem           string             `mapstructure:"build-system"`
+	BuildCommand          string             `mapstructure:"build-command"`
+	CleanCommand          string             `mapstructure:"clean-command"`
+	NumBuildJobs          uint               `mapstructure:"build-jobs"`
+	Dictionary            string             `mapstructure:"dict"`
+	EngineArgs            []string           `mapstructure:"engine-args"`
+	SeedCorpusDirs        []string           `mapstructure:"seed-corpus-dirs"`
+	Timeout               time.Duration      `mapstructure:"timeout"`
+	Interactive           bool               `mapstructure:"interactive"`
+	Server                string             `mapstructure:"server"`
+	Project               string             `mapstructure:"project"`
+	UseSandbox            bool               `mapstructure:"use-sandbox"`
+	PrintJSON             bool               `mapstructure:"print-json"`
+	BuildOnly             bool               `mapstructure:"build-only"`
+	Baseline              string             `mapstructure:"baseline"`
+	ErrorDetailsFile      string             `mapstructure:"error-details-file"`
+	CheckFlaky            uint               `mapstructure:"check-flaky"`
+	InputView             *inputview.Options `mapstructure:"input-view"`
+	ErrorIDRules          []errorid.Rule     `mapstructure:"error-id-rules"`
+	ErrorIDRulesFile      string             `mapstructure:"error-id-rules-file"`
 	ResolveSourceFilePath bool
 
 	ProjectDir      string
@@ -75,6 +83,10 @@ type runOptions struct {
 
 	buildStdout io.Writer
 	buildStderr io.Writer
+
+	// The directory in which the build commands and the fuzz test are
+	// executed, the current working directory if empty
+	workDir string
 }
 
 func (opts *runOptions) validate() error {
@@ -251,6 +263,9 @@ depends on the build system configured for the project.
 				log.Errorf(err, "Failed to parse cifuzz.yaml: %v", err.Error())
 				return cmdutils.WrapSilentError(err)
 			}
+			if opts.InputView != nil {
+				opts.InputView.ResolvePaths(opts.ProjectDir)
+			}
 
 			if sliceutil.Contains(
 				[]string{config.BuildSystemMaven, config.BuildSystemGradle},
@@ -330,6 +345,8 @@ depends on the build system configured for the project.
 		cmdutils.AddTimeoutFlag,
 		cmdutils.AddUseSandboxFlag,
 		cmdutils.AddResolveSourceFileFlag,
+		cmdutils.AddBaselineFlag,
+		cmdutils.AddCheckFlakyFlag,
 	}
 	bindFlags = cmdutils.AddFlags(cmd, funcs...)
 	return cmd
@@ -350,10 +367,30 @@ func (c *runCmd) run() error {
 		return err
 	}
 
-	if authenticatedUser {
-		errorDetails, err = c.errorDetails()
+	errorDetails, err = c.errorDetails(authenticatedUser)
+	if err != nil {
+		return err
+	}
+
+	// Load the error ID rules before building and running the fuzz test
+	// to fail early if they are invalid
+	var classifier *errorid.Classifier
+	if len(c.opts.ErrorIDRules) > 0 || c.opts.ErrorIDRulesFile != "" {
+		classifier, err = errorid.NewProjectClassifier(c.opts.ProjectDir, c.opts.ErrorIDRules, c.opts.ErrorIDRulesFile)
 		if err != nil {
-			return err
+			log.Errorf(err, "Failed to load error ID rules: %v", err.Error())
+			return cmdutils.WrapSilentError(err)
+		}
+	}
+
+	// Load the baseline before building and running the fuzz test to
+	// fail early if it's invalid
+	var baseline []*finding.Finding
+	if c.opts.Baseline != "" {
+		baseline, err = finding.LoadBaseline(c.opts.ProjectDir, c.opts.Baseline, errorDetails)
+		if err != nil {
+			log.Errorf(err, "Failed to load baseline: %v", err.Error())
+			return cmdutils.WrapSilentError(err)
 		}
 	}
 
@@ -400,6 +437,8 @@ func (c *runCmd) run() error {
 			ManagedSeedCorpusDir: buildResult.SeedCorpus,
 			UserSeedCorpusDirs:   c.opts.SeedCorpusDirs,
 			PrintJSON:            c.opts.PrintJSON,
+			InputView:            c.opts.InputView,
+			Classifier:           classifier,
 		})
 	if err != nil {
 		return err
@@ -421,10 +460,33 @@ func (c *runCmd) run() error {
 		return err
 	}
 
+	if c.opts.CheckFlaky > 0 && len(c.reportHandler.Findings) > 0 {
+		err = c.checkFlakiness(buildResult)
+		if err != nil {
+			return err
+		}
+	}
+
+	// If a baseline was specified, the exit code only depends on
+	// whether there are new findings. We still want to upload the
+	// findings in that case, so the error is only returned at the end.
+	var baselineErr error
+	if c.opts.Baseline != "" {
+		out := c.OutOrStderr()
+		if c.opts.PrintJSON {
+			out = c.OutOrStdout()
+		}
+		// Only the baseline findings of this fuzz test can be found or
+		// resolved by this run
+		baseline = finding.FilterByFuzzTest(baseline, c.opts.fuzzTest)
+		comparison := finding.CompareWithBaseline(c.reportHandler.Findings, baseline, c.baselineReproduceFunc(buildResult))
+		baselineErr = cmdutils.PrintBaselineComparison(out, comparison, c.opts.PrintJSON)
+	}
+
 	// We need this check, otherwise we might hang forever in CI
 	if c.opts.Project == "" && !c.opts.Interactive {
 		log.Info("Skipping upload of findings because no project was specified and running in non-interactive mode.")
-		return nil
+		return baselineErr
 	}
 
 	// check if there are findings that should be uploaded
@@ -435,9 +497,86 @@ func (c *runCmd) run() error {
 		}
 	}
 
+	return baselineErr
+}
+
+// checkFlakiness replays the crashing inputs of the findings of this
+// run in new processes to determine how reliably they reproduce the
+// findings and stores the result in the findings.
+func (c *runCmd) checkFlakiness(buildResult *build.Result) error {
+	if c.opts.BuildSystem == config.BuildSystemNodeJS {
+		log.Warn("Checking findings for flakiness is not supported for Node.js projects yet")
+		return nil
+	}
+
+	var output io.Writer = io.Discard
+	if viper.GetBool("verbose") {
+		output = c.ErrOrStderr()
+	}
+	reproducer := c.newReproducerForBuild(buildResult, output)
+
+	for _, f := range c.reportHandler.Findings {
+		log.Infof("Checking finding %s for flakiness (%d runs)", f.Name, c.opts.CheckFlaky)
+		reproducibility, err := reproducer.CheckReproducibility(f, c.opts.CheckFlaky)
+		if err != nil {
+			log.Warnf("Failed to check finding %s for flakiness: %v", f.Name, err)
+			continue
+		}
+		f.Reproducibility = reproducibility
+		err = f.Save(c.opts.ProjectDir)
+		if err != nil {
+			return err
+		}
+		cmdutils.PrintReproducibility(f)
+	}
 	return nil
 }
 
+// baselineReproduceFunc returns a function which executes the crashing
+// input of a baseline finding to check whether the finding was
+// resolved, nil if that's not supported for the build system
+func (c *runCmd) baselineReproduceFunc(buildResult *build.Result) finding.ReproduceFunc {
+	if c.opts.BuildSystem == config.BuildSystemNodeJS {
+		return nil
+	}
+
+	var output io.Writer = io.Discard
+	if viper.GetBool("verbose") {
+		output = c.ErrOrStderr()
+	}
+	reproducer := c.newReproducerForBuild(buildResult, output)
+
+	return func(f *finding.Finding) (bool, error) {
+		// The crashing input is taken from the finding data, because
+		// the baseline might have been extracted to a directory which
+		// was already removed
+		if len(f.InputData) == 0 {
+			return false, errors.Errorf("Finding %s has no crashing input", f.Name)
+		}
+		input, err := os.CreateTemp(c.tempDir, "baseline-input-")
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		defer fileutil.Cleanup(input.Name())
+		_, err = input.Write(f.InputData)
+		if err != nil {
+			input.Close()
+			return false, errors.WithStack(err)
+		}
+		err = input.Close()
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+
+		log.Infof("Checking if baseline finding %s was resolved", f.Name)
+		findings, err := reproducer.Reproduce(input.Name())
+		if err != nil {
+			return false, err
+		}
+		return f.IsReproducedBy(findings), nil
+	}
+}
+
 func (c *runCmd) buildFuzzTest() (*build.Result, error) {
 	var err error
 
@@ -462,6 +601,7 @@ func (c *runCmd) buildFuzzTest() (*build.Result, error) {
 		// if the fuzz test name appended with "_bin" is a valid target
 		// and use that in that case
 		cmd := exec.Command("bazel", "query", c.opts.fuzzTest+"_bin")
+		cmd.Dir = c.opts.workDir
 		err = cmd.Run()
 		if err == nil {
 			c.opts.fuzzTest += "_bin"
@@ -476,6 +616,7 @@ func (c *runCmd) buildFuzzTest() (*build.Result, error) {
 			Stderr:     c.opts.buildStderr,
 			TempDir:    c.tempDir,
 			Verbose:    viper.GetBool("verbose"),
+			WorkDir:    c.opts.workDir,
 		})
 		if err != nil {
 			return nil, err
@@ -594,6 +735,7 @@ func (c *runCmd) buildFuzzTest() (*build.Result, error) {
 			Sanitizers:   sanitizers,
 			Stdout:       c.opts.buildStdout,
 			Stderr:       c.opts.buildStderr,
+			WorkDir:      c.opts.workDir,
 		})
 		if err != nil {
 			return nil, err
@@ -821,24 +963,39 @@ Your results will not be synced.`)
 	return willSync, nil
 }
 
-func (c *runCmd) errorDetails() (*[]finding.ErrorDetails, error) {
-	token := auth.GetToken(c.opts.Server)
-	if token == "" {
-		return nil, errors.New("No access token found")
-	}
+// errorDetails returns the error details shipped with cifuzz, extended
+// by the error details from the API if the user is authenticated and
+// overridden by the error details file of the project, if any.
+func (c *runCmd) errorDetails(authenticatedUser bool) (*[]finding.ErrorDetails, error) {
+	var remoteErrorDetails []finding.ErrorDetails
+	if authenticatedUser {
+		token := auth.GetToken(c.opts.Server)
+		if token == "" {
+			return nil, errors.New("No access token found")
+		}
 
-	errorDetails, err := c.apiClient.GetErrorDetails(token)
-	if err != nil {
-		var connErr *api.ConnectionError
-		if !errors.As(err, &connErr) {
-			return nil, err
-		} else {
-			log.Warn("Connection to API failed. Skipping error details.")
-			log.Debugf("Connection error: %v (continiung gracefully)", connErr)
-			return nil, nil
+		var err error
+		remoteErrorDetails, err = c.apiClient.GetErrorDetails(token)
+		if err != nil {
+			var connErr *api.ConnectionError
+			if !errors.As(err, &connErr) {
+				return nil, err
+			} else {
+				log.Warn("Connection to API failed. Skipping remote error details.")
+				log.Debugf("Connection error: %v (continiung gracefully)", connErr)
+			}
 		}
 	}
 
+	errorDetailsFile := c.opts.ErrorDetailsFile
+	if errorDetailsFile != "" && !filepath.IsAbs(errorDetailsFile) {
+		errorDetailsFile = filepath.Join(c.opts.ProjectDir, errorDetailsFile)
+	}
+	errorDetails, err := finding.ResolveErrorDetails(remoteErrorDetails, errorDetailsFile)
+	if err != nil {
+		log.Errorf(err, "Failed to load error details: %v", err.Error())
+		return nil, cmdutils.WrapSilentError(err)
+	}
 	return &errorDetails, nil
 }
 
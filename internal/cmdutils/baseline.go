@@ -0,0 +1,60 @@
+package cmdutils
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/stringutil"
+)
+
+// PrintBaselineComparison prints the findings of the comparison
+// together with their status to out. If there are new findings, a
+// silent error is returned, which results in a non-zero exit code.
+func PrintBaselineComparison(out io.Writer, comparison *finding.BaselineComparison, printJSON bool) error {
+	if printJSON {
+		s, err := stringutil.ToJSONString(comparison)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(out, s)
+	} else {
+		data := [][]string{
+			{"Status", "Name", "Description", "Location"},
+		}
+		addRows := func(status string, findings []*finding.Finding) {
+			for _, f := range findings {
+				location := "n/a"
+				if len(f.ShortDescriptionColumns()) > 1 {
+					location = f.ShortDescriptionColumns()[1]
+				}
+				data = append(data, []string{status, f.Name, f.ShortDescriptionColumns()[0], location})
+			}
+		}
+		addRows(pterm.Red("new"), comparison.New)
+		addRows(pterm.Yellow("still present"), comparison.StillPresent)
+		addRows(pterm.Green("resolved"), comparison.Resolved)
+		addRows(pterm.Gray("unverified"), comparison.Unverified)
+
+		if len(data) > 1 {
+			table, err := pterm.DefaultTable.WithHasHeader().WithData(data).Srender()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			_, _ = fmt.Fprintln(out, table)
+		}
+		log.Infof("Compared to the baseline: %d new, %d still present, %d resolved, %d unverified",
+			len(comparison.New), len(comparison.StillPresent), len(comparison.Resolved), len(comparison.Unverified))
+	}
+
+	if len(comparison.New) > 0 {
+		err := errors.Errorf("Found %d new findings compared to the baseline", len(comparison.New))
+		log.Error(err)
+		return WrapSilentError(err)
+	}
+	return nil
+}
@@ -22,7 +22,7 @@ func ListNodeFuzzTests(projectDir string, prefixFilter string) ([]string, error)
 
 	var fuzzTests []string
 	for _, testFile := range fuzzTestFiles {
-		methods, err := getTargetMethodsFromNodeTestFile(testFile)
+		methods, err := GetTargetMethodsFromNodeTestFile(testFile)
 		if err != nil {
 			return nil, err
 		}
@@ -51,7 +51,7 @@ func ListNodeFuzzTests(projectDir string, prefixFilter string) ([]string, error)
 	return fuzzTests, nil
 }
 
-func getTargetMethodsFromNodeTestFile(path string) ([]string, error) {
+func GetTargetMethodsFromNodeTestFile(path string) ([]string, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -62,6 +62,26 @@ func AddAdditionalFilesFlag(cmd *cobra.Command) func() {
 	}
 }
 
+func AddBaselineFlag(cmd *cobra.Command) func() {
+	cmd.Flags().String("baseline", "",
+		"Only report findings which are new compared to the specified baseline, which\n"+
+			"is either a `ref` of the Git repository, a directory containing findings\n"+
+			"(like the project directory of another checkout) or an archive thereof.\n"+
+			"If set, the exit code is non-zero if and only if there are new findings.")
+	return func() {
+		ViperMustBindPFlag("baseline", cmd.Flags().Lookup("baseline"))
+	}
+}
+
+func AddBaseImageFlag(cmd *cobra.Command) func() {
+	cmd.Flags().String("base-image", "",
+		"Path of the base image of the fuzz container (OCI image layout directory or tarball,\n"+
+			"or 'docker save' tarball).")
+	return func() {
+		ViperMustBindPFlag("base-image", cmd.Flags().Lookup("base-image"))
+	}
+}
+
 func AddBranchFlag(cmd *cobra.Command) func() {
 	cmd.Flags().String("branch", "",
 		"Branch name to use in the bundle config.\n"+
@@ -71,6 +91,16 @@ func AddBranchFlag(cmd *cobra.Command) func() {
 	}
 }
 
+func AddBundleFormatFlag(cmd *cobra.Command) func() {
+	cmd.Flags().String("format", "",
+		"The `format` of the bundle: tar.gz, tar.zst or zip.\n"+
+			"By default, the format is chosen based on the extension of the output path,\n"+
+			"or tar.gz if it has none of these extensions.")
+	return func() {
+		ViperMustBindPFlag("bundle-format", cmd.Flags().Lookup("format"))
+	}
+}
+
 func AddBuildCommandFlag(cmd *cobra.Command) func() {
 	cmd.Flags().String("build-command", "",
 		"The `command` to build the fuzz test for other build systems.")
@@ -79,6 +109,15 @@ func AddBuildCommandFlag(cmd *cobra.Command) func() {
 	}
 }
 
+func AddCheckFlakyFlag(cmd *cobra.Command) func() {
+	cmd.Flags().Uint("check-flaky", 0,
+		"Replay the crashing input of each finding the specified `number` of times,\n"+
+			"each time in a new process, to determine whether the finding is flaky.")
+	return func() {
+		ViperMustBindPFlag("check-flaky", cmd.Flags().Lookup("check-flaky"))
+	}
+}
+
 func AddCleanCommandFlag(cmd *cobra.Command) func() {
 	cmd.Flags().String("clean-command", "",
 		"The `command` to clean the fuzz test and its dependencies for other build systems.")
@@ -114,6 +153,33 @@ func AddCommitFlag(cmd *cobra.Command) func() {
 	}
 }
 
+func AddCompressionLevelFlag(cmd *cobra.Command) func() {
+	cmd.Flags().Int("compression-level", 0,
+		"The compression `level` of the bundle: 1 (fastest) to 9 (best) for tar.gz and zip,\n"+
+			"1 to 22 for tar.zst. By default, the default level of the format is used.")
+	return func() {
+		ViperMustBindPFlag("compression-level", cmd.Flags().Lookup("compression-level"))
+	}
+}
+
+func AddCompressionThreadsFlag(cmd *cobra.Command) func() {
+	cmd.Flags().Int("compression-threads", 0,
+		"The `number` of threads used to compress tar.zst bundles.\n"+
+			"By default, one thread per CPU core is used.")
+	return func() {
+		ViperMustBindPFlag("compression-threads", cmd.Flags().Lookup("compression-threads"))
+	}
+}
+
+func AddContainerBackendFlag(cmd *cobra.Command) func() {
+	cmd.Flags().String("backend", "",
+		"The `backend` which builds and runs the fuzz container: auto, docker, podman or runc.\n"+
+			"By default, Docker or Podman is selected automatically.")
+	return func() {
+		ViperMustBindPFlag("container-backend", cmd.Flags().Lookup("backend"))
+	}
+}
+
 func AddDictFlag(cmd *cobra.Command) func() {
 	// TODO(afl): Also link to https://github.com/AFLplusplus/AFLplusplus/blob/stable/dictionaries/README.md
 	cmd.Flags().String("dict", "",
@@ -230,6 +296,16 @@ func AddServerFlag(cmd *cobra.Command) func() {
 	}
 }
 
+func AddSignKeyFlag(cmd *cobra.Command) func() {
+	cmd.Flags().String("sign-key", "",
+		"Sign the content manifest of the bundle with the ed25519 private key in this key `file`\n"+
+			"(relative to the project directory). Alternatively, the key itself can be passed\n"+
+			"via the CIFUZZ_SIGN_KEY environment variable.")
+	// The flag is deliberately not bound to viper, so that the private
+	// key can't be set in cifuzz.yaml, which is usually checked in
+	return func() {}
+}
+
 func AddTimeoutFlag(cmd *cobra.Command) func() {
 	cmd.Flags().Duration("timeout", 0,
 		"Maximum time to run the fuzz test, e.g. \"30m\", \"1h\". The default is to run indefinitely.")
@@ -238,6 +314,15 @@ func AddTimeoutFlag(cmd *cobra.Command) func() {
 	}
 }
 
+func AddTrustedKeyFlag(cmd *cobra.Command) func() {
+	cmd.Flags().String("trusted-key", "",
+		"Only accept bundles which are signed with the private key of this ed25519 public `key`,\n"+
+			"which is either the path of a PEM-encoded key file or the base64-encoded key itself.")
+	return func() {
+		ViperMustBindPFlag("trusted-key", cmd.Flags().Lookup("trusted-key"))
+	}
+}
+
 func AddUseSandboxFlag(cmd *cobra.Command) func() {
 	cmd.Flags().Bool("use-sandbox", false,
 		"By default, fuzz tests are executed in a sandbox to prevent accidental damage to the system.\n"+
@@ -0,0 +1,28 @@
+package cmdutils
+
+import (
+	"github.com/pterm/pterm"
+
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+// PrintReproducibility logs how reliably the finding was reproduced.
+func PrintReproducibility(f *finding.Finding) {
+	r := f.Reproducibility
+	if r == nil {
+		return
+	}
+	msg := ReproducibilityString(r)
+	if r.Flaky {
+		log.Warnf("Finding %s is flaky: %s", f.Name, msg)
+	} else {
+		log.Successf("Finding %s is reproducible: %s", f.Name, msg)
+	}
+}
+
+// ReproducibilityString returns a short description of the
+// reproducibility, like "3/10 runs (30%)".
+func ReproducibilityString(r *finding.Reproducibility) string {
+	return pterm.Sprintf("%d/%d runs (%.0f%%)", r.Reproduced, r.Runs, r.Ratio*100)
+}
@@ -0,0 +1,181 @@
+package container
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+const (
+	BackendAuto   = "auto"
+	BackendDocker = "docker"
+	BackendPodman = "podman"
+	BackendRunc   = "runc"
+)
+
+var Backends = []string{BackendAuto, BackendDocker, BackendPodman, BackendRunc}
+
+// The name of the fuzz container image built from the bundle
+const imageName = "cifuzz"
+
+// OutputDir is the directory in the fuzz container in which 'cifuzz
+// execute' stores the findings and the generated corpus. After the run,
+// it can be copied out of the container via Backend.CopyFrom.
+const OutputDir = "/cifuzz-output"
+
+// The default locations of the Docker and Podman API sockets. These are
+// variables so that they can be changed in tests.
+var (
+	dockerSocket         = "/var/run/docker.sock"
+	rootfulPodmanSocket  = "/run/podman/podman.sock"
+	podmanExecutableName = "podman"
+)
+
+// Backend builds and runs fuzz containers. All backends build the image
+// from a bundle and run 'cifuzz execute' in the container, so that
+// 'cifuzz container run' behaves the same on every backend.
+type Backend interface {
+	// BuildImage builds the fuzz container image from the bundle
+	BuildImage(bundlePath string) error
+	// Create creates a container from the image built by BuildImage
+	// which executes the fuzz test and returns the ID of the container
+	Create(fuzzTest string) (string, error)
+	// Start starts the container, writes its output to stdout and
+	// stderr and waits until it exits. The exit code of the container
+	// is not treated as an error, only failures of the backend are.
+	Start(id string, stdout, stderr io.Writer) error
+	// Stop stops the running container
+	Stop(id string) error
+	// CopyFrom copies the file or directory at path in the container
+	// into the existing directory destDir. The container must have
+	// exited.
+	CopyFrom(id, path, destDir string) error
+	// SystemLibraries returns the file names of the shared libraries
+	// which are available in the system library directories of the
+	// image
+	SystemLibraries(image string) (map[string]bool, error)
+	// Close releases the resources of the backend
+	Close() error
+}
+
+type BackendOptions struct {
+	// The path of the base image (OCI image layout directory or tarball,
+	// or 'docker save' tarball). Only used by the runc backend, which
+	// can't pull the base image specified in the bundle.
+	BaseImage string
+	// The public key which 'cifuzz execute' uses to verify the bundle in
+	// the container, nil if the bundle isn't verified
+	TrustedKey ed25519.PublicKey
+}
+
+// NewBackend returns the backend with the specified name. If name is
+// BackendAuto or empty, a Docker or Podman backend is selected based on
+// the available API sockets and executables.
+func NewBackend(name string, opts *BackendOptions) (Backend, error) {
+	if name == "" || name == BackendAuto {
+		var err error
+		name, err = detectBackend()
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Using the %s container backend", name)
+	}
+
+	switch name {
+	case BackendDocker:
+		return newDockerBackend(BackendDocker, "", opts)
+	case BackendPodman:
+		// Prefer Podman's Docker-compatible API, which is also used by
+		// tools like docker-compose, over its CLI
+		if socket := podmanSocket(); socket != "" {
+			log.Debugf("Using the Podman API socket %s", socket)
+			return newDockerBackend(BackendPodman, "unix://"+socket, opts)
+		}
+		return newPodmanBackend(opts)
+	case BackendRunc:
+		return newRuncBackend(opts)
+	default:
+		return nil, errors.Errorf("Unsupported container backend %q, must be one of %s", name, strings.Join(Backends, ", "))
+	}
+}
+
+// detectBackend returns the name of the backend to use if none was
+// specified. Docker is preferred over Podman if both are available. The
+// runc backend is never selected automatically, because it requires a
+// local base image.
+func detectBackend() (string, error) {
+	// Docker Desktop on macOS and Windows doesn't use the default
+	// socket path, so we don't try to detect Podman there
+	if runtime.GOOS != "linux" {
+		return BackendDocker, nil
+	}
+	if os.Getenv("DOCKER_HOST") != "" || socketExists(dockerSocket) {
+		return BackendDocker, nil
+	}
+	if podmanSocket() != "" {
+		return BackendPodman, nil
+	}
+	if _, err := exec.LookPath(podmanExecutableName); err == nil {
+		return BackendPodman, nil
+	}
+	return "", errors.Errorf(`Neither Docker nor Podman was found. Please install one of them or select
+the container backend via the "container-backend" setting (%s).`, strings.Join(Backends, ", "))
+}
+
+// podmanSocket returns the path of the Podman API socket, an empty
+// string if there is none
+func podmanSocket() string {
+	// Podman's remote client uses CONTAINER_HOST, which may also be a
+	// non-local URL like ssh://...
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		if socket, found := strings.CutPrefix(host, "unix://"); found && socketExists(socket) {
+			return socket
+		}
+		return ""
+	}
+	// The socket of the rootless Podman service
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		socket := filepath.Join(runtimeDir, "podman", "podman.sock")
+		if socketExists(socket) {
+			return socket
+		}
+	}
+	if socketExists(rootfulPodmanSocket) {
+		return rootfulPodmanSocket
+	}
+	return ""
+}
+
+func socketExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().Type() == os.ModeSocket
+}
+
+// containerCommand returns the command which is executed in the fuzz
+// container and the environment variables it needs in addition to the
+// ones of the image
+func containerCommand(fuzzTest string, trustedKey ed25519.PublicKey) ([]string, []string) {
+	cmd := []string{"/bin/cifuzz", "execute", fuzzTest, "--output-dir", OutputDir}
+	if viper.GetBool("verbose") {
+		cmd = append(cmd, "-v")
+	}
+
+	// Let 'cifuzz execute' verify the bundle in the container. The key
+	// is passed by value because a key file on the host is not
+	// available in the container.
+	var env []string
+	if trustedKey != nil {
+		env = append(env, "CIFUZZ_TRUSTED_KEY="+base64.StdEncoding.EncodeToString(trustedKey))
+	}
+	return cmd, env
+}
@@ -0,0 +1,101 @@
+package container
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+// createSocket creates a unix socket at path which exists until the
+// end of the test
+func createSocket(t *testing.T, path string) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	require.NoError(t, err)
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+}
+
+func TestDetectBackend(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Podman is only detected on Linux")
+	}
+
+	dir := testutil.MkdirTemp(t, "", "backend-test-*")
+	oldDockerSocket, oldRootfulPodmanSocket := dockerSocket, rootfulPodmanSocket
+	t.Cleanup(func() {
+		dockerSocket, rootfulPodmanSocket = oldDockerSocket, oldRootfulPodmanSocket
+	})
+	dockerSocket = filepath.Join(dir, "docker.sock")
+	rootfulPodmanSocket = filepath.Join(dir, "podman.sock")
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(dir, "run"))
+	t.Setenv("PATH", filepath.Join(dir, "bin"))
+
+	// Neither Docker nor Podman is available
+	_, err := detectBackend()
+	require.Error(t, err)
+
+	// The podman command is used if there is no socket
+	podman := filepath.Join(dir, "bin", "podman")
+	err = os.MkdirAll(filepath.Dir(podman), 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(podman, []byte("#!/bin/sh\n"), 0o755)
+	require.NoError(t, err)
+	backend, err := detectBackend()
+	require.NoError(t, err)
+	assert.Equal(t, BackendPodman, backend)
+	assert.Empty(t, podmanSocket())
+
+	// The socket of the rootless Podman service is preferred over the
+	// one of the rootful service
+	createSocket(t, rootfulPodmanSocket)
+	assert.Equal(t, rootfulPodmanSocket, podmanSocket())
+	rootlessPodmanSocket := filepath.Join(dir, "run", "podman", "podman.sock")
+	createSocket(t, rootlessPodmanSocket)
+	assert.Equal(t, rootlessPodmanSocket, podmanSocket())
+
+	// CONTAINER_HOST takes precedence over the default sockets
+	containerHostSocket := filepath.Join(dir, "container-host.sock")
+	createSocket(t, containerHostSocket)
+	t.Setenv("CONTAINER_HOST", "unix://"+containerHostSocket)
+	assert.Equal(t, containerHostSocket, podmanSocket())
+	t.Setenv("CONTAINER_HOST", "ssh://user@host/run/podman/podman.sock")
+	assert.Empty(t, podmanSocket())
+
+	// Docker is preferred over Podman
+	createSocket(t, dockerSocket)
+	backend, err = detectBackend()
+	require.NoError(t, err)
+	assert.Equal(t, BackendDocker, backend)
+}
+
+func TestNewBackend_Unsupported(t *testing.T) {
+	_, err := NewBackend("lxc", &BackendOptions{})
+	require.Error(t, err)
+
+	// The runc backend can't build an image without a local base image
+	_, err = NewBackend(BackendRunc, &BackendOptions{})
+	require.Error(t, err)
+}
+
+func TestBuildStepRegex(t *testing.T) {
+	for _, line := range []string{
+		`{"stream":"Step 2/6 : COPY --from=cifuzz-cli /bin/cifuzz /bin/cifuzz"}`,
+		`{"stream":"STEP 2/6: COPY --from=cifuzz-cli /bin/cifuzz /bin/cifuzz"}`,
+		"STEP 2/6: COPY --from=cifuzz-cli /bin/cifuzz /bin/cifuzz",
+	} {
+		matches := buildStepRegex.FindStringSubmatch(line)
+		require.NotNil(t, matches, line)
+		assert.Equal(t, []string{"2", "6"}, matches[1:])
+	}
+	assert.Nil(t, buildStepRegex.FindStringSubmatch(`{"stream":" ---> Running in 1234"}`))
+}
@@ -12,10 +12,26 @@ func getDockerClient() (*client.Client, error) {
 	if dockerClient != nil {
 		return dockerClient, nil
 	}
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := newDockerClient("")
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
-	defer dockerClient.Close()
+	dockerClient = cli
 	return dockerClient, nil
 }
+
+// newDockerClient returns a client for the Docker API at the specified
+// host, e.g. "unix:///run/podman/podman.sock". If host is empty, the
+// host is taken from the DOCKER_HOST environment variable or the default
+// Docker socket is used.
+func newDockerClient(host string) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return cli, nil
+}
@@ -0,0 +1,210 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/archiveutil"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// dockerBackend runs fuzz containers via the Docker API, which is also
+// provided by Podman's API socket
+type dockerBackend struct {
+	// The name of the backend, used in log messages
+	name       string
+	cli        *client.Client
+	trustedKey ed25519.PublicKey
+}
+
+func newDockerBackend(name, host string, opts *BackendOptions) (*dockerBackend, error) {
+	cli, err := newDockerClient(host)
+	if err != nil {
+		return nil, err
+	}
+	return &dockerBackend{name: name, cli: cli, trustedKey: opts.TrustedKey}, nil
+}
+
+// BuildImage creates an image based on an existing bundle
+func (b *dockerBackend) BuildImage(bundlePath string) error {
+	buildContextDir, err := prepareBuildContext(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer fileutil.Cleanup(buildContextDir)
+	return buildImageFromDir(b.cli, buildContextDir)
+}
+
+func (b *dockerBackend) Create(fuzzTest string) (string, error) {
+	cmd, env := containerCommand(fuzzTest, b.trustedKey)
+
+	containerConfig := &container.Config{
+		Image: imageName,
+		Tty:   false,
+		Cmd:   cmd,
+		Env:   env,
+	}
+
+	ctx := context.Background()
+	cont, err := b.cli.ContainerCreate(
+		ctx,
+		containerConfig,
+		nil,
+		nil,
+		&v1.Platform{
+			Architecture: "amd64",
+			OS:           "linux",
+		},
+		"", // TODO: should the container have a name?
+	)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	log.Debugf("Created fuzz container %s based on image %s via %s", cont.ID, containerConfig.Image, b.name)
+	return cont.ID, nil
+}
+
+func (b *dockerBackend) Start(id string, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	err := b.cli.ContainerStart(ctx, id, types.ContainerStartOptions{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	log.Debugf("started container %s", id)
+
+	// Stream the logs until the container exits
+	out, err := b.cli.ContainerLogs(ctx, id, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer out.Close()
+	_, err = stdcopy.StdCopy(stdout, stderr, out)
+	if err != nil && err != io.EOF {
+		return errors.WithStack(err)
+	}
+
+	statusCh, errCh := b.cli.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	select {
+	case err = <-errCh:
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	case status := <-statusCh:
+		log.Debugf("Container %s exited with code %d", id, status.StatusCode)
+	}
+	return nil
+}
+
+func (b *dockerBackend) Stop(id string) error {
+	ctx := context.Background()
+	return errors.WithStack(b.cli.ContainerStop(ctx, id, container.StopOptions{}))
+}
+
+func (b *dockerBackend) CopyFrom(id, path, destDir string) error {
+	ctx := context.Background()
+	// The archive contains the file or directory under its base name
+	out, _, err := b.cli.CopyFromContainer(ctx, id, path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer out.Close()
+	return archiveutil.Untar(out, destDir)
+}
+
+// SystemLibraries runs a container of the image which lists its system
+// libraries. The image is pulled if it doesn't exist locally.
+func (b *dockerBackend) SystemLibraries(image string) (map[string]bool, error) {
+	ctx := context.Background()
+	err := b.ensureImage(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	cont, err := b.cli.ContainerCreate(
+		ctx,
+		&container.Config{
+			Image:      image,
+			Entrypoint: []string{"sh", "-c"},
+			Cmd:        []string{listLibrariesScript},
+		},
+		nil,
+		nil,
+		&v1.Platform{
+			Architecture: "amd64",
+			OS:           "linux",
+		},
+		"",
+	)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		err := b.cli.ContainerRemove(ctx, cont.ID, types.ContainerRemoveOptions{Force: true})
+		if err != nil {
+			log.Debugf("Failed to remove container %s: %v", cont.ID, err)
+		}
+	}()
+
+	err = b.cli.ContainerStart(ctx, cont.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	statusCh, errCh := b.cli.ContainerWait(ctx, cont.ID, container.WaitConditionNotRunning)
+	select {
+	case err = <-errCh:
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			return nil, errors.Errorf("Listing the libraries of image %s failed with exit code %d", image, status.StatusCode)
+		}
+	}
+
+	out, err := b.cli.ContainerLogs(ctx, cont.ID, types.ContainerLogsOptions{ShowStdout: true})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer out.Close()
+	stdout := &bytes.Buffer{}
+	_, err = stdcopy.StdCopy(stdout, io.Discard, out)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parseLibraries(stdout)
+}
+
+// ensureImage pulls the image if it doesn't exist locally
+func (b *dockerBackend) ensureImage(ctx context.Context, image string) error {
+	_, _, err := b.cli.ImageInspectWithRaw(ctx, image)
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return errors.WithStack(err)
+	}
+
+	log.Infof("Pulling image %s via %s", image, b.name)
+	out, err := b.cli.ImagePull(ctx, image, types.ImagePullOptions{Platform: "linux/amd64"})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer out.Close()
+	_, err = io.Copy(io.Discard, out)
+	return errors.WithStack(err)
+}
+
+func (b *dockerBackend) Close() error {
+	return errors.WithStack(b.cli.Close())
+}
@@ -11,6 +11,7 @@ import (
 	"text/template"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/moby/term"
 	"github.com/otiai10/copy"
@@ -31,19 +32,22 @@ var ensureCifuzzScript string
 //go:embed Dockerfile.tmpl
 var dockerfileTemplate string
 
+// BuildContextFiles are the slash-separated paths of the files and
+// directories which are added to the extracted bundle to build the fuzz
+// container image. They are not part of the bundle, so 'cifuzz execute'
+// ignores them when it verifies the bundle in the container.
+var BuildContextFiles = []string{"Dockerfile", "ensure-cifuzz.sh", "internal/cifuzz_binaries"}
+
 type dockerfileConfig struct {
 	CIFuzzImage string
 	Base        string
 }
 
-// BuildImageFromBundle creates an image based on an existing bundle
-func BuildImageFromBundle(bundlePath string) error {
-	buildContextDir, err := prepareBuildContext(bundlePath)
-	if err != nil {
-		return err
-	}
-	return buildImageFromDir(buildContextDir)
-}
+// buildStepRegex matches the progress messages of Docker and Podman
+// builds, which are either JSON messages of the API like
+// {"stream":"Step 1/6 : FROM ..."} or lines like "STEP 1/6: FROM ..."
+// printed by 'podman build'
+var buildStepRegex = regexp.MustCompile(`^(?:{"stream":")?(?:Step|STEP) (?P<currentStep>\d+)/(?P<totalSteps>\d+) ?: `)
 
 // prepareBuildContext takes a existing artifact bundle, extracts it
 // and adds needed files/information
@@ -59,6 +63,18 @@ func prepareBuildContext(bundlePath string) (string, error) {
 		return "", err
 	}
 
+	// The build context files would replace files of the bundle, which
+	// would then be ignored when the bundle is verified
+	for _, name := range BuildContextFiles {
+		exists, err := fileutil.Exists(filepath.Join(buildContextDir, filepath.FromSlash(name)))
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if exists {
+			return "", errors.Errorf("The bundle contains %s, which is reserved for the build context of the fuzz container image", name)
+		}
+	}
+
 	// read metadata from bundle to use information for building
 	// the right image
 	metadata, err := archive.MetadataFromPath(filepath.Join(buildContextDir, archive.MetadataFileName))
@@ -83,25 +99,20 @@ func prepareBuildContext(bundlePath string) (string, error) {
 }
 
 // builds an image based on an existing directory
-func buildImageFromDir(buildContextDir string) error {
+func buildImageFromDir(dockerClient *client.Client, buildContextDir string) error {
 	imageTar, err := createImageTar(buildContextDir)
 	if err != nil {
 		return err
 	}
 	defer fileutil.Cleanup(imageTar.Name())
 
-	dockerClient, err := getDockerClient()
-	if err != nil {
-		return errors.WithStack(err)
-	}
-
 	ctx := context.Background()
 	opts := types.ImageBuildOptions{
 		Dockerfile:  "Dockerfile",
 		Platform:    "linux/amd64",
 		Remove:      true,
 		ForceRemove: true,
-		Tags:        []string{"cifuzz"},
+		Tags:        []string{imageName},
 	}
 	res, err := dockerClient.ImageBuild(ctx, imageTar, opts)
 	if err != nil {
@@ -120,13 +131,7 @@ func buildImageFromDir(buildContextDir string) error {
 		scanner := bufio.NewScanner(res.Body)
 		scanner.Split(bufio.ScanLines)
 		for scanner.Scan() {
-			// If scanner.Text matches a regex for "Step X/Y" then extract the current step X and all steps Y
-			stepRegex := regexp.MustCompile(`^{"stream":"Step (?P<currentStep>\d+)/(?P<totalSteps>\d+) : `)
-			if stepRegex.MatchString(scanner.Text()) {
-				matches := stepRegex.FindStringSubmatch(scanner.Text())
-				stepString := fmt.Sprintf("%s (Step %s/%s)", log.ContainerBuildInProgressMsg, matches[1], matches[2])
-				log.UpdateCurrentProgressSpinner(stepString)
-			}
+			reportBuildStep(scanner.Text())
 		}
 	}
 
@@ -134,6 +139,17 @@ func buildImageFromDir(buildContextDir string) error {
 	return nil
 }
 
+// reportBuildStep updates the progress spinner if the line of the build
+// output reports the start of a build step
+func reportBuildStep(line string) {
+	// If the line matches a regex for "Step X/Y" then extract the current step X and all steps Y
+	matches := buildStepRegex.FindStringSubmatch(line)
+	if matches != nil {
+		stepString := fmt.Sprintf("%s (Step %s/%s)", log.ContainerBuildInProgressMsg, matches[1], matches[2])
+		log.UpdateCurrentProgressSpinner(stepString)
+	}
+}
+
 // creates a tar archive that can be used for building an image
 // based on a given directory
 func createImageTar(buildContextDir string) (*os.File, error) {
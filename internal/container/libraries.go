@@ -0,0 +1,73 @@
+package container
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// listLibrariesScript prints the names of the shared libraries known to
+// the dynamic linker and the files in the standard library directories
+const listLibrariesScript = `ldconfig -p 2>/dev/null | sed -n 's/^[[:space:]]*\([^[:space:]]*\) .*/\1/p'
+for dir in /lib /lib64 /usr/lib /usr/lib64 /usr/local/lib /lib/*-linux-gnu /usr/lib/*-linux-gnu; do
+  [ -d "$dir" ] && ls -1 "$dir"
+done
+true`
+
+// libraryDirPatterns are the patterns of the standard library
+// directories listed by listLibrariesScript
+var libraryDirPatterns = []string{
+	"/lib",
+	"/lib64",
+	"/usr/lib",
+	"/usr/lib64",
+	"/usr/local/lib",
+	"/lib/*-linux-gnu",
+	"/usr/lib/*-linux-gnu",
+}
+
+// parseLibraries returns the library names printed by
+// listLibrariesScript
+func parseLibraries(r io.Reader) (map[string]bool, error) {
+	libraries := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name != "" {
+			libraries[name] = true
+		}
+	}
+	return libraries, errors.WithStack(scanner.Err())
+}
+
+// libraryFiles returns the names of the files in the standard library
+// directories of the flattened image filesystem read from r. Unlike
+// listLibrariesScript, it doesn't know the libraries in the other
+// directories of the dynamic linker's cache.
+func libraryFiles(r io.Reader) (map[string]bool, error) {
+	libraries := make(map[string]bool)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return libraries, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		name := path.Clean("/" + header.Name)
+		for _, pattern := range libraryDirPatterns {
+			if matched, _ := path.Match(pattern, path.Dir(name)); matched {
+				libraries[path.Base(name)] = true
+				break
+			}
+		}
+	}
+}
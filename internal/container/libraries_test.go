@@ -0,0 +1,39 @@
+package container
+
+import (
+	"archive/tar"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLibraries(t *testing.T) {
+	libraries, err := parseLibraries(strings.NewReader("libc.so.6\n  libm.so.6 \n\nlibc.so.6\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"libc.so.6": true, "libm.so.6": true}, libraries)
+}
+
+func TestLibraryFiles(t *testing.T) {
+	tarball := createRootfsTar(t, []*tar.Header{
+		{Name: "usr/lib/x86_64-linux-gnu/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "usr/lib/x86_64-linux-gnu/libc.so.6", Typeflag: tar.TypeReg, Mode: 0o755},
+		{Name: "usr/lib/x86_64-linux-gnu/libm.so", Typeflag: tar.TypeSymlink, Linkname: "libm.so.6"},
+		{Name: "./lib64/ld-linux-x86-64.so.2", Typeflag: tar.TypeReg, Mode: 0o755},
+		{Name: "usr/local/lib/libfoo.so", Typeflag: tar.TypeReg, Mode: 0o755},
+		// Files outside of the standard library directories and in
+		// their subdirectories are not listed
+		{Name: "usr/bin/bash", Typeflag: tar.TypeReg, Mode: 0o755},
+		{Name: "usr/lib/python3/libbar.so", Typeflag: tar.TypeReg, Mode: 0o755},
+	}, nil)
+
+	libraries, err := libraryFiles(tarball)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"libc.so.6":            true,
+		"libm.so":              true,
+		"ld-linux-x86-64.so.2": true,
+		"libfoo.so":            true,
+	}, libraries)
+}
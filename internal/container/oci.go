@@ -0,0 +1,354 @@
+package container
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/archiveutil"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// The directory in the image into which the bundle is extracted, which
+// is also the working directory of 'cifuzz execute'. It matches the
+// directory used in Dockerfile.tmpl.
+const imageBundleDir = "cifuzz"
+
+// OCIImageOptions configures BuildOCIImageFromBundle
+type OCIImageOptions struct {
+	// The path of the base image, either an OCI image layout directory,
+	// a tarball of an OCI image layout or a tarball created by
+	// 'docker save'
+	BaseImage string
+	// The path of the cifuzz executable which is added to the image.
+	// Defaults to the running executable if it can be executed in the
+	// image.
+	CIFuzzBinary string
+	// The path to which the image is written. If it ends with ".tar", a
+	// tarball which can be loaded with 'docker load' is written, else
+	// an OCI image layout directory.
+	OutputPath string
+	// The name and tag of the image
+	Tag string
+}
+
+// BuildOCIImageFromBundle creates a fuzz container image from the bundle
+// without a Docker daemon. The image consists of the layers of the base
+// image and a layer with the extracted bundle and the cifuzz executable.
+func BuildOCIImageFromBundle(bundlePath string, opts *OCIImageOptions) error {
+	tag, err := name.NewTag(opts.Tag)
+	if err != nil {
+		return errors.Wrapf(err, "invalid image tag %q", opts.Tag)
+	}
+
+	tempDir, err := os.MkdirTemp("", "cifuzz-oci-image-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tempDir)
+
+	img, err := buildOCIImage(bundlePath, opts, tempDir)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(opts.OutputPath, ".tar") {
+		err = tarball.WriteToFile(opts.OutputPath, tag, img)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write image to %s", opts.OutputPath)
+		}
+	} else {
+		p, err := layout.Write(opts.OutputPath, empty.Index)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write image to %s", opts.OutputPath)
+		}
+		err = p.AppendImage(img, layout.WithAnnotations(map[string]string{
+			"org.opencontainers.image.ref.name": tag.String(),
+		}))
+		if err != nil {
+			return errors.Wrapf(err, "failed to write image to %s", opts.OutputPath)
+		}
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	log.Debugf("Created fuzz container image %s with digest %s at %s", tag, digest, opts.OutputPath)
+	return nil
+}
+
+// buildOCIImage creates the fuzz container image from the bundle and
+// the base image. The layers of the image are read lazily from tempDir,
+// which must exist until the image was written.
+func buildOCIImage(bundlePath string, opts *OCIImageOptions, tempDir string) (v1.Image, error) {
+	baseImage, err := loadBaseImage(opts.BaseImage, tempDir)
+	if err != nil {
+		return nil, err
+	}
+	baseConfig, err := baseImage.ConfigFile()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the config of the base image")
+	}
+
+	cifuzzBinary := opts.CIFuzzBinary
+	if cifuzzBinary == "" {
+		if runtime.GOOS != baseConfig.OS || runtime.GOARCH != baseConfig.Architecture {
+			return nil, errors.Errorf(`The base image is a %s/%s image, so the running cifuzz executable (%s/%s)
+can't be added to it. Please specify a cifuzz executable for %s/%s via --cifuzz-binary.`,
+				baseConfig.OS, baseConfig.Architecture, runtime.GOOS, runtime.GOARCH, baseConfig.OS, baseConfig.Architecture)
+		}
+		cifuzzBinary, err = os.Executable()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	// The layer is reproducible like the bundle: It has the same
+	// modification times and its entries are sorted
+	modTime, err := archive.SourceDateEpoch()
+	if err != nil {
+		return nil, err
+	}
+	layerPath, err := createBundleLayer(bundlePath, cifuzzBinary, tempDir, modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the media type of the base image's layers for the new layer,
+	// so that Docker and OCI media types are not mixed
+	manifestMediaType, err := baseImage.MediaType()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	layerMediaType := types.DockerLayer
+	if manifestMediaType == types.OCIManifestSchema1 {
+		layerMediaType = types.OCILayer
+	}
+	layer, err := tarball.LayerFromFile(layerPath, tarball.WithMediaType(layerMediaType))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	img, err := mutate.Append(baseImage, mutate.Addendum{
+		Layer: layer,
+		History: v1.History{
+			Author:    "cifuzz",
+			Created:   v1.Time{Time: modTime},
+			CreatedBy: "cifuzz container build",
+			Comment:   "fuzz test bundle and cifuzz executable",
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Configure the image like the image built from Dockerfile.tmpl
+	config := baseConfig.Config.DeepCopy()
+	config.Env = append(config.Env, "CIFUZZ_PRERELEASE=1")
+	config.WorkingDir = "/" + imageBundleDir
+	config.Cmd = []string{"/bin/cifuzz", "execute"}
+	img, err = mutate.Config(img, *config)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	img, err = mutate.CreatedAt(img, v1.Time{Time: modTime})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return img, nil
+}
+
+// createBundleLayer writes an uncompressed layer tarball which contains
+// the extracted bundle and the cifuzz executable and returns its path
+func createBundleLayer(bundlePath, cifuzzBinary, tempDir string, modTime time.Time) (string, error) {
+	bundleDir := filepath.Join(tempDir, "bundle")
+	err := archive.Extract(bundlePath, bundleDir)
+	if err != nil {
+		return "", err
+	}
+
+	layerPath := filepath.Join(tempDir, "layer.tar")
+	f, err := os.Create(layerPath)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+	archiveWriter, err := archive.NewWriter(f, &archive.WriterOptions{
+		Format:        archive.FormatTar,
+		Deterministic: true,
+		ModTime:       modTime,
+	})
+	if err != nil {
+		return "", err
+	}
+	err = archiveWriter.WriteDir(imageBundleDir, bundleDir)
+	if err != nil {
+		return "", err
+	}
+	// The cifuzz executable is copied to make sure that it's executable
+	// in the image, even if the specified file is not executable
+	executable := filepath.Join(tempDir, "cifuzz")
+	err = copyExecutable(cifuzzBinary, executable)
+	if err != nil {
+		return "", err
+	}
+	err = archiveWriter.WriteFile("bin/cifuzz", executable)
+	if err != nil {
+		return "", err
+	}
+	err = archiveWriter.Close()
+	if err != nil {
+		return "", err
+	}
+	return layerPath, errors.WithStack(f.Close())
+}
+
+func copyExecutable(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(out.Close())
+}
+
+// loadBaseImage loads the image from an OCI image layout directory, a
+// tarball of an OCI image layout or a tarball created by 'docker save'.
+// Tarballs of OCI image layouts are extracted into tempDir, which must
+// exist until the image was written, because the layers are read
+// lazily.
+func loadBaseImage(path string, tempDir string) (v1.Image, error) {
+	if fileutil.IsDir(path) {
+		return imageFromLayout(path)
+	}
+
+	isOCILayout, err := isOCILayoutTarball(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isOCILayout {
+		img, err := tarball.ImageFromPath(path, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load base image from %s", path)
+		}
+		return img, nil
+	}
+
+	layoutDir := filepath.Join(tempDir, "base-image")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	err = archiveutil.Untar(f, layoutDir)
+	if err != nil {
+		return nil, err
+	}
+	return imageFromLayout(layoutDir)
+}
+
+// imageFromLayout returns the linux/amd64 image of the OCI image layout
+// in dir, or its only image if there is just one
+func imageFromLayout(dir string) (v1.Image, error) {
+	index, err := layout.ImageIndexFromPath(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load base image from %s", dir)
+	}
+	var images []v1.Image
+	var platformImage v1.Image
+	err = collectImages(index, &images, &platformImage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load base image from %s", dir)
+	}
+	if len(images) == 1 {
+		return images[0], nil
+	}
+	if platformImage != nil {
+		return platformImage, nil
+	}
+	if len(images) == 0 {
+		return nil, errors.Errorf("OCI image layout %s doesn't contain an image", dir)
+	}
+	return nil, errors.Errorf("OCI image layout %s contains multiple images but none for linux/amd64", dir)
+}
+
+// collectImages adds the images of the index and its nested indexes to
+// images and sets platformImage to the linux/amd64 image, if any
+func collectImages(index v1.ImageIndex, images *[]v1.Image, platformImage *v1.Image) error {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, desc := range indexManifest.Manifests {
+		switch {
+		case desc.MediaType.IsIndex():
+			nestedIndex, err := index.ImageIndex(desc.Digest)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = collectImages(nestedIndex, images, platformImage)
+			if err != nil {
+				return err
+			}
+		case desc.MediaType.IsImage():
+			img, err := index.Image(desc.Digest)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			*images = append(*images, img)
+			if desc.Platform != nil && desc.Platform.OS == "linux" && desc.Platform.Architecture == "amd64" {
+				*platformImage = img
+			}
+		}
+	}
+	return nil
+}
+
+// isOCILayoutTarball returns true if the tarball contains an OCI image
+// layout, i.e. an oci-layout file
+func isOCILayoutTarball(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "%s is neither an OCI image layout nor a tarball", path)
+		}
+		if filepath.Clean(header.Name) == "oci-layout" {
+			return true, nil
+		}
+	}
+}
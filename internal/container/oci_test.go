@@ -0,0 +1,195 @@
+package container
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/bundler/archive"
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+// createBaseImage returns a linux/amd64 image with two random layers
+func createBaseImage(t *testing.T) v1.Image {
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	configFile, err := img.ConfigFile()
+	require.NoError(t, err)
+	configFile = configFile.DeepCopy()
+	configFile.OS = "linux"
+	configFile.Architecture = "amd64"
+	configFile.Config.Env = []string{"PATH=/usr/bin"}
+	img, err = mutate.ConfigFile(img, configFile)
+	require.NoError(t, err)
+	return img
+}
+
+func mustTag(t *testing.T, tag string) name.Tag {
+	ref, err := name.NewTag(tag)
+	require.NoError(t, err)
+	return ref
+}
+
+func createTestBundle(t *testing.T) string {
+	dir := testutil.MkdirTemp(t, "", "oci-test-bundle-*")
+	metadata := &archive.Metadata{
+		RunEnvironment: &archive.RunEnvironment{Docker: "ubuntu:rolling"},
+		Fuzzers:        []*archive.Fuzzer{{Target: "my_fuzz_test", Engine: "LIBFUZZER", Path: "my_fuzz_test"}},
+	}
+	metadataYaml, err := metadata.ToYaml()
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, archive.MetadataFileName), metadataYaml, 0o644)
+	require.NoError(t, err)
+
+	bundle := filepath.Join(testutil.MkdirTemp(t, "", "oci-test-*"), "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	require.NoError(t, err)
+	archiveWriter := archive.NewTarArchiveWriter(f, true)
+	err = archiveWriter.WriteDir("", dir)
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+	return bundle
+}
+
+// layerFiles returns the modes of the files in the layer by their paths
+func layerFiles(t *testing.T, layer v1.Layer) map[string]int64 {
+	rc, err := layer.Uncompressed()
+	require.NoError(t, err)
+	defer rc.Close()
+	files := make(map[string]int64)
+	tr := tar.NewReader(rc)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		files[header.Name] = header.Mode
+	}
+}
+
+func TestBuildOCIImageFromBundle(t *testing.T) {
+	baseImage := createBaseImage(t)
+	dir := testutil.MkdirTemp(t, "", "oci-test-*")
+
+	// A base image saved by 'docker save'
+	dockerTarball := filepath.Join(dir, "base.tar")
+	err := tarball.WriteToFile(dockerTarball, mustTag(t, "base:latest"), baseImage)
+	require.NoError(t, err)
+	// A base image in an OCI image layout
+	ociLayout := filepath.Join(dir, "base-layout")
+	p, err := layout.Write(ociLayout, empty.Index)
+	require.NoError(t, err)
+	err = p.AppendImage(baseImage, layout.WithPlatform(v1.Platform{OS: "linux", Architecture: "amd64"}))
+	require.NoError(t, err)
+	// A base image in a tarball of an OCI image layout
+	ociTarball := filepath.Join(dir, "base-oci.tar")
+	f, err := os.Create(ociTarball)
+	require.NoError(t, err)
+	archiveWriter := archive.NewTarArchiveWriter(f, false)
+	err = archiveWriter.WriteDir("", ociLayout)
+	require.NoError(t, err)
+	err = archiveWriter.Close()
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	// The cifuzz executable is added to the image as an executable,
+	// even if the file is not executable
+	cifuzzBinary := filepath.Join(dir, "cifuzz_linux")
+	err = os.WriteFile(cifuzzBinary, []byte("cifuzz"), 0o644)
+	require.NoError(t, err)
+
+	bundle := createTestBundle(t)
+	baseImages := map[string]string{
+		"docker tarball":   dockerTarball,
+		"OCI image layout": ociLayout,
+		"OCI tarball":      ociTarball,
+	}
+	for format, baseImagePath := range baseImages {
+		baseImagePath := baseImagePath
+		t.Run(format, func(t *testing.T) {
+			outputDir := testutil.MkdirTemp(t, "", "oci-test-output-*")
+			opts := &OCIImageOptions{
+				BaseImage:    baseImagePath,
+				CIFuzzBinary: cifuzzBinary,
+				Tag:          "my-fuzz-test:latest",
+			}
+
+			opts.OutputPath = filepath.Join(outputDir, "image.tar")
+			err := BuildOCIImageFromBundle(bundle, opts)
+			require.NoError(t, err)
+			tarballImage, err := tarball.ImageFromPath(opts.OutputPath, nil)
+			require.NoError(t, err)
+
+			opts.OutputPath = filepath.Join(outputDir, "image")
+			err = BuildOCIImageFromBundle(bundle, opts)
+			require.NoError(t, err)
+			layoutImage, err := imageFromLayout(opts.OutputPath)
+			require.NoError(t, err)
+
+			for _, img := range []v1.Image{tarballImage, layoutImage} {
+				layers, err := img.Layers()
+				require.NoError(t, err)
+				require.Len(t, layers, 3)
+				files := layerFiles(t, layers[2])
+				assert.Equal(t, int64(0o644), files["cifuzz/"+archive.MetadataFileName])
+				assert.Equal(t, int64(0o755), files["bin/cifuzz"])
+
+				configFile, err := img.ConfigFile()
+				require.NoError(t, err)
+				assert.Equal(t, []string{"/bin/cifuzz", "execute"}, configFile.Config.Cmd)
+				assert.Equal(t, "/cifuzz", configFile.Config.WorkingDir)
+				assert.Equal(t, []string{"PATH=/usr/bin", "CIFUZZ_PRERELEASE=1"}, configFile.Config.Env)
+			}
+
+			// The image is reproducible
+			layerDigest := func(img v1.Image) v1.Hash {
+				layers, err := img.Layers()
+				require.NoError(t, err)
+				digest, err := layers[2].DiffID()
+				require.NoError(t, err)
+				return digest
+			}
+			assert.Equal(t, layerDigest(tarballImage), layerDigest(layoutImage))
+		})
+	}
+}
+
+func TestBuildOCIImageFromBundle_PlatformMismatch(t *testing.T) {
+	img := createBaseImage(t)
+	configFile, err := img.ConfigFile()
+	require.NoError(t, err)
+	configFile = configFile.DeepCopy()
+	configFile.Architecture = "s390x"
+	img, err = mutate.ConfigFile(img, configFile)
+	require.NoError(t, err)
+	baseImage := filepath.Join(testutil.MkdirTemp(t, "", "oci-test-*"), "base.tar")
+	err = tarball.WriteToFile(baseImage, mustTag(t, "base:latest"), img)
+	require.NoError(t, err)
+
+	// Without a cifuzz executable for the platform of the base image,
+	// the image can't be built
+	err = BuildOCIImageFromBundle(createTestBundle(t), &OCIImageOptions{
+		BaseImage:  baseImage,
+		OutputPath: filepath.Join(testutil.MkdirTemp(t, "", "oci-test-output-*"), "image.tar"),
+		Tag:        "cifuzz:latest",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--cifuzz-binary")
+}
@@ -0,0 +1,171 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// The exit code of podman if the error occurred in podman itself and
+// not in the container
+const podmanErrorExitCode = 125
+
+// podmanBackend runs fuzz containers via the podman CLI. It's used if
+// the Podman API service is not running.
+type podmanBackend struct {
+	podman     string
+	trustedKey ed25519.PublicKey
+}
+
+func newPodmanBackend(opts *BackendOptions) (*podmanBackend, error) {
+	podman, err := exec.LookPath(podmanExecutableName)
+	if err != nil {
+		return nil, errors.Wrap(err, "podman is not installed and no Podman API socket was found")
+	}
+	return &podmanBackend{podman: podman, trustedKey: opts.TrustedKey}, nil
+}
+
+func (b *podmanBackend) BuildImage(bundlePath string) error {
+	buildContextDir, err := prepareBuildContext(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer fileutil.Cleanup(buildContextDir)
+
+	cmd := exec.Command(b.podman, "build",
+		"--platform", "linux/amd64",
+		"--tag", imageName,
+		"--file", filepath.Join(buildContextDir, "Dockerfile"),
+		buildContextDir,
+	)
+	cmd.Stderr = os.Stderr
+	if viper.GetBool("verbose") {
+		cmd.Stdout = os.Stderr
+		log.Debugf("Command: %s", cmd.String())
+		err = cmd.Run()
+		if err != nil {
+			return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+		}
+	} else {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		log.Debugf("Command: %s", cmd.String())
+		err = cmd.Start()
+		if err != nil {
+			return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+		}
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			reportBuildStep(scanner.Text())
+		}
+		err = cmd.Wait()
+		if err != nil {
+			return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+		}
+	}
+
+	log.Debugf("Created fuzz container image with tag %s", imageName)
+	return nil
+}
+
+func (b *podmanBackend) Create(fuzzTest string) (string, error) {
+	containerCmd, env := containerCommand(fuzzTest, b.trustedKey)
+
+	args := []string{"create", "--platform", "linux/amd64"}
+	for _, e := range env {
+		args = append(args, "--env", e)
+	}
+	args = append(args, imageName)
+	args = append(args, containerCmd...)
+	cmd := exec.Command(b.podman, args...)
+	log.Debugf("Command: %s", cmd.String())
+	out, err := cmd.Output()
+	if err != nil {
+		return "", cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+
+	// podman prints the ID of the container in the last line, after the
+	// progress of pulling the image, if any
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	id := strings.TrimSpace(lines[len(lines)-1])
+	if id == "" {
+		return "", errors.New("podman create didn't print a container ID")
+	}
+
+	log.Debugf("Created fuzz container %s based on image %s via podman", id, imageName)
+	return id, nil
+}
+
+func (b *podmanBackend) Start(id string, stdout, stderr io.Writer) error {
+	cmd := exec.Command(b.podman, "start", "--attach", id)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	log.Debugf("Command: %s", cmd.String())
+	err := cmd.Run()
+	if err != nil {
+		// With --attach, podman exits with the exit code of the
+		// container, which is not an error of the backend
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() != podmanErrorExitCode {
+			log.Debugf("Container %s exited with code %d", id, exitErr.ExitCode())
+			return nil
+		}
+		return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return nil
+}
+
+func (b *podmanBackend) Stop(id string) error {
+	cmd := exec.Command(b.podman, "stop", id)
+	log.Debugf("Command: %s", cmd.String())
+	_, err := cmd.Output()
+	if err != nil {
+		return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return nil
+}
+
+func (b *podmanBackend) CopyFrom(id, path, destDir string) error {
+	cmd := exec.Command(b.podman, "cp", id+":"+path, destDir)
+	log.Debugf("Command: %s", cmd.String())
+	_, err := cmd.Output()
+	if err != nil {
+		return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return nil
+}
+
+// SystemLibraries runs a container of the image which lists its system
+// libraries. podman pulls the image if it doesn't exist locally.
+func (b *podmanBackend) SystemLibraries(image string) (map[string]bool, error) {
+	cmd := exec.Command(b.podman, "run", "--rm",
+		"--platform", "linux/amd64",
+		"--entrypoint", "sh",
+		image, "-c", listLibrariesScript,
+	)
+	cmd.Stderr = os.Stderr
+	log.Debugf("Command: %s", cmd.String())
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return parseLibraries(bytes.NewReader(out))
+}
+
+func (b *podmanBackend) Close() error {
+	return nil
+}
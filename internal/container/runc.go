@@ -0,0 +1,513 @@
+package container
+
+import (
+	"archive/tar"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/otiai10/copy"
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/internal/cmdutils"
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// runcBackend runs fuzz containers with runc. The image is built from
+// a local base image like by 'cifuzz container build' and unpacked into
+// a root filesystem, which is shared by the OCI runtime bundles of the
+// created containers.
+type runcBackend struct {
+	runc       string
+	baseImage  string
+	trustedKey ed25519.PublicKey
+	tempDir    string
+	// The root filesystem of the image, empty until the image was built
+	rootfs string
+	// The environment variables of the image
+	env []string
+	// The OCI runtime bundle directories of the containers by their IDs
+	bundles map[string]string
+}
+
+func newRuncBackend(opts *BackendOptions) (*runcBackend, error) {
+	if opts.BaseImage == "" {
+		return nil, errors.New(`The runc container backend requires a local base image, please specify
+it via the "base-image" setting.`)
+	}
+	runc, err := exec.LookPath("runc")
+	if err != nil {
+		return nil, errors.Wrap(err, "runc is not installed")
+	}
+	tempDir, err := os.MkdirTemp("", "cifuzz-runc-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &runcBackend{
+		runc:       runc,
+		baseImage:  opts.BaseImage,
+		trustedKey: opts.TrustedKey,
+		tempDir:    tempDir,
+		bundles:    make(map[string]string),
+	}, nil
+}
+
+func (b *runcBackend) BuildImage(bundlePath string) error {
+	img, err := buildOCIImage(bundlePath, &OCIImageOptions{BaseImage: b.baseImage}, b.tempDir)
+	if err != nil {
+		return err
+	}
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Unpack the layers of the image into the root filesystem
+	rootfs := filepath.Join(b.tempDir, "rootfs")
+	err = extractImage(img, rootfs)
+	if err != nil {
+		return err
+	}
+
+	b.rootfs = rootfs
+	b.env = configFile.Config.Env
+	log.Debugf("Unpacked fuzz container image to %s", rootfs)
+	return nil
+}
+
+func (b *runcBackend) Create(fuzzTest string) (string, error) {
+	if b.rootfs == "" {
+		return "", errors.New("the image must be built before a container can be created")
+	}
+	containerCmd, env := containerCommand(fuzzTest, b.trustedKey)
+
+	bundleDir, err := os.MkdirTemp(b.tempDir, "cifuzz-")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	id := filepath.Base(bundleDir)
+
+	// Let runc create a default config which we adjust, so that we
+	// don't have to maintain the namespaces and mounts ourselves
+	args := []string{"spec", "--bundle", bundleDir}
+	if os.Geteuid() != 0 {
+		args = append(args, "--rootless")
+	}
+	cmd := exec.Command(b.runc, args...)
+	log.Debugf("Command: %s", cmd.String())
+	_, err = cmd.Output()
+	if err != nil {
+		return "", cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	containerEnv := append(append([]string{}, b.env...), env...)
+	err = configureRuncSpec(filepath.Join(bundleDir, "config.json"), containerCmd, containerEnv, b.rootfs)
+	if err != nil {
+		return "", err
+	}
+
+	b.bundles[id] = bundleDir
+	log.Debugf("Created fuzz container %s with OCI runtime bundle %s", id, bundleDir)
+	return id, nil
+}
+
+func (b *runcBackend) Start(id string, stdout, stderr io.Writer) error {
+	bundleDir, ok := b.bundles[id]
+	if !ok {
+		return errors.Errorf("unknown container %s", id)
+	}
+
+	cmd := exec.Command(b.runc, "run", "--bundle", bundleDir, id)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	log.Debugf("Command: %s", cmd.String())
+	err := cmd.Run()
+	if err != nil {
+		// 'runc run' exits with the exit code of the container, which is
+		// not an error of the backend. Errors of runc itself are
+		// printed to stderr.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			log.Debugf("Container %s exited with code %d", id, exitErr.ExitCode())
+			return nil
+		}
+		return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return nil
+}
+
+func (b *runcBackend) Stop(id string) error {
+	cmd := exec.Command(b.runc, "kill", id, "TERM")
+	log.Debugf("Command: %s", cmd.String())
+	_, err := cmd.Output()
+	if err != nil {
+		return cmdutils.WrapExecError(errors.WithStack(err), cmd)
+	}
+	return nil
+}
+
+func (b *runcBackend) CopyFrom(id, path, destDir string) error {
+	if _, ok := b.bundles[id]; !ok {
+		return errors.Errorf("unknown container %s", id)
+	}
+	// The container writes into the root filesystem, which is kept
+	// until the backend is closed
+	rootfs, err := filepath.EvalSymlinks(b.rootfs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	src, err := rootfsPath(rootfs, path)
+	if err != nil {
+		return err
+	}
+	err = copy.Copy(src, filepath.Join(destDir, filepath.Base(path)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// SystemLibraries lists the library files of the local base image,
+// because runc can't pull the image
+func (b *runcBackend) SystemLibraries(image string) (map[string]bool, error) {
+	img, err := loadBaseImage(b.baseImage, b.tempDir)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Listing the system libraries of the base image %s instead of image %s", b.baseImage, image)
+	rc := mutate.Extract(img)
+	defer rc.Close()
+	return libraryFiles(rc)
+}
+
+func (b *runcBackend) Close() error {
+	fileutil.Cleanup(b.tempDir)
+	return nil
+}
+
+// configureRuncSpec changes the config.json created by 'runc spec' to
+// run the command in the root filesystem of the fuzz container image.
+// The config is changed as a map, so that all other settings are kept.
+func configureRuncSpec(path string, args, env []string, rootfs string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var spec map[string]any
+	err = json.Unmarshal(data, &spec)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", path)
+	}
+
+	process, ok := spec["process"].(map[string]any)
+	if !ok {
+		return errors.Errorf("%s doesn't contain a process config", path)
+	}
+	process["args"] = args
+	process["env"] = env
+	process["cwd"] = "/" + imageBundleDir
+	process["terminal"] = false
+
+	absRootfs, err := filepath.Abs(rootfs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// The fuzz test writes its findings and the generated corpus into
+	// the root filesystem, from which they are copied by CopyFrom
+	spec["root"] = map[string]any{
+		"path":     absRootfs,
+		"readonly": false,
+	}
+
+	data, err = json.MarshalIndent(spec, "", "\t")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, data, 0o644))
+}
+
+// The prefix of the whiteout files, which mark files of lower layers as
+// deleted, and the name of the opaque whiteout file, which marks the
+// files of lower layers in its directory as deleted
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// maxSymlinks is the maximum number of symlinks which are followed when
+// a path in the root filesystem is resolved, like the limit of Linux
+const maxSymlinks = 40
+
+// extractImage applies the layers of the image to the root filesystem
+// dir from the bottom up, like a container runtime does
+func extractImage(img v1.Image, dir string) error {
+	layers, err := img.Layers()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, layer := range layers {
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = extractLayer(rc, dir)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractLayer extracts a layer into the root filesystem dir. Files
+// replace the ones of lower layers, directories are merged with them
+// and whiteout files remove them. Device files and FIFOs are skipped,
+// because they can't be created without privileges and runc creates the
+// required devices itself. The files are owned by the current user,
+// which is mapped to root in the container by rootless runc.
+func extractLayer(r io.Reader, dir string) error {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Resolve the directory itself, so that the paths in it can be
+	// compared to resolved paths
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// The files created by this layer, which are not removed by its
+	// opaque whiteouts
+	created := make(map[string]bool)
+	// Hard links are created after all other files of the layer, so
+	// that their targets exist
+	var hardLinks []*tar.Header
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		name := path.Clean("/" + header.Name)
+		if name == "/" {
+			continue
+		}
+		// Symlinks of lower layers in the parent directories are
+		// followed inside of the root filesystem, the file itself
+		// is replaced
+		parent, err := rootfsPath(dir, path.Dir(name))
+		if err != nil {
+			return err
+		}
+		base := path.Base(name)
+		target := filepath.Join(parent, base)
+
+		switch {
+		case base == opaqueWhiteout:
+			err = removeLowerFiles(parent, created)
+			if err != nil {
+				return err
+			}
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			err = os.RemoveAll(filepath.Join(parent, strings.TrimPrefix(base, whiteoutPrefix)))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		case header.Typeflag == tar.TypeLink:
+			hardLinks = append(hardLinks, header)
+			continue
+		case header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeSymlink:
+			log.Debugf("Skipping %s in the image", header.Name)
+			continue
+		}
+
+		err = prepareRootfsTarget(target, header.Typeflag == tar.TypeDir)
+		if err != nil {
+			return err
+		}
+		mode := header.FileInfo().Mode().Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			// Directories must be writable by the owner, so that files
+			// can be extracted into them and the rootfs can be removed
+			err = os.MkdirAll(target, 0o755)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = os.Chmod(target, mode|0o700)
+		case tar.TypeReg:
+			err = writeRootfsFile(target, tr, mode)
+		case tar.TypeSymlink:
+			err = os.Symlink(header.Linkname, target)
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		created[target] = true
+	}
+
+	for _, header := range hardLinks {
+		name := path.Clean("/" + header.Name)
+		parent, err := rootfsPath(dir, path.Dir(name))
+		if err != nil {
+			return err
+		}
+		linkname := path.Clean("/" + header.Linkname)
+		linkParent, err := rootfsPath(dir, path.Dir(linkname))
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(parent, path.Base(name))
+		err = prepareRootfsTarget(target, false)
+		if err != nil {
+			return err
+		}
+		err = os.Link(filepath.Join(linkParent, path.Base(linkname)), target)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		created[target] = true
+	}
+	return nil
+}
+
+// prepareRootfsTarget removes the file of a lower layer at path, unless
+// both it and the new file are directories, and creates the missing
+// parent directories
+func prepareRootfsTarget(path string, isDir bool) error {
+	info, err := os.Lstat(path)
+	if err == nil && !(isDir && info.IsDir()) {
+		err = os.RemoveAll(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.MkdirAll(filepath.Dir(path), 0o755))
+}
+
+// removeLowerFiles removes the files in dir which were not created by
+// the current layer. Directories which contain files of the current
+// layer are kept, but the files of lower layers in them are removed.
+func removeLowerFiles(dir string, created map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && (created[path] || containsCreated(path, created)) {
+			err = removeLowerFiles(path, created)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if created[path] {
+			continue
+		}
+		err = os.RemoveAll(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// containsCreated returns true if a file below dir was created by the
+// current layer
+func containsCreated(dir string, created map[string]bool) bool {
+	for path := range created {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootfsPath returns the path of the file with the specified name in the
+// root filesystem dir. Symlinks are resolved like in the container, so
+// absolute symlinks and ".." are resolved relative to dir and the path
+// can't point outside of dir. Missing directories are not resolved.
+func rootfsPath(dir, name string) (string, error) {
+	// The components of the path which remain to be resolved and the
+	// path in the root filesystem which was resolved so far
+	remaining := strings.Split(name, "/")
+	resolved := "/"
+	symlinks := 0
+	for len(remaining) > 0 {
+		component := remaining[0]
+		remaining = remaining[1:]
+		if component == "" || component == "." {
+			continue
+		}
+		if component == ".." {
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, component)
+		hostPath := filepath.Join(dir, filepath.FromSlash(next))
+		info, err := os.Lstat(hostPath)
+		if os.IsNotExist(err) {
+			// The missing directories are created as regular
+			// directories, so the rest of the path doesn't contain
+			// any symlinks
+			rest := path.Join(append([]string{next}, remaining...)...)
+			return filepath.Join(dir, filepath.FromSlash(rest)), nil
+		}
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		symlinks++
+		if symlinks > maxSymlinks {
+			return "", errors.Errorf("too many levels of symbolic links in %s in the image", name)
+		}
+		target, err := os.Readlink(hostPath)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+	}
+	return filepath.Join(dir, filepath.FromSlash(resolved)), nil
+}
+
+func writeRootfsFile(path string, r io.Reader, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(f.Close())
+}
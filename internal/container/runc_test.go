@@ -0,0 +1,219 @@
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/internal/testutil"
+)
+
+// createRootfsTar returns a tarball with the specified entries, which
+// are regular files unless their type flag is set
+func createRootfsTar(t *testing.T, headers []*tar.Header, contents map[string]string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(contents[header.Name]))
+		}
+		err := tw.WriteHeader(header)
+		require.NoError(t, err)
+		_, err = tw.Write([]byte(contents[header.Name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf
+}
+
+func TestExtractRootfs(t *testing.T) {
+	rootfs := filepath.Join(testutil.MkdirTemp(t, "", "rootfs-test-*"), "rootfs")
+	tarball := createRootfsTar(t, []*tar.Header{
+		{Name: "./", Typeflag: tar.TypeDir, Mode: 0o755},
+		// The hard link precedes its target
+		{Name: "usr/bin/sh", Typeflag: tar.TypeLink, Linkname: "usr/bin/bash"},
+		{Name: "usr/bin/", Typeflag: tar.TypeDir, Mode: 0o555},
+		{Name: "usr/bin/bash", Typeflag: tar.TypeReg, Mode: 0o755},
+		{Name: "bin", Typeflag: tar.TypeSymlink, Linkname: "usr/bin"},
+		{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0o666, Devmajor: 1, Devminor: 3},
+		{Name: "cifuzz/cifuzz.yaml", Typeflag: tar.TypeReg, Mode: 0o644},
+	}, map[string]string{
+		"usr/bin/bash":       "bash",
+		"cifuzz/cifuzz.yaml": "metadata",
+	})
+
+	err := extractLayer(tarball, rootfs)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(rootfs, "bin", "sh"))
+	require.NoError(t, err)
+	assert.Equal(t, "bash", string(content))
+	info, err := os.Stat(filepath.Join(rootfs, "usr", "bin", "bash"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	// Directories are writable by the owner
+	info, err = os.Stat(filepath.Join(rootfs, "usr", "bin"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	assert.FileExists(t, filepath.Join(rootfs, "cifuzz", "cifuzz.yaml"))
+	assert.NoFileExists(t, filepath.Join(rootfs, "dev", "null"))
+}
+
+func TestExtractRootfs_SymlinkOutsideOfRootfs(t *testing.T) {
+	dir := testutil.MkdirTemp(t, "", "rootfs-test-*")
+	outside := filepath.Join(dir, "outside")
+	err := os.Mkdir(outside, 0o755)
+	require.NoError(t, err)
+	rootfs := filepath.Join(dir, "rootfs")
+
+	tarball := createRootfsTar(t, []*tar.Header{
+		{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: outside},
+		{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "up", Typeflag: tar.TypeSymlink, Linkname: "../../.."},
+		{Name: "up/group", Typeflag: tar.TypeReg, Mode: 0o644},
+	}, map[string]string{"etc/passwd": "root", "up/group": "root"})
+
+	// The symlinks are resolved inside of the root filesystem, like in
+	// the container
+	err = extractLayer(tarball, rootfs)
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(outside, "passwd"))
+	assert.FileExists(t, filepath.Join(rootfs, outside, "passwd"))
+	assert.NoFileExists(t, filepath.Join(dir, "group"))
+	assert.FileExists(t, filepath.Join(rootfs, "group"))
+}
+
+func TestExtractRootfs_Layers(t *testing.T) {
+	rootfs := filepath.Join(testutil.MkdirTemp(t, "", "rootfs-test-*"), "rootfs")
+	lower := createRootfsTar(t, []*tar.Header{
+		{Name: "usr/bin/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "usr/bin/bash", Typeflag: tar.TypeReg, Mode: 0o755},
+		{Name: "bin", Typeflag: tar.TypeSymlink, Linkname: "/usr/bin"},
+		{Name: "etc/hosts", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "opt/old/file", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "cifuzz.yaml", Typeflag: tar.TypeReg, Mode: 0o644},
+	}, map[string]string{"usr/bin/bash": "bash", "cifuzz.yaml": "old"})
+	upper := createRootfsTar(t, []*tar.Header{
+		// A file below a directory symlink of the lower layer
+		{Name: "bin/cifuzz", Typeflag: tar.TypeReg, Mode: 0o755},
+		{Name: "etc/.wh.hosts", Typeflag: tar.TypeReg},
+		{Name: "opt/new/file", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "opt/.wh..wh..opq", Typeflag: tar.TypeReg},
+		{Name: "cifuzz.yaml", Typeflag: tar.TypeReg, Mode: 0o644},
+	}, map[string]string{"bin/cifuzz": "cifuzz", "cifuzz.yaml": "new"})
+
+	var layers []v1.Layer
+	for _, buf := range []*bytes.Buffer{lower, upper} {
+		data := buf.Bytes()
+		layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		})
+		require.NoError(t, err)
+		layers = append(layers, layer)
+	}
+	img, err := mutate.AppendLayers(empty.Image, layers...)
+	require.NoError(t, err)
+	err = extractImage(img, rootfs)
+	require.NoError(t, err)
+
+	// The symlink of the lower layer is kept and followed
+	target, err := os.Readlink(filepath.Join(rootfs, "bin"))
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin", target)
+	content, err := os.ReadFile(filepath.Join(rootfs, "usr", "bin", "cifuzz"))
+	require.NoError(t, err)
+	assert.Equal(t, "cifuzz", string(content))
+	assert.FileExists(t, filepath.Join(rootfs, "usr", "bin", "bash"))
+	// Files of the upper layer replace the ones of the lower layer
+	content, err = os.ReadFile(filepath.Join(rootfs, "cifuzz.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+	// Whiteouts remove the files of the lower layer
+	assert.NoFileExists(t, filepath.Join(rootfs, "etc", "hosts"))
+	assert.NoFileExists(t, filepath.Join(rootfs, "etc", ".wh.hosts"))
+	assert.FileExists(t, filepath.Join(rootfs, "etc", "passwd"))
+	assert.NoDirExists(t, filepath.Join(rootfs, "opt", "old"))
+	assert.FileExists(t, filepath.Join(rootfs, "opt", "new", "file"))
+}
+
+func TestConfigureRuncSpec(t *testing.T) {
+	dir := testutil.MkdirTemp(t, "", "runc-spec-test-*")
+	configPath := filepath.Join(dir, "config.json")
+	// A shortened config created by 'runc spec'
+	err := os.WriteFile(configPath, []byte(`{
+	"ociVersion": "1.0.2-dev",
+	"process": {
+		"terminal": true,
+		"user": {"uid": 0, "gid": 0},
+		"args": ["sh"],
+		"env": ["PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "TERM=xterm"],
+		"cwd": "/"
+	},
+	"root": {"path": "rootfs", "readonly": true},
+	"hostname": "runc"
+}`), 0o644)
+	require.NoError(t, err)
+
+	args := []string{"/bin/cifuzz", "execute", "my_fuzz_test"}
+	env := []string{"PATH=/usr/bin", "CIFUZZ_PRERELEASE=1"}
+	err = configureRuncSpec(configPath, args, env, filepath.Join(dir, "rootfs"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	var spec struct {
+		Process struct {
+			Terminal bool     `json:"terminal"`
+			Args     []string `json:"args"`
+			Env      []string `json:"env"`
+			Cwd      string   `json:"cwd"`
+		} `json:"process"`
+		Root struct {
+			Path     string `json:"path"`
+			Readonly bool   `json:"readonly"`
+		} `json:"root"`
+		Hostname string `json:"hostname"`
+	}
+	err = json.Unmarshal(data, &spec)
+	require.NoError(t, err)
+
+	assert.False(t, spec.Process.Terminal)
+	assert.Equal(t, args, spec.Process.Args)
+	assert.Equal(t, env, spec.Process.Env)
+	assert.Equal(t, "/cifuzz", spec.Process.Cwd)
+	assert.Equal(t, filepath.Join(dir, "rootfs"), spec.Root.Path)
+	assert.False(t, spec.Root.Readonly)
+	// Other settings are kept
+	assert.Equal(t, "runc", spec.Hostname)
+}
+
+func TestRuncCopyFrom(t *testing.T) {
+	dir := testutil.MkdirTemp(t, "", "runc-copy-test-*")
+	rootfs := filepath.Join(dir, "rootfs")
+	err := os.MkdirAll(filepath.Join(rootfs, "cifuzz-output", "corpus"), 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(rootfs, "cifuzz-output", "corpus", "input"), []byte("input"), 0o644)
+	require.NoError(t, err)
+
+	b := &runcBackend{rootfs: rootfs, bundles: map[string]string{"cifuzz-1": filepath.Join(dir, "cifuzz-1")}}
+	destDir := filepath.Join(dir, "dest")
+	err = os.Mkdir(destDir, 0o755)
+	require.NoError(t, err)
+	err = b.CopyFrom("cifuzz-1", OutputDir, destDir)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(destDir, "cifuzz-output", "corpus", "input"))
+
+	require.Error(t, b.CopyFrom("unknown", OutputDir, destDir))
+}
@@ -5,12 +5,30 @@ import "code-intelligence.com/cifuzz/internal/config"
 const FormatHTML = "html"
 const FormatLCOV = "lcov"
 const FormatJacocoXML = "jacocoxml"
+const FormatCobertura = "cobertura"
+const FormatJSON = "json"
 
 var ValidOutputFormats = map[string][]string{
-	config.BuildSystemCMake:  {FormatHTML, FormatLCOV},
-	config.BuildSystemBazel:  {FormatHTML, FormatLCOV},
-	config.BuildSystemOther:  {FormatHTML, FormatLCOV},
-	config.BuildSystemMaven:  {FormatHTML, FormatJacocoXML},
-	config.BuildSystemGradle: {FormatHTML, FormatJacocoXML},
-	config.BuildSystemNodeJS: {FormatHTML, FormatLCOV},
+	config.BuildSystemCMake:  {FormatHTML, FormatLCOV, FormatCobertura, FormatJSON},
+	config.BuildSystemBazel:  {FormatHTML, FormatLCOV, FormatCobertura, FormatJSON},
+	config.BuildSystemOther:  {FormatHTML, FormatLCOV, FormatCobertura, FormatJSON},
+	config.BuildSystemMaven:  {FormatHTML, FormatJacocoXML, FormatCobertura, FormatJSON},
+	config.BuildSystemGradle: {FormatHTML, FormatJacocoXML, FormatCobertura, FormatJSON},
+	config.BuildSystemNodeJS: {FormatHTML, FormatLCOV, FormatCobertura, FormatJSON},
 }
+
+// IsConvertedFormat returns true if reports in the format are converted
+// from the lcov or JaCoCo XML report produced by the build system
+func IsConvertedFormat(format string) bool {
+	return format == FormatCobertura || format == FormatJSON
+}
+
+// The renderers of HTML reports for lcov based build systems
+const (
+	// Use genhtml if it's available and the built-in renderer otherwise
+	HTMLRendererAuto    = "auto"
+	HTMLRendererGenHTML = "genhtml"
+	HTMLRendererBuiltin = "builtin"
+)
+
+var HTMLRenderers = []string{HTMLRendererAuto, HTMLRendererGenHTML, HTMLRendererBuiltin}
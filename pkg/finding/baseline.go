@@ -0,0 +1,237 @@
+package finding
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/pkg/vcs"
+	"code-intelligence.com/cifuzz/util/archiveutil"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// The number of stack frames which are included in the signature of a
+// finding. Frames further down the stack are usually part of the fuzz
+// test or the setup code and don't help to tell bugs apart.
+const numSignatureFrames = 3
+
+// Signature returns a string which identifies the bug that caused the
+// finding. In contrast to the name of the finding, the signature does
+// not depend on the crashing input or on line numbers, so that the
+// same bug has the same signature on different commits and when it
+// was found with different inputs. It is computed from the error ID
+// and the functions and source files of the top project stack frames.
+func (f *Finding) Signature() string {
+	var parts []string
+	if f.MoreDetails != nil && f.MoreDetails.ID != "" {
+		parts = append(parts, f.MoreDetails.ID)
+	} else {
+		// Findings created by older cifuzz versions don't have an
+		// error ID, fall back to the error type.
+		parts = append(parts, f.ShortDescriptionColumns()[0])
+	}
+
+	for i, frame := range f.StackTrace {
+		if i == numSignatureFrames {
+			break
+		}
+		parts = append(parts, frame.SourceFile+":"+frame.Function)
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// BaselineComparison is the result of comparing findings against the
+// findings of a baseline.
+type BaselineComparison struct {
+	// New contains the findings which don't exist in the baseline
+	New []*Finding `json:"new"`
+	// StillPresent contains the findings which also exist in the
+	// baseline and the findings of the baseline whose crashing input
+	// still reproduces them
+	StillPresent []*Finding `json:"still_present"`
+	// Resolved contains the findings of the baseline whose crashing
+	// input doesn't reproduce them anymore
+	Resolved []*Finding `json:"resolved"`
+	// Unverified contains the findings of the baseline which were not
+	// found, but whose crashing input could not be executed to check
+	// whether they were resolved
+	Unverified []*Finding `json:"unverified"`
+}
+
+// ReproduceFunc executes the crashing input of a finding and returns
+// whether the finding was reproduced
+type ReproduceFunc func(f *Finding) (bool, error)
+
+// CompareWithBaseline classifies the findings as new or still present.
+// Findings are considered the same if they have the same signature. A
+// fuzzing run stops at the first crash, so the baseline findings which
+// are not among the findings are not necessarily resolved: Their
+// crashing inputs are executed via reproduce and only the ones which
+// don't reproduce the finding are classified as resolved. If reproduce
+// is nil or fails, they are classified as unverified.
+func CompareWithBaseline(findings, baseline []*Finding, reproduce ReproduceFunc) *BaselineComparison {
+	res := &BaselineComparison{
+		New:          []*Finding{},
+		StillPresent: []*Finding{},
+		Resolved:     []*Finding{},
+		Unverified:   []*Finding{},
+	}
+
+	// Group the baseline findings by signature, keeping their order
+	var baselineSignatures []string
+	baselineFindings := make(map[string][]*Finding)
+	for _, f := range baseline {
+		signature := f.Signature()
+		if _, ok := baselineFindings[signature]; !ok {
+			baselineSignatures = append(baselineSignatures, signature)
+		}
+		baselineFindings[signature] = append(baselineFindings[signature], f)
+	}
+
+	signatures := make(map[string]bool)
+	for _, f := range findings {
+		signature := f.Signature()
+		signatures[signature] = true
+		if _, ok := baselineFindings[signature]; ok {
+			res.StillPresent = append(res.StillPresent, f)
+		} else {
+			res.New = append(res.New, f)
+		}
+	}
+
+	// Only report one finding per bug of the baseline which was not
+	// found. The bug is still present if any of its findings is
+	// reproduced.
+	for _, signature := range baselineSignatures {
+		if signatures[signature] {
+			continue
+		}
+		resolved := true
+		var reproduced *Finding
+		for _, f := range baselineFindings[signature] {
+			if reproduce == nil {
+				resolved = false
+				break
+			}
+			ok, err := reproduce(f)
+			if err != nil {
+				log.Warnf("Failed to check if baseline finding %s was resolved: %v", f.Name, err)
+				resolved = false
+				continue
+			}
+			if ok {
+				reproduced = f
+				break
+			}
+		}
+		switch {
+		case reproduced != nil:
+			res.StillPresent = append(res.StillPresent, reproduced)
+		case resolved:
+			res.Resolved = append(res.Resolved, baselineFindings[signature][0])
+		default:
+			res.Unverified = append(res.Unverified, baselineFindings[signature][0])
+		}
+	}
+
+	return res
+}
+
+// FilterByFuzzTest returns the findings which were found by the
+// specified fuzz test
+func FilterByFuzzTest(findings []*Finding, fuzzTest string) []*Finding {
+	var res []*Finding
+	for _, f := range findings {
+		if f.FuzzTest == fuzzTest {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// LoadBaseline returns the findings of the specified baseline, which
+// can be one of:
+//   - a directory containing a .cifuzz-findings directory (e.g. another
+//     checkout of the project) or a findings directory itself
+//   - a tar, tar.gz or zip archive of one of the above
+//   - a Git ref, in which case the findings directory of the project as
+//     of that ref is used
+func LoadBaseline(projectDir, baseline string, errorDetails *[]ErrorDetails) ([]*Finding, error) {
+	info, statErr := os.Stat(baseline)
+	if statErr != nil && !os.IsNotExist(statErr) {
+		return nil, errors.WithStack(statErr)
+	}
+	if statErr == nil && info.IsDir() {
+		return listBaselineFindings(baseline, errorDetails)
+	}
+
+	tempDir, err := os.MkdirTemp("", "cifuzz-baseline-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tempDir)
+
+	if statErr == nil {
+		err = extractBaselineArchive(baseline, tempDir)
+		if err != nil {
+			return nil, err
+		}
+		return listBaselineFindings(tempDir, errorDetails)
+	}
+
+	if !vcs.GitIsRef(projectDir, baseline) {
+		return nil, errors.Errorf("Baseline %q is neither a directory, an archive nor a Git ref", baseline)
+	}
+	err = vcs.GitExportDir(projectDir, baseline, nameFindingsDir, tempDir)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Debugf("No findings stored for %s", baseline)
+		return []*Finding{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return listFindingsInDir(filepath.Join(tempDir, nameFindingsDir), errorDetails)
+}
+
+func listBaselineFindings(dir string, errorDetails *[]ErrorDetails) ([]*Finding, error) {
+	findingsDir := filepath.Join(dir, nameFindingsDir)
+	exists, err := fileutil.Exists(findingsDir)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		// Assume that the directory is a findings directory itself
+		findingsDir = dir
+	}
+	return listFindingsInDir(findingsDir, errorDetails)
+}
+
+func extractBaselineArchive(archive, dest string) error {
+	switch {
+	case strings.HasSuffix(archive, ".zip"):
+		return archiveutil.Unzip(archive, dest)
+	case strings.HasSuffix(archive, ".tar.gz"), strings.HasSuffix(archive, ".tgz"):
+		f, err := os.Open(archive)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer gr.Close()
+		return archiveutil.Untar(gr, dest)
+	case strings.HasSuffix(archive, ".tar"):
+		return archiveutil.UntarFile(archive, dest)
+	}
+	return errors.Errorf("Unsupported archive format of baseline %q", archive)
+}
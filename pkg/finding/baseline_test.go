@@ -0,0 +1,133 @@
+package finding
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+)
+
+func TestFinding_Signature(t *testing.T) {
+	f1 := baselineTestFinding("finding-1", "heap_buffer_overflow", 10)
+	// Same bug, found with a different input on a different commit
+	f2 := baselineTestFinding("finding-2", "heap_buffer_overflow", 12)
+	// Different bug in the same location
+	f3 := baselineTestFinding("finding-3", "double_free", 10)
+
+	assert.Equal(t, f1.Signature(), f2.Signature())
+	assert.NotEqual(t, f1.Signature(), f3.Signature())
+}
+
+func TestCompareWithBaseline(t *testing.T) {
+	stillPresent := baselineTestFinding("still-present", "heap_buffer_overflow", 10)
+	newFinding := baselineTestFinding("new", "double_free", 10)
+	resolved := baselineTestFinding("resolved", "memory_leak", 20)
+	reproduced := baselineTestFinding("reproduced", "stack_buffer_overflow", 30)
+	unverified := baselineTestFinding("unverified", "use_after_free", 40)
+
+	reproduce := func(f *Finding) (bool, error) {
+		switch f {
+		case reproduced:
+			return true, nil
+		case unverified:
+			return false, errors.New("failed to execute the fuzz test")
+		}
+		return false, nil
+	}
+	comparison := CompareWithBaseline(
+		[]*Finding{stillPresent, newFinding},
+		[]*Finding{baselineTestFinding("still-present-baseline", "heap_buffer_overflow", 8), resolved, reproduced, unverified},
+		reproduce,
+	)
+	assert.Equal(t, []*Finding{newFinding}, comparison.New)
+	assert.Equal(t, []*Finding{stillPresent, reproduced}, comparison.StillPresent)
+	assert.Equal(t, []*Finding{resolved}, comparison.Resolved)
+	assert.Equal(t, []*Finding{unverified}, comparison.Unverified)
+}
+
+func TestCompareWithBaseline_NoReproduce(t *testing.T) {
+	// Without executing the crashing inputs, no baseline finding can be
+	// classified as resolved
+	notFound := baselineTestFinding("not-found", "memory_leak", 20)
+	comparison := CompareWithBaseline([]*Finding{}, []*Finding{notFound}, nil)
+	assert.Empty(t, comparison.Resolved)
+	assert.Equal(t, []*Finding{notFound}, comparison.Unverified)
+}
+
+func TestFilterByFuzzTest(t *testing.T) {
+	f1 := baselineTestFinding("finding-1", "heap_buffer_overflow", 10)
+	f1.FuzzTest = "my_fuzz_test"
+	f2 := baselineTestFinding("finding-2", "double_free", 10)
+	f2.FuzzTest = "other_fuzz_test"
+	assert.Equal(t, []*Finding{f1}, FilterByFuzzTest([]*Finding{f1, f2}, "my_fuzz_test"))
+}
+
+func TestLoadBaseline_Directory(t *testing.T) {
+	baselineDir, err := os.MkdirTemp(testBaseDir, "baseline-dir-")
+	require.NoError(t, err)
+	f := baselineTestFinding("baseline-finding", "heap_buffer_overflow", 10)
+	err = f.Save(baselineDir)
+	require.NoError(t, err)
+
+	// Both the project directory and the findings directory itself
+	// can be used as baseline
+	for _, dir := range []string{baselineDir, filepath.Join(baselineDir, nameFindingsDir)} {
+		findings, err := LoadBaseline(testBaseDir, dir, nil)
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, f.Name, findings[0].Name)
+	}
+}
+
+func TestLoadBaseline_Archive(t *testing.T) {
+	baselineDir, err := os.MkdirTemp(testBaseDir, "baseline-archive-")
+	require.NoError(t, err)
+	f := baselineTestFinding("archived-finding", "heap_buffer_overflow", 10)
+	err = f.Save(baselineDir)
+	require.NoError(t, err)
+
+	// Create a zip archive of the findings directory
+	archivePath := filepath.Join(testBaseDir, "baseline.zip")
+	archive, err := os.Create(archivePath)
+	require.NoError(t, err)
+	zw := zip.NewWriter(archive)
+	jsonPath := filepath.Join(nameFindingsDir, f.Name, nameJSONFile)
+	w, err := zw.Create(filepath.ToSlash(jsonPath))
+	require.NoError(t, err)
+	jsonFile, err := os.Open(filepath.Join(baselineDir, jsonPath))
+	require.NoError(t, err)
+	_, err = io.Copy(w, jsonFile)
+	require.NoError(t, err)
+	require.NoError(t, jsonFile.Close())
+	require.NoError(t, zw.Close())
+	require.NoError(t, archive.Close())
+
+	findings, err := LoadBaseline(testBaseDir, archivePath, nil)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, f.Name, findings[0].Name)
+}
+
+func TestLoadBaseline_Invalid(t *testing.T) {
+	_, err := LoadBaseline(testBaseDir, "does-not-exist", nil)
+	require.Error(t, err)
+}
+
+func baselineTestFinding(name, errorID string, line uint32) *Finding {
+	return &Finding{
+		Name:        name,
+		Type:        ErrorTypeCrash,
+		MoreDetails: &ErrorDetails{ID: errorID},
+		StackTrace: []*stacktrace.StackFrame{
+			{SourceFile: "src/explore_me.cpp", Line: line, Function: "exploreMe"},
+			{SourceFile: "my_fuzz_test.cpp", Line: 18, Function: "LLVMFuzzerTestOneInputNoReturn"},
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package finding
+
+import (
+	"bytes"
+	_ "embed"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+// The schema version of error details files which is supported by this
+// version of cifuzz
+const errorDetailsSchemaVersion = 1
+
+//go:embed error_details.yaml
+var builtinErrorDetailsYAML []byte
+
+// ErrorDetailsFile is the format of the error details database shipped
+// with cifuzz and of the error details files which projects can use to
+// override or extend it.
+type ErrorDetailsFile struct {
+	SchemaVersion int            `yaml:"schema_version"`
+	Version       int            `yaml:"version"`
+	ErrorDetails  []ErrorDetails `yaml:"error_details"`
+}
+
+// BuiltinErrorDetails returns the error details shipped with cifuzz,
+// which cover all error IDs assigned by cifuzz.
+func BuiltinErrorDetails() ([]ErrorDetails, error) {
+	file, err := parseErrorDetails(builtinErrorDetailsYAML)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to parse built-in error details")
+	}
+	log.Debugf("Using built-in error details version %d", file.Version)
+	return file.ErrorDetails, nil
+}
+
+// LoadErrorDetailsFile reads the error details from the specified YAML
+// file.
+func LoadErrorDetailsFile(path string) ([]ErrorDetails, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	file, err := parseErrorDetails(data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to parse error details file %s", path)
+	}
+	return file.ErrorDetails, nil
+}
+
+func parseErrorDetails(data []byte) (*ErrorDetailsFile, error) {
+	file := &ErrorDetailsFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if file.SchemaVersion > errorDetailsSchemaVersion {
+		return nil, errors.Errorf("Unsupported schema version %d (supported: %d)",
+			file.SchemaVersion, errorDetailsSchemaVersion)
+	}
+	for _, d := range file.ErrorDetails {
+		if d.ID == "" {
+			return nil, errors.Errorf("Error details %q have no ID", d.Name)
+		}
+	}
+	return file, nil
+}
+
+// MergeErrorDetails returns the error details of base, overridden and
+// extended by the error details of each of the overrides in order.
+// Error details with the same ID are merged field by field, so that an
+// override only has to specify the fields it changes. Error details
+// with a new ID are appended.
+func MergeErrorDetails(base []ErrorDetails, overrides ...[]ErrorDetails) []ErrorDetails {
+	res := make([]ErrorDetails, len(base))
+	copy(res, base)
+	index := make(map[string]int)
+	for i, d := range res {
+		index[d.ID] = i
+	}
+
+	for _, override := range overrides {
+		for _, d := range override {
+			i, ok := index[d.ID]
+			if !ok || d.ID == "" {
+				index[d.ID] = len(res)
+				res = append(res, d)
+				continue
+			}
+			merged := &res[i]
+			if d.Name != "" {
+				merged.Name = d.Name
+			}
+			if d.Description != "" {
+				merged.Description = d.Description
+			}
+			if d.Severity != nil {
+				merged.Severity = d.Severity
+			}
+			if d.Mitigation != "" {
+				merged.Mitigation = d.Mitigation
+			}
+			if d.Links != nil {
+				merged.Links = d.Links
+			}
+			if d.OwaspDetails != nil {
+				merged.OwaspDetails = d.OwaspDetails
+			}
+			if d.CweDetails != nil {
+				merged.CweDetails = d.CweDetails
+			}
+		}
+	}
+	return res
+}
+
+// ResolveErrorDetails returns the built-in error details, overridden and
+// extended by the remote error details (if any) and by the error
+// details of the specified project file (if not empty).
+func ResolveErrorDetails(remote []ErrorDetails, projectFile string) ([]ErrorDetails, error) {
+	builtin, err := BuiltinErrorDetails()
+	if err != nil {
+		return nil, err
+	}
+	overrides := [][]ErrorDetails{remote}
+	if projectFile != "" {
+		project, err := LoadErrorDetailsFile(projectFile)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, project)
+	}
+	return MergeErrorDetails(builtin, overrides...), nil
+}
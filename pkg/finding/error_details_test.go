@@ -0,0 +1,92 @@
+package finding
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinErrorDetails(t *testing.T) {
+	errorDetails, err := BuiltinErrorDetails()
+	require.NoError(t, err)
+	require.NotEmpty(t, errorDetails)
+
+	ids := make(map[string]bool)
+	for _, d := range errorDetails {
+		assert.Falsef(t, ids[d.ID], "Duplicate error ID %q", d.ID)
+		ids[d.ID] = true
+		assert.NotEmptyf(t, d.Name, "Error details %q have no name", d.ID)
+		assert.NotEmptyf(t, d.Description, "Error details %q have no description", d.ID)
+		assert.NotEmptyf(t, d.Mitigation, "Error details %q have no mitigation", d.ID)
+		if assert.NotNilf(t, d.Severity, "Error details %q have no severity", d.ID) {
+			assert.Contains(t, []SeverityLevel{SeverityLevelLow, SeverityLevelMedium, SeverityLevelHigh, SeverityLevelCritical}, d.Severity.Level)
+		}
+	}
+}
+
+func TestResolveErrorDetails(t *testing.T) {
+	projectFile := filepath.Join(testBaseDir, "error-details.yaml")
+	err := os.WriteFile(projectFile, []byte(`
+schema_version: 1
+error_details:
+  - id: memory_leak
+    severity:
+      level: HIGH
+      score: 7.5
+  - id: my_custom_error
+    name: My Custom Error
+`), 0o644)
+	require.NoError(t, err)
+
+	remote := []ErrorDetails{
+		{ID: "memory_leak", Mitigation: "Remote mitigation"},
+		{ID: "remote_error", Name: "Remote Error"},
+	}
+	errorDetails, err := ResolveErrorDetails(remote, projectFile)
+	require.NoError(t, err)
+
+	byID := make(map[string]ErrorDetails)
+	for _, d := range errorDetails {
+		byID[d.ID] = d
+	}
+	// Fields which are not overridden are kept
+	assert.Equal(t, "Memory Leak", byID["memory_leak"].Name)
+	// The remote error details override the built-in ones...
+	assert.Equal(t, "Remote mitigation", byID["memory_leak"].Mitigation)
+	// ...and the project file overrides both
+	assert.Equal(t, &Severity{Level: SeverityLevelHigh, Score: 7.5}, byID["memory_leak"].Severity)
+	// New error IDs are added
+	assert.Equal(t, "Remote Error", byID["remote_error"].Name)
+	assert.Equal(t, "My Custom Error", byID["my_custom_error"].Name)
+}
+
+func TestLoadErrorDetailsFile_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown-field.yaml":  "error_details:\n  - id: foo\n    nmae: Foo\n",
+		"missing-id.yaml":     "error_details:\n  - name: Foo\n",
+		"newer-version.yaml":  "schema_version: 100\n",
+		"invalid-syntax.yaml": "error_details: [",
+	} {
+		path := filepath.Join(testBaseDir, name)
+		err := os.WriteFile(path, []byte(content), 0o644)
+		require.NoError(t, err)
+		_, err = LoadErrorDetailsFile(path)
+		assert.Errorf(t, err, "Expected an error for %s", name)
+	}
+}
+
+func TestEnhanceWithErrorDetails_PrefersID(t *testing.T) {
+	errorDetails := []ErrorDetails{
+		{ID: "out_of_bounds", Name: "Out of Bounds"},
+		{ID: "java_out_of_bounds", Name: "Java Out of Bounds"},
+	}
+	f := &Finding{
+		Details:     "Java Out of Bounds",
+		MoreDetails: &ErrorDetails{ID: "java_out_of_bounds"},
+	}
+	f.EnhanceWithErrorDetails(&errorDetails)
+	assert.Equal(t, "Java Out of Bounds", f.MoreDetails.Name)
+}
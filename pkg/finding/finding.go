@@ -42,6 +42,19 @@ type Finding struct {
 	CreatedAt  time.Time                `json:"created_at,omitempty"`
 	InputFile  string                   `json:"input_file,omitempty"`
 	StackTrace []*stacktrace.StackFrame `json:"stack_trace,omitempty"`
+	// The first commit in which the finding is reproducible, as
+	// determined via `cifuzz finding bisect`
+	FirstBadCommit string `json:"first_bad_commit,omitempty"`
+	// How reliably the crashing input reproduces the finding, as
+	// determined via `cifuzz run --check-flaky` or
+	// `cifuzz finding check-flaky`
+	Reproducibility *Reproducibility `json:"reproducibility,omitempty"`
+	// Structured information from the sanitizer report, like the
+	// faulting memory access and the allocation and free stacks
+	SanitizerDetails *SanitizerDetails `json:"sanitizer_details,omitempty"`
+	// The logs as they were before the stack frames were symbolized via
+	// `cifuzz finding symbolize`
+	OriginalLogs []string `json:"original_logs,omitempty"`
 
 	seedPath string
 
@@ -64,14 +77,14 @@ const (
 )
 
 type ErrorDetails struct {
-	ID           string          `json:"id,omitempty"`
-	Name         string          `json:"name,omitempty"`
-	Description  string          `json:"description,omitempty"`
-	Severity     *Severity       `json:"severity,omitempty"`
-	Mitigation   string          `json:"mitigation,omitempty"`
-	Links        []Link          `json:"links,omitempty"`
-	OwaspDetails *ExternalDetail `json:"owasp_details,omitempty"`
-	CweDetails   *ExternalDetail `json:"cwe_details,omitempty"`
+	ID           string          `json:"id,omitempty" yaml:"id,omitempty"`
+	Name         string          `json:"name,omitempty" yaml:"name,omitempty"`
+	Description  string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Severity     *Severity       `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Mitigation   string          `json:"mitigation,omitempty" yaml:"mitigation,omitempty"`
+	Links        []Link          `json:"links,omitempty" yaml:"links,omitempty"`
+	OwaspDetails *ExternalDetail `json:"owasp_details,omitempty" yaml:"owasp_details,omitempty"`
+	CweDetails   *ExternalDetail `json:"cwe_details,omitempty" yaml:"cwe_details,omitempty"`
 }
 
 type SeverityLevel string
@@ -84,19 +97,19 @@ const (
 )
 
 type Severity struct {
-	Level SeverityLevel `json:"description,omitempty"`
-	Score float32       `json:"score,omitempty"`
+	Level SeverityLevel `json:"description,omitempty" yaml:"level,omitempty"`
+	Score float32       `json:"score,omitempty" yaml:"score,omitempty"`
 }
 
 type ExternalDetail struct {
-	ID          int64  `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
+	ID          int64  `json:"id,omitempty" yaml:"id,omitempty"`
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 }
 
 type Link struct {
-	Description string `json:"description,omitempty"`
-	URL         string `json:"url,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	URL         string `json:"url,omitempty" yaml:"url,omitempty"`
 }
 
 func (f *Finding) GetDetails() string {
@@ -298,7 +311,10 @@ func (f *Finding) ShortDescriptionColumns() []string {
 // ListFindings parses the JSON files of all findings and returns the
 // result.
 func ListFindings(projectDir string, errorDetails *[]ErrorDetails) ([]*Finding, error) {
-	findingsDir := filepath.Join(projectDir, nameFindingsDir)
+	return listFindingsInDir(filepath.Join(projectDir, nameFindingsDir), errorDetails)
+}
+
+func listFindingsInDir(findingsDir string, errorDetails *[]ErrorDetails) ([]*Finding, error) {
 	entries, err := os.ReadDir(findingsDir)
 	if os.IsNotExist(err) {
 		return []*Finding{}, nil
@@ -309,7 +325,7 @@ func ListFindings(projectDir string, errorDetails *[]ErrorDetails) ([]*Finding,
 
 	var res []*Finding
 	for _, e := range entries {
-		f, err := LoadFinding(projectDir, e.Name(), errorDetails)
+		f, err := loadFindingFromDir(findingsDir, e.Name(), errorDetails)
 		if err != nil {
 			return nil, err
 		}
@@ -329,7 +345,11 @@ func ListFindings(projectDir string, errorDetails *[]ErrorDetails) ([]*Finding,
 // If the specified finding does not exist, a NotExistError is returned.
 // If the user is logged in, the error details are added to the finding.
 func LoadFinding(projectDir, findingName string, errorDetails *[]ErrorDetails) (*Finding, error) {
-	findingDir := filepath.Join(projectDir, nameFindingsDir, findingName)
+	return loadFindingFromDir(filepath.Join(projectDir, nameFindingsDir), findingName, errorDetails)
+}
+
+func loadFindingFromDir(findingsDir, findingName string, errorDetails *[]ErrorDetails) (*Finding, error) {
+	findingDir := filepath.Join(findingsDir, findingName)
 	jsonPath := filepath.Join(findingDir, nameJSONFile)
 	bytes, err := os.ReadFile(jsonPath)
 	if os.IsNotExist(err) {
@@ -355,11 +375,28 @@ func (f *Finding) EnhanceWithErrorDetails(errorDetails *[]ErrorDetails) {
 	if errorDetails == nil {
 		return
 	}
+	// Prefer a match of the error ID over a match of the name, because
+	// the name of one error can be contained in the description of
+	// another error (e.g. "Out of Bounds" in "Java Out of Bounds")
+	if f.MoreDetails != nil && f.MoreDetails.ID != "" {
+		for _, d := range *errorDetails {
+			d := d
+			if d.ID == f.MoreDetails.ID {
+				f.MoreDetails = &d
+				return
+			}
+		}
+		// The error ID might have been assigned by an error ID rule of
+		// the project, which must not be replaced by the ID of an error
+		// with a matching name
+		log.Debugf("No error details found for error ID %s of finding %s", f.MoreDetails.ID, f.Name)
+		return
+	}
 	for _, d := range *errorDetails {
-		if (f.MoreDetails != nil && f.MoreDetails.ID == d.ID) ||
-			strings.Contains(
-				strings.ToLower(f.ShortDescriptionColumns()[0]),
-				strings.ToLower(d.Name)) {
+		d := d
+		if d.Name != "" && strings.Contains(
+			strings.ToLower(f.ShortDescriptionColumns()[0]),
+			strings.ToLower(d.Name)) {
 
 			f.MoreDetails = &d
 			return
@@ -0,0 +1,166 @@
+package finding
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The keys by which findings can be sorted
+const (
+	SortByCreated  = "created"
+	SortByName     = "name"
+	SortBySeverity = "severity"
+	SortByFuzzTest = "fuzz-test"
+	SortByErrorID  = "error-id"
+)
+
+var SortKeys = []string{SortByCreated, SortByName, SortBySeverity, SortByFuzzTest, SortByErrorID}
+
+var severityLevelRanks = map[SeverityLevel]int{
+	SeverityLevelLow:      1,
+	SeverityLevelMedium:   2,
+	SeverityLevelHigh:     3,
+	SeverityLevelCritical: 4,
+}
+
+// ParseSeverityLevel returns the severity level with the specified
+// name, which is case-insensitive.
+func ParseSeverityLevel(s string) (SeverityLevel, error) {
+	level := SeverityLevel(strings.ToUpper(s))
+	if _, ok := severityLevelRanks[level]; !ok {
+		return "", errors.Errorf("Invalid severity level %q (valid levels: low, medium, high, critical)", s)
+	}
+	return level, nil
+}
+
+// Query describes which findings should be selected. Empty fields
+// match all findings.
+type Query struct {
+	// FuzzTest selects findings found by this fuzz test
+	FuzzTest string
+	// ErrorID selects findings with this error ID
+	ErrorID string
+	// MinSeverityLevel selects findings with at least this severity
+	// level
+	MinSeverityLevel SeverityLevel
+	// MinSeverityScore selects findings with at least this severity
+	// score
+	MinSeverityScore float32
+	// CreatedAfter selects findings created after this time
+	CreatedAfter time.Time
+	// SourceFile selects findings with a stack frame in a source file
+	// with this path, a path ending with this path or a path matching
+	// this glob pattern
+	SourceFile string
+}
+
+// Matches returns whether the finding is selected by the query.
+func (q *Query) Matches(f *Finding) bool {
+	if q.FuzzTest != "" && f.FuzzTest != q.FuzzTest {
+		return false
+	}
+	if q.ErrorID != "" && f.errorID() != q.ErrorID {
+		return false
+	}
+	if q.MinSeverityLevel != "" {
+		if f.severityLevelRank() < severityLevelRanks[q.MinSeverityLevel] {
+			return false
+		}
+	}
+	if q.MinSeverityScore > 0 && f.severityScore() < q.MinSeverityScore {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && !f.CreatedAt.After(q.CreatedAfter) {
+		return false
+	}
+	if q.SourceFile != "" && !f.hasSourceFile(q.SourceFile) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the findings which are selected by the query.
+func (q *Query) Filter(findings []*Finding) []*Finding {
+	res := []*Finding{}
+	for _, f := range findings {
+		if q.Matches(f) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// SortFindings sorts the findings by the specified key. Findings with
+// the same value are kept in their current order. Findings are sorted
+// in ascending order, except for the creation time and the severity,
+// which are sorted starting with the newest and the most severe.
+func SortFindings(findings []*Finding, key string, reverse bool) error {
+	var less func(a, b *Finding) bool
+	switch key {
+	case SortByCreated, "":
+		less = func(a, b *Finding) bool { return a.CreatedAt.After(b.CreatedAt) }
+	case SortByName:
+		less = func(a, b *Finding) bool { return a.Name < b.Name }
+	case SortBySeverity:
+		less = func(a, b *Finding) bool {
+			if a.severityScore() != b.severityScore() {
+				return a.severityScore() > b.severityScore()
+			}
+			return a.severityLevelRank() > b.severityLevelRank()
+		}
+	case SortByFuzzTest:
+		less = func(a, b *Finding) bool { return a.FuzzTest < b.FuzzTest }
+	case SortByErrorID:
+		less = func(a, b *Finding) bool { return a.errorID() < b.errorID() }
+	default:
+		return errors.Errorf("Invalid sort key %q (valid keys: %s)", key, strings.Join(SortKeys, ", "))
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if reverse {
+			return less(findings[j], findings[i])
+		}
+		return less(findings[i], findings[j])
+	})
+	return nil
+}
+
+func (f *Finding) errorID() string {
+	if f.MoreDetails == nil {
+		return ""
+	}
+	return f.MoreDetails.ID
+}
+
+func (f *Finding) severityScore() float32 {
+	if f.MoreDetails == nil || f.MoreDetails.Severity == nil {
+		return 0
+	}
+	return f.MoreDetails.Severity.Score
+}
+
+func (f *Finding) severityLevelRank() int {
+	if f.MoreDetails == nil || f.MoreDetails.Severity == nil {
+		return 0
+	}
+	return severityLevelRanks[SeverityLevel(strings.ToUpper(string(f.MoreDetails.Severity.Level)))]
+}
+
+func (f *Finding) hasSourceFile(pattern string) bool {
+	pattern = filepath.ToSlash(pattern)
+	for _, frame := range f.StackTrace {
+		sourceFile := filepath.ToSlash(frame.SourceFile)
+		if sourceFile == pattern || strings.HasSuffix(sourceFile, "/"+pattern) {
+			return true
+		}
+		if matched, _ := path.Match(pattern, sourceFile); matched {
+			return true
+		}
+	}
+	return false
+}
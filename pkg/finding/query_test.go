@@ -0,0 +1,91 @@
+package finding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+)
+
+func TestQuery_Filter(t *testing.T) {
+	now := time.Now()
+	heapOverflow := &Finding{
+		Name:      "heap-overflow",
+		FuzzTest:  "parser_fuzz_test",
+		CreatedAt: now.Add(-time.Hour),
+		MoreDetails: &ErrorDetails{
+			ID:       "heap_buffer_overflow",
+			Severity: &Severity{Level: SeverityLevelCritical, Score: 9.8},
+		},
+		StackTrace: []*stacktrace.StackFrame{{SourceFile: "src/parser/parse.cpp", Function: "parse"}},
+	}
+	leak := &Finding{
+		Name:      "leak",
+		FuzzTest:  "api_fuzz_test",
+		CreatedAt: now.Add(-10 * 24 * time.Hour),
+		MoreDetails: &ErrorDetails{
+			ID:       "memory_leak",
+			Severity: &Severity{Level: "Low", Score: 3.7},
+		},
+		StackTrace: []*stacktrace.StackFrame{{SourceFile: "src/api/api.cpp", Function: "handle"}},
+	}
+	noDetails := &Finding{Name: "no-details"}
+	findings := []*Finding{heapOverflow, leak, noDetails}
+
+	testCases := []struct {
+		name     string
+		query    Query
+		expected []*Finding
+	}{
+		{"empty", Query{}, findings},
+		{"fuzz test", Query{FuzzTest: "api_fuzz_test"}, []*Finding{leak}},
+		{"error id", Query{ErrorID: "heap_buffer_overflow"}, []*Finding{heapOverflow}},
+		{"severity level", Query{MinSeverityLevel: SeverityLevelHigh}, []*Finding{heapOverflow}},
+		{"severity level (case-insensitive)", Query{MinSeverityLevel: SeverityLevelLow}, []*Finding{heapOverflow, leak}},
+		{"severity score", Query{MinSeverityScore: 3.7}, []*Finding{heapOverflow, leak}},
+		{"created after", Query{CreatedAfter: now.Add(-24 * time.Hour)}, []*Finding{heapOverflow}},
+		{"source file path", Query{SourceFile: "src/api/api.cpp"}, []*Finding{leak}},
+		{"source file name", Query{SourceFile: "parse.cpp"}, []*Finding{heapOverflow}},
+		{"source file glob", Query{SourceFile: "src/*/*.cpp"}, []*Finding{heapOverflow, leak}},
+		{"combined", Query{FuzzTest: "api_fuzz_test", MinSeverityLevel: SeverityLevelHigh}, []*Finding{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.query.Filter(findings))
+		})
+	}
+}
+
+func TestSortFindings(t *testing.T) {
+	now := time.Now()
+	a := &Finding{Name: "a", FuzzTest: "z_fuzz_test", CreatedAt: now.Add(-time.Hour),
+		MoreDetails: &ErrorDetails{ID: "timeout", Severity: &Severity{Score: 5.3}}}
+	b := &Finding{Name: "b", FuzzTest: "y_fuzz_test", CreatedAt: now,
+		MoreDetails: &ErrorDetails{ID: "double_free", Severity: &Severity{Score: 8.1}}}
+	c := &Finding{Name: "c", FuzzTest: "x_fuzz_test", CreatedAt: now.Add(-2 * time.Hour)}
+
+	testCases := []struct {
+		key      string
+		reverse  bool
+		expected []*Finding
+	}{
+		{SortByCreated, false, []*Finding{b, a, c}},
+		{SortByCreated, true, []*Finding{c, a, b}},
+		{SortByName, false, []*Finding{a, b, c}},
+		{SortBySeverity, false, []*Finding{b, a, c}},
+		{SortByFuzzTest, false, []*Finding{c, b, a}},
+		{SortByErrorID, false, []*Finding{c, b, a}},
+	}
+	for _, tc := range testCases {
+		findings := []*Finding{a, b, c}
+		err := SortFindings(findings, tc.key, tc.reverse)
+		require.NoError(t, err)
+		assert.Equalf(t, tc.expected, findings, "sort by %s (reverse: %t)", tc.key, tc.reverse)
+	}
+
+	err := SortFindings([]*Finding{a, b}, "invalid", false)
+	require.Error(t, err)
+}
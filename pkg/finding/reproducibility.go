@@ -0,0 +1,47 @@
+package finding
+
+// Reproducibility describes how reliably the crashing input of a
+// finding reproduces it when it's executed in a fresh process.
+type Reproducibility struct {
+	// Runs is the number of times the crashing input was executed
+	Runs uint `json:"runs"`
+	// Reproduced is the number of runs which reproduced the finding
+	Reproduced uint `json:"reproduced"`
+	// Ratio is the ratio of runs which reproduced the finding
+	Ratio float64 `json:"ratio"`
+	// Flaky is true if not all runs reproduced the finding
+	Flaky bool `json:"flaky"`
+}
+
+func NewReproducibility(runs, reproduced uint) *Reproducibility {
+	r := &Reproducibility{Runs: runs, Reproduced: reproduced}
+	if runs > 0 {
+		r.Ratio = float64(reproduced) / float64(runs)
+	}
+	r.Flaky = reproduced < runs
+	return r
+}
+
+// IsFlaky returns whether the finding is known to be flaky, i.e. its
+// crashing input doesn't reproduce it reliably.
+func (f *Finding) IsFlaky() bool {
+	return f.Reproducibility != nil && f.Reproducibility.Flaky
+}
+
+// IsReproducedBy returns whether one of the findings has the same error
+// as f, i.e. the same error ID or, if f has no error ID, the same error
+// type.
+func (f *Finding) IsReproducedBy(findings []*Finding) bool {
+	for _, other := range findings {
+		if f.errorID() != "" {
+			if other.errorID() == f.errorID() {
+				return true
+			}
+			continue
+		}
+		if other.ShortDescriptionColumns()[0] == f.ShortDescriptionColumns()[0] {
+			return true
+		}
+	}
+	return false
+}
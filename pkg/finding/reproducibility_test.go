@@ -0,0 +1,36 @@
+package finding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReproducibility(t *testing.T) {
+	r := NewReproducibility(10, 10)
+	assert.Equal(t, 1.0, r.Ratio)
+	assert.False(t, r.Flaky)
+
+	r = NewReproducibility(10, 3)
+	assert.Equal(t, 0.3, r.Ratio)
+	assert.True(t, r.Flaky)
+
+	r = NewReproducibility(10, 0)
+	assert.Equal(t, 0.0, r.Ratio)
+	assert.True(t, r.Flaky)
+}
+
+func TestFinding_IsReproducedBy(t *testing.T) {
+	f := &Finding{MoreDetails: &ErrorDetails{ID: "heap_buffer_overflow"}}
+	assert.True(t, f.IsReproducedBy([]*Finding{
+		{MoreDetails: &ErrorDetails{ID: "memory_leak"}},
+		{MoreDetails: &ErrorDetails{ID: "heap_buffer_overflow"}},
+	}))
+	assert.False(t, f.IsReproducedBy([]*Finding{{MoreDetails: &ErrorDetails{ID: "memory_leak"}}}))
+	assert.False(t, f.IsReproducedBy(nil))
+
+	// Without an error ID, the error type is compared
+	f = &Finding{Type: ErrorTypeCrash, Details: "heap-buffer-overflow on address 0x602000000e31"}
+	assert.True(t, f.IsReproducedBy([]*Finding{{Type: ErrorTypeCrash, Details: "heap-buffer-overflow on address 0x1234"}}))
+	assert.False(t, f.IsReproducedBy([]*Finding{{Type: ErrorTypeCrash, Details: "detected memory leaks"}}))
+}
@@ -0,0 +1,42 @@
+package finding
+
+import (
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+)
+
+// SanitizerDetails holds the structured information which is extracted
+// from the report of a sanitizer.
+type SanitizerDetails struct {
+	// The sanitizer which reported the finding, e.g. "AddressSanitizer"
+	Sanitizer string `json:"sanitizer,omitempty"`
+	// The memory access which caused the finding
+	Access *MemoryAccess `json:"access,omitempty"`
+	// The stack trace of the allocation of the accessed memory, e.g.
+	// for heap buffer overflows and use-after-free
+	AllocationStack []*stacktrace.StackFrame `json:"allocation_stack,omitempty"`
+	// The stack trace of the deallocation of the accessed memory, e.g.
+	// for use-after-free and double-free
+	FreeStack []*stacktrace.StackFrame `json:"free_stack,omitempty"`
+	// The message of the UBSan diagnostic, e.g. "signed integer
+	// overflow: 2147483647 + 1 cannot be represented in type 'int'"
+	UBSanMessage string `json:"ubsan_message,omitempty"`
+	// The SUMMARY line of the report, without the "SUMMARY: " prefix
+	Summary string `json:"summary,omitempty"`
+}
+
+// Access types of a MemoryAccess
+const (
+	AccessTypeRead  = "READ"
+	AccessTypeWrite = "WRITE"
+)
+
+// MemoryAccess describes an invalid memory access reported by a
+// sanitizer.
+type MemoryAccess struct {
+	// The accessed address in hexadecimal notation
+	Address string `json:"address,omitempty"`
+	// The size of the access in bytes, zero if unknown
+	Size uint64 `json:"size,omitempty"`
+	// Either AccessTypeRead or AccessTypeWrite, empty if unknown
+	Type string `json:"type,omitempty"`
+}
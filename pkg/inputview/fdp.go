@@ -0,0 +1,707 @@
+package inputview
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+// The FuzzedDataProvider implementations which are supported by the
+// decoder
+const (
+	// The FuzzedDataProvider of LLVM, used by C/C++ fuzz tests
+	FlavorLLVM = "llvm"
+	// The FuzzedDataProvider of Jazzer, used by Java fuzz tests
+	FlavorJazzer = "jazzer"
+)
+
+// A Call is a call of a consume method of a FuzzedDataProvider, as
+// specified by the user, for example `ConsumeIntegralInRange<int>(0, 10)`.
+type Call struct {
+	Name string
+	// The template argument of LLVM consume methods
+	Type string
+	Args []string
+	// If true, the call is repeated until the data is consumed
+	Repeat bool
+
+	text string
+}
+
+func (c *Call) String() string {
+	return c.text
+}
+
+var callRegex = regexp.MustCompile(`^(\w+)\s*(?:<\s*([\w\s:]+?)\s*>)?\s*(?:\((.*)\))?\s*(\*)?$`)
+
+// ParseCalls parses a list of consume calls separated by semicolons or
+// newlines. A call followed by a "*" is repeated until all data is
+// consumed. Empty lines and lines starting with "#" or "//" are
+// ignored. Example:
+//
+//	ConsumeIntegral<uint8_t>(); ConsumeRandomLengthString(16)*
+func ParseCalls(s string) ([]*Call, error) {
+	var calls []*Call
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		for _, text := range strings.Split(line, ";") {
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			match := callRegex.FindStringSubmatch(text)
+			if match == nil {
+				return nil, errors.Errorf("Invalid FuzzedDataProvider call %q", text)
+			}
+			call := &Call{
+				Name:   match[1],
+				Type:   strings.TrimPrefix(strings.Join(strings.Fields(match[2]), " "), "std::"),
+				Repeat: match[4] != "",
+				text:   strings.TrimSuffix(text, "*"),
+			}
+			if args := strings.TrimSpace(match[3]); args != "" {
+				for _, arg := range strings.Split(args, ",") {
+					call.Args = append(call.Args, strings.TrimSpace(arg))
+				}
+			}
+			calls = append(calls, call)
+		}
+	}
+	if len(calls) == 0 {
+		return nil, errors.New("No FuzzedDataProvider calls specified")
+	}
+	return calls, nil
+}
+
+// A Value is the result of a consume call together with the range of
+// bytes it was produced from.
+type Value struct {
+	Call   string
+	Offset int
+	Length int
+	Value  string
+}
+
+// DecodeFDP replays the consume calls on the data like the
+// FuzzedDataProvider of the specified flavor and returns the values
+// they produce.
+func DecodeFDP(data []byte, calls []*Call, flavor string) ([]*Value, error) {
+	var consume func(p *provider, c *Call) (*Value, error)
+	switch flavor {
+	case FlavorLLVM, "":
+		consume = consumeLLVM
+	case FlavorJazzer:
+		consume = consumeJazzer
+	default:
+		return nil, errors.Errorf("Unsupported FuzzedDataProvider flavor %q", flavor)
+	}
+
+	p := &provider{data: data, end: len(data)}
+	var values []*Value
+	for _, c := range calls {
+		for {
+			remainingBefore := p.remaining()
+			v, err := consume(p, c)
+			if err != nil {
+				return nil, err
+			}
+			v.Call = c.String()
+			values = append(values, v)
+			if !c.Repeat || p.remaining() == 0 || p.remaining() == remainingBefore {
+				break
+			}
+		}
+	}
+	return values, nil
+}
+
+// RenderFDP returns a table of the values produced by the consume calls
+// followed by a hexdump annotated with the values.
+func RenderFDP(data []byte, calls []*Call, flavor string) (string, error) {
+	values, err := DecodeFDP(data, calls, flavor)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Bytes\tCall\tValue")
+	var annotations []Annotation
+	consumed := 0
+	for _, v := range values {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", formatRange(v.Offset, v.Length), v.Call, v.Value)
+		annotations = append(annotations, Annotation{
+			Offset: v.Offset,
+			Length: v.Length,
+			Label:  v.Call + " = " + v.Value,
+		})
+		consumed += v.Length
+	}
+	err = w.Flush()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if consumed < len(data) {
+		fmt.Fprintf(&b, "%d of %d bytes were not consumed\n", len(data)-consumed, len(data))
+	}
+	b.WriteString("\n")
+	b.WriteString(Hexdump(data, annotations))
+	return b.String(), nil
+}
+
+// provider keeps track of the consumed data like a FuzzedDataProvider:
+// Bytes and strings are consumed from the front of the data, integral
+// and floating point values from the back.
+type provider struct {
+	data  []byte
+	start int
+	end   int
+}
+
+func (p *provider) remaining() int {
+	return p.end - p.start
+}
+
+// consumeFront consumes up to n bytes from the front of the data.
+func (p *provider) consumeFront(n int) (int, []byte) {
+	if n > p.remaining() {
+		n = p.remaining()
+	}
+	offset := p.start
+	p.start += n
+	return offset, p.data[offset:p.start]
+}
+
+// consumeIntegralInRange implements FuzzedDataProvider's
+// ConsumeIntegralInRange for a type of the specified size in bytes. The
+// bounds and the result are two's complement bit patterns.
+func (p *provider) consumeIntegralInRange(min, max uint64, size int) (uint64, *Value) {
+	end := p.end
+	rangeSize := max - min
+	var result uint64
+	for offset := 0; offset < size*8 && rangeSize>>offset > 0 && p.remaining() != 0; offset += 8 {
+		p.end--
+		result = (result << 8) | uint64(p.data[p.end])
+	}
+	if rangeSize != math.MaxUint64 {
+		result = result % (rangeSize + 1)
+	}
+	return min + result, &Value{Offset: p.end, Length: end - p.end}
+}
+
+func (p *provider) consumeBool() (bool, *Value) {
+	v, value := p.consumeIntegralInRange(0, math.MaxUint8, 1)
+	return v&1 == 1, value
+}
+
+func (p *provider) consumeProbability(bits int) (float64, *Value) {
+	if bits == 32 {
+		v, value := p.consumeIntegralInRange(0, math.MaxUint32, 4)
+		return float64(float32(v) / float32(math.MaxUint32)), value
+	}
+	v, value := p.consumeIntegralInRange(0, math.MaxUint64, 8)
+	return float64(v) / float64(math.MaxUint64), value
+}
+
+func (p *provider) consumeFloatingPointInRange(min, max float64, bits int) (float64, *Value, error) {
+	if min > max {
+		return 0, nil, errors.Errorf("Invalid range [%g, %g]", min, max)
+	}
+	end := p.end
+	typeMax := math.MaxFloat64
+	if bits == 32 {
+		typeMax = math.MaxFloat32
+	}
+	var rangeSize float64
+	result := min
+	if max > 0 && min < 0 && max > min+typeMax {
+		// The range doesn't fit into the type, so it's split in two
+		// halves
+		rangeSize = max/2.0 - min/2.0
+		if b, _ := p.consumeBool(); b {
+			result += rangeSize
+		}
+	} else {
+		rangeSize = max - min
+	}
+	probability, _ := p.consumeProbability(bits)
+	if bits == 32 {
+		result = float64(float32(result) + float32(rangeSize)*float32(probability))
+	} else {
+		result += rangeSize * probability
+	}
+	return result, &Value{Offset: p.end, Length: end - p.end}, nil
+}
+
+// consumeRandomLengthString implements FuzzedDataProvider's
+// ConsumeRandomLengthString: A backslash followed by another character
+// than a backslash terminates the string, two backslashes are an
+// escaped backslash.
+func (p *provider) consumeRandomLengthString(maxLength int) ([]byte, *Value) {
+	offset := p.start
+	var result []byte
+	for i := 0; i < maxLength && p.remaining() != 0; i++ {
+		next := p.data[p.start]
+		p.start++
+		if next == '\\' && p.remaining() != 0 {
+			next = p.data[p.start]
+			p.start++
+			if next != '\\' {
+				break
+			}
+		}
+		result = append(result, next)
+	}
+	return result, &Value{Offset: offset, Length: p.start - offset}
+}
+
+// consumeJazzerString implements the string methods of Jazzer's
+// FuzzedDataProvider, which consume bytes from the front of the data
+// and interpret them as modified UTF-8, the encoding used by the JNI,
+// with up to maxLength UTF-16 code units. If asciiOnly is true, the
+// highest bit of every byte is cleared. If stopOnBackslash is true, a
+// backslash followed by another character than a backslash terminates
+// the string, two backslashes are an escaped backslash.
+//
+// Bytes which don't form valid modified UTF-8 are fixed up, so that
+// every consumed byte results in at most one byte of the string:
+// Invalid leading bytes are turned into ASCII characters by clearing
+// their highest bit, invalid continuation bytes are turned into valid
+// ones by replacing their highest bits, and a character which is
+// incomplete at the end of the data is dropped.
+func (p *provider) consumeJazzerString(maxLength int, asciiOnly bool, stopOnBackslash bool) (string, *Value) {
+	offset := p.start
+	var units []uint16
+	// The code point of the current multi-byte character and the number
+	// of its continuation bytes which are still expected
+	var codePoint rune
+	var pending int
+	// The minimum value of the first continuation byte of the current
+	// character, to avoid overlong encodings
+	var minContinuation byte
+	afterBackslash := false
+
+	for len(units) < maxLength && p.remaining() != 0 {
+		c := p.data[p.start]
+		p.start++
+		if asciiOnly {
+			c &= 0x7f
+		}
+
+		if pending > 0 {
+			c = 0x80 | (c & 0x3f)
+			if c < minContinuation {
+				c |= minContinuation
+			}
+			minContinuation = 0x80
+			codePoint = codePoint<<6 | rune(c&0x3f)
+			pending--
+			if pending == 0 {
+				units = append(units, uint16(codePoint))
+			}
+			continue
+		}
+
+		if afterBackslash {
+			afterBackslash = false
+			if c != '\\' {
+				break
+			}
+			units = append(units, '\\')
+			continue
+		}
+
+		switch {
+		case c == 0xc0 && p.remaining() != 0 && p.data[p.start] == 0x80:
+			// The zero character is coded on two bytes in modified UTF-8
+			codePoint, pending, minContinuation = 0, 1, 0x80
+		case c >= 0xc2 && c <= 0xdf:
+			codePoint, pending, minContinuation = rune(c&0x1f), 1, 0x80
+		case c == 0xe0:
+			codePoint, pending, minContinuation = 0, 2, 0xa0
+		case c >= 0xe1 && c <= 0xef:
+			codePoint, pending, minContinuation = rune(c&0x0f), 2, 0x80
+		default:
+			// Either a one byte character or an invalid leading byte,
+			// which is turned into a one byte character
+			c &= 0x7f
+			if stopOnBackslash && c == '\\' {
+				// The backslash either terminates the string or is
+				// escaped, so it's not appended here
+				afterBackslash = true
+				continue
+			}
+			units = append(units, uint16(c))
+		}
+	}
+
+	return string(utf16.Decode(units)), &Value{Offset: offset, Length: p.start - offset}
+}
+
+type integralType struct {
+	size   int
+	signed bool
+}
+
+func (t integralType) limits() (uint64, uint64) {
+	bits := t.size * 8
+	if t.signed {
+		return uint64(-(int64(1) << (bits - 1))), uint64(int64(1)<<(bits-1) - 1)
+	}
+	if bits == 64 {
+		return 0, math.MaxUint64
+	}
+	return 0, uint64(1)<<bits - 1
+}
+
+func (t integralType) parse(s string) (uint64, error) {
+	if t.signed {
+		v, err := strconv.ParseInt(s, 0, t.size*8)
+		return uint64(v), errors.WithStack(err)
+	}
+	v, err := strconv.ParseUint(s, 0, t.size*8)
+	return v, errors.WithStack(err)
+}
+
+func (t integralType) format(v uint64) string {
+	if t.signed {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+var llvmIntegralTypes = map[string]integralType{
+	"bool":               {1, false},
+	"char":               {1, true},
+	"signed char":        {1, true},
+	"unsigned char":      {1, false},
+	"int8_t":             {1, true},
+	"uint8_t":            {1, false},
+	"short":              {2, true},
+	"unsigned short":     {2, false},
+	"int16_t":            {2, true},
+	"uint16_t":           {2, false},
+	"int":                {4, true},
+	"unsigned":           {4, false},
+	"unsigned int":       {4, false},
+	"int32_t":            {4, true},
+	"uint32_t":           {4, false},
+	"long":               {8, true},
+	"unsigned long":      {8, false},
+	"long long":          {8, true},
+	"unsigned long long": {8, false},
+	"int64_t":            {8, true},
+	"uint64_t":           {8, false},
+	"size_t":             {8, false},
+	"ssize_t":            {8, true},
+}
+
+func llvmIntegralType(c *Call) (integralType, error) {
+	t, ok := llvmIntegralTypes[c.Type]
+	if !ok {
+		return integralType{}, errors.Errorf("Unsupported integral type %q in %s", c.Type, c)
+	}
+	return t, nil
+}
+
+func llvmFloatingPointBits(c *Call) (int, error) {
+	switch c.Type {
+	case "float":
+		return 32, nil
+	case "double":
+		return 64, nil
+	}
+	return 0, errors.Errorf("Unsupported floating point type %q in %s", c.Type, c)
+}
+
+func checkNumArgs(c *Call, allowed ...int) error {
+	for _, n := range allowed {
+		if len(c.Args) == n {
+			return nil
+		}
+	}
+	return errors.Errorf("Wrong number of arguments in %s", c)
+}
+
+func parseLength(c *Call, s string) (int, error) {
+	n, err := strconv.ParseUint(s, 0, 31)
+	if err != nil {
+		return 0, errors.Errorf("Invalid length %q in %s", s, c)
+	}
+	return int(n), nil
+}
+
+func consumeIntegralInRange(p *provider, c *Call, t integralType) (*Value, error) {
+	err := checkNumArgs(c, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	min, max := t.limits()
+	if len(c.Args) == 2 {
+		min, err = t.parse(c.Args[0])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid minimum in %s", c)
+		}
+		max, err = t.parse(c.Args[1])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid maximum in %s", c)
+		}
+		if (t.signed && int64(min) > int64(max)) || (!t.signed && min > max) {
+			return nil, errors.Errorf("Invalid range in %s", c)
+		}
+	}
+	v, value := p.consumeIntegralInRange(min, max, t.size)
+	value.Value = t.format(v)
+	return value, nil
+}
+
+// pickIndex picks an index of an array of the size specified by the
+// first argument, like PickValueInArray.
+func pickIndex(p *provider, c *Call, t integralType) (*Value, error) {
+	err := checkNumArgs(c, 1)
+	if err != nil {
+		return nil, err
+	}
+	size, err := parseLength(c, c.Args[0])
+	if err != nil || size == 0 {
+		return nil, errors.Errorf("Invalid array size in %s", c)
+	}
+	v, value := p.consumeIntegralInRange(0, uint64(size-1), t.size)
+	value.Value = "index " + t.format(v)
+	return value, nil
+}
+
+func consumeFloatingPoint(p *provider, c *Call, bits int, inRange bool) (*Value, error) {
+	min, max := -math.MaxFloat64, math.MaxFloat64
+	if bits == 32 {
+		min, max = -math.MaxFloat32, math.MaxFloat32
+	}
+	if inRange {
+		err := checkNumArgs(c, 2)
+		if err != nil {
+			return nil, err
+		}
+		min, err = strconv.ParseFloat(c.Args[0], bits)
+		if err != nil {
+			return nil, errors.Errorf("Invalid minimum in %s", c)
+		}
+		max, err = strconv.ParseFloat(c.Args[1], bits)
+		if err != nil {
+			return nil, errors.Errorf("Invalid maximum in %s", c)
+		}
+	} else {
+		err := checkNumArgs(c, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	v, value, err := p.consumeFloatingPointInRange(min, max, bits)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Invalid arguments in %s", c)
+	}
+	value.Value = strconv.FormatFloat(v, 'g', -1, bits)
+	return value, nil
+}
+
+func consumeProbability(p *provider, c *Call, bits int) (*Value, error) {
+	err := checkNumArgs(c, 0)
+	if err != nil {
+		return nil, err
+	}
+	v, value := p.consumeProbability(bits)
+	value.Value = strconv.FormatFloat(v, 'g', -1, bits)
+	return value, nil
+}
+
+func consumeBytes(p *provider, c *Call, asString bool) (*Value, error) {
+	err := checkNumArgs(c, 1)
+	if err != nil {
+		return nil, err
+	}
+	n, err := parseLength(c, c.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	offset, b := p.consumeFront(n)
+	return &Value{Offset: offset, Length: len(b), Value: formatBytes(b, asString)}, nil
+}
+
+func consumeRemainingBytes(p *provider, c *Call, asString bool) (*Value, error) {
+	err := checkNumArgs(c, 0)
+	if err != nil {
+		return nil, err
+	}
+	offset, b := p.consumeFront(p.remaining())
+	return &Value{Offset: offset, Length: len(b), Value: formatBytes(b, asString)}, nil
+}
+
+func formatBytes(b []byte, asString bool) string {
+	if asString {
+		return strconv.Quote(string(b))
+	}
+	return fmt.Sprintf("[% x]", b)
+}
+
+func consumeLLVM(p *provider, c *Call) (*Value, error) {
+	switch c.Name {
+	case "ConsumeBool":
+		err := checkNumArgs(c, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, value := p.consumeBool()
+		value.Value = strconv.FormatBool(b)
+		return value, nil
+	case "ConsumeIntegral", "ConsumeIntegralInRange":
+		t, err := llvmIntegralType(c)
+		if err != nil {
+			return nil, err
+		}
+		if c.Name == "ConsumeIntegral" {
+			err = checkNumArgs(c, 0)
+		} else {
+			err = checkNumArgs(c, 2)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return consumeIntegralInRange(p, c, t)
+	case "ConsumeEnum":
+		// ConsumeEnum consumes a value between 0 and the kMaxValue of
+		// the enum, which has to be specified as argument
+		err := checkNumArgs(c, 1)
+		if err != nil {
+			return nil, err
+		}
+		t := llvmIntegralTypes["uint32_t"]
+		maxValue, err := t.parse(c.Args[0])
+		if err != nil {
+			return nil, errors.Errorf("Invalid kMaxValue in %s", c)
+		}
+		v, value := p.consumeIntegralInRange(0, maxValue, t.size)
+		value.Value = t.format(v)
+		return value, nil
+	case "PickValueInArray":
+		return pickIndex(p, c, llvmIntegralTypes["size_t"])
+	case "ConsumeProbability":
+		bits, err := llvmFloatingPointBits(c)
+		if err != nil {
+			return nil, err
+		}
+		return consumeProbability(p, c, bits)
+	case "ConsumeFloatingPoint", "ConsumeFloatingPointInRange":
+		bits, err := llvmFloatingPointBits(c)
+		if err != nil {
+			return nil, err
+		}
+		return consumeFloatingPoint(p, c, bits, c.Name == "ConsumeFloatingPointInRange")
+	case "ConsumeBytes":
+		return consumeBytes(p, c, false)
+	case "ConsumeBytesAsString":
+		return consumeBytes(p, c, true)
+	case "ConsumeBytesWithTerminator":
+		// The terminator is appended, but not consumed
+		err := checkNumArgs(c, 1, 2)
+		if err != nil {
+			return nil, err
+		}
+		return consumeBytes(p, &Call{Name: c.Name, Args: c.Args[:1], text: c.text}, false)
+	case "ConsumeRandomLengthString":
+		err := checkNumArgs(c, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		maxLength := p.remaining()
+		if len(c.Args) == 1 {
+			maxLength, err = parseLength(c, c.Args[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+		s, value := p.consumeRandomLengthString(maxLength)
+		value.Value = strconv.Quote(string(s))
+		return value, nil
+	case "ConsumeRemainingBytes":
+		return consumeRemainingBytes(p, c, false)
+	case "ConsumeRemainingBytesAsString":
+		return consumeRemainingBytes(p, c, true)
+	}
+	return nil, errors.Errorf("Unsupported FuzzedDataProvider method %q", c.Name)
+}
+
+var jazzerIntegralTypes = map[string]integralType{
+	"consumeByte":  {1, true},
+	"consumeShort": {2, true},
+	"consumeInt":   {4, true},
+	"consumeLong":  {8, true},
+	"consumeChar":  {2, false},
+}
+
+func consumeJazzer(p *provider, c *Call) (*Value, error) {
+	if t, ok := jazzerIntegralTypes[c.Name]; ok {
+		value, err := consumeIntegralInRange(p, c, t)
+		if err != nil {
+			return nil, err
+		}
+		if c.Name == "consumeChar" {
+			v, _ := strconv.ParseUint(value.Value, 10, 16)
+			value.Value = strconv.QuoteRune(rune(v))
+		}
+		return value, nil
+	}
+
+	switch c.Name {
+	case "consumeBoolean":
+		err := checkNumArgs(c, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, value := p.consumeBool()
+		value.Value = strconv.FormatBool(b)
+		return value, nil
+	case "pickValue":
+		return pickIndex(p, c, jazzerIntegralTypes["consumeInt"])
+	case "consumeProbabilityFloat":
+		return consumeProbability(p, c, 32)
+	case "consumeProbabilityDouble":
+		return consumeProbability(p, c, 64)
+	case "consumeRegularFloat":
+		return consumeFloatingPoint(p, c, 32, len(c.Args) > 0)
+	case "consumeRegularDouble":
+		return consumeFloatingPoint(p, c, 64, len(c.Args) > 0)
+	case "consumeBytes":
+		return consumeBytes(p, c, false)
+	case "consumeRemainingAsBytes":
+		return consumeRemainingBytes(p, c, false)
+	case "consumeString", "consumeAsciiString":
+		err := checkNumArgs(c, 1)
+		if err != nil {
+			return nil, err
+		}
+		maxLength, err := parseLength(c, c.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		s, value := p.consumeJazzerString(maxLength, c.Name == "consumeAsciiString", true)
+		value.Value = strconv.Quote(s)
+		return value, nil
+	case "consumeRemainingAsString", "consumeRemainingAsAsciiString":
+		err := checkNumArgs(c, 0)
+		if err != nil {
+			return nil, err
+		}
+		s, value := p.consumeJazzerString(math.MaxInt32, c.Name == "consumeRemainingAsAsciiString", false)
+		value.Value = strconv.Quote(s)
+		return value, nil
+	}
+	return nil, errors.Errorf("Unsupported FuzzedDataProvider method %q", c.Name)
+}
@@ -0,0 +1,142 @@
+package inputview
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCalls(t *testing.T) {
+	calls, err := ParseCalls(`
+# The fuzz test first consumes the length
+ConsumeIntegralInRange<std::size_t>(0, 10); ConsumeBool()
+// followed by strings
+ConsumeRandomLengthString(16)*
+`)
+	require.NoError(t, err)
+	require.Len(t, calls, 3)
+
+	assert.Equal(t, "ConsumeIntegralInRange", calls[0].Name)
+	assert.Equal(t, "size_t", calls[0].Type)
+	assert.Equal(t, []string{"0", "10"}, calls[0].Args)
+	assert.False(t, calls[0].Repeat)
+
+	assert.Equal(t, "ConsumeBool", calls[1].Name)
+	assert.Empty(t, calls[1].Args)
+
+	assert.Equal(t, "ConsumeRandomLengthString", calls[2].Name)
+	assert.Equal(t, []string{"16"}, calls[2].Args)
+	assert.True(t, calls[2].Repeat)
+	assert.Equal(t, "ConsumeRandomLengthString(16)", calls[2].String())
+
+	_, err = ParseCalls("not a call!")
+	assert.Error(t, err)
+	_, err = ParseCalls("# only a comment")
+	assert.Error(t, err)
+}
+
+func decodeFDP(t *testing.T, data []byte, calls string, flavor string) []*Value {
+	parsed, err := ParseCalls(calls)
+	require.NoError(t, err)
+	values, err := DecodeFDP(data, parsed, flavor)
+	require.NoError(t, err)
+	return values
+}
+
+func TestDecodeFDP_LLVM(t *testing.T) {
+	// Integral values are consumed from the back of the data, starting
+	// with the least significant byte, bytes and strings from the front.
+	data := []byte("ab\\\\c\\xyz\x02\x03\x04\x05")
+	values := decodeFDP(t, data, `
+ConsumeIntegral<uint16_t>()
+ConsumeIntegralInRange<int>(-1, 1)
+ConsumeBool()
+ConsumeRandomLengthString()
+ConsumeRemainingBytesAsString()
+`, FlavorLLVM)
+	require.Len(t, values, 5)
+
+	assert.Equal(t, &Value{Call: "ConsumeIntegral<uint16_t>()", Offset: 11, Length: 2, Value: "1284"}, values[0])
+	// 0x03 % 3 == 0, so the result is the minimum
+	assert.Equal(t, &Value{Call: "ConsumeIntegralInRange<int>(-1, 1)", Offset: 10, Length: 1, Value: "-1"}, values[1])
+	assert.Equal(t, &Value{Call: "ConsumeBool()", Offset: 9, Length: 1, Value: "false"}, values[2])
+	// The escaped backslash is part of the string, the unescaped
+	// backslash terminates it
+	assert.Equal(t, &Value{Call: "ConsumeRandomLengthString()", Offset: 0, Length: 7, Value: `"ab\\c"`}, values[3])
+	assert.Equal(t, &Value{Call: "ConsumeRemainingBytesAsString()", Offset: 7, Length: 2, Value: `"yz"`}, values[4])
+}
+
+func TestDecodeFDP_LLVMExhaustedData(t *testing.T) {
+	values := decodeFDP(t, nil, "ConsumeIntegralInRange<int>(5, 10); ConsumeBytes<uint8_t>(4)", FlavorLLVM)
+	require.Len(t, values, 2)
+	// If the data is exhausted, the minimum and empty values are
+	// returned
+	assert.Equal(t, "5", values[0].Value)
+	assert.Equal(t, 0, values[0].Length)
+	assert.Equal(t, 0, values[1].Length)
+}
+
+func TestDecodeFDP_Repeat(t *testing.T) {
+	values := decodeFDP(t, []byte("ab\\xcd\\yef"), "ConsumeRandomLengthString(8)*", FlavorLLVM)
+	require.Len(t, values, 3)
+	assert.Equal(t, `"ab"`, values[0].Value)
+	assert.Equal(t, `"cd"`, values[1].Value)
+	assert.Equal(t, `"ef"`, values[2].Value)
+}
+
+func TestDecodeFDP_Jazzer(t *testing.T) {
+	data := []byte{'x', 'y', 0x41, 0x00, 0xff, 0xff, 0xff, 0xfe}
+	values := decodeFDP(t, data, "consumeInt(); consumeChar(); consumeRemainingAsBytes()", FlavorJazzer)
+	require.Len(t, values, 3)
+	// The minimum of the range plus 0xfeffffff
+	assert.Equal(t, "2130706431", values[0].Value)
+	assert.Equal(t, `'A'`, values[1].Value)
+	assert.Equal(t, 0, values[2].Offset)
+	assert.Equal(t, 2, values[2].Length)
+
+	_, err := DecodeFDP(data, []*Call{{Name: "consumeFoo", text: "consumeFoo()"}}, FlavorJazzer)
+	assert.Error(t, err)
+}
+
+func TestDecodeFDP_JazzerStrings(t *testing.T) {
+	// "ä" and "€" are valid UTF-8, the escaped backslash is part of the
+	// string and the unescaped one terminates it
+	data := []byte("a\xc3\xa4\xe2\x82\xac\\\\b\\cd")
+	values := decodeFDP(t, data, "consumeString(10); consumeRemainingAsString()", FlavorJazzer)
+	require.Len(t, values, 2)
+	assert.Equal(t, &Value{Call: "consumeString(10)", Offset: 0, Length: 11, Value: `"aä€\\b"`}, values[0])
+	assert.Equal(t, &Value{Call: "consumeRemainingAsString()", Offset: 11, Length: 1, Value: `"d"`}, values[1])
+
+	// The maximum length is the number of characters, not bytes
+	values = decodeFDP(t, data, "consumeString(2)", FlavorJazzer)
+	assert.Equal(t, `"aä"`, values[0].Value)
+	assert.Equal(t, 3, values[0].Length)
+
+	// The ASCII variants clear the highest bit of every byte
+	values = decodeFDP(t, []byte("a\xc1\xe2\\x"), "consumeAsciiString(10)", FlavorJazzer)
+	assert.Equal(t, `"aAb"`, values[0].Value)
+	assert.Equal(t, 5, values[0].Length)
+	values = decodeFDP(t, []byte("a\xc1\\x"), "consumeRemainingAsAsciiString()", FlavorJazzer)
+	assert.Equal(t, `"aA\\x"`, values[0].Value)
+
+	// Zero characters are coded on two bytes, invalid bytes are fixed
+	// up and an incomplete character at the end is dropped
+	values = decodeFDP(t, []byte("\xc0\x80\x00\xff\xc3\x41\xe2\x82"), "consumeRemainingAsString()", FlavorJazzer)
+	assert.Equal(t, `"\x00\x00\x7fÁ"`, values[0].Value)
+	assert.Equal(t, 8, values[0].Length)
+
+	// Surrogate pairs are coded as two three byte sequences
+	values = decodeFDP(t, []byte("\xed\xa0\xbd\xed\xb8\x80"), "consumeString(2)", FlavorJazzer)
+	assert.Equal(t, `"😀"`, values[0].Value)
+}
+
+func TestRenderFDP(t *testing.T) {
+	calls, err := ParseCalls("ConsumeBool()")
+	require.NoError(t, err)
+	res, err := RenderFDP([]byte{0x00, 0x01}, calls, FlavorLLVM)
+	require.NoError(t, err)
+	assert.Contains(t, res, "0x1    ConsumeBool()  true")
+	assert.Contains(t, res, "1 of 2 bytes were not consumed")
+	assert.Contains(t, res, "^ 0x1: ConsumeBool() = true")
+}
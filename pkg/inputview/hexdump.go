@@ -0,0 +1,84 @@
+package inputview
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const bytesPerLine = 16
+
+// An Annotation labels a range of bytes of an input.
+type Annotation struct {
+	Offset int
+	Length int
+	Label  string
+}
+
+// Hexdump returns a hexdump of the data in the format of `hexdump -C`.
+// Each line is followed by the labels of the annotations which start in
+// that line.
+func Hexdump(data []byte, annotations []Annotation) string {
+	annotations = append([]Annotation{}, annotations...)
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].Offset < annotations[j].Offset
+	})
+
+	var b strings.Builder
+	next := 0
+	for lineStart := 0; lineStart < len(data); lineStart += bytesPerLine {
+		lineEnd := lineStart + bytesPerLine
+		if lineEnd > len(data) {
+			lineEnd = len(data)
+		}
+		line := data[lineStart:lineEnd]
+
+		fmt.Fprintf(&b, "%08x ", lineStart)
+		for i := 0; i < bytesPerLine; i++ {
+			if i == bytesPerLine/2 {
+				b.WriteString(" ")
+			}
+			if i < len(line) {
+				fmt.Fprintf(&b, " %02x", line[i])
+			} else {
+				b.WriteString("   ")
+			}
+		}
+		b.WriteString("  |")
+		for _, c := range line {
+			b.WriteByte(printableASCII(c))
+		}
+		b.WriteString("|\n")
+
+		for next < len(annotations) && annotations[next].Offset < lineEnd {
+			a := annotations[next]
+			fmt.Fprintf(&b, "         ^ %s: %s\n", formatRange(a.Offset, a.Length), a.Label)
+			next++
+		}
+	}
+	fmt.Fprintf(&b, "%08x\n", len(data))
+
+	// Annotations of empty ranges at the end of the data
+	for ; next < len(annotations); next++ {
+		a := annotations[next]
+		fmt.Fprintf(&b, "         ^ %s: %s\n", formatRange(a.Offset, a.Length), a.Label)
+	}
+	return b.String()
+}
+
+func printableASCII(c byte) byte {
+	if c >= 0x20 && c < 0x7f {
+		return c
+	}
+	return '.'
+}
+
+func formatRange(offset, length int) string {
+	if length == 1 {
+		return fmt.Sprintf("0x%x", offset)
+	}
+	if length == 0 {
+		return fmt.Sprintf("0x%x (0 bytes)", offset)
+	}
+	return fmt.Sprintf("0x%x-0x%x", offset, offset+length-1)
+}
@@ -0,0 +1,26 @@
+package inputview
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHexdump(t *testing.T) {
+	data := []byte("Hello, World!\x00\x01\x02\xffabc")
+	expected := `00000000  48 65 6c 6c 6f 2c 20 57  6f 72 6c 64 21 00 01 02  |Hello, World!...|
+         ^ 0x0-0x4: greeting
+00000010  ff 61 62 63                                       |.abc|
+         ^ 0x10: marker
+00000014
+`
+	res := Hexdump(data, []Annotation{
+		{Offset: 16, Length: 1, Label: "marker"},
+		{Offset: 0, Length: 5, Label: "greeting"},
+	})
+	assert.Equal(t, expected, res)
+}
+
+func TestHexdump_Empty(t *testing.T) {
+	assert.Equal(t, "00000000\n", Hexdump(nil, nil))
+}
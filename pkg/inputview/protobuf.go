@@ -0,0 +1,321 @@
+package inputview
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/fileutil"
+)
+
+// The maximum nesting depth of messages which is decoded, to avoid
+// excessive recursion on malicious inputs
+const maxProtobufDepth = 64
+
+// Protobuf decodes protobuf messages of a message type from a set of
+// file descriptors.
+type Protobuf struct {
+	messages map[string]*descriptorpb.DescriptorProto
+	enums    map[string]*descriptorpb.EnumDescriptorProto
+}
+
+// LoadProtobuf loads the message types from a .proto file, which
+// requires protoc, or from a serialized FileDescriptorSet as created by
+// `protoc --include_imports --descriptor_set_out`.
+func LoadProtobuf(path string, includeDirs []string) (*Protobuf, error) {
+	var data []byte
+	var err error
+	if strings.HasSuffix(path, ".proto") {
+		data, err = compileProto(path, includeDirs)
+	} else {
+		data, err = os.ReadFile(path)
+		err = errors.WithStack(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	err = proto.Unmarshal(data, set)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse file descriptor set %s", path)
+	}
+
+	p := &Protobuf{
+		messages: make(map[string]*descriptorpb.DescriptorProto),
+		enums:    make(map[string]*descriptorpb.EnumDescriptorProto),
+	}
+	for _, file := range set.File {
+		prefix := ""
+		if file.GetPackage() != "" {
+			prefix = file.GetPackage() + "."
+		}
+		for _, m := range file.MessageType {
+			p.addMessage(prefix, m)
+		}
+		for _, e := range file.EnumType {
+			p.enums[prefix+e.GetName()] = e
+		}
+	}
+	return p, nil
+}
+
+func (p *Protobuf) addMessage(prefix string, m *descriptorpb.DescriptorProto) {
+	name := prefix + m.GetName()
+	p.messages[name] = m
+	for _, nested := range m.NestedType {
+		p.addMessage(name+".", nested)
+	}
+	for _, e := range m.EnumType {
+		p.enums[name+"."+e.GetName()] = e
+	}
+}
+
+func compileProto(path string, includeDirs []string) ([]byte, error) {
+	protoc, err := exec.LookPath("protoc")
+	if err != nil {
+		return nil, errors.New("protoc is required to decode inputs with a .proto file, either install it or specify a descriptor set created with `protoc --include_imports --descriptor_set_out`")
+	}
+	tempDir, err := os.MkdirTemp("", "cifuzz-proto-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fileutil.Cleanup(tempDir)
+
+	out := filepath.Join(tempDir, "descriptor_set.pb")
+	args := []string{"--include_imports", "--descriptor_set_out=" + out, "-I", filepath.Dir(path)}
+	for _, dir := range includeDirs {
+		args = append(args, "-I", dir)
+	}
+	args = append(args, path)
+	cmd := exec.Command(protoc, args...)
+	log.Debugf("Command: %s", cmd.String())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.Errorf("Failed to compile %s: %v\n%s", path, err, output)
+	}
+	data, err := os.ReadFile(out)
+	return data, errors.WithStack(err)
+}
+
+// MessageNames returns the full names of all message types, sorted
+// alphabetically.
+func (p *Protobuf) MessageNames() []string {
+	var names []string
+	for name := range p.messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Decode decodes the binary protobuf message of the specified type and
+// returns it in text format.
+func (p *Protobuf) Decode(data []byte, messageName string) (string, error) {
+	m, ok := p.messages[strings.TrimPrefix(messageName, ".")]
+	if !ok {
+		return "", errors.Errorf("Unknown message type %q", messageName)
+	}
+	var b strings.Builder
+	err := p.decodeMessage(&b, data, m, 0)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// DecodeProtobufInput decodes an input of a libprotobuf-mutator fuzz
+// test. Inputs of fuzz tests defined via DEFINE_BINARY_PROTO_FUZZER are
+// decoded from the binary format. Inputs of fuzz tests defined via
+// DEFINE_PROTO_FUZZER are already in text format and returned as is.
+func DecodeProtobufInput(data []byte, p *Protobuf, messageName string) (string, error) {
+	res, err := p.Decode(data, messageName)
+	if err == nil {
+		return res, nil
+	}
+	if utf8.Valid(data) {
+		log.Debugf("Input is not a binary %s message (%v), assuming text format", messageName, err)
+		return string(data), nil
+	}
+	return "", err
+}
+
+func (p *Protobuf) decodeMessage(b *strings.Builder, data []byte, m *descriptorpb.DescriptorProto, depth int) error {
+	if depth > maxProtobufDepth {
+		return errors.New("Maximum nesting depth exceeded")
+	}
+	indent := strings.Repeat("  ", depth)
+
+	fields := make(map[int32]*descriptorpb.FieldDescriptorProto)
+	for _, f := range m.Field {
+		fields[f.GetNumber()] = f
+	}
+
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("Invalid field key")
+		}
+		data = data[n:]
+		number := int32(key >> 3)
+		wireType := key & 7
+		field := fields[number]
+
+		name := strconv.Itoa(int(number))
+		if field != nil {
+			name = field.GetName()
+		}
+
+		var raw []byte
+		var value uint64
+		switch wireType {
+		case 0:
+			value, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errors.Errorf("Invalid varint in field %s", name)
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return errors.Errorf("Truncated fixed64 in field %s", name)
+			}
+			value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 5:
+			if len(data) < 4 {
+				return errors.Errorf("Truncated fixed32 in field %s", name)
+			}
+			value = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return errors.Errorf("Invalid length of field %s", name)
+			}
+			raw = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return errors.Errorf("Unsupported wire type %d in field %s", wireType, name)
+		}
+
+		if field == nil {
+			// Unknown field, print the raw value
+			if wireType == 2 {
+				fmt.Fprintf(b, "%s%s: %s\n", indent, name, strconv.Quote(string(raw)))
+			} else {
+				fmt.Fprintf(b, "%s%s: %d\n", indent, name, value)
+			}
+			continue
+		}
+
+		if wireType == 2 && field.GetType() != descriptorpb.FieldDescriptorProto_TYPE_STRING &&
+			field.GetType() != descriptorpb.FieldDescriptorProto_TYPE_BYTES &&
+			field.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+			// Packed repeated scalar field
+			values, err := p.decodePacked(raw, field)
+			if err != nil {
+				return err
+			}
+			for _, v := range values {
+				fmt.Fprintf(b, "%s%s: %s\n", indent, name, v)
+			}
+			continue
+		}
+
+		switch field.GetType() {
+		case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+			nested, ok := p.messages[strings.TrimPrefix(field.GetTypeName(), ".")]
+			if !ok {
+				return errors.Errorf("Unknown message type %q of field %s", field.GetTypeName(), name)
+			}
+			fmt.Fprintf(b, "%s%s {\n", indent, name)
+			err := p.decodeMessage(b, raw, nested, depth+1)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "%s}\n", indent)
+		case descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+			fmt.Fprintf(b, "%s%s: %s\n", indent, name, strconv.Quote(string(raw)))
+		default:
+			fmt.Fprintf(b, "%s%s: %s\n", indent, name, p.formatScalar(value, field))
+		}
+	}
+	return nil
+}
+
+func (p *Protobuf) decodePacked(data []byte, field *descriptorpb.FieldDescriptorProto) ([]string, error) {
+	var values []string
+	for len(data) > 0 {
+		var value uint64
+		switch field.GetType() {
+		case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+			descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+			descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+			if len(data) < 8 {
+				return nil, errors.Errorf("Truncated packed field %s", field.GetName())
+			}
+			value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+			descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+			descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+			if len(data) < 4 {
+				return nil, errors.Errorf("Truncated packed field %s", field.GetName())
+			}
+			value = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			var n int
+			value, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errors.Errorf("Invalid varint in packed field %s", field.GetName())
+			}
+			data = data[n:]
+		}
+		values = append(values, p.formatScalar(value, field))
+	}
+	return values, nil
+}
+
+func (p *Protobuf) formatScalar(value uint64, field *descriptorpb.FieldDescriptorProto) string {
+	switch field.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		return strconv.FormatFloat(math.Float64frombits(value), 'g', -1, 64)
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(value))), 'g', -1, 32)
+	case descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return strconv.FormatInt(int64(value), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+		return strconv.FormatInt(int64(int32(value)), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_FIXED32:
+		return strconv.FormatUint(uint64(uint32(value)), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT32:
+		return strconv.FormatInt(int64(int32(uint32(value)>>1)^-int32(value&1)), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT64:
+		return strconv.FormatInt(int64(value>>1)^-int64(value&1), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		return strconv.FormatBool(value != 0)
+	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		if e, ok := p.enums[strings.TrimPrefix(field.GetTypeName(), ".")]; ok {
+			for _, v := range e.Value {
+				if v.GetNumber() == int32(value) {
+					return v.GetName()
+				}
+			}
+		}
+		return strconv.FormatInt(int64(int32(value)), 10)
+	}
+	return strconv.FormatUint(value, 10)
+}
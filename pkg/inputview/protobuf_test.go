@@ -0,0 +1,132 @@
+package inputview
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func field(name string, number int32, t descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   t.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+// writeDescriptorSet writes a descriptor set for the following proto
+// file and returns its path:
+//
+//	package test;
+//	message Inner { enum Kind { A = 0; B = 1; } Kind kind = 1; }
+//	message Outer {
+//	  string name = 1;
+//	  sint32 delta = 2;
+//	  repeated int32 values = 3;
+//	  Inner inner = 4;
+//	  double ratio = 5;
+//	}
+func writeDescriptorSet(t *testing.T) string {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("test.proto"),
+			Package: proto.String("test"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Inner"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("kind", 1, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".test.Inner.Kind"),
+					},
+					EnumType: []*descriptorpb.EnumDescriptorProto{{
+						Name: proto.String("Kind"),
+						Value: []*descriptorpb.EnumValueDescriptorProto{
+							{Name: proto.String("A"), Number: proto.Int32(0)},
+							{Name: proto.String("B"), Number: proto.Int32(1)},
+						},
+					}},
+				},
+				{
+					Name: proto.String("Outer"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("delta", 2, descriptorpb.FieldDescriptorProto_TYPE_SINT32, ""),
+						field("values", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+						field("inner", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Inner"),
+						field("ratio", 5, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+					},
+				},
+			},
+		}},
+	}
+	data, err := proto.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "test.desc")
+	err = os.WriteFile(path, data, 0o644)
+	require.NoError(t, err)
+	return path
+}
+
+func TestProtobuf_Decode(t *testing.T) {
+	p, err := LoadProtobuf(writeDescriptorSet(t), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.Inner", "test.Outer"}, p.MessageNames())
+
+	data := []byte{
+		0x0a, 0x02, 'h', 'i', // name: "hi"
+		0x10, 0x03, // delta: -2 (zigzag)
+		0x1a, 0x02, 0x01, 0x7f, // values: [1, 127] (packed)
+		0x22, 0x02, 0x08, 0x01, // inner { kind: B }
+		0x29, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, // ratio: 1.5
+		0x30, 0x2a, // unknown field 6: 42
+	}
+	res, err := p.Decode(data, "test.Outer")
+	require.NoError(t, err)
+	assert.Equal(t, `name: "hi"
+delta: -2
+values: 1
+values: 127
+inner {
+  kind: B
+}
+ratio: 1.5
+6: 42
+`, res)
+
+	_, err = p.Decode([]byte{0x0a, 0x10}, "test.Outer")
+	assert.Error(t, err)
+	_, err = p.Decode(data, "test.Unknown")
+	assert.Error(t, err)
+}
+
+func TestDecodeProtobufInput_TextFormat(t *testing.T) {
+	p, err := LoadProtobuf(writeDescriptorSet(t), nil)
+	require.NoError(t, err)
+
+	// Inputs of DEFINE_PROTO_FUZZER fuzz tests are in text format
+	input := []byte("name: \"hi\"\ninner {\n  kind: B\n}\n")
+	res, err := DecodeProtobufInput(input, p, "test.Outer")
+	require.NoError(t, err)
+	assert.Equal(t, string(input), res)
+}
+
+func TestRender_Protobuf(t *testing.T) {
+	path := writeDescriptorSet(t)
+
+	// The message type is required because the file defines more than
+	// one message type
+	_, err := Render([]byte{0x0a, 0x00}, &Options{Format: FormatProtobuf, Proto: path})
+	require.Error(t, err)
+
+	res, err := Render([]byte{0x0a, 0x00}, &Options{Format: FormatProtobuf, Proto: path, ProtoMessage: "test.Outer"})
+	require.NoError(t, err)
+	assert.Equal(t, "name: \"\"\n", res)
+}
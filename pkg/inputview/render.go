@@ -0,0 +1,117 @@
+package inputview
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The supported renderings of an input
+const (
+	FormatHexdump   = "hexdump"
+	FormatFDP       = "fdp"
+	FormatJazzerFDP = "jazzer-fdp"
+	FormatProtobuf  = "protobuf"
+)
+
+var Formats = []string{FormatHexdump, FormatFDP, FormatJazzerFDP, FormatProtobuf}
+
+// Options specifies how an input is rendered. They can be set in the
+// `input-view` section of the project config.
+type Options struct {
+	Format string `mapstructure:"format"`
+	// The FuzzedDataProvider consume calls, in the syntax accepted by
+	// ParseCalls, used by the "fdp" and "jazzer-fdp" formats
+	Calls string `mapstructure:"calls"`
+	// A file containing the consume calls, used if Calls is empty
+	CallsFile string `mapstructure:"calls-file"`
+	// A .proto file or serialized FileDescriptorSet, used by the
+	// "protobuf" format
+	Proto string `mapstructure:"proto"`
+	// The additional import paths used to compile a .proto file
+	ProtoIncludeDirs []string `mapstructure:"proto-include-dirs"`
+	// The full name of the message type of the input
+	ProtoMessage string `mapstructure:"proto-message"`
+	// The maximum number of bytes which are rendered by the "hexdump"
+	// format, zero means unlimited
+	MaxBytes int `mapstructure:"max-bytes"`
+}
+
+// DefaultMaxBytes is the maximum number of bytes rendered by the
+// hexdump which is stored in findings if no rendering is configured
+const DefaultMaxBytes = 4096
+
+// ResolvePaths makes the relative paths of the options relative to the
+// specified directory, which is usually the project directory.
+func (opts *Options) ResolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+	opts.CallsFile = resolve(opts.CallsFile)
+	opts.Proto = resolve(opts.Proto)
+	for i, includeDir := range opts.ProtoIncludeDirs {
+		opts.ProtoIncludeDirs[i] = resolve(includeDir)
+	}
+}
+
+// Render renders the input as specified by the options. An empty format
+// defaults to "hexdump".
+func Render(data []byte, opts *Options) (string, error) {
+	switch opts.Format {
+	case FormatHexdump, "":
+		return renderHexdump(data, opts.MaxBytes), nil
+
+	case FormatFDP, FormatJazzerFDP:
+		callsText := opts.Calls
+		if callsText == "" && opts.CallsFile != "" {
+			bytes, err := os.ReadFile(opts.CallsFile)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			callsText = string(bytes)
+		}
+		if callsText == "" {
+			return "", errors.Errorf("The %q format requires the FuzzedDataProvider calls to be specified", opts.Format)
+		}
+		calls, err := ParseCalls(callsText)
+		if err != nil {
+			return "", err
+		}
+		flavor := FlavorLLVM
+		if opts.Format == FormatJazzerFDP {
+			flavor = FlavorJazzer
+		}
+		return RenderFDP(data, calls, flavor)
+
+	case FormatProtobuf:
+		if opts.Proto == "" {
+			return "", errors.New("The \"protobuf\" format requires a .proto file or descriptor set to be specified")
+		}
+		p, err := LoadProtobuf(opts.Proto, opts.ProtoIncludeDirs)
+		if err != nil {
+			return "", err
+		}
+		messageName := opts.ProtoMessage
+		if messageName == "" {
+			names := p.MessageNames()
+			if len(names) != 1 {
+				return "", errors.Errorf("%s defines %d message types, please specify the message type of the input", opts.Proto, len(names))
+			}
+			messageName = names[0]
+		}
+		return DecodeProtobufInput(data, p, messageName)
+	}
+	return "", errors.Errorf("Unsupported format %q, supported formats are: %s", opts.Format, strings.Join(Formats, ", "))
+}
+
+func renderHexdump(data []byte, maxBytes int) string {
+	if maxBytes <= 0 || len(data) <= maxBytes {
+		return Hexdump(data, nil)
+	}
+	return Hexdump(data[:maxBytes], nil) + "...\n"
+}
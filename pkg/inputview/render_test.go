@@ -0,0 +1,52 @@
+package inputview
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_Hexdump(t *testing.T) {
+	data := make([]byte, 40)
+
+	res, err := Render(data, &Options{})
+	require.NoError(t, err)
+	assert.Equal(t, Hexdump(data, nil), res)
+
+	res, err = Render(data, &Options{Format: FormatHexdump, MaxBytes: 16})
+	require.NoError(t, err)
+	assert.Equal(t, Hexdump(data[:16], nil)+"...\n", res)
+}
+
+func TestRender_FDP(t *testing.T) {
+	_, err := Render([]byte{0x01}, &Options{Format: FormatFDP})
+	require.Error(t, err)
+
+	callsFile := filepath.Join(t.TempDir(), "calls.txt")
+	err = os.WriteFile(callsFile, []byte("consumeBoolean()\n"), 0o644)
+	require.NoError(t, err)
+	res, err := Render([]byte{0x01}, &Options{Format: FormatJazzerFDP, CallsFile: callsFile})
+	require.NoError(t, err)
+	assert.Contains(t, res, "consumeBoolean() = true")
+}
+
+func TestRender_UnsupportedFormat(t *testing.T) {
+	_, err := Render([]byte{0x01}, &Options{Format: "foo"})
+	require.Error(t, err)
+}
+
+func TestOptions_ResolvePaths(t *testing.T) {
+	callsFile := filepath.Join(t.TempDir(), "calls.txt")
+	opts := &Options{
+		Proto:            "fuzz/input.proto",
+		CallsFile:        callsFile,
+		ProtoIncludeDirs: []string{"include"},
+	}
+	opts.ResolvePaths("project")
+	assert.Equal(t, filepath.Join("project", "fuzz", "input.proto"), opts.Proto)
+	assert.Equal(t, callsFile, opts.CallsFile)
+	assert.Equal(t, []string{filepath.Join("project", "include")}, opts.ProtoIncludeDirs)
+}
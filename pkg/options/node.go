@@ -8,6 +8,8 @@ const JazzerJSTestNamePattern string = "--testNamePattern"
 const JazzerJSTestPathPattern string = "--testPathPattern"
 const JazzerJSCoverageDirectory string = "--coverageDirectory"
 const JazzerJSCoverageReporters string = "--coverageReporters"
+const JazzerJSCollectCoverageFrom string = "--collectCoverageFrom"
+const JazzerJSCoveragePathIgnorePatterns string = "--coveragePathIgnorePatterns"
 
 func JazzerJSTestNamePatternFlag(value string) string {
 	return JazzerJSTestNamePattern + fmt.Sprintf("='%s'", value)
@@ -24,3 +26,11 @@ func JazzerJSCoverageDirectoryFlag(value string) string {
 func JazzerJSCoverageReportersFlag(value string) string {
 	return JazzerJSCoverageReporters + fmt.Sprintf("='%s'", value)
 }
+
+func JazzerJSCollectCoverageFromFlag(value string) string {
+	return JazzerJSCollectCoverageFrom + fmt.Sprintf("=%s", value)
+}
+
+func JazzerJSCoveragePathIgnorePatternsFlag(value string) string {
+	return JazzerJSCoveragePathIgnorePatterns + fmt.Sprintf("=%s", value)
+}
@@ -12,6 +12,9 @@ type matcher struct {
 	id         string
 	substrings []string
 	regexs     []*regexp.Regexp
+	// Whether the logs of the finding are matched in addition to the
+	// details
+	matchLogs bool
 }
 
 func (m *matcher) Match(input string) bool {
@@ -29,6 +32,20 @@ func (m *matcher) Match(input string) bool {
 	return false
 }
 
+func (m *matcher) matchFinding(f *finding.Finding) bool {
+	if m.Match(f.Details) {
+		return true
+	}
+	if m.matchLogs {
+		for _, line := range f.Logs {
+			if m.Match(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 var matchers = []matcher{
 	{id: "alloc_dealloc_mismatch", substrings: []string{"attempting free on address which was not malloc"}},
 	{id: "deadly_signal", substrings: []string{"deadly signal"}},
@@ -71,12 +88,14 @@ var matchers = []matcher{
 	{id: "jazzer_security_issue", substrings: []string{"Security Issue:"}},
 }
 
+var defaultClassifier = &Classifier{matchers: matchers}
+
+// ForFinding returns the error ID of the finding determined by the
+// built-in matchers.
 func ForFinding(f *finding.Finding) string {
-	for _, m := range matchers {
-		if m.Match(f.Details) {
-			return m.id
-		}
+	id := defaultClassifier.ForFinding(f)
+	if id == "" {
+		log.Warnf("unable to find matching error id for given finding: %s", f.Details)
 	}
-	log.Warnf("unable to find matching error id for given finding: %s", f.Details)
-	return ""
+	return id
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"code-intelligence.com/cifuzz/pkg/finding"
 )
@@ -42,3 +43,15 @@ func TestForFinding(t *testing.T) {
 		})
 	}
 }
+
+func TestBuiltinErrorDetailsCoverAllIDs(t *testing.T) {
+	errorDetails, err := finding.BuiltinErrorDetails()
+	require.NoError(t, err)
+	ids := make(map[string]bool)
+	for _, d := range errorDetails {
+		ids[d.ID] = true
+	}
+	for _, m := range matchers {
+		assert.Truef(t, ids[m.id], "No built-in error details for error ID %q", m.id)
+	}
+}
@@ -0,0 +1,128 @@
+package errorid
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"code-intelligence.com/cifuzz/pkg/finding"
+)
+
+// A Rule assigns an error ID to the findings whose details contain one
+// of the substrings or match one of the regular expressions. Projects
+// can define rules in cifuzz.yaml or in a rules file to classify
+// findings of custom Jazzer sanitizers or assertion macros.
+type Rule struct {
+	ID         string   `mapstructure:"id" yaml:"id"`
+	Substrings []string `mapstructure:"substrings" yaml:"substrings,omitempty"`
+	Regexes    []string `mapstructure:"regexes" yaml:"regexes,omitempty"`
+	// Rules with a higher priority are checked first. All rules are
+	// checked before the built-in matchers.
+	Priority int `mapstructure:"priority" yaml:"priority,omitempty"`
+	// If true, the rule is also matched against the logs of the
+	// finding, which is useful if the details of the finding are not
+	// specific enough (e.g. "deadly signal" for a failed assertion).
+	MatchLogs bool `mapstructure:"match-logs" yaml:"match-logs,omitempty"`
+}
+
+// RulesFile is the format of a rules file
+type RulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadRules loads the rules from a YAML file.
+func LoadRules(path string) ([]Rule, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var file RulesFile
+	err = yaml.Unmarshal(bytes, &file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse rules file %s", path)
+	}
+	return file.Rules, nil
+}
+
+// A Classifier determines the error ID of findings via the rules it
+// was created with and the built-in matchers.
+type Classifier struct {
+	matchers []matcher
+}
+
+// NewClassifier creates a classifier which checks the rules in the
+// order of their priority before the built-in matchers.
+func NewClassifier(rules []Rule) (*Classifier, error) {
+	rules = append([]Rule{}, rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+
+	c := &Classifier{}
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, errors.New("Error ID rule without an ID")
+		}
+		if len(rule.Substrings) == 0 && len(rule.Regexes) == 0 {
+			return nil, errors.Errorf("Error ID rule %q has neither substrings nor regexes", rule.ID)
+		}
+		m := matcher{id: rule.ID, substrings: rule.Substrings, matchLogs: rule.MatchLogs}
+		for _, r := range rule.Regexes {
+			regex, err := regexp.Compile(r)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid regex in error ID rule %q", rule.ID)
+			}
+			m.regexs = append(m.regexs, regex)
+		}
+		c.matchers = append(c.matchers, m)
+	}
+	c.matchers = append(c.matchers, matchers...)
+	return c, nil
+}
+
+// NewProjectClassifier creates a classifier from the rules defined in
+// the project config and the rules file, which is relative to the
+// project directory. The rules from the project config are checked
+// before the ones from the rules file if they have the same priority.
+func NewProjectClassifier(projectDir string, rules []Rule, rulesFile string) (*Classifier, error) {
+	if rulesFile != "" {
+		if !filepath.IsAbs(rulesFile) {
+			rulesFile = filepath.Join(projectDir, rulesFile)
+		}
+		fileRules, err := LoadRules(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(append([]Rule{}, rules...), fileRules...)
+	}
+	return NewClassifier(rules)
+}
+
+// ForFinding returns the error ID of the finding or an empty string if
+// none of the rules and built-in matchers match.
+func (c *Classifier) ForFinding(f *finding.Finding) string {
+	for _, m := range c.matchers {
+		if m.matchFinding(f) {
+			return m.id
+		}
+	}
+	return ""
+}
+
+// Classify sets the error ID of the finding to the one determined via
+// the rules and built-in matchers and returns whether it changed. The
+// error details of a previous error ID are dropped, because they don't
+// apply to the new one. The finding is left unchanged if none of the
+// rules and built-in matchers match.
+func (c *Classifier) Classify(f *finding.Finding) bool {
+	id := c.ForFinding(f)
+	if id == "" || (f.MoreDetails != nil && f.MoreDetails.ID == id) {
+		return false
+	}
+	f.MoreDetails = &finding.ErrorDetails{ID: id}
+	return true
+}
@@ -0,0 +1,89 @@
+package errorid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/pkg/finding"
+)
+
+func TestClassifier_CustomRules(t *testing.T) {
+	c, err := NewClassifier([]Rule{
+		{ID: "custom_sanitizer", Substrings: []string{"Security Issue: Custom Sanitizer"}},
+		{ID: "assertion_low", Regexes: []string{`MY_ASSERT\(.*\) failed`}, MatchLogs: true},
+		{ID: "assertion_high", Substrings: []string{"MY_ASSERT(ptr != nullptr)"}, MatchLogs: true, Priority: 10},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		id string
+		f  *finding.Finding
+	}{
+		// Custom rules are checked before the built-in jazzer_security_issue
+		{id: "custom_sanitizer", f: &finding.Finding{Details: "Security Issue: Custom Sanitizer"}},
+		// Rules with a higher priority are checked first
+		{id: "assertion_high", f: &finding.Finding{Details: "deadly signal", Logs: []string{"MY_ASSERT(ptr != nullptr) failed"}}},
+		{id: "assertion_low", f: &finding.Finding{Details: "deadly signal", Logs: []string{"MY_ASSERT(x > 0) failed"}}},
+		// Built-in rules are used if no custom rule matches
+		{id: "deadly_signal", f: &finding.Finding{Details: "deadly signal"}},
+		{id: "", f: &finding.Finding{Details: "something unknown"}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.id, c.ForFinding(tc.f), tc.f.Details)
+	}
+}
+
+func TestClassifier_InvalidRules(t *testing.T) {
+	_, err := NewClassifier([]Rule{{Substrings: []string{"foo"}}})
+	assert.Error(t, err)
+	_, err = NewClassifier([]Rule{{ID: "foo"}})
+	assert.Error(t, err)
+	_, err = NewClassifier([]Rule{{ID: "foo", Regexes: []string{"("}}})
+	assert.Error(t, err)
+}
+
+func TestNewProjectClassifier(t *testing.T) {
+	projectDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(projectDir, "rules.yaml"), []byte(`
+rules:
+  - id: from_file
+    substrings: ["custom error"]
+`), 0o644)
+	require.NoError(t, err)
+
+	c, err := NewProjectClassifier(projectDir, []Rule{{ID: "from_config", Substrings: []string{"custom error"}}}, "rules.yaml")
+	require.NoError(t, err)
+	// Rules from the project config come first if they have the same
+	// priority as rules from the rules file
+	assert.Equal(t, "from_config", c.ForFinding(&finding.Finding{Details: "custom error"}))
+
+	c, err = NewProjectClassifier(projectDir, nil, "rules.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "from_file", c.ForFinding(&finding.Finding{Details: "custom error"}))
+
+	_, err = NewProjectClassifier(projectDir, nil, "does-not-exist.yaml")
+	assert.Error(t, err)
+}
+
+func TestClassifier_Classify(t *testing.T) {
+	c, err := NewClassifier([]Rule{{ID: "custom_error", Substrings: []string{"custom error"}}})
+	require.NoError(t, err)
+
+	f := &finding.Finding{
+		Details:     "custom error",
+		MoreDetails: &finding.ErrorDetails{ID: "deadly_signal", Name: "Deadly Signal"},
+	}
+	assert.True(t, c.Classify(f))
+	assert.Equal(t, &finding.ErrorDetails{ID: "custom_error"}, f.MoreDetails)
+	// Classifying the finding again doesn't change it
+	assert.False(t, c.Classify(f))
+
+	// Findings which don't match any rule are left unchanged
+	f = &finding.Finding{Details: "something unknown", MoreDetails: &finding.ErrorDetails{ID: "foo"}}
+	assert.False(t, c.Classify(f))
+	assert.Equal(t, "foo", f.MoreDetails.ID)
+}
@@ -596,6 +596,12 @@ func (p *parser) finalizeAndSendPendingFinding(ctx context.Context) error {
 		return err
 	}
 
+	// Extract structured information from the sanitizer report
+	p.pendingFinding.SanitizerDetails, err = sanitizer.ParseDetails(p.pendingFinding.Logs, parserOpts)
+	if err != nil {
+		return err
+	}
+
 	p.pendingFinding.MoreDetails = &finding.ErrorDetails{
 		ID: errorid.ForFinding(p.pendingFinding),
 	}
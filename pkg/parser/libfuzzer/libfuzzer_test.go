@@ -137,6 +137,10 @@ error info 2`,
 							"error info 1",
 							"error info 2",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Sanitizer: "AddressSanitizer",
+							Access:    &finding.MemoryAccess{Address: "0x00"},
+						},
 					},
 				},
 			},
@@ -190,6 +194,11 @@ SUMMARY: UndefinedBehaviorSanitizer: undefined-behavior fuzz_targets/manual.cpp:
 							"fuzz_targets/manual.cpp:6:5: runtime error: signed integer overflow: 2147483647 + 1 cannot be represented in type 'int'",
 							"SUMMARY: UndefinedBehaviorSanitizer: undefined-behavior fuzz_targets/manual.cpp:6:5 in",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Sanitizer:    "UndefinedBehaviorSanitizer",
+							UBSanMessage: "signed integer overflow: 2147483647 + 1 cannot be represented in type 'int'",
+							Summary:      "UndefinedBehaviorSanitizer: undefined-behavior fuzz_targets/manual.cpp:6:5 in",
+						},
 						StackTrace: []*stacktrace.StackFrame{
 							{
 								SourceFile: "fuzz_targets/manual.cpp",
@@ -311,6 +320,10 @@ Base64: i/SIw2hR3wI=`,
 							"AddressSanitizer:DEADLYSIGNAL",
 							"=================================================================",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Sanitizer: "AddressSanitizer",
+							Access:    &finding.MemoryAccess{Address: "0x7fffb9492184"},
+						},
 					},
 				},
 				{
@@ -330,6 +343,10 @@ Base64: i/SIw2hR3wI=`,
 							"artifact_prefix='./'; Test unit written to " + testInputFile.Name(),
 							"Base64: i/SIw2hR3wI=",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Sanitizer: "AddressSanitizer",
+							Access:    &finding.MemoryAccess{Address: "0x000000000000"},
+						},
 					},
 				},
 			},
@@ -366,6 +383,9 @@ error info 2`,
 							"error info 1",
 							"error info 2",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Sanitizer: "MemorySanitizer",
+						},
 					},
 				},
 			},
@@ -581,6 +601,9 @@ Base64: CiMKIQoDZm9vEhoaGGJeAABkZWFkYmVlZjEyMzQ1Njc4OVfHng==`, llvmDir, testInpu
 							fmt.Sprintf("artifact_prefix='./'; Test unit written to %s", testInputFile.Name()),
 							"Base64: CiMKIQoDZm9vEhoaGGJeAABkZWFkYmVlZjEyMzQ1Njc4OVfHng==",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Summary: "libFuzzer: deadly signal",
+						},
 					},
 				},
 			},
@@ -629,6 +652,9 @@ Base64: J3JycnJiYXJycnJycnJycmZvb3IAcgAAAXJyAAAAAHJycnJycnJycnJycnJycnJycnJycnJy
 							fmt.Sprintf("artifact_prefix='./'; Test unit written to %s", testInputFile.Name()),
 							"Base64: J3JycnJiYXJycnJycnJycmZvb3IAcgAAAXJyAAAAAHJycnJycnJycnJycnJycnJycnJycnJycnI=",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Summary: "libFuzzer: deadly signal",
+						},
 					},
 				},
 			},
@@ -660,6 +686,10 @@ Base64: i/SIw2hR3wI=`,
 							"[...end of report not detected...]",
 							"3280532619",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Sanitizer: "AddressSanitizer",
+							Access:    &finding.MemoryAccess{Address: "0x7fffb9492184"},
+						},
 					},
 				},
 				{
@@ -679,6 +709,10 @@ Base64: i/SIw2hR3wI=`,
 							"artifact_prefix='./'; Test unit written to " + testInputFile.Name(),
 							"Base64: i/SIw2hR3wI=",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Sanitizer: "AddressSanitizer",
+							Access:    &finding.MemoryAccess{Address: "0x000000000000"},
+						},
 					},
 				},
 			},
@@ -737,6 +771,9 @@ SUMMARY: libFuzzer: timeout`,
 							"error info 2",
 							"SUMMARY: libFuzzer: timeout",
 						},
+						SanitizerDetails: &finding.SanitizerDetails{
+							Summary: "libFuzzer: timeout",
+						},
 					},
 				},
 			},
@@ -825,6 +862,10 @@ func TestBufferOverflowCrashLogs(t *testing.T) {
 		"global_buffer_overflow",
 		expectedCrashFile.Name(),
 		testInput,
+		&finding.SanitizerDetails{
+			Sanitizer: "AddressSanitizer",
+			Access:    &finding.MemoryAccess{Address: "0x00"},
+		},
 		[]string{
 			"==8141==ERROR: AddressSanitizer: global-buffer-overflow on address 0x00",
 			"error info 1",
@@ -847,6 +888,7 @@ func TestOOMCrashLogs(t *testing.T) {
 		"out_of_memory",
 		expectedCrashFile.Name(),
 		testInput,
+		nil,
 		[]string{
 			"==18== ERROR: libFuzzer: out-of-memory (used: 251Mb; limit: 250Mb)",
 			"error info 1",
@@ -855,7 +897,7 @@ func TestOOMCrashLogs(t *testing.T) {
 		})
 }
 
-func assertCorrectCrashesParsing(t *testing.T, errorDetails, errorID, crashFile string, crashingInput []byte, logs []string) {
+func assertCorrectCrashesParsing(t *testing.T, errorDetails, errorID, crashFile string, crashingInput []byte, sanitizerDetails *finding.SanitizerDetails, logs []string) {
 	expectedReports := []*report.Report{
 		{
 			Status: report.RunStatusRunning,
@@ -868,6 +910,7 @@ func assertCorrectCrashesParsing(t *testing.T, errorDetails, errorID, crashFile
 				MoreDetails: &finding.ErrorDetails{
 					ID: errorID,
 				},
+				SanitizerDetails: sanitizerDetails,
 			},
 		},
 	}
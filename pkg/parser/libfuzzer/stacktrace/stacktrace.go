@@ -22,9 +22,8 @@ var framePatternNode = regexp.MustCompile(`\s*at\s((?P<function>\S+)\s+(\[.*\])?
 
 // This matches diagnostic messages printed by UBSan when it reports an
 // error. UBSan doesn't always print a stack trace, so we extract the
-// source file from this line.
-// TODO: The <message> part contains useful information which we should
-// store in the finding
+// source file from this line. The <message> part is returned by
+// UBSanMessage.
 var ubSanDiagPattern = regexp.MustCompile(`^(?P<source_file>\S+?):((?P<line>\d+):)?((?P<column>\d+):)? runtime error: (?P<message>.*)$`)
 
 // A StackFrame represents an element of the stack trace
@@ -71,6 +70,15 @@ func (p *parser) Parse(logs []string) ([]*StackFrame, error) {
 	return p.parseSourceLocation(logs)
 }
 
+// ParseStackTrace returns the first stack trace found in the logs. In
+// contrast to Parse, it doesn't fall back to a source location if no
+// stack trace was found, which makes it suitable for parsing the
+// additional stack traces of a sanitizer report (e.g. the stack trace
+// of the allocation of a buffer).
+func (p *parser) ParseStackTrace(logs []string) ([]*StackFrame, error) {
+	return p.parseStackTrace(logs)
+}
+
 func (p *parser) parseStackTrace(logs []string) ([]*StackFrame, error) {
 	var frames []*StackFrame
 	for _, line := range logs {
@@ -258,3 +266,17 @@ func (p *parser) sourceLocationFromLine(line string) (*StackFrame, error) {
 		Column:     uint32(column),
 	}, nil
 }
+
+// UBSanMessage returns the message of the first UBSan diagnostic in the
+// logs, for example "signed integer overflow: 2147483647 + 1 cannot be
+// represented in type 'int'", or an empty string if the logs don't
+// contain a UBSan diagnostic.
+func UBSanMessage(logs []string) string {
+	for _, line := range logs {
+		matches, found := regexutil.FindNamedGroupsMatch(ubSanDiagPattern, line)
+		if found {
+			return strings.TrimSpace(matches["message"])
+		}
+	}
+	return ""
+}
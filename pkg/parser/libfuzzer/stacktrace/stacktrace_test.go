@@ -174,3 +174,13 @@ func TestStackTrace(t *testing.T) {
 		})
 	}
 }
+
+func TestUBSanMessage(t *testing.T) {
+	logs := []string{
+		"INFO: Running with entropic power schedule (0xFF, 100).",
+		"fuzz_targets/manual.cpp:6:5: runtime error: shift exponent 32 is too large for 32-bit type 'int'",
+		"SUMMARY: UndefinedBehaviorSanitizer: undefined-behavior fuzz_targets/manual.cpp:6:5 in",
+	}
+	require.Equal(t, "shift exponent 32 is too large for 32-bit type 'int'", UBSanMessage(logs))
+	require.Empty(t, UBSanMessage(logs[:1]))
+}
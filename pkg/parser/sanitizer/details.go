@@ -0,0 +1,125 @@
+package sanitizer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+	"code-intelligence.com/cifuzz/util/regexutil"
+)
+
+var (
+	// Examples for matching strings:
+	// ==16==ERROR: AddressSanitizer: heap-use-after-free on address 0x602000000010 at pc 0x55f3 bp 0x7ffc sp 0x7ffc
+	// ==16==ERROR: AddressSanitizer: SEGV on unknown address 0x000000000000 (pc 0x55f3 bp 0x7ffc sp 0x7ffc T0)
+	sanitizerErrorPattern = regexp.MustCompile(
+		`==\d+==\s*(ERROR|WARNING):\s*(?P<sanitizer>\w+Sanitizer):`,
+	)
+	addressPattern = regexp.MustCompile(`\son (unknown )?address (?P<address>0x[0-9a-fA-F]+)`)
+	// READ of size 4 at 0x602000000010 thread T0
+	accessPattern = regexp.MustCompile(
+		`^\s*(?P<type>READ|WRITE) of size (?P<size>\d+) at (?P<address>0x[0-9a-fA-F]+)`,
+	)
+	// ==16==The signal is caused by a WRITE memory access.
+	signalAccessPattern = regexp.MustCompile(
+		`The signal is caused by a (?P<type>READ|WRITE) memory access`,
+	)
+	// previously allocated by thread T0 here:
+	allocationStackPattern = regexp.MustCompile(`^\s*(previously )?allocated by thread \S+ here:`)
+	// freed by thread T0 here:
+	freeStackPattern = regexp.MustCompile(`^\s*freed by thread \S+ here:`)
+	// SUMMARY: AddressSanitizer: heap-use-after-free /src/uaf.cpp:10:3 in main
+	summaryPattern = regexp.MustCompile(`^\s*SUMMARY:\s*(?P<summary>(?P<sanitizer>\w+):.*?)\s*$`)
+)
+
+// ParseDetails extracts structured information from the logs of a
+// sanitizer report, like the faulting memory access, the allocation and
+// free stacks, the UBSan message and the SUMMARY line. It returns nil
+// if the logs don't contain any of that.
+func ParseDetails(logs []string, opts *stacktrace.ParserOptions) (*finding.SanitizerDetails, error) {
+	var err error
+	details := &finding.SanitizerDetails{}
+	parser := stacktrace.NewParser(opts)
+
+	for i, line := range logs {
+		if matches, found := regexutil.FindNamedGroupsMatch(sanitizerErrorPattern, line); found {
+			if details.Sanitizer == "" {
+				details.Sanitizer = matches["sanitizer"]
+			}
+			if matches, found := regexutil.FindNamedGroupsMatch(addressPattern, line); found && details.Access == nil {
+				details.Access = &finding.MemoryAccess{Address: matches["address"]}
+			}
+			continue
+		}
+
+		if matches, found := regexutil.FindNamedGroupsMatch(accessPattern, line); found {
+			size, err := strconv.ParseUint(matches["size"], 10, 64)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			details.Access = &finding.MemoryAccess{
+				Address: matches["address"],
+				Size:    size,
+				Type:    matches["type"],
+			}
+			continue
+		}
+
+		if matches, found := regexutil.FindNamedGroupsMatch(signalAccessPattern, line); found {
+			if details.Access == nil {
+				details.Access = &finding.MemoryAccess{}
+			}
+			details.Access.Type = matches["type"]
+			continue
+		}
+
+		if allocationStackPattern.MatchString(line) && details.AllocationStack == nil {
+			details.AllocationStack, err = parser.ParseStackTrace(section(logs[i+1:]))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if freeStackPattern.MatchString(line) && details.FreeStack == nil {
+			details.FreeStack, err = parser.ParseStackTrace(section(logs[i+1:]))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if matches, found := regexutil.FindNamedGroupsMatch(summaryPattern, line); found && details.Summary == "" {
+			details.Summary = matches["summary"]
+			if details.Sanitizer == "" && strings.HasSuffix(matches["sanitizer"], "Sanitizer") {
+				details.Sanitizer = matches["sanitizer"]
+			}
+		}
+	}
+
+	details.UBSanMessage = stacktrace.UBSanMessage(logs)
+	if details.UBSanMessage != "" && details.Sanitizer == "" {
+		details.Sanitizer = "UndefinedBehaviorSanitizer"
+	}
+
+	if details.Sanitizer == "" && details.Access == nil && details.AllocationStack == nil &&
+		details.FreeStack == nil && details.Summary == "" {
+		return nil, nil
+	}
+	return details, nil
+}
+
+// section returns the lines up to the first empty line, which separates
+// the sections of a sanitizer report.
+func section(logs []string) []string {
+	for i, line := range logs {
+		if strings.TrimSpace(line) == "" {
+			return logs[:i]
+		}
+	}
+	return logs
+}
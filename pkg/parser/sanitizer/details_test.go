@@ -0,0 +1,85 @@
+package sanitizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"code-intelligence.com/cifuzz/pkg/finding"
+	"code-intelligence.com/cifuzz/pkg/parser/libfuzzer/stacktrace"
+)
+
+func TestParseDetails_HeapUseAfterFree(t *testing.T) {
+	// The project dir doesn't have to exist, see TestStackTrace
+	projectDir := os.TempDir()
+	src := filepath.Join(projectDir, "src", "uaf.cpp")
+	logs := strings.Split(fmt.Sprintf(`==16==ERROR: AddressSanitizer: heap-use-after-free on address 0x602000000010 at pc 0x55f3 bp 0x7ffc sp 0x7ffc
+READ of size 4 at 0x602000000010 thread T0
+    #0 0x55f3 in use(int*) %[1]s:10:3
+    #1 0x55f4 in LLVMFuzzerTestOneInput %[1]s:20:3
+
+0x602000000010 is located 0 bytes inside of 4-byte region [0x602000000010,0x602000000014)
+freed by thread T0 here:
+    #0 0x4a1d in free /llvm/compiler-rt/lib/asan/asan_malloc_linux.cpp:52:3
+    #1 0x55f5 in release(int*) %[1]s:5:3
+    #2 0x55f6 in LLVMFuzzerTestOneInput %[1]s:19:3
+
+previously allocated by thread T0 here:
+    #0 0x4a2d in malloc /llvm/compiler-rt/lib/asan/asan_malloc_linux.cpp:69:3
+    #1 0x55f7 in LLVMFuzzerTestOneInput %[1]s:18:12
+
+SUMMARY: AddressSanitizer: heap-use-after-free %[1]s:10:3 in use(int*)`, src), "\n")
+
+	details, err := ParseDetails(logs, &stacktrace.ParserOptions{ProjectDir: projectDir})
+	require.NoError(t, err)
+	require.NotNil(t, details)
+
+	assert.Equal(t, "AddressSanitizer", details.Sanitizer)
+	assert.Equal(t, &finding.MemoryAccess{Address: "0x602000000010", Size: 4, Type: finding.AccessTypeRead}, details.Access)
+	assert.Equal(t, []*stacktrace.StackFrame{
+		{SourceFile: "src/uaf.cpp", Line: 5, Column: 3, FrameNumber: 1, Function: "release"},
+		{SourceFile: "src/uaf.cpp", Line: 19, Column: 3, FrameNumber: 2, Function: "LLVMFuzzerTestOneInput"},
+	}, details.FreeStack)
+	assert.Equal(t, []*stacktrace.StackFrame{
+		{SourceFile: "src/uaf.cpp", Line: 18, Column: 12, FrameNumber: 1, Function: "LLVMFuzzerTestOneInput"},
+	}, details.AllocationStack)
+	assert.Equal(t, fmt.Sprintf("AddressSanitizer: heap-use-after-free %s:10:3 in use(int*)", src), details.Summary)
+	assert.Empty(t, details.UBSanMessage)
+}
+
+func TestParseDetails_SEGV(t *testing.T) {
+	logs := []string{
+		"==16==ERROR: AddressSanitizer: SEGV on unknown address 0x000000000010 (pc 0x55f3 bp 0x7ffc sp 0x7ffc T0)",
+		"==16==The signal is caused by a WRITE memory access.",
+		"==16==Hint: address points to the zero page.",
+	}
+	details, err := ParseDetails(logs, &stacktrace.ParserOptions{ProjectDir: os.TempDir()})
+	require.NoError(t, err)
+	require.NotNil(t, details)
+	assert.Equal(t, &finding.MemoryAccess{Address: "0x000000000010", Type: finding.AccessTypeWrite}, details.Access)
+}
+
+func TestParseDetails_UBSan(t *testing.T) {
+	logs := []string{
+		"src/overflow.cpp:6:5: runtime error: signed integer overflow: 2147483647 + 1 cannot be represented in type 'int'",
+		"SUMMARY: UndefinedBehaviorSanitizer: undefined-behavior src/overflow.cpp:6:5 in",
+	}
+	details, err := ParseDetails(logs, &stacktrace.ParserOptions{ProjectDir: os.TempDir()})
+	require.NoError(t, err)
+	assert.Equal(t, &finding.SanitizerDetails{
+		Sanitizer:    "UndefinedBehaviorSanitizer",
+		UBSanMessage: "signed integer overflow: 2147483647 + 1 cannot be represented in type 'int'",
+		Summary:      "UndefinedBehaviorSanitizer: undefined-behavior src/overflow.cpp:6:5 in",
+	}, details)
+}
+
+func TestParseDetails_NoSanitizerReport(t *testing.T) {
+	details, err := ParseDetails([]string{"== Java Exception: java.lang.NullPointerException"}, &stacktrace.ParserOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, details)
+}
@@ -53,6 +53,9 @@ type RunnerOptions struct {
 	Timeout            time.Duration
 	UseMinijail        bool
 	Verbose            bool
+	// The directory in which the fuzz target is executed, the current
+	// working directory if empty
+	WorkDir string
 }
 
 func (options *RunnerOptions) ValidateOptions() error {
@@ -199,6 +202,7 @@ func (r *Runner) RunLibfuzzerAndReport(ctx context.Context, args []string, env [
 	}
 	defer cancelCmdCtx()
 	r.cmd = executil.CommandContext(cmdCtx, args[0], args[1:]...)
+	r.cmd.Dir = r.WorkDir
 	r.cmd.Env, err = envutil.Copy(os.Environ(), env)
 	if err != nil {
 		return err
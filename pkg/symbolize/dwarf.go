@@ -0,0 +1,151 @@
+package symbolize
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// DWARFSymbolizer resolves locations via the DWARF debug information
+// of ELF modules. In contrast to LLVMSymbolizer, it doesn't require any
+// external tools, but doesn't support split DWARF.
+type DWARFSymbolizer struct{}
+
+func (s *DWARFSymbolizer) Symbolize(module string, offsets []uint64) ([]*Location, error) {
+	file, err := elf.Open(module)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open %s", module)
+	}
+	defer file.Close()
+
+	data, err := file.DWARF()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read debug information of %s", module)
+	}
+
+	table, err := newDWARFTable(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read debug information of %s", module)
+	}
+
+	locations := make([]*Location, len(offsets))
+	for i, offset := range offsets {
+		locations[i] = table.lookup(offset)
+	}
+	return locations, nil
+}
+
+type lineRow struct {
+	address uint64
+	file    string
+	line    int
+	column  int
+	// Whether this row marks the end of a sequence, i.e. the address is
+	// the first one after the sequence
+	endSequence bool
+}
+
+type function struct {
+	name      string
+	low, high uint64
+}
+
+// dwarfTable holds the line table rows and the functions of a module,
+// sorted by address.
+type dwarfTable struct {
+	rows      []lineRow
+	functions []function
+}
+
+func newDWARFTable(data *dwarf.Data) (*dwarfTable, error) {
+	t := &dwarfTable{}
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if entry == nil {
+			break
+		}
+
+		switch entry.Tag {
+		case dwarf.TagCompileUnit:
+			lineReader, err := data.LineReader(entry)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if lineReader == nil {
+				continue
+			}
+			var le dwarf.LineEntry
+			for {
+				err := lineReader.Next(&le)
+				if err != nil {
+					break
+				}
+				row := lineRow{address: le.Address, line: le.Line, column: le.Column, endSequence: le.EndSequence}
+				if le.File != nil {
+					row.file = le.File.Name
+				}
+				t.rows = append(t.rows, row)
+			}
+
+		case dwarf.TagSubprogram:
+			name, _ := entry.Val(dwarf.AttrName).(string)
+			if linkageName, ok := entry.Val(dwarf.AttrLinkageName).(string); ok && name == "" {
+				name = linkageName
+			}
+			if name == "" {
+				continue
+			}
+			ranges, err := data.Ranges(entry)
+			if err != nil {
+				continue
+			}
+			for _, r := range ranges {
+				t.functions = append(t.functions, function{name: name, low: r[0], high: r[1]})
+			}
+		}
+	}
+
+	// If a sequence ends at the address at which another one starts,
+	// the end-of-sequence row has to come first
+	sort.SliceStable(t.rows, func(i, j int) bool {
+		if t.rows[i].address == t.rows[j].address {
+			return t.rows[i].endSequence && !t.rows[j].endSequence
+		}
+		return t.rows[i].address < t.rows[j].address
+	})
+	sort.SliceStable(t.functions, func(i, j int) bool {
+		return t.functions[i].low < t.functions[j].low
+	})
+	return t, nil
+}
+
+func (t *dwarfTable) lookup(address uint64) *Location {
+	// Find the last row with an address lower or equal to the address
+	i := sort.Search(len(t.rows), func(i int) bool {
+		return t.rows[i].address > address
+	}) - 1
+	if i < 0 || t.rows[i].endSequence || t.rows[i].line == 0 {
+		return nil
+	}
+	row := t.rows[i]
+	loc := &Location{File: row.file, Line: uint32(row.line), Column: uint32(row.column)}
+
+	// Find the innermost function containing the address, which is the
+	// one with the highest start address
+	j := sort.Search(len(t.functions), func(j int) bool {
+		return t.functions[j].low > address
+	}) - 1
+	for ; j >= 0; j-- {
+		if t.functions[j].low <= address && address < t.functions[j].high {
+			loc.Function = t.functions[j].name
+			break
+		}
+	}
+	return loc
+}
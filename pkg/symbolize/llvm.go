@@ -0,0 +1,112 @@
+package symbolize
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/pkg/log"
+)
+
+// LLVMSymbolizer resolves locations via llvm-symbolizer.
+type LLVMSymbolizer struct {
+	Path string
+}
+
+func (s *LLVMSymbolizer) Symbolize(module string, offsets []uint64) ([]*Location, error) {
+	var input bytes.Buffer
+	for _, offset := range offsets {
+		fmt.Fprintf(&input, "0x%x\n", offset)
+	}
+	cmd := exec.Command(s.Path, "--obj="+module, "--no-inlines", "--demangle")
+	cmd.Stdin = &input
+	log.Debugf("Command: %s", cmd.String())
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, errors.Errorf("llvm-symbolizer failed: %v\n%s", err, exitErr.Stderr)
+		}
+		return nil, errors.WithStack(err)
+	}
+	return parseLLVMSymbolizerOutput(output, len(offsets))
+}
+
+// parseLLVMSymbolizerOutput parses the output of llvm-symbolizer, which
+// consists of a block of a function name line and a location line per
+// address, separated by empty lines:
+//
+//	foo
+//	/src/foo.c:1:16
+//
+//	??
+//	??:0:0
+func parseLLVMSymbolizerOutput(output []byte, numAddresses int) ([]*Location, error) {
+	var blocks [][]string
+	var block []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	if len(blocks) != numAddresses {
+		return nil, errors.Errorf("Unexpected output of llvm-symbolizer: expected %d locations, got %d", numAddresses, len(blocks))
+	}
+
+	locations := make([]*Location, numAddresses)
+	for i, block := range blocks {
+		if len(block) < 2 {
+			continue
+		}
+		loc := parseLocation(block[1])
+		if loc == nil {
+			continue
+		}
+		if block[0] != "??" {
+			loc.Function = block[0]
+		}
+		locations[i] = loc
+	}
+	return locations, nil
+}
+
+// parseLocation parses a location of the form file:line[:column]. It
+// returns nil if the location is unknown.
+func parseLocation(s string) *Location {
+	parts := strings.Split(s, ":")
+	// The file name itself can contain colons (e.g. on Windows), so we
+	// parse the line and column from the end
+	var numbers []uint32
+	for len(parts) > 1 && len(numbers) < 2 {
+		n, err := strconv.ParseUint(parts[len(parts)-1], 10, 32)
+		if err != nil {
+			break
+		}
+		numbers = append([]uint32{uint32(n)}, numbers...)
+		parts = parts[:len(parts)-1]
+	}
+	file := strings.Join(parts, ":")
+	if file == "??" || file == "" || len(numbers) == 0 || numbers[0] == 0 {
+		return nil
+	}
+	loc := &Location{File: file, Line: numbers[0]}
+	if len(numbers) == 2 {
+		loc.Column = numbers[1]
+	}
+	return loc
+}
@@ -0,0 +1,161 @@
+// Package symbolize resolves the unsymbolized stack frames in the logs
+// of findings, which are printed by the sanitizers if llvm-symbolizer
+// was not available or the binary was stripped during the run.
+package symbolize
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"code-intelligence.com/cifuzz/util/regexutil"
+)
+
+// Examples for matching strings:
+//
+//	#0 0x55a0e3c5c1b2  (/out/my_fuzz_test+0x1231b2) (BuildId: 4c1f...)
+//	#1 0x55a0e3c5c1b2 in LLVMFuzzerTestOneInput (/out/my_fuzz_test+0x1231b2)
+var rawFramePattern = regexp.MustCompile(
+	`^(?P<prefix>\s*#(?P<frame_number>\d+)\s+(?P<pc>0x[0-9a-fA-F]+))\s+(in\s+(?P<function>.+?)\s+)?\((?P<module>[^()+]+)\+(?P<offset>0x[0-9a-fA-F]+)\)(?P<suffix>.*)$`)
+
+// A Location is a source location of a machine code address.
+type Location struct {
+	Function string
+	File     string
+	Line     uint32
+	Column   uint32
+}
+
+// A Symbolizer resolves offsets in a module (an executable or shared
+// library) to source locations.
+type Symbolizer interface {
+	// Symbolize returns the locations of the offsets in the module.
+	// The result has the same length as the offsets, with nil entries
+	// for offsets which couldn't be resolved.
+	Symbolize(module string, offsets []uint64) ([]*Location, error)
+}
+
+// A RawFrame is a stack frame in the logs which was printed without
+// source location.
+type RawFrame struct {
+	// The index of the log line
+	LogIndex    int
+	FrameNumber uint32
+	Function    string
+	Module      string
+	Offset      uint64
+
+	prefix string
+	suffix string
+}
+
+// ParseRawFrames returns the stack frames in the logs which don't have
+// a source location.
+func ParseRawFrames(logs []string) ([]*RawFrame, error) {
+	var frames []*RawFrame
+	for i, line := range logs {
+		matches, found := regexutil.FindNamedGroupsMatch(rawFramePattern, line)
+		if !found {
+			continue
+		}
+		frameNumber, err := strconv.ParseUint(matches["frame_number"], 10, 32)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		offset, err := strconv.ParseUint(matches["offset"], 0, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		frames = append(frames, &RawFrame{
+			LogIndex:    i,
+			FrameNumber: uint32(frameNumber),
+			Function:    matches["function"],
+			Module:      matches["module"],
+			Offset:      offset,
+			prefix:      matches["prefix"],
+			suffix:      matches["suffix"],
+		})
+	}
+	return frames, nil
+}
+
+// String returns the frame with the location in the format printed by
+// the sanitizers when the frame is symbolized.
+func (f *RawFrame) String(loc *Location) string {
+	function := loc.Function
+	if function == "" {
+		function = f.Function
+	}
+	if function == "" {
+		function = "??"
+	}
+	s := fmt.Sprintf("%s in %s %s:%d", f.prefix, function, loc.File, loc.Line)
+	if loc.Column != 0 {
+		s += fmt.Sprintf(":%d", loc.Column)
+	}
+	return s
+}
+
+// Result describes the result of symbolizing logs.
+type Result struct {
+	Logs []string
+	// The number of frames without source location
+	NumRawFrames int
+	// The number of frames which were symbolized
+	NumSymbolized int
+}
+
+// Logs symbolizes the frames without source location in the logs and
+// returns the logs with the symbolized frames. The logs which are
+// passed in are not modified. resolveModule maps the module paths from
+// the logs, which are paths on the system the fuzz test was run on, to
+// paths on this system. It returns an empty string if the module can't
+// be found, in which case the frames of that module are kept as they
+// are.
+func Logs(logs []string, symbolizer Symbolizer, resolveModule func(string) string) (*Result, error) {
+	frames, err := ParseRawFrames(logs)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{
+		Logs:         append([]string{}, logs...),
+		NumRawFrames: len(frames),
+	}
+
+	// Symbolize the frames of each module in a single batch
+	var modules []string
+	framesByModule := make(map[string][]*RawFrame)
+	for _, frame := range frames {
+		if _, ok := framesByModule[frame.Module]; !ok {
+			modules = append(modules, frame.Module)
+		}
+		framesByModule[frame.Module] = append(framesByModule[frame.Module], frame)
+	}
+
+	for _, module := range modules {
+		path := resolveModule(module)
+		if path == "" {
+			continue
+		}
+		moduleFrames := framesByModule[module]
+		var offsets []uint64
+		for _, frame := range moduleFrames {
+			offsets = append(offsets, frame.Offset)
+		}
+		locations, err := symbolizer.Symbolize(path, offsets)
+		if err != nil {
+			return nil, err
+		}
+		for i, loc := range locations {
+			if loc == nil || loc.File == "" {
+				continue
+			}
+			frame := moduleFrames[i]
+			res.Logs[frame.LogIndex] = frame.String(loc)
+			res.NumSymbolized++
+		}
+	}
+	return res, nil
+}
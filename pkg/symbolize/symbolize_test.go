@@ -0,0 +1,134 @@
+package symbolize
+
+import (
+	"debug/elf"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSource = `int foo(int x) {
+  return x * 2;
+}
+
+int main(int argc, char **argv) {
+  return foo(argc);
+}
+`
+
+// buildTestBinary compiles the test source with debug information and
+// returns the path of the binary and the address of the function foo.
+func buildTestBinary(t *testing.T) (string, uint64) {
+	if runtime.GOOS != "linux" {
+		t.Skip("The test binary is only built on Linux")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("No C compiler found")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "test.c")
+	err = os.WriteFile(src, []byte(testSource), 0o644)
+	require.NoError(t, err)
+	binary := filepath.Join(dir, "test")
+	out, err := exec.Command(cc, "-g", "-O0", "-o", binary, src).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	file, err := elf.Open(binary)
+	require.NoError(t, err)
+	defer file.Close()
+	symbols, err := file.Symbols()
+	require.NoError(t, err)
+	for _, symbol := range symbols {
+		if symbol.Name == "foo" {
+			return binary, symbol.Value
+		}
+	}
+	require.FailNow(t, "Symbol foo not found")
+	return "", 0
+}
+
+func TestParseRawFrames(t *testing.T) {
+	logs := []string{
+		"==16==ERROR: AddressSanitizer: heap-use-after-free on address 0x602000000010",
+		"    #0 0x55a0e3c5c1b2  (/out/my_fuzz_test+0x1231b2) (BuildId: 4c1f)",
+		"    #1 0x55a0e3c5c1c3 in LLVMFuzzerTestOneInput (/out/my_fuzz_test+0x1231c3)",
+		"    #2 0x55a0e3c5c1d4 in main /src/main.cpp:5:3",
+	}
+	frames, err := ParseRawFrames(logs)
+	require.NoError(t, err)
+	require.Len(t, frames, 2)
+
+	assert.Equal(t, 1, frames[0].LogIndex)
+	assert.Equal(t, uint32(0), frames[0].FrameNumber)
+	assert.Equal(t, "/out/my_fuzz_test", frames[0].Module)
+	assert.Equal(t, uint64(0x1231b2), frames[0].Offset)
+	assert.Equal(t, "", frames[0].Function)
+
+	assert.Equal(t, uint32(1), frames[1].FrameNumber)
+	assert.Equal(t, "LLVMFuzzerTestOneInput", frames[1].Function)
+
+	assert.Equal(t, "    #0 0x55a0e3c5c1b2 in foo /src/foo.c:1:16",
+		frames[0].String(&Location{Function: "foo", File: "/src/foo.c", Line: 1, Column: 16}))
+	assert.Equal(t, "    #1 0x55a0e3c5c1c3 in LLVMFuzzerTestOneInput /src/fuzz.c:7",
+		frames[1].String(&Location{File: "/src/fuzz.c", Line: 7}))
+}
+
+func TestParseLLVMSymbolizerOutput(t *testing.T) {
+	output := "foo\n/src/foo.c:1:16\n\n??\n??:0:0\n\nbar\nC:\\src\\bar.c:7\n\n"
+	locations, err := parseLLVMSymbolizerOutput([]byte(output), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []*Location{
+		{Function: "foo", File: "/src/foo.c", Line: 1, Column: 16},
+		nil,
+		{Function: "bar", File: "C:\\src\\bar.c", Line: 7},
+	}, locations)
+
+	_, err = parseLLVMSymbolizerOutput([]byte(output), 2)
+	assert.Error(t, err)
+}
+
+func testSymbolizer(t *testing.T, symbolizer Symbolizer) {
+	binary, fooAddress := buildTestBinary(t)
+	src := filepath.Join(filepath.Dir(binary), "test.c")
+
+	logs := []string{
+		"==16==ERROR: AddressSanitizer: SEGV on unknown address 0x000000000000",
+		"    #0 0x55a0e3c5c1b2  (/original/build/test+0x" + strconv.FormatUint(fooAddress, 16) + ")",
+		"    #1 0x55a0e3c5c1c3  (/usr/lib/libc.so.6+0x1234)",
+	}
+	res, err := Logs(logs, symbolizer, func(module string) string {
+		if filepath.Base(module) == "test" {
+			return binary
+		}
+		return ""
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.NumRawFrames)
+	assert.Equal(t, 1, res.NumSymbolized)
+	assert.Regexp(t, `^    #0 0x55a0e3c5c1b2 in foo \S*test\.c:1(:\d+)?$`, res.Logs[1])
+	assert.Contains(t, res.Logs[1], filepath.Base(src))
+	// Frames of modules which can't be resolved are kept as they are
+	assert.Equal(t, logs[2], res.Logs[2])
+	// The original logs are not modified
+	assert.Contains(t, logs[1], "/original/build/test+0x")
+}
+
+func TestDWARFSymbolizer(t *testing.T) {
+	testSymbolizer(t, &DWARFSymbolizer{})
+}
+
+func TestLLVMSymbolizer(t *testing.T) {
+	path, err := exec.LookPath("llvm-symbolizer")
+	if err != nil {
+		t.Skip("llvm-symbolizer not found")
+	}
+	testSymbolizer(t, &LLVMSymbolizer{Path: path})
+}
@@ -1,12 +1,16 @@
 package vcs
 
 import (
+	"bytes"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
 
 	"github.com/pkg/errors"
 
 	"code-intelligence.com/cifuzz/pkg/log"
+	"code-intelligence.com/cifuzz/util/archiveutil"
 )
 
 // GitCommit returns the full SHA of the current commit if the working directory is contained in a Git repository.
@@ -41,3 +45,135 @@ func GitIsDirty() bool {
 	}
 	return len(strings.TrimSpace(string(commit))) != 0
 }
+
+// GitIsRef returns true if and only if ref can be resolved to a commit
+// in the Git repository containing dir.
+func GitIsRef(dir, ref string) bool {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+// GitExportDir writes the contents of the directory path, as of the
+// commit ref, to the directory dest. The path is interpreted relative
+// to dir, which must be contained in a Git repository. If the path
+// does not exist in ref, an error wrapping os.ErrNotExist is returned.
+func GitExportDir(dir, ref, path, dest string) error {
+	cmd := exec.Command("git", "cat-file", "-e", ref+":./"+path)
+	cmd.Dir = dir
+	err := cmd.Run()
+	if err != nil {
+		return errors.Wrapf(os.ErrNotExist, "%s does not exist in %s", path, ref)
+	}
+
+	var stderr bytes.Buffer
+	cmd = exec.Command("git", "archive", "--format=tar", ref, "--", path)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	log.Debugf("Command: %s", cmd.String())
+	err = cmd.Start()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	untarErr := archiveutil.Untar(out, dest)
+	// Consume the remaining output to not block git on a full pipe
+	_, _ = io.Copy(io.Discard, out)
+	err = cmd.Wait()
+	if err != nil {
+		return errors.Wrapf(err, "git archive failed: %s", stderr.String())
+	}
+	return untarErr
+}
+
+// GitTopLevel returns the absolute path of the top-level directory of
+// the Git repository containing dir.
+func GitTopLevel(dir string) (string, error) {
+	return gitOutput(dir, "rev-parse", "--show-toplevel")
+}
+
+// GitRevParse returns the full SHA of the commit which ref resolves to
+// in the Git repository containing dir.
+func GitRevParse(dir, ref string) (string, error) {
+	return gitOutput(dir, "rev-parse", "--verify", ref+"^{commit}")
+}
+
+// GitWorktreeAdd creates a new worktree at path with ref checked out as
+// detached HEAD.
+func GitWorktreeAdd(dir, path, ref string) error {
+	_, err := gitOutput(dir, "worktree", "add", "--detach", path, ref)
+	return err
+}
+
+// GitCheckout checks out the specified commit with a detached HEAD in
+// the worktree containing dir.
+func GitCheckout(dir, ref string) error {
+	_, err := gitOutput(dir, "checkout", "--detach", ref)
+	return err
+}
+
+// GitWorktreeRemove removes the worktree at path, even if it contains
+// modified or untracked files.
+func GitWorktreeRemove(dir, path string) error {
+	_, err := gitOutput(dir, "worktree", "remove", "--force", path)
+	return err
+}
+
+// GitBisectStart starts a bisect session in the worktree containing dir
+// and checks out the first commit to test. If the first bad commit is
+// already known (because bad is a child of good), its full SHA is
+// returned, else an empty string.
+func GitBisectStart(dir, bad, good string) (string, error) {
+	out, err := gitOutput(dir, "bisect", "start", bad, good)
+	if err != nil {
+		return "", err
+	}
+	return parseBisectOutput(out)
+}
+
+// GitBisectMark marks the currently checked out commit with the
+// specified term ("good", "bad" or "skip") and checks out the next
+// commit to test. If the first bad commit was found, its full SHA is
+// returned, else an empty string.
+func GitBisectMark(dir, term string) (string, error) {
+	out, err := gitOutput(dir, "bisect", term)
+	if err != nil && !strings.Contains(out, "There are only 'skip'ped commits left to test.") {
+		return "", err
+	}
+	return parseBisectOutput(out)
+}
+
+func parseBisectOutput(out string) (string, error) {
+	if strings.Contains(out, "There are only 'skip'ped commits left to test.") {
+		return "", errors.Errorf("The first bad commit could not be determined because commits had to be skipped:\n%s", out)
+	}
+	firstLine, _, _ := strings.Cut(out, "\n")
+	if strings.HasSuffix(firstLine, " is the first bad commit") {
+		return strings.TrimSuffix(firstLine, " is the first bad commit"), nil
+	}
+	return "", nil
+}
+
+// GitBisectReset ends the bisect session in the worktree containing dir.
+func GitBisectReset(dir string) error {
+	_, err := gitOutput(dir, "bisect", "reset")
+	return err
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	log.Debugf("Command: %s", cmd.String())
+	out, err := cmd.Output()
+	if err != nil {
+		// Also return the output, some git commands print relevant
+		// information to stdout on failure
+		return strings.TrimSpace(string(out)), errors.Wrapf(err, "%s failed: %s", cmd.String(), stderr.String())
+	}
+	return strings.TrimSpace(string(out)), nil
+}
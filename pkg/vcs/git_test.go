@@ -73,6 +73,72 @@ func TestGitIsDirty(t *testing.T) {
 	require.True(t, vcs.GitIsDirty())
 }
 
+func TestGitExportDir(t *testing.T) {
+	repo := createGitRepoWithCommits(t)
+	defer os.RemoveAll(repo)
+
+	require.True(t, vcs.GitIsRef(repo, "main"))
+	require.False(t, vcs.GitIsRef(repo, "does-not-exist"))
+
+	err := os.MkdirAll(filepath.Join(repo, "dir"), 0o755)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(repo, "dir", "file"), []byte("content"), 0o644)
+	require.NoError(t, err)
+	runGit(t, repo, "add", "dir")
+	runGit(t, repo, "commit", "-m", "Add dir")
+
+	dest := testutil.MkdirTemp(t, "", "git-export-*")
+	err = vcs.GitExportDir(repo, "main", "dir", dest)
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(dest, "dir", "file"))
+	require.NoError(t, err)
+	require.Equal(t, "content", string(content))
+
+	// The directory didn't exist in the previous commit
+	err = vcs.GitExportDir(repo, "main~", "dir", dest)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGitBisect(t *testing.T) {
+	repo := testutil.MkdirTemp(t, "", "git-bisect-test-*")
+	runGit(t, repo, "init")
+	runGit(t, repo, "config", "user.email", "you@example.com")
+	runGit(t, repo, "config", "user.name", "Your Name")
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		err := os.WriteFile(filepath.Join(repo, "file"), []byte(content), 0o644)
+		require.NoError(t, err)
+		runGit(t, repo, "add", "file")
+		runGit(t, repo, "commit", "-m", "Commit "+content)
+	}
+	expectedFirstBadCommit, err := vcs.GitRevParse(repo, "HEAD~2")
+	require.NoError(t, err)
+
+	worktree := filepath.Join(testutil.MkdirTemp(t, "", "git-bisect-worktree-*"), "worktree")
+	err = vcs.GitWorktreeAdd(repo, worktree, "HEAD")
+	require.NoError(t, err)
+
+	// Commits with content 3 and higher are bad
+	firstBadCommit, err := vcs.GitBisectStart(worktree, "HEAD", "HEAD~4")
+	require.NoError(t, err)
+	for firstBadCommit == "" {
+		content, err := os.ReadFile(filepath.Join(worktree, "file"))
+		require.NoError(t, err)
+		term := "good"
+		if string(content) >= "3" {
+			term = "bad"
+		}
+		firstBadCommit, err = vcs.GitBisectMark(worktree, term)
+		require.NoError(t, err)
+	}
+	require.Equal(t, expectedFirstBadCommit, firstBadCommit)
+
+	err = vcs.GitBisectReset(worktree)
+	require.NoError(t, err)
+	err = vcs.GitWorktreeRemove(repo, worktree)
+	require.NoError(t, err)
+	require.NoDirExists(t, worktree)
+}
+
 func createGitRepoWithCommits(t *testing.T) string {
 	t.Helper()
 
@@ -66,6 +66,10 @@ func Untar(r io.Reader, dest string) error {
 			targetpath := filepath.Join(dest, header.Linkname)
 			linkpath := filepath.Join(dest, header.Name)
 			hardlinks[linkpath] = targetpath
+		case tar.TypeXGlobalHeader:
+			// Global PAX headers (as written by e.g. git archive) only
+			// contain metadata, there is nothing to extract
+			continue
 		default:
 			return errors.Errorf("unsupported file type: %d", header.Typeflag)
 		}
@@ -129,7 +133,9 @@ func Unzip(src, dest string) error {
 				return errors.WithStack(err)
 			}
 		} else {
-			err = os.MkdirAll(filepath.Dir(path), f.Mode())
+			// The parent directories must be traversable, so they are
+			// not created with the mode of the file
+			err = os.MkdirAll(filepath.Dir(path), 0755)
 			if err != nil {
 				return errors.WithStack(err)
 			}